@@ -0,0 +1,127 @@
+// Package sunatlib provides local archival of signed documents
+package sunatlib
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// documentNameXML maps the elements of a UBL document used by DeriveFileName
+type documentNameXML struct {
+	ID       string `xml:"ID"`
+	Supplier struct {
+		AccountID string `xml:"CustomerAssignedAccountID"`
+		PartyID   string `xml:"Party>PartyIdentification>ID"`
+	} `xml:"AccountingSupplierParty"`
+	DespatchSupplier struct {
+		PartyID string `xml:"Party>PartyIdentification>ID"`
+	} `xml:"DespatchSupplierParty"`
+}
+
+// DeriveFileName returns the SUNAT file name, without extension, of a UBL document from its
+// content: the issuer RUC, the document type and its identifier (e.g., 20123456789-01-F001-1
+// or 20123456789-RA-20260427-001)
+func DeriveFileName(xmlContent []byte) (string, error) {
+	documentType, issuerRUC, id, err := parseDocumentIdentity(xmlContent)
+	if err != nil {
+		return "", err
+	}
+
+	switch documentType {
+	case "RA", "RC":
+		// Summaries and voided documents carry the type in their identifier
+		return fmt.Sprintf("%s-%s", issuerRUC, id), nil
+	default:
+		serie, numero, err := utils.SplitSerieNumero(id)
+		if err != nil {
+			return "", err
+		}
+		return BuildDocumentName(issuerRUC, documentType, utils.JoinSerieNumero(serie, numero)), nil
+	}
+}
+
+// parseDocumentIdentity returns the document type, the issuer RUC and the cbc:ID of a UBL document
+func parseDocumentIdentity(xmlContent []byte) (documentType, issuerRUC, id string, err error) {
+	documentType, err = DetectDocumentType(xmlContent)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(xmlContent))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	var doc documentNameXML
+	if err := decoder.Decode(&doc); err != nil {
+		return "", "", "", fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	id = strings.TrimSpace(doc.ID)
+	if id == "" {
+		return "", "", "", fmt.Errorf("document without cbc:ID")
+	}
+
+	issuerRUC = strings.TrimSpace(doc.Supplier.PartyID)
+	if issuerRUC == "" {
+		issuerRUC = strings.TrimSpace(doc.Supplier.AccountID)
+	}
+	if issuerRUC == "" {
+		issuerRUC = strings.TrimSpace(doc.DespatchSupplier.PartyID)
+	}
+	if !utils.ValidateRUC(issuerRUC) {
+		return "", "", "", fmt.Errorf("invalid issuer RUC in document %s: %q", id, issuerRUC)
+	}
+
+	return documentType, issuerRUC, id, nil
+}
+
+// PackSignedDocuments bundles signed documents into a single ZIP for local archival, each one
+// stored under the name given by DeriveFileName. The keys of docs only identify the documents
+// in errors; entries are written in key order. Use SendPack to transmit documents instead
+func PackSignedDocuments(docs map[string][]byte) ([]byte, error) {
+	if len(docs) == 0 {
+		return nil, ErrEmptyPack
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	seen := make(map[string]string, len(docs))
+	for _, key := range keys {
+		name, err := DeriveFileName(docs[key])
+		if err != nil {
+			return nil, fmt.Errorf("document %s: %w", key, err)
+		}
+		if previous, ok := seen[name]; ok {
+			return nil, fmt.Errorf("document %s: duplicate of %s (%s)", key, previous, name)
+		}
+		seen[name] = key
+
+		fw, err := zipWriter.Create(name + ".xml")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(docs[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package sunatlib
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestArchiveDocuments returns an invoice, a boleta and a voided documents communication
+func newTestArchiveDocuments(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	invoice := newTestInvoice()
+	invoiceXML, err := GenerateInvoiceXML(invoice)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	boleta := newTestInvoice()
+	boleta.DocumentType = "03"
+	boleta.Series = "B001"
+	boleta.Number = "00000007"
+	boleta.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+	boletaXML, err := GenerateInvoiceXML(boleta)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	voidedXML, err := client.GenerateVoidedDocumentsXML(newTestVoidedDocumentsRequest())
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+
+	return map[string][]byte{
+		"factura.xml": invoiceXML,
+		"boleta.xml":  boletaXML,
+		"baja.xml":    voidedXML,
+	}
+}
+
+func TestPackSignedDocuments(t *testing.T) {
+	docs := newTestArchiveDocuments(t)
+
+	archive, err := PackSignedDocuments(docs)
+	if err != nil {
+		t.Fatalf("PackSignedDocuments() error = %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("archive is not a ZIP: %v", err)
+	}
+
+	// Entries follow the order of the keys
+	wantEntries := []struct {
+		name string
+		key  string
+	}{
+		{testRUC + "-RA-20260427-001.xml", "baja.xml"},
+		{"20000000001-03-B001-00000007.xml", "boleta.xml"},
+		{"20000000001-01-F001-1.xml", "factura.xml"},
+	}
+	if len(reader.File) != len(wantEntries) {
+		t.Fatalf("archive has %d entries, want %d", len(reader.File), len(wantEntries))
+	}
+	for i, want := range wantEntries {
+		entry := reader.File[i]
+		if entry.Name != want.name {
+			t.Errorf("entry %d = %q, want %q", i, entry.Name, want.name)
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", entry.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if !bytes.Equal(content, docs[want.key]) {
+			t.Errorf("%s does not hold %s", entry.Name, want.key)
+		}
+	}
+}
+
+func TestPackSignedDocuments_Invalid(t *testing.T) {
+	if _, err := PackSignedDocuments(nil); !errors.Is(err, ErrEmptyPack) {
+		t.Errorf("error = %v, want ErrEmptyPack", err)
+	}
+
+	docs := newTestArchiveDocuments(t)
+	docs["factura-copia.xml"] = docs["factura.xml"]
+	if _, err := PackSignedDocuments(docs); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("error = %v, want duplicate document error", err)
+	}
+
+	docs = map[string][]byte{"otro.xml": []byte("<Order/>")}
+	if _, err := PackSignedDocuments(docs); err == nil || !strings.Contains(err.Error(), "otro.xml") {
+		t.Errorf("error = %v, want an error naming the document", err)
+	}
+}
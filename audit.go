@@ -0,0 +1,167 @@
+// Package sunatlib provides JSON audit records of SUNAT responses for document stores
+package sunatlib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// Audit states derived from a response
+const (
+	AuditStateAccepted     = "ACEPTADO"      // CDR accepted without observations
+	AuditStateObserved     = "OBSERVADO"     // CDR accepted with observations
+	AuditStateRejected     = "RECHAZADO"     // CDR rejected (codes 2000-3999)
+	AuditStateException    = "EXCEPCION"     // CDR with an exception code (0100-1999)
+	AuditStateInProgress   = "EN_PROCESO"    // Ticket still being processed
+	AuditStateSent         = "ENVIADO"       // Accepted without a CDR to inspect
+	AuditStateError        = "ERROR"         // SOAP fault or failed request
+	AuditStateUnrecognized = "NO_RECONOCIDO" // Response could not be interpreted
+)
+
+// responseAudit is the JSON audit record of a SUNAT response. It only holds data returned by
+// SUNAT, redacted of anything that looks like a credential
+type responseAudit struct {
+	Operation         string     `json:"operation"`           // sendBill or getStatus
+	Timestamp         *time.Time `json:"timestamp,omitempty"` // When the response was received
+	Success           bool       `json:"success"`
+	State             string     `json:"state"` // One of the AuditState constants
+	Message           string     `json:"message,omitempty"`
+	FaultCode         string     `json:"fault_code,omitempty"`
+	FaultDetail       string     `json:"fault_detail,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	Attempts          int        `json:"attempts,omitempty"`
+	Ticket            string     `json:"ticket,omitempty"`
+	StatusCode        string     `json:"status_code,omitempty"`
+	StatusDescription string     `json:"status_description,omitempty"`
+	ProcessDate       *time.Time `json:"process_date,omitempty"`
+	CDR               *cdrAudit  `json:"cdr,omitempty"`
+	CDRBase64         string     `json:"cdr_base64,omitempty"` // CDR ZIP as returned by SUNAT
+}
+
+// cdrAudit holds the CDR fields of an audit record
+type cdrAudit struct {
+	ID           string           `json:"id"`
+	DocumentID   string           `json:"document_id"`
+	ResponseCode string           `json:"response_code"`
+	Description  string           `json:"description,omitempty"`
+	IssueDate    string           `json:"issue_date,omitempty"`
+	ResponseDate string           `json:"response_date,omitempty"`
+	Observations []CDRObservation `json:"observations,omitempty"`
+}
+
+// setCDR adds the CDR to the audit record and returns the state derived from it, or an empty
+// string when there is no CDR or it cannot be parsed
+func (a *responseAudit) setCDR(applicationResponse []byte) string {
+	if len(applicationResponse) == 0 {
+		return ""
+	}
+	a.CDRBase64 = base64.StdEncoding.EncodeToString(applicationResponse)
+
+	cdr, err := ParseCDR(applicationResponse)
+	if err != nil {
+		return ""
+	}
+	a.CDR = &cdrAudit{
+		ID:           cdr.ID,
+		DocumentID:   cdr.DocumentID,
+		ResponseCode: cdr.ResponseCode,
+		Description:  cdr.Description,
+		IssueDate:    cdr.IssueDate,
+		ResponseDate: cdr.ResponseDate,
+		Observations: cdr.Observations,
+	}
+
+	switch {
+	case cdr.IsRejected():
+		return AuditStateRejected
+	case cdr.IsAccepted() && cdr.HasObservations():
+		return AuditStateObserved
+	case cdr.IsAccepted():
+		return AuditStateAccepted
+	default:
+		return AuditStateException
+	}
+}
+
+// auditTimestamp returns t for an audit record, or nil when it is not set
+func auditTimestamp(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// setError fills the message and error of the audit record, redacting credentials
+func (a *responseAudit) setError(message string, err error) {
+	a.Message = utils.RedactCredentials(message)
+	if err != nil {
+		a.Error = utils.RedactCredentials(err.Error())
+	}
+}
+
+// ToJSON serializes the response as a JSON audit record: operation, time received, outcome,
+// fault, derived state (see the AuditState constants), the parsed CDR and the CDR ZIP in base64.
+// The raw SOAP response is not included and credentials are redacted
+func (r *SUNATResponse) ToJSON() ([]byte, error) {
+	audit := &responseAudit{
+		Operation:   r.Operation,
+		Timestamp:   auditTimestamp(r.ReceivedAt),
+		Success:     r.Success,
+		FaultCode:   r.FaultCode,
+		FaultDetail: utils.RedactCredentials(r.FaultDetail),
+		Attempts:    r.Attempts,
+	}
+	audit.setError(r.Message, r.Error)
+
+	cdrState := audit.setCDR(r.ApplicationResponse)
+	switch {
+	case r.Unrecognized:
+		audit.State = AuditStateUnrecognized
+	case !r.Success:
+		audit.State = AuditStateError
+	case cdrState != "":
+		audit.State = cdrState
+	default:
+		audit.State = AuditStateSent
+	}
+
+	return json.Marshal(audit)
+}
+
+// ToJSON serializes the ticket status as a JSON audit record, like SUNATResponse.ToJSON
+func (r *TicketStatusResponse) ToJSON() ([]byte, error) {
+	audit := &responseAudit{
+		Operation:         "getStatus",
+		Timestamp:         auditTimestamp(r.ReceivedAt),
+		Success:           r.Success,
+		FaultCode:         r.FaultCode,
+		FaultDetail:       utils.RedactCredentials(r.FaultDetail),
+		Attempts:          r.Attempts,
+		Ticket:            r.Ticket,
+		StatusCode:        r.StatusCode,
+		StatusDescription: r.StatusDescription,
+	}
+	audit.setError(r.Message, r.Error)
+	audit.ProcessDate = auditTimestamp(r.ProcessDate)
+
+	cdrState := audit.setCDR(r.ApplicationResponse)
+	switch {
+	case r.Unrecognized:
+		audit.State = AuditStateUnrecognized
+	case !r.Success:
+		audit.State = AuditStateError
+	case !r.IsProcessed():
+		audit.State = AuditStateInProgress
+	case cdrState != "":
+		audit.State = cdrState
+	case r.StatusCode == "99":
+		audit.State = AuditStateRejected
+	default:
+		audit.State = AuditStateAccepted
+	}
+
+	return json.Marshal(audit)
+}
@@ -0,0 +1,116 @@
+package sunatlib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSUNATResponse_ToJSON(t *testing.T) {
+	cdrZip := readTestCDR(t, "R-20000000001-01-F001-00000002_observado.xml", true)
+	response := &SUNATResponse{
+		Success:             true,
+		Operation:           "sendBill",
+		Message:             "Documento enviado exitosamente",
+		ResponseXML:         []byte("<wsse:Password>S3cretClave</wsse:Password>"),
+		ApplicationResponse: cdrZip,
+	}
+
+	data, err := response.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+
+	var audit map[string]interface{}
+	if err := json.Unmarshal(data, &audit); err != nil {
+		t.Fatalf("ToJSON() is not valid JSON: %v", err)
+	}
+	if audit["operation"] != "sendBill" || audit["success"] != true || audit["state"] != AuditStateObserved {
+		t.Errorf("audit = %s, want a successful observed sendBill", data)
+	}
+	cdr, ok := audit["cdr"].(map[string]interface{})
+	if !ok || cdr["document_id"] != "F001-00000002" || cdr["response_code"] != "0" {
+		t.Errorf("cdr = %v, want F001-00000002 with response code 0", audit["cdr"])
+	}
+	decoded, err := base64.StdEncoding.DecodeString(audit["cdr_base64"].(string))
+	if err != nil || !bytes.Equal(decoded, cdrZip) {
+		t.Errorf("cdr_base64 does not decode to the CDR ZIP (err = %v)", err)
+	}
+	if strings.Contains(string(data), "S3cretClave") {
+		t.Errorf("ToJSON() leaks the raw response: %s", data)
+	}
+}
+
+func TestSUNATResponse_ToJSON_RedactsCredentials(t *testing.T) {
+	response := &SUNATResponse{
+		Message:   "Fault for <wsse:Password>S3cretClave</wsse:Password>",
+		FaultCode: "0102",
+		Error:     errors.New("request with <wsse:Password>S3cretClave</wsse:Password> failed"),
+	}
+
+	data, err := response.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	if strings.Contains(string(data), "S3cretClave") {
+		t.Errorf("ToJSON() contains the password: %s", data)
+	}
+	if !strings.Contains(string(data), `"state":"ERROR"`) || !strings.Contains(string(data), `"fault_code":"0102"`) {
+		t.Errorf("ToJSON() = %s, want an ERROR state with fault code 0102", data)
+	}
+}
+
+func TestTicketStatusResponse_ToJSON(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString(readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true))
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("0", cdr) },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "S3cretClave", server.URL)
+	status, err := client.QueryVoidedDocumentsTicket(testTicket)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+
+	data, err := status.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	for _, want := range []string{`"operation":"getStatus"`, `"ticket":"` + testTicket + `"`, `"status_code":"0"`, `"state":"ACEPTADO"`, `"cdr_base64":"` + cdr + `"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("ToJSON() = %s, want %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "S3cretClave") || strings.Contains(string(data), "MODDATOS") {
+		t.Errorf("ToJSON() contains credentials: %s", data)
+	}
+}
+
+func TestSUNATResponse_ToJSON_Operation(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString(readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true))
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("0", cdr) },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.Clock = newFakeClock()
+	response, err := client.GetVoidedDocumentsStatus(testTicket)
+	if err != nil {
+		t.Fatalf("GetVoidedDocumentsStatus() error = %v", err)
+	}
+
+	data, err := response.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	for _, want := range []string{`"operation":"getStatus"`, `"timestamp":"2026-04-27T10:00:00Z"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("ToJSON() = %s, want %s", data, want)
+		}
+	}
+}
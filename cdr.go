@@ -0,0 +1,337 @@
+// Package sunatlib provides parsing of SUNAT's CDR (Constancia de Recepción)
+package sunatlib
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// ErrInvalidCDR is returned when the content is not a valid CDR
+var ErrInvalidCDR = errors.New("invalid CDR")
+
+// ErrCDRNotSignedBySUNAT is returned by CDR.VerifySignature when the CDR is validly signed
+// with a trusted certificate that does not belong to SUNAT
+var ErrCDRNotSignedBySUNAT = errors.New("CDR not signed by SUNAT")
+
+// ErrCDRDocumentMismatch is returned by CDR.VerifyDocument when the CDR acknowledges a document
+// other than the one given
+var ErrCDRDocumentMismatch = errors.New("CDR does not match the sent document")
+
+// SUNATRUC is the RUC of SUNAT, the issuer of the certificate CDRs are signed with
+const SUNATRUC = "20131312955"
+
+// CDR represents the ApplicationResponse returned by SUNAT for a submitted document
+type CDR struct {
+	ID           string           // CDR identifier
+	IssueDate    string           // Issue date of the CDR (YYYY-MM-DD)
+	ResponseDate string           // Date SUNAT processed the document (YYYY-MM-DD)
+	ReferenceID  string           // Identifier of the document the CDR responds to (e.g., F001-1)
+	DocumentID   string           // Referenced document (cac:DocumentReference/cbc:ID), or ReferenceID when absent
+	DocumentHash string           // Digest of the acknowledged document (cbc:DocumentHash), empty when not echoed
+	ReceiverRUC  string           // RUC the CDR is addressed to (cac:ReceiverParty), without the "6-" prefix
+	ResponseCode string           // 0 = accepted, 0100-1999 = exception, 2000-3999 = rejected
+	Description  string           // Response description
+	Notes        []string         // Raw cbc:Note values
+	Observations []CDRObservation // Observations (codes 4000+) parsed from the notes
+
+	content []byte // ApplicationResponse XML, kept for VerifySignature
+}
+
+// CDRObservation represents an observation reported in a CDR note ("4252 - El dato ingresado ...")
+type CDRObservation struct {
+	Code        string
+	Description string
+}
+
+// cdrXML maps the elements of an ApplicationResponse used by ParseCDR
+type cdrXML struct {
+	XMLName       xml.Name `xml:"ApplicationResponse"`
+	ID            string   `xml:"ID"`
+	IssueDate     string   `xml:"IssueDate"`
+	ResponseDate  string   `xml:"ResponseDate"`
+	Notes         []string `xml:"Note"`
+	ReceiverParty struct {
+		ID string `xml:"PartyIdentification>ID"`
+	} `xml:"ReceiverParty"`
+	DocumentResponse struct {
+		Response struct {
+			ReferenceID  string `xml:"ReferenceID"`
+			ResponseCode string `xml:"ResponseCode"`
+			Description  string `xml:"Description"`
+		} `xml:"Response"`
+		DocumentReference struct {
+			ID         string `xml:"ID"`
+			Attachment struct {
+				ExternalReference struct {
+					DocumentHash string `xml:"DocumentHash"`
+				} `xml:"ExternalReference"`
+			} `xml:"Attachment"`
+		} `xml:"DocumentReference"`
+	} `xml:"DocumentResponse"`
+}
+
+// ParseCDR parses a CDR, either as the ZIP returned by SUNAT or as the extracted XML
+func ParseCDR(data []byte) (*CDR, error) {
+	content := data
+	if bytes.HasPrefix(data, []byte("PK")) {
+		_, xmlContent, err := utils.ExtractXMLFromZip(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCDR, err)
+		}
+		content = xmlContent
+	}
+
+	var raw cdrXML
+	if err := xml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCDR, err)
+	}
+
+	response := raw.DocumentResponse.Response
+	if response.ResponseCode == "" {
+		return nil, fmt.Errorf("%w: missing response code", ErrInvalidCDR)
+	}
+
+	cdr := &CDR{
+		ID:           strings.TrimSpace(raw.ID),
+		IssueDate:    strings.TrimSpace(raw.IssueDate),
+		ResponseDate: strings.TrimSpace(raw.ResponseDate),
+		ReferenceID:  strings.TrimSpace(response.ReferenceID),
+		ResponseCode: strings.TrimSpace(response.ResponseCode),
+		Description:  strings.TrimSpace(response.Description),
+		DocumentID:   strings.TrimSpace(raw.DocumentResponse.DocumentReference.ID),
+		DocumentHash: strings.TrimSpace(raw.DocumentResponse.DocumentReference.Attachment.ExternalReference.DocumentHash),
+		content:      content,
+	}
+	if cdr.DocumentID == "" {
+		cdr.DocumentID = cdr.ReferenceID
+	}
+
+	// The receiver is identified as TIPO-NUMERO (e.g., 6-20000000001)
+	cdr.ReceiverRUC = strings.TrimSpace(raw.ReceiverParty.ID)
+	if i := strings.LastIndex(cdr.ReceiverRUC, "-"); i != -1 {
+		cdr.ReceiverRUC = cdr.ReceiverRUC[i+1:]
+	}
+
+	for _, note := range raw.Notes {
+		note = strings.TrimSpace(note)
+		cdr.Notes = append(cdr.Notes, note)
+		if observation, ok := parseCDRObservation(note); ok {
+			cdr.Observations = append(cdr.Observations, observation)
+		}
+	}
+
+	return cdr, nil
+}
+
+// parseCDRObservation parses a note in "CODE - Description" format
+func parseCDRObservation(note string) (CDRObservation, bool) {
+	code, description, found := strings.Cut(note, "-")
+	code = strings.TrimSpace(code)
+	if !found || code == "" {
+		return CDRObservation{}, false
+	}
+	if _, err := strconv.Atoi(code); err != nil {
+		return CDRObservation{}, false
+	}
+
+	return CDRObservation{Code: code, Description: strings.TrimSpace(description)}, true
+}
+
+// responseCodeNumber returns the numeric response code, or -1 if it is not numeric
+func (c *CDR) responseCodeNumber() int {
+	code, err := strconv.Atoi(c.ResponseCode)
+	if err != nil {
+		return -1
+	}
+	return code
+}
+
+// IsAccepted returns true if SUNAT accepted the document (with or without observations)
+func (c *CDR) IsAccepted() bool {
+	return c.responseCodeNumber() == 0
+}
+
+// IsRejected returns true if SUNAT rejected the document (codes 2000-3999)
+func (c *CDR) IsRejected() bool {
+	code := c.responseCodeNumber()
+	return code >= 2000 && code < 4000
+}
+
+// HasObservations returns true if the CDR reports observations
+func (c *CDR) HasObservations() bool {
+	return len(c.Observations) > 0
+}
+
+// VerifyDocument checks that the CDR acknowledges the given signed document: the referenced
+// document must be the document's cbc:ID and, when the CDR echoes a cbc:DocumentHash, it must
+// equal the document's ds:DigestValue. It detects the wrong file being resent or a document
+// modified in transit, returning ErrCDRDocumentMismatch
+func (c *CDR) VerifyDocument(signedXML []byte) error {
+	_, _, id, err := parseDocumentIdentity(signedXML)
+	if err != nil {
+		return fmt.Errorf("failed to identify document: %w", err)
+	}
+	if c.DocumentID != "" && !strings.EqualFold(c.DocumentID, id) {
+		return fmt.Errorf("%w: CDR references %s, document is %s", ErrCDRDocumentMismatch, c.DocumentID, id)
+	}
+
+	if c.DocumentHash == "" {
+		return nil
+	}
+	digest, err := signer.ExtractDigestValue(signedXML)
+	if err != nil {
+		return err
+	}
+	if digest != c.DocumentHash {
+		return fmt.Errorf("%w: CDR digest %s, document digest %s", ErrCDRDocumentMismatch, c.DocumentHash, digest)
+	}
+	return nil
+}
+
+// VerifySignature verifies the ds:Signature SUNAT adds to the CDR, that the signing certificate
+// chains to one of roots (the CA that issues SUNAT's certificate, or a pinned root) and that it
+// is issued to SUNAT's RUC, proving the CDR was not forged or modified. Requires xmlsec1
+func (c *CDR) VerifySignature(roots *x509.CertPool) error {
+	if len(c.content) == 0 {
+		return fmt.Errorf("%w: CDR content not available, use ParseCDR", ErrInvalidCDR)
+	}
+
+	certificate, err := signer.VerifyXML(c.content)
+	if err != nil {
+		return fmt.Errorf("CDR signature: %w", err)
+	}
+
+	if err := utils.VerifyCertificate(certificate, roots, nil); err != nil {
+		return fmt.Errorf("CDR certificate: %w", err)
+	}
+
+	if !isSUNATCertificate(certificate) {
+		return fmt.Errorf("%w: certificate issued to %s", ErrCDRNotSignedBySUNAT, certificate.Subject)
+	}
+
+	return nil
+}
+
+// isSUNATCertificate returns true if an attribute of the certificate subject carries SUNAT's
+// RUC (e.g., serialNumber or organizationIdentifier)
+func isSUNATCertificate(certificate *x509.Certificate) bool {
+	for _, attribute := range certificate.Subject.Names {
+		if value, ok := attribute.Value.(string); ok && strings.Contains(value, SUNATRUC) {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeCDRTo decodes a base64 CDR (as found in applicationResponse or content) straight into w,
+// without holding the decoded ZIP in memory. Line breaks in the base64 text are ignored
+func DecodeCDRTo(w io.Writer, b64 string) error {
+	decoder := base64.NewDecoder(base64.StdEncoding, strings.NewReader(strings.TrimSpace(b64)))
+	if _, err := io.Copy(w, decoder); err != nil {
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: %v", ErrInvalidCDR, err)
+		}
+		return fmt.Errorf("failed to write CDR: %w", err)
+	}
+	return nil
+}
+
+// SaveCDRBase64 decodes a base64 CDR into the file at outputPath using DecodeCDRTo.
+// The file is removed if the CDR cannot be decoded
+func SaveCDRBase64(outputPath, b64 string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CDR file: %w", err)
+	}
+
+	if err := DecodeCDRTo(file, b64); err != nil {
+		file.Close()
+		os.Remove(outputPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save CDR: %w", err)
+	}
+	return nil
+}
+
+// SummarizeCDRs returns a histogram of observation codes across a batch of CDRs.
+// Accepted, observed and rejected CDRs can be mixed; CDRs without observations add nothing
+func SummarizeCDRs(cdrs [][]byte) (map[string]int, error) {
+	summary := make(map[string]int)
+
+	for i, data := range cdrs {
+		cdr, err := ParseCDR(data)
+		if err != nil {
+			return nil, fmt.Errorf("cdr %d: %w", i+1, err)
+		}
+
+		for _, observation := range cdr.Observations {
+			summary[observation.Code]++
+		}
+	}
+
+	return summary, nil
+}
+
+// ArchiveCDR writes a CDR under baseDir/RUC/YYYY-MM/, using the RUC the CDR is addressed to and
+// the month of its response date (or issue date), and returns the path of the written file.
+// ZIPs returned by SUNAT keep the name of their XML (R-RUC-TIPO-SERIE-NUMERO.zip); an extracted
+// XML is stored as R-RUC-DOCUMENTO.xml
+func ArchiveCDR(cdrZip []byte, baseDir string) (string, error) {
+	cdr, err := ParseCDR(cdrZip)
+	if err != nil {
+		return "", err
+	}
+	if !utils.ValidateRUC(cdr.ReceiverRUC) {
+		return "", fmt.Errorf("%w: invalid receiver RUC %q", ErrInvalidCDR, cdr.ReceiverRUC)
+	}
+
+	date := cdr.ResponseDate
+	if date == "" {
+		date = cdr.IssueDate
+	}
+	responseDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid response date %q", ErrInvalidCDR, date)
+	}
+
+	if cdr.DocumentID == "" || strings.ContainsAny(cdr.DocumentID, `/\`) {
+		return "", fmt.Errorf("%w: invalid document reference %q", ErrInvalidCDR, cdr.DocumentID)
+	}
+
+	fileName := fmt.Sprintf("R-%s-%s.xml", cdr.ReceiverRUC, cdr.DocumentID)
+	if bytes.HasPrefix(cdrZip, []byte("PK")) {
+		xmlName, _, err := utils.ExtractXMLFromZip(cdrZip)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidCDR, err)
+		}
+		fileName = strings.TrimSuffix(filepath.Base(xmlName), filepath.Ext(xmlName)) + ".zip"
+	}
+
+	dir := filepath.Join(baseDir, cdr.ReceiverRUC, responseDate.Format("2006-01"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create CDR directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, cdrZip, 0644); err != nil {
+		return "", fmt.Errorf("failed to save CDR: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,443 @@
+package sunatlib
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// readTestCDR reads a CDR fixture from testdata/cdr, zipping it as SUNAT returns it when zipped is true
+func readTestCDR(t *testing.T, name string, zipped bool) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "cdr", name))
+	if err != nil {
+		t.Fatalf("failed to read CDR fixture %s: %v", name, err)
+	}
+	if !zipped {
+		return content
+	}
+
+	zipContent, err := utils.CreateZip(name, content)
+	if err != nil {
+		t.Fatalf("failed to zip CDR fixture %s: %v", name, err)
+	}
+	return zipContent
+}
+
+func TestParseCDR(t *testing.T) {
+	cdr, err := ParseCDR(readTestCDR(t, "R-20000000001-01-F001-00000002_observado.xml", true))
+	if err != nil {
+		t.Fatalf("ParseCDR() error = %v", err)
+	}
+
+	if cdr.ReferenceID != "F001-00000002" {
+		t.Errorf("Expected reference F001-00000002, got %s", cdr.ReferenceID)
+	}
+	if !cdr.IsAccepted() || cdr.IsRejected() {
+		t.Errorf("Expected accepted CDR, got response code %s", cdr.ResponseCode)
+	}
+	if len(cdr.Observations) != 2 || cdr.Observations[0].Code != "4252" {
+		t.Fatalf("Expected observations 4252 and 4287, got %+v", cdr.Observations)
+	}
+	if cdr.Observations[0].Description != "El dato ingresado como atributo @listName es incorrecto." {
+		t.Errorf("Unexpected observation description: %s", cdr.Observations[0].Description)
+	}
+}
+
+func TestParseCDR_Invalid(t *testing.T) {
+	_, err := ParseCDR([]byte("<Invoice/>"))
+	if !errors.Is(err, ErrInvalidCDR) {
+		t.Fatalf("Expected ErrInvalidCDR, got %v", err)
+	}
+}
+
+func TestSummarizeCDRs(t *testing.T) {
+	cdrs := [][]byte{
+		readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true),
+		readTestCDR(t, "R-20000000001-01-F001-00000002_observado.xml", true),
+		readTestCDR(t, "R-20000000001-01-F001-00000003_observado.xml", false),
+		readTestCDR(t, "R-20000000001-01-F001-00000004_rechazado.xml", true),
+	}
+
+	summary, err := SummarizeCDRs(cdrs)
+	if err != nil {
+		t.Fatalf("SummarizeCDRs() error = %v", err)
+	}
+
+	expected := map[string]int{"4252": 2, "4287": 1, "4260": 1}
+	if len(summary) != len(expected) {
+		t.Errorf("Expected %d observation codes, got %v", len(expected), summary)
+	}
+	for code, count := range expected {
+		if summary[code] != count {
+			t.Errorf("Expected %d occurrences of %s, got %d", count, code, summary[code])
+		}
+	}
+}
+
+func TestSummarizeCDRs_InvalidCDR(t *testing.T) {
+	cdrs := [][]byte{
+		readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true),
+		[]byte("not a cdr"),
+	}
+
+	_, err := SummarizeCDRs(cdrs)
+	if !errors.Is(err, ErrInvalidCDR) {
+		t.Fatalf("Expected ErrInvalidCDR, got %v", err)
+	}
+	if !contains(err.Error(), "cdr 2") {
+		t.Errorf("Expected error to identify the CDR, got %v", err)
+	}
+}
+
+func TestParseCDR_DocumentID(t *testing.T) {
+	cdrs := map[string]string{
+		"R-20000000001-01-F001-00000001_aceptado.xml":  "F001-00000001",
+		"R-20000000001-01-F001-00000004_rechazado.xml": "F001-00000004",
+		// cbc:ReferenceID is F001-6: the referenced document wins over the response reference
+		"R-20000000001-01-F001-00000006_referencia.xml": "F001-00000006",
+	}
+	for name, want := range cdrs {
+		cdr, err := ParseCDR(readTestCDR(t, name, true))
+		if err != nil {
+			t.Fatalf("ParseCDR(%s) error = %v", name, err)
+		}
+		if cdr.DocumentID != want {
+			t.Errorf("ParseCDR(%s).DocumentID = %q, want %q", name, cdr.DocumentID, want)
+		}
+	}
+
+	// Without cac:DocumentReference the response reference is used
+	cdr, err := ParseCDR([]byte(`<ApplicationResponse><cbc:ID xmlns:cbc="urn:cbc">1</cbc:ID><DocumentResponse><Response><ReferenceID>RC-20260427-001</ReferenceID><ResponseCode>0</ResponseCode></Response></DocumentResponse></ApplicationResponse>`))
+	if err != nil {
+		t.Fatalf("ParseCDR() error = %v", err)
+	}
+	if cdr.DocumentID != "RC-20260427-001" {
+		t.Errorf("DocumentID = %q, want RC-20260427-001", cdr.DocumentID)
+	}
+}
+
+// newTestSignedCDR returns the accepted CDR fixture carrying a signature block with certificate,
+// whose digest is accepted by installTestXMLSec1Verify
+func newTestSignedCDR(t *testing.T, certificate *x509.Certificate) []byte {
+	t.Helper()
+
+	unsigned := strings.Replace(string(readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", false)),
+		"<ext:ExtensionContent/>", "<ext:ExtensionContent>\n</ext:ExtensionContent>", 1)
+	digest := sha1.Sum([]byte(unsigned))
+	signature := `        <ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Id="SignSUNAT">
+          <ds:SignedInfo>
+            <ds:Reference URI="">
+              <ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>
+            </ds:Reference>
+          </ds:SignedInfo>
+          <ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>
+          <ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(certificate.Raw) + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+        </ds:Signature>
+`
+	anchor := "<ext:ExtensionContent>\n"
+	return []byte(strings.Replace(unsigned, anchor, anchor+signature, 1))
+}
+
+func TestCDR_VerifySignature(t *testing.T) {
+	installTestXMLSec1Verify(t)
+
+	sunatSubject := pkix.Name{Country: []string{"PE"}, CommonName: "SUNAT", SerialNumber: SUNATRUC}
+	notBefore, notAfter := time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour)
+	ca := testcert.NewCA(t, pkix.Name{CommonName: "Test Root CA"}, notBefore, notAfter, nil)
+	sunatCert := testcert.New(t, sunatSubject, notBefore, notAfter, ca).Cert
+	otherCert := testcert.New(t, pkix.Name{CommonName: "EMPRESA", SerialNumber: "20000000001"}, notBefore, notAfter, ca).Cert
+	selfSigned := testcert.New(t, sunatSubject, notBefore, notAfter, nil).Cert
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	signed := newTestSignedCDR(t, sunatCert)
+	zipped, err := utils.CreateZip("R-20000000001-01-F001-00000001.xml", signed)
+	if err != nil {
+		t.Fatalf("CreateZip() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		roots   *x509.CertPool
+		wantErr error
+	}{
+		{
+			name:    "signed by SUNAT",
+			content: signed,
+			roots:   roots,
+		},
+		{
+			name:    "zipped",
+			content: zipped,
+			roots:   roots,
+		},
+		{
+			name:    "tampered",
+			content: []byte(strings.Replace(string(signed), "<cbc:ResponseCode>0</cbc:ResponseCode>", "<cbc:ResponseCode>2000</cbc:ResponseCode>", 1)),
+			roots:   roots,
+			wantErr: signer.ErrSignatureVerification,
+		},
+		{
+			name:    "unsigned",
+			content: readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", false),
+			roots:   roots,
+			wantErr: signer.ErrInvalidSignedXML,
+		},
+		{
+			name:    "self-signed SUNAT certificate",
+			content: newTestSignedCDR(t, selfSigned),
+			roots:   roots,
+			wantErr: utils.ErrUntrustedCertificate,
+		},
+		{
+			name:    "no trusted roots",
+			content: signed,
+			wantErr: utils.ErrUntrustedCertificate,
+		},
+		{
+			name:    "trusted certificate of another RUC",
+			content: newTestSignedCDR(t, otherCert),
+			roots:   roots,
+			wantErr: ErrCDRNotSignedBySUNAT,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, err := ParseCDR(tt.content)
+			if err != nil {
+				t.Fatalf("ParseCDR() error = %v", err)
+			}
+
+			err = cdr.VerifySignature(tt.roots)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifySignature() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (&CDR{}).VerifySignature(roots); !errors.Is(err, ErrInvalidCDR) {
+		t.Errorf("VerifySignature() on a CDR not built by ParseCDR error = %v, want ErrInvalidCDR", err)
+	}
+}
+
+// installTestXMLSec1Verify puts on PATH a fake xmlsec1 whose verify command fails when the
+// SHA-1 of the document without its ds:Signature does not match ds:DigestValue. Like the real
+// verification without --insecure, it also fails unless the key is given with --pubkey-cert-pem,
+// and only accepts the certificate embedded in the document
+func installTestXMLSec1Verify(t *testing.T) {
+	t.Helper()
+	for _, tool := range []string{"sh", "sed", "tr", "openssl", "base64"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+cert=
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --insecure) echo "unexpected --insecure"; exit 2 ;;
+    --pubkey-cert-pem) cert="$2"; shift ;;
+  esac
+  shift
+done
+file="$1"
+embedded=$(sed -n 's|.*<ds:X509Certificate>\(.*\)</ds:X509Certificate>.*|\1|p' "$file")
+if [ -z "$cert" ] || [ "$(sed '/-----/d' "$cert" | tr -d '\n')" != "$embedded" ]; then
+  echo "func=xmlSecKeysMngrGetKey:failed to find key"
+  echo "FAIL"
+  exit 1
+fi
+expected=$(sed -n 's|.*<ds:DigestValue>\(.*\)</ds:DigestValue>.*|\1|p' "$file")
+actual=$(sed '/<ds:Signature/,/<\/ds:Signature>/d' "$file" | openssl dgst -sha1 -binary | base64)
+if [ "$expected" != "$actual" ]; then
+  echo "func=xmlSecDSigReferenceCtxProcessNode:data and digest do not match"
+  echo "FAIL"
+  exit 1
+fi
+echo "OK"
+`
+	if err := os.WriteFile(filepath.Join(dir, "xmlsec1"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake xmlsec1: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestDecodeCDRTo(t *testing.T) {
+	cdrZIP := readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true)
+	b64 := base64.StdEncoding.EncodeToString(cdrZIP)
+
+	// SUNAT may wrap the base64 content in lines
+	var wrapped strings.Builder
+	for i := 0; i < len(b64); i += 76 {
+		end := i + 76
+		if end > len(b64) {
+			end = len(b64)
+		}
+		wrapped.WriteString(b64[i:end] + "\n")
+	}
+
+	for name, encoded := range map[string]string{"single line": b64, "wrapped": wrapped.String()} {
+		t.Run(name, func(t *testing.T) {
+			direct, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatalf("DecodeString() error = %v", err)
+			}
+
+			var streamed bytes.Buffer
+			if err := DecodeCDRTo(&streamed, encoded); err != nil {
+				t.Fatalf("DecodeCDRTo() error = %v", err)
+			}
+			if !bytes.Equal(streamed.Bytes(), direct) {
+				t.Errorf("DecodeCDRTo() wrote %d bytes, want the %d bytes of the direct decode", streamed.Len(), len(direct))
+			}
+		})
+	}
+
+	if err := DecodeCDRTo(io.Discard, "not base64!"); !errors.Is(err, ErrInvalidCDR) {
+		t.Errorf("DecodeCDRTo() error = %v, want ErrInvalidCDR", err)
+	}
+}
+
+func TestSaveCDRBase64(t *testing.T) {
+	cdrZIP := readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true)
+	path := filepath.Join(t.TempDir(), "R-20000000001-01-F001-00000001.zip")
+
+	if err := SaveCDRBase64(path, base64.StdEncoding.EncodeToString(cdrZIP)); err != nil {
+		t.Fatalf("SaveCDRBase64() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved CDR: %v", err)
+	}
+	if !bytes.Equal(saved, cdrZIP) {
+		t.Error("saved CDR does not match the original ZIP")
+	}
+
+	if err := SaveCDRBase64(path, "@@@"); !errors.Is(err, ErrInvalidCDR) {
+		t.Errorf("SaveCDRBase64() error = %v, want ErrInvalidCDR", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("a CDR that cannot be decoded should not be left on disk")
+	}
+}
+
+func TestCDR_VerifyDocument(t *testing.T) {
+	document := newTestPreSubmitDocument(t, time.Now().Add(24*time.Hour), nil)
+	digest, err := signer.ExtractDigestValue(document)
+	if err != nil {
+		t.Fatalf("ExtractDigestValue() error = %v", err)
+	}
+
+	cdrFor := func(documentID, hash string) *CDR {
+		t.Helper()
+		cdr, err := ParseCDR([]byte(`<ApplicationResponse><DocumentResponse><Response><ReferenceID>` + documentID + `</ReferenceID><ResponseCode>0</ResponseCode></Response><DocumentReference><ID>` + documentID + `</ID><Attachment><ExternalReference><DocumentHash>` + hash + `</DocumentHash></ExternalReference></Attachment></DocumentReference></DocumentResponse></ApplicationResponse>`))
+		if err != nil {
+			t.Fatalf("ParseCDR() error = %v", err)
+		}
+		return cdr
+	}
+
+	tests := []struct {
+		name     string
+		cdr      *CDR
+		mismatch bool
+	}{
+		{"Matching Digest", cdrFor("F001-1", digest), false},
+		{"Digest Not Echoed", cdrFor("F001-1", ""), false},
+		{"Mismatched Digest", cdrFor("F001-1", "AAAAAAAAAAAAAAAAAAAAAAAAAAA="), true},
+		{"Other Document", cdrFor("F001-2", digest), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cdr.VerifyDocument(document)
+			if tt.mismatch {
+				if !errors.Is(err, ErrCDRDocumentMismatch) {
+					t.Errorf("VerifyDocument() error = %v, want ErrCDRDocumentMismatch", err)
+				}
+			} else if err != nil {
+				t.Errorf("VerifyDocument() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestArchiveCDR(t *testing.T) {
+	baseDir := t.TempDir()
+
+	zipped := readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true)
+	path, err := ArchiveCDR(zipped, baseDir)
+	if err != nil {
+		t.Fatalf("ArchiveCDR() error = %v", err)
+	}
+	want := filepath.Join(baseDir, "20000000001", "2026-04", "R-20000000001-01-F001-00000001_aceptado.zip")
+	if path != want {
+		t.Errorf("ArchiveCDR() = %q, want %q", path, want)
+	}
+	if saved, err := os.ReadFile(path); err != nil || !bytes.Equal(saved, zipped) {
+		t.Errorf("archived CDR differs from the original (err = %v)", err)
+	}
+
+	extracted := readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", false)
+	path, err = ArchiveCDR(extracted, baseDir)
+	if err != nil {
+		t.Fatalf("ArchiveCDR() with XML error = %v", err)
+	}
+	want = filepath.Join(baseDir, "20000000001", "2026-04", "R-20000000001-F001-00000001.xml")
+	if path != want {
+		t.Errorf("ArchiveCDR() with XML = %q, want %q", path, want)
+	}
+
+	if _, err := ArchiveCDR([]byte(`<ApplicationResponse><DocumentResponse><Response><ResponseCode>0</ResponseCode></Response></DocumentResponse></ApplicationResponse>`), baseDir); !errors.Is(err, ErrInvalidCDR) {
+		t.Errorf("ArchiveCDR() without receiver error = %v, want ErrInvalidCDR", err)
+	}
+}
+
+func TestSUNATResponse_SaveApplicationResponse(t *testing.T) {
+	cdrZIP := readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true)
+	b64 := base64.StdEncoding.EncodeToString(cdrZIP)
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	response, err := client.parseResponse("sendBill", []byte(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:sendBillResponse xmlns:br="http://service.sunat.gob.pe"><applicationResponse>`+b64+`</applicationResponse></br:sendBillResponse></soap-env:Body></soap-env:Envelope>`))
+	if err != nil {
+		t.Fatalf("parseResponse() error = %v", err)
+	}
+	if response.applicationResponseB64 != b64 {
+		t.Fatal("Expected the base64 CDR to be kept for SaveApplicationResponse")
+	}
+
+	path := filepath.Join(t.TempDir(), "R-cdr.zip")
+	if err := response.SaveApplicationResponse(path); err != nil {
+		t.Fatalf("SaveApplicationResponse() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved CDR: %v", err)
+	}
+	if !bytes.Equal(saved, cdrZIP) {
+		t.Error("saved CDR does not match the original ZIP")
+	}
+}
@@ -0,0 +1,48 @@
+// Package sunatlib provides typed errors for SOAP faults caused by the signing certificate
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCertificateRejectedBySUNAT is reported when SUNAT refuses the certificate the document was
+// signed with (not registered, withdrawn, expired or revoked on SUNAT's side). The XML is not at
+// fault: renew the certificate or register it in SUNAT Operaciones en Línea
+var ErrCertificateRejectedBySUNAT = errors.New("signing certificate rejected by SUNAT")
+
+// certificateFaultCodes are the faults of the SUNAT catalog about the signing certificate
+var certificateFaultCodes = map[string]string{
+	"2325": "El certificado usado no es el comunicado a SUNAT",
+	"2326": "El certificado usado se encuentra de baja",
+	"2327": "El certificado usado no se encuentra vigente",
+	"2328": "El certificado usado se encuentra revocado",
+}
+
+// CertificateFault returns an error wrapping ErrCertificateRejectedBySUNAT for the SOAP faults
+// about the signing certificate, or nil for other faults
+func CertificateFault(faultCode, faultString string) error {
+	code := strings.TrimSpace(faultCode)
+	if i := strings.LastIndex(code, "."); i != -1 {
+		code = code[i+1:]
+	}
+
+	description, ok := certificateFaultCodes[code]
+	if !ok {
+		return nil
+	}
+	if strings.TrimSpace(faultString) == "" {
+		faultString = description
+	}
+	return fmt.Errorf("%w (SUNAT %s): %s", ErrCertificateRejectedBySUNAT, code, faultString)
+}
+
+// faultError returns the typed error of a SOAP fault (see CredentialFault and CertificateFault),
+// or nil when the fault has no typed error
+func faultError(faultCode, faultString string) error {
+	if err := CredentialFault(faultCode, faultString); err != nil {
+		return err
+	}
+	return CertificateFault(faultCode, faultString)
+}
@@ -0,0 +1,56 @@
+package sunatlib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCertificateFault(t *testing.T) {
+	tests := []struct {
+		name        string
+		faultCode   string
+		faultString string
+		want        error
+	}{
+		{"Not Registered", "soap-env:Client.2325", "El certificado usado no es el comunicado a SUNAT", ErrCertificateRejectedBySUNAT},
+		{"Withdrawn", "2326", "El certificado usado se encuentra de baja", ErrCertificateRejectedBySUNAT},
+		{"Expired", "2327", "El certificado usado no se encuentra vigente", ErrCertificateRejectedBySUNAT},
+		{"Revoked", "2328", "", ErrCertificateRejectedBySUNAT},
+		{"Altered Document", "2334", "El documento electrónico ingresado ha sido alterado", nil},
+		{"Wrong Password", "0102", "Usuario o contraseña incorrectos", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CertificateFault(tt.faultCode, tt.faultString)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("CertificateFault() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CertificateFault() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateFault_SendBill(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendBill": func() string { return soapFaultResponse("2327", "El certificado usado no se encuentra vigente") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	response, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+	if response.Success || !errors.Is(response.Error, ErrCertificateRejectedBySUNAT) {
+		t.Errorf("response = %+v, want ErrCertificateRejectedBySUNAT", response)
+	}
+	if errors.Is(response.Error, ErrInvalidCredentials) {
+		t.Errorf("certificate fault reported as a credentials error")
+	}
+}
@@ -0,0 +1,185 @@
+// Package sunatlib provides per-issuer certificates for multi-tenant signing
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// ErrCertificateNotRegistered is returned when signing for a RUC without a registered certificate
+var ErrCertificateNotRegistered = errors.New("no certificate registered for RUC")
+
+// CertificateRegistry holds the signing certificate of each issuer RUC, for billers that sign
+// on behalf of many companies. It is safe for concurrent use: a certificate replaced or removed
+// while documents are being signed with it is cleaned up once those signatures finish. Call
+// Cleanup when done to remove the signers' temporary key files, if any
+type CertificateRegistry struct {
+	mu      sync.Mutex
+	xmlsec1 bool   // Sign with xmlsec1, writing each key to disk (NewXMLSec1CertificateRegistry)
+	tempDir string // Base directory of the xmlsec1 signers' key files
+	signers map[string]*registeredSigner
+}
+
+// registeredSigner is a signer of the registry and the number of signatures in progress with it
+type registeredSigner struct {
+	signer  *signer.XMLSigner
+	inUse   int  // Signatures in progress
+	retired bool // Replaced or removed; cleaned up when no longer in use
+}
+
+// NewCertificateRegistry creates an empty registry whose signers sign in pure Go (see
+// signer.NewInMemorySigner), so no tenant's private key is written to disk
+func NewCertificateRegistry() *CertificateRegistry {
+	return &CertificateRegistry{
+		signers: make(map[string]*registeredSigner),
+	}
+}
+
+// NewXMLSec1CertificateRegistry creates an empty registry whose signers run xmlsec1, for
+// canonicalization methods the in-memory signer does not support (exclusive C14N). xmlsec1
+// only reads keys from files, so each key is written with 0600 permissions to a private
+// directory under tempDir (empty uses os.TempDir()) until it is replaced or Cleanup is called
+func NewXMLSec1CertificateRegistry(tempDir string) *CertificateRegistry {
+	return &CertificateRegistry{
+		xmlsec1: true,
+		tempDir: tempDir,
+		signers: make(map[string]*registeredSigner),
+	}
+}
+
+// RegisterPFX registers the PFX (PKCS#12) certificate of ruc, replacing any previous one
+func (r *CertificateRegistry) RegisterPFX(ruc string, pfxData []byte, password string) error {
+	if !utils.ValidateRUC(ruc) {
+		return fmt.Errorf("invalid RUC: %s", ruc)
+	}
+
+	keyPEM, certPEM, err := utils.PEMFromPFXData(pfxData, password)
+	if err != nil {
+		return fmt.Errorf("certificate for %s: %w", ruc, err)
+	}
+	var xmlSigner *signer.XMLSigner
+	if r.xmlsec1 {
+		xmlSigner, err = signer.NewXMLSignerFromPEMInDir(keyPEM, certPEM, r.tempDir)
+	} else {
+		xmlSigner, err = signer.NewInMemorySignerFromPEM(keyPEM, certPEM)
+	}
+	if err != nil {
+		return fmt.Errorf("certificate for %s: %w", ruc, err)
+	}
+
+	r.mu.Lock()
+	previous := r.signers[ruc]
+	r.signers[ruc] = &registeredSigner{signer: xmlSigner}
+	idle := previous != nil && r.retire(previous)
+	r.mu.Unlock()
+
+	if idle {
+		previous.signer.Cleanup()
+	}
+	return nil
+}
+
+// RUCs returns the RUCs with a registered certificate, sorted
+func (r *CertificateRegistry) RUCs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rucs := make([]string, 0, len(r.signers))
+	for ruc := range r.signers {
+		rucs = append(rucs, ruc)
+	}
+	sort.Strings(rucs)
+	return rucs
+}
+
+// has reports whether a certificate is registered for ruc
+func (r *CertificateRegistry) has(ruc string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.signers[ruc]
+	return ok
+}
+
+// acquire returns the signer registered for ruc, marked in use until it is released
+func (r *CertificateRegistry) acquire(ruc string) (*registeredSigner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.signers[ruc]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrCertificateNotRegistered, ruc)
+	}
+	entry.inUse++
+	return entry, nil
+}
+
+// release ends a use of entry, cleaning it up if it was retired meanwhile
+func (r *CertificateRegistry) release(entry *registeredSigner) {
+	r.mu.Lock()
+	entry.inUse--
+	idle := entry.retired && entry.inUse == 0
+	r.mu.Unlock()
+
+	if idle {
+		entry.signer.Cleanup()
+	}
+}
+
+// retire marks entry as no longer registered and returns true if it is idle, so the caller
+// must clean it up. r.mu must be held
+func (r *CertificateRegistry) retire(entry *registeredSigner) bool {
+	entry.retired = true
+	return entry.inUse == 0
+}
+
+// Cleanup removes the temporary key files of every registered signer and empties the registry.
+// Signers still in use are cleaned up when their signatures finish. It returns the first error found
+func (r *CertificateRegistry) Cleanup() error {
+	r.mu.Lock()
+	idle := make(map[string]*registeredSigner)
+	for ruc, entry := range r.signers {
+		if r.retire(entry) {
+			idle[ruc] = entry
+		}
+		delete(r.signers, ruc)
+	}
+	r.mu.Unlock()
+
+	var firstErr error
+	for ruc, entry := range idle {
+		if err := entry.signer.Cleanup(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("certificate for %s: %w", ruc, err)
+		}
+	}
+	return firstErr
+}
+
+// SetCertificateRegistry configures the per-issuer certificates used by SignXMLForRUC
+func (c *SUNATClient) SetCertificateRegistry(registry *CertificateRegistry) {
+	c.certificates = registry
+}
+
+// SignXMLForRUC signs an XML document with the certificate registered for ruc, running the
+// same checks as SignXML. The document must be issued by ruc
+func (c *SUNATClient) SignXMLForRUC(ruc string, xmlContent []byte) ([]byte, error) {
+	if c.certificates == nil {
+		return nil, fmt.Errorf("certificate registry not configured - use SetCertificateRegistry() first")
+	}
+	if _, issuerRUC, id, err := parseDocumentIdentity(xmlContent); err == nil && issuerRUC != ruc {
+		return nil, fmt.Errorf("document %s is issued by %s, not by %s", id, issuerRUC, ruc)
+	}
+
+	entry, err := c.certificates.acquire(ruc)
+	if err != nil {
+		return nil, err
+	}
+	defer c.certificates.release(entry)
+
+	return c.signWith(entry.signer, xmlContent)
+}
@@ -0,0 +1,214 @@
+package sunatlib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// newTestPFX returns a PFX holding a newTestPEMKeyPair key and certificate, and the certificate's DER
+func newTestPFX(t *testing.T, password string) (pfxData, certDER []byte) {
+	t.Helper()
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load key pair: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	pfxData, err = pkcs12.Modern.Encode(pair.PrivateKey, cert, nil, password)
+	if err != nil {
+		t.Fatalf("failed to encode PFX: %v", err)
+	}
+	return pfxData, cert.Raw
+}
+
+// installTestXMLSec1WithCertificate puts on PATH an xmlsec1 stand-in that fills the signature
+// template with the certificate given in --privkey-pem, so tests can tell which one was used
+func installTestXMLSec1WithCertificate(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --output) output="$2"; shift ;;
+    --privkey-pem) certificate="${2#*,}"; shift ;;
+  esac
+  shift
+done
+value=$(sed -e '/-----/d' "$certificate" | tr -d '\n')
+sed -e 's|<ds:DigestValue/>|<ds:DigestValue>ZGlnZXN0</ds:DigestValue>|' \
+    -e 's|<ds:SignatureValue/>|<ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>|' \
+    -e "s|<ds:X509Certificate/>|<ds:X509Certificate>$value</ds:X509Certificate>|" "$1" > "$output"
+echo "Signature status: OK"
+`
+	if err := os.WriteFile(filepath.Join(dir, "xmlsec1"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake xmlsec1: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSignXMLForRUC(t *testing.T) {
+	// Keys are kept in memory: nothing is written to the temp directory
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	rucs := []string{"20000000001", testRUC}
+	registry := NewCertificateRegistry()
+	defer registry.Cleanup()
+
+	certificates := make(map[string]string)
+	for _, ruc := range rucs {
+		pfxData, certDER := newTestPFX(t, "secret")
+		if err := registry.RegisterPFX(ruc, pfxData, "secret"); err != nil {
+			t.Fatalf("RegisterPFX(%s) error = %v", ruc, err)
+		}
+		certificates[ruc] = base64.StdEncoding.EncodeToString(certDER)
+	}
+	if got := registry.RUCs(); strings.Join(got, ",") != strings.Join(rucs, ",") {
+		t.Errorf("RUCs() = %v, want %v", got, rucs)
+	}
+	if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no key files on disk, got %v (%v)", entries, err)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	client.SetCertificateRegistry(registry)
+
+	for _, ruc := range rucs {
+		inv := newTestInvoice()
+		inv.Supplier.DocumentNumber = ruc
+		xmlContent, err := GenerateInvoiceXML(inv)
+		if err != nil {
+			t.Fatalf("GenerateInvoiceXML() error = %v", err)
+		}
+
+		signedXML, err := client.SignXMLForRUC(ruc, xmlContent)
+		if err != nil {
+			t.Fatalf("SignXMLForRUC(%s) error = %v", ruc, err)
+		}
+		if got := between(string(signedXML), "<ds:X509Certificate>", "</ds:X509Certificate>"); got != certificates[ruc] {
+			t.Errorf("SignXMLForRUC(%s) signed with another certificate", ruc)
+		}
+	}
+}
+
+func TestSignXMLForRUC_Errors(t *testing.T) {
+	registry := NewCertificateRegistry()
+	defer registry.Cleanup()
+	pfxData, _ := newTestPFX(t, "secret")
+	if err := registry.RegisterPFX(testRUC, pfxData, "secret"); err != nil {
+		t.Fatalf("RegisterPFX() error = %v", err)
+	}
+	if err := registry.RegisterPFX(testRUC, pfxData, "wrong"); err == nil {
+		t.Errorf("RegisterPFX() with a wrong password error = nil")
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if _, err := client.SignXMLForRUC(testRUC, []byte("<Invoice/>")); err == nil {
+		t.Errorf("SignXMLForRUC() without registry error = nil")
+	}
+	client.SetCertificateRegistry(registry)
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice()) // Issued by 20000000001
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	if _, err := client.SignXMLForRUC("20000000001", xmlContent); !errors.Is(err, ErrCertificateNotRegistered) {
+		t.Errorf("SignXMLForRUC() unregistered error = %v, want ErrCertificateNotRegistered", err)
+	}
+	if _, err := client.SignXMLForRUC(testRUC, xmlContent); err == nil || !strings.Contains(err.Error(), "is issued by") {
+		t.Errorf("SignXMLForRUC() for another issuer error = %v", err)
+	}
+}
+
+func TestSignXMLForRUC_Concurrent(t *testing.T) {
+	installTestXMLSec1WithCertificate(t)
+
+	registry := NewXMLSec1CertificateRegistry(t.TempDir())
+	defer registry.Cleanup()
+	pfxData, certDER := newTestPFX(t, "secret")
+	if err := registry.RegisterPFX(testRUC, pfxData, "secret"); err != nil {
+		t.Fatalf("RegisterPFX() error = %v", err)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	client.SetCertificateRegistry(registry)
+
+	inv := newTestInvoice()
+	inv.Supplier.DocumentNumber = testRUC
+	var documents [8][]byte
+	for i := range documents {
+		inv.Number = strconv.Itoa(i + 1)
+		xmlContent, err := GenerateInvoiceXML(inv)
+		if err != nil {
+			t.Fatalf("GenerateInvoiceXML() error = %v", err)
+		}
+		documents[i] = xmlContent
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(documents))
+	for i := range documents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			signedXML, err := client.SignXMLForRUC(testRUC, documents[i])
+			if err != nil {
+				errs <- err
+				return
+			}
+			want := "<cbc:ID>" + inv.Series + "-" + strconv.Itoa(i+1) + "</cbc:ID>"
+			if !strings.Contains(string(signedXML), want) {
+				errs <- fmt.Errorf("document %d: signed XML of another document", i+1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// A certificate replaced while in use is removed once its signature finishes
+	entry, err := registry.acquire(testRUC)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if err := registry.RegisterPFX(testRUC, pfxData, "secret"); err != nil {
+		t.Fatalf("RegisterPFX() error = %v", err)
+	}
+	if signedXML, err := entry.signer.SignXML(documents[0]); err != nil {
+		t.Errorf("SignXML() with the replaced signer error = %v", err)
+	} else if !strings.Contains(string(signedXML), base64.StdEncoding.EncodeToString(certDER)) {
+		t.Error("replaced signer used another certificate")
+	}
+
+	workDirs, _ := filepath.Glob(filepath.Join(registry.tempDir, "sunatlib_*"))
+	if len(workDirs) != 2 {
+		t.Fatalf("Expected the replaced and the new signer directories, got %v", workDirs)
+	}
+	registry.release(entry)
+	workDirs, _ = filepath.Glob(filepath.Join(registry.tempDir, "sunatlib_*"))
+	if len(workDirs) != 1 {
+		t.Errorf("Expected the replaced signer to be cleaned up once released, got %v", workDirs)
+	}
+}
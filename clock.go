@@ -0,0 +1,60 @@
+// Package sunatlib provides the clock used by time-dependent client logic
+package sunatlib
+
+import "time"
+
+// Clock is the source of time of the client: polling deadlines, retry backoff and certificate
+// validity checks go through it, so tests can replace it with a fake clock
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                  { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration { return time.Since(t) }
+func (systemClock) Sleep(d time.Duration)           { time.Sleep(d) }
+
+// SystemClock is the real clock used when SUNATClient.Clock is nil
+var SystemClock Clock = systemClock{}
+
+// clock returns the configured clock, falling back to SystemClock
+func (c *SUNATClient) clock() Clock {
+	if c.Clock == nil {
+		return SystemClock
+	}
+	return c.Clock
+}
+
+// sleepOrStop waits for d on the client clock and returns false if stop is closed first
+func (c *SUNATClient) sleepOrStop(d time.Duration, stop <-chan struct{}) bool {
+	clock := c.clock()
+	if _, real := clock.(systemClock); real {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	// Other clocks cannot be interrupted: check stop before and after sleeping on them
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+	clock.Sleep(d)
+	select {
+	case <-stop:
+		return false
+	default:
+		return true
+	}
+}
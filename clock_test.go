@@ -0,0 +1,118 @@
+package sunatlib
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose Sleep advances the time instantly and records the durations
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 4, 27, 10, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	f.sleeps = append(f.sleeps, d)
+}
+
+func (f *fakeClock) Sleeps() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.sleeps...)
+}
+
+func TestWaitForTicketProcessing_FakeClockTimeout(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("98", "") },
+	})
+	defer server.Close()
+
+	clock := newFakeClock()
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.Clock = clock
+
+	started := time.Now()
+	status, err := client.WaitForTicketProcessing(testTicket, 10*time.Minute, 30*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForTicketProcessing() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("WaitForTicketProcessing() took %v with a fake clock", elapsed)
+	}
+
+	if status.StatusCode != "98" || status.Message != LanguageSpanish.message(msgTicketWaitTimeout) {
+		t.Errorf("status = %q (%q), want the timeout of an in-progress ticket", status.StatusCode, status.Message)
+	}
+
+	sleeps := clock.Sleeps()
+	if len(sleeps) != 20 {
+		t.Errorf("Expected 20 polls of 30s within 10 minutes, got %d sleeps", len(sleeps))
+	}
+	for _, d := range sleeps {
+		if d != 30*time.Second {
+			t.Errorf("Expected every sleep to be the poll interval, got %v", d)
+		}
+	}
+}
+
+func TestWaitForTicketProcessingWithStop_FakeClock(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("98", "") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.Clock = newFakeClock()
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, err := client.WaitForTicketProcessingWithStop(testTicket, time.Hour, time.Minute, stop); !errors.Is(err, ErrTicketWaitCancelled) {
+		t.Errorf("WaitForTicketProcessingWithStop() error = %v, want ErrTicketWaitCancelled", err)
+	}
+}
+
+func TestStatusRetry_FakeClockBackoff(t *testing.T) {
+	server, _ := newFlakyStatusServer(t, 5, getStatusResponse("0", ""))
+	defer server.Close()
+
+	clock := newFakeClock()
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.Clock = clock
+	client.StatusRetry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}
+
+	if _, err := client.QueryVoidedDocumentsTicket(testTicket); err == nil {
+		t.Fatal("Expected error after exhausting the retries")
+	}
+
+	sleeps := clock.Sleeps()
+	if len(sleeps) != 2 || sleeps[0] != time.Minute || sleeps[1] != 2*time.Minute {
+		t.Errorf("Expected backoff sleeps [1m 2m], got %v", sleeps)
+	}
+}
+
+func TestSUNATClient_ClockDefault(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+	if client.clock() != SystemClock {
+		t.Error("Expected a client without Clock to use SystemClock")
+	}
+}
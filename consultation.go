@@ -1,11 +1,34 @@
 package sunatlib
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNameSearchNotSupported is returned by Search for text queries, since the RUC and DNI
+// providers only support lookups by document number
+var ErrNameSearchNotSupported = errors.New("name search not supported by the consultation providers")
+
+// ErrInvalidSearchQuery is returned by Search for numeric queries that are neither a RUC
+// (11 digits) nor a DNI (8 digits)
+var ErrInvalidSearchQuery = errors.New("invalid document number: expected a RUC (11 digits) or DNI (8 digits)")
+
+// TaxpayerInfo is the provider-independent result of a Search
+type TaxpayerInfo struct {
+	DocumentType   string // Identity document type (Catálogo 06: 6=RUC, 1=DNI)
+	DocumentNumber string // Document number
+	Name           string // Razón social or full name
+	Status         string // Taxpayer status (RUC only)
+	Condition      string // Domicile condition (RUC only)
+	Address        string // Fiscal address (RUC only)
+}
 
 // ConsultationClient handles RUC and DNI consultation services independently
 type ConsultationClient struct {
 	rucService *RUCService
 	dniService *DNIService
+	metrics    clientMetrics
 }
 
 // NewConsultationClient creates a new consultation client with both services
@@ -32,12 +55,24 @@ func NewDNIConsultationClient() *ConsultationClient {
 	}
 }
 
+// SetExtraHeaders sets headers added to every RUC, DNI and CE request, see SUNATClient.SetExtraHeaders
+func (c *ConsultationClient) SetExtraHeaders(headers map[string]string) {
+	if c.rucService != nil {
+		c.rucService.SetExtraHeaders(headers)
+	}
+	if c.dniService != nil {
+		c.dniService.SetExtraHeaders(headers)
+	}
+}
+
 // ConsultRUC performs a basic RUC consultation
 func (c *ConsultationClient) ConsultRUC(ruc string) (*RUCBasicResponse, error) {
 	if c.rucService == nil {
 		return nil, fmt.Errorf("RUC service not available - use NewConsultationClient() or NewRUCConsultationClient()")
 	}
-	return c.rucService.ConsultBasic(ruc)
+	response, err := c.rucService.ConsultBasic(ruc)
+	c.metrics.recordConsultation(err)
+	return response, err
 }
 
 // ConsultRUCFull performs a complete RUC consultation
@@ -45,7 +80,9 @@ func (c *ConsultationClient) ConsultRUCFull(ruc string) (*RUCFullResponse, error
 	if c.rucService == nil {
 		return nil, fmt.Errorf("RUC service not available - use NewConsultationClient() or NewRUCConsultationClient()")
 	}
-	return c.rucService.ConsultFull(ruc)
+	response, err := c.rucService.ConsultFull(ruc)
+	c.metrics.recordConsultation(err)
+	return response, err
 }
 
 // ConsultDNI performs a DNI consultation
@@ -53,7 +90,9 @@ func (c *ConsultationClient) ConsultDNI(dni string) (*DNIResponse, error) {
 	if c.dniService == nil {
 		return nil, fmt.Errorf("DNI service not available - use NewConsultationClient() or NewDNIConsultationClient()")
 	}
-	return c.dniService.ConsultDNI(dni)
+	response, err := c.dniService.ConsultDNI(dni)
+	c.metrics.recordConsultation(err)
+	return response, err
 }
 
 // ConsultCE performs a Carnet de Extranjería consultation
@@ -61,5 +100,60 @@ func (c *ConsultationClient) ConsultCE(ce string) (*DNIResponse, error) {
 	if c.dniService == nil {
 		return nil, fmt.Errorf("DNI service not available - use NewConsultationClient() or NewDNIConsultationClient()")
 	}
-	return c.dniService.ConsultCE(ce)
-}
\ No newline at end of file
+	response, err := c.dniService.ConsultCE(ce)
+	c.metrics.recordConsultation(err)
+	return response, err
+}
+
+// Search looks up a taxpayer by RUC (11 digits) or DNI (8 digits). Other numeric queries return
+// ErrInvalidSearchQuery; text queries are name searches, which the current providers do not
+// support: they return ErrNameSearchNotSupported
+func (c *ConsultationClient) Search(query string) ([]TaxpayerInfo, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+
+	if !isDigits(query) {
+		return nil, fmt.Errorf("%w: %q", ErrNameSearchNotSupported, query)
+	}
+	if len(query) != 11 && len(query) != 8 {
+		return nil, fmt.Errorf("%w, got %d digits in %q (use ConsultRUC or ConsultDNI)", ErrInvalidSearchQuery, len(query), query)
+	}
+
+	if len(query) == 11 {
+		resp, err := c.ConsultRUC(query)
+		if err != nil {
+			return nil, err
+		}
+		data := resp.Data
+		return []TaxpayerInfo{{
+			DocumentType:   "6",
+			DocumentNumber: data.RUC,
+			Name:           data.RazonSocial,
+			Status:         data.Estado,
+			Condition:      data.Condicion,
+			Address:        data.Direccion,
+		}}, nil
+	}
+
+	resp, err := c.ConsultDNI(query)
+	if err != nil {
+		return nil, err
+	}
+	return []TaxpayerInfo{{
+		DocumentType:   "1",
+		DocumentNumber: resp.Data.DNI,
+		Name:           resp.Data.NombreCompleto,
+	}}, nil
+}
+
+// isDigits returns true if s only contains ASCII digits
+func isDigits(s string) bool {
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,98 @@
+package sunatlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConsultationClient_Search(t *testing.T) {
+	rucServer := newTestRUCServer(t, "ACTIVO", "HABIDO")
+	defer rucServer.Close()
+
+	dniServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"datos": "JUAN PEREZ GOMEZ", "nombres": "JUAN"})
+	}))
+	defer dniServer.Close()
+
+	client := NewConsultationClient("")
+	client.rucService.BaseURL = rucServer.URL
+	client.dniService.BaseURL = dniServer.URL
+
+	tests := []struct {
+		name     string
+		query    string
+		wantType string
+		wantName string
+		wantErr  error
+	}{
+		{"RUC", "20100070970", "6", "CLIENTE S.A.", nil},
+		{"DNI", " 12345678 ", "1", "JUAN PEREZ GOMEZ", nil},
+		{"Name", "CLIENTE S.A.", "", "", ErrNameSearchNotSupported},
+		{"Numeric With Other Length", "123456", "", "", ErrInvalidSearchQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := client.Search(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Search() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %d", len(results))
+			}
+			if results[0].DocumentType != tt.wantType || results[0].Name != tt.wantName {
+				t.Errorf("Search() = %+v, want type %s and name %s", results[0], tt.wantType, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestConsultationClient_SetExtraHeaders(t *testing.T) {
+	var rucKey, dniKey, dniAccept string
+	rucServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rucKey = r.Header.Get("X-Api-Key")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "success",
+			"lista":   []map[string]string{{"apenomdenunciado": "CLIENTE S.A.", "estado": "ACTIVO", "condicion": "HABIDO"}},
+		})
+	}))
+	defer rucServer.Close()
+
+	dniServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dniKey, dniAccept = r.Header.Get("X-Api-Key"), r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"datos": "JUAN PEREZ GOMEZ", "nombres": "JUAN"})
+	}))
+	defer dniServer.Close()
+
+	client := NewConsultationClient("")
+	client.rucService.BaseURL = rucServer.URL
+	client.dniService.BaseURL = dniServer.URL
+	client.SetExtraHeaders(map[string]string{"X-Api-Key": "secret", "Accept": "text/html"})
+
+	if _, err := client.ConsultRUC("20100070970"); err != nil {
+		t.Fatalf("ConsultRUC() error = %v", err)
+	}
+	if _, err := client.ConsultDNI("12345678"); err != nil {
+		t.Fatalf("ConsultDNI() error = %v", err)
+	}
+
+	if rucKey != "secret" || dniKey != "secret" {
+		t.Errorf("X-Api-Key = %q (RUC) and %q (DNI), want the extra header on both", rucKey, dniKey)
+	}
+	if dniAccept != "application/json, text/plain, */*" {
+		t.Errorf("Accept = %q, want the service header to be kept", dniAccept)
+	}
+}
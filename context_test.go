@@ -0,0 +1,105 @@
+package sunatlib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForTicketProcessingContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests int32
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string {
+			if atomic.AddInt32(&requests, 1) == 2 {
+				cancel()
+			}
+			return getStatusResponse("98", "")
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	status, err := client.WaitForTicketProcessingContext(ctx, testTicket, time.Minute, time.Millisecond)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitForTicketProcessingContext() error = %v, want context.Canceled", err)
+	}
+	if status == nil || status.StatusCode != "98" {
+		t.Errorf("status = %+v, want the last in-progress status", status)
+	}
+	if got := atomic.LoadInt32(&requests); got > 2 {
+		t.Errorf("Expected polling to stop after cancellation, got %d requests", got)
+	}
+}
+
+func TestQueryVoidedDocumentsTicketContext_CancelledBeforeRetry(t *testing.T) {
+	server, requests := newFlakyStatusServer(t, 5, getStatusResponse("0", ""))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.StatusRetry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.QueryVoidedDocumentsTicketContext(ctx, testTicket); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("QueryVoidedDocumentsTicketContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the retry backoff to be cancelled, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("Expected 1 request before the deadline, got %d", got)
+	}
+}
+
+func TestValidateDocumentContext_Deadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	params := &ValidationParams{IssuerRUC: testRUC, DocumentType: "01", SeriesNumber: "F001", DocumentNumber: "1", IssueDate: "2026-04-27", TotalAmount: 118}
+	if _, err := client.ValidateDocumentContext(ctx, params); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ValidateDocumentContext() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSendVoidedDocumentsContext_Cancelled(t *testing.T) {
+	var requests int32
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendSummary": func() string {
+			atomic.AddInt32(&requests, 1)
+			return sendSummaryResponse(testTicket)
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.SendVoidedDocumentsContext(ctx, newTestVoidedDocumentsRequest()); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendVoidedDocumentsContext() error = %v, want context.Canceled", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("Expected no request with a cancelled context, got %d", got)
+	}
+}
@@ -0,0 +1,69 @@
+// Package sunatlib provides typed errors for SOAP faults caused by the SOL credentials
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCredentials is reported when SUNAT rejects the SOL user or password
+var ErrInvalidCredentials = errors.New("invalid SOL credentials")
+
+// ErrCredentialLocked is reported when the SOL user is locked or inactive: unlock it in
+// SUNAT Operaciones en Línea before retrying
+var ErrCredentialLocked = errors.New("SOL credentials locked")
+
+// ErrCredentialExpired is reported when the clave SOL has expired and must be renewed
+var ErrCredentialExpired = errors.New("SOL credentials expired")
+
+// credentialFaultCodes maps the authentication faults of the SUNAT catalog to their error
+var credentialFaultCodes = map[string]error{
+	"0102": ErrInvalidCredentials, // Usuario o contraseña incorrectos
+	"0103": ErrInvalidCredentials, // El Usuario ingresado no existe
+	"0104": ErrInvalidCredentials, // La Clave ingresada es incorrecta
+	"0105": ErrCredentialLocked,   // El Usuario no está activo
+	"0106": ErrInvalidCredentials, // El Usuario no es válido
+}
+
+// credentialFaultKeywords detect locked and expired credentials from the fault string, since
+// SUNAT and OSEs report them with generic authentication codes
+var credentialFaultKeywords = []struct {
+	keyword string
+	err     error
+}{
+	{"bloquead", ErrCredentialLocked},
+	{"suspendid", ErrCredentialLocked},
+	{"expirad", ErrCredentialExpired},
+	{"caducad", ErrCredentialExpired},
+	{"vencid", ErrCredentialExpired},
+}
+
+// CredentialFault returns the typed error (ErrInvalidCredentials, ErrCredentialLocked or
+// ErrCredentialExpired) of a SOAP fault caused by the SOL credentials, or nil for other faults.
+// Responses with such a fault carry it in their Error field
+func CredentialFault(faultCode, faultString string) error {
+	code := strings.TrimSpace(faultCode)
+	if i := strings.LastIndex(code, "."); i != -1 {
+		code = code[i+1:]
+	}
+
+	sentinel, known := credentialFaultCodes[code]
+	message := strings.ToLower(faultString)
+	if known || code == "" {
+		for _, k := range credentialFaultKeywords {
+			if strings.Contains(message, k.keyword) {
+				sentinel, known = k.err, true
+				break
+			}
+		}
+	}
+	if !known {
+		return nil
+	}
+
+	if code == "" {
+		return fmt.Errorf("%w: %s", sentinel, faultString)
+	}
+	return fmt.Errorf("%w (SUNAT %s): %s", sentinel, code, faultString)
+}
@@ -0,0 +1,76 @@
+package sunatlib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCredentialFault(t *testing.T) {
+	tests := []struct {
+		name        string
+		faultCode   string
+		faultString string
+		want        error
+	}{
+		{"Wrong Password", "0102", "Usuario o contraseña incorrectos", ErrInvalidCredentials},
+		{"Unknown User", "soap-env:Client.0103", "El Usuario ingresado no existe", ErrInvalidCredentials},
+		{"Wrong Clave", "0104", "La Clave ingresada es incorrecta", ErrInvalidCredentials},
+		{"Inactive User", "0105", "El Usuario no está activo", ErrCredentialLocked},
+		{"Invalid User", "0106", "El Usuario no es válido", ErrInvalidCredentials},
+		{"Locked Clave", "0102", "Su clave SOL se encuentra bloqueada", ErrCredentialLocked},
+		{"Expired Clave", "0104", "La clave SOL ha expirado", ErrCredentialExpired},
+		{"Expired Without Code", "", "Clave SOL caducada", ErrCredentialExpired},
+		{"Other Fault", "0127", "El ticket no existe", nil},
+		{"Other Fault Mentioning Expiry", "2108", "Presentación fuera de fecha: plazo vencido", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CredentialFault(tt.faultCode, tt.faultString)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("CredentialFault() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CredentialFault() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialFault_Responses(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendBill":    func() string { return soapFaultResponse("0105", "El Usuario no está activo") },
+		"sendSummary": func() string { return soapFaultResponse("0102", "Usuario o contraseña incorrectos") },
+		"getStatus":   func() string { return soapFaultResponse("0104", "La clave SOL ha expirado") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	billResponse, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+	if billResponse.FaultCode != "0105" || !errors.Is(billResponse.Error, ErrCredentialLocked) {
+		t.Errorf("sendBill response = %+v, want ErrCredentialLocked", billResponse)
+	}
+
+	voidResponse, err := client.SendVoidedDocuments(newTestVoidedDocumentsRequest())
+	if err != nil {
+		t.Fatalf("SendVoidedDocuments() error = %v", err)
+	}
+	if !errors.Is(voidResponse.Error, ErrInvalidCredentials) {
+		t.Errorf("sendSummary Error = %v, want ErrInvalidCredentials", voidResponse.Error)
+	}
+
+	status, err := client.QueryVoidedDocumentsTicket(testTicket)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+	if !errors.Is(status.Error, ErrCredentialExpired) {
+		t.Errorf("getStatus Error = %v, want ErrCredentialExpired", status.Error)
+	}
+}
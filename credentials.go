@@ -0,0 +1,93 @@
+// Package sunatlib provides named credential sets to use several environments from one client
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownCredentialSet is returned by Using for names that were not added with AddCredentialSet
+var ErrUnknownCredentialSet = errors.New("unknown credential set")
+
+// CredentialSet is a named pair of SOL credentials and endpoints, e.g., "prod" and "beta"
+type CredentialSet struct {
+	SenderRUC string                 // RUC used in the UsernameToken (empty keeps the client's SenderRUC)
+	Username  string                 // SOL user
+	Password  string                 // SOL password
+	Endpoint  string                 // Default endpoint of the environment
+	Endpoints map[ServiceType]string // Per-service overrides, see SUNATClient.SetEndpoints
+}
+
+// AddCredentialSet registers a named credential set, replacing any set with the same name.
+// The client's own credentials are unaffected and still used by direct calls
+func (c *SUNATClient) AddCredentialSet(name string, set CredentialSet) error {
+	if name == "" {
+		return fmt.Errorf("credential set name is required")
+	}
+	if set.Endpoint == "" && len(set.Endpoints) == 0 {
+		return fmt.Errorf("credential set %s: endpoint is required", name)
+	}
+
+	if c.credentialSets == nil {
+		c.credentialSets = make(map[string]CredentialSet)
+	}
+	endpoints := make(map[ServiceType]string, len(set.Endpoints))
+	for service, endpoint := range set.Endpoints {
+		endpoints[service] = endpoint
+	}
+	set.Endpoints = endpoints
+	c.credentialSets[name] = set
+	return nil
+}
+
+// CredentialSets returns the names of the registered credential sets in alphabetical order
+func (c *SUNATClient) CredentialSets() []string {
+	names := make([]string, 0, len(c.credentialSets))
+	for name := range c.credentialSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Using returns a client that sends with the named credential set, e.g.,
+// c.Using("beta") then SendToSUNAT. It shares the issuer RUC, signer, HTTP client and
+// settings of c, and keeps its own metrics. Call Cleanup on c only, not on derived clients
+func (c *SUNATClient) Using(name string) (*SUNATClient, error) {
+	set, ok := c.credentialSets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCredentialSet, name)
+	}
+
+	senderRUC := set.SenderRUC
+	if senderRUC == "" {
+		senderRUC = c.SenderRUC
+	}
+
+	derived := &SUNATClient{
+		RUC:                         c.RUC,
+		SenderRUC:                   senderRUC,
+		Username:                    set.Username,
+		Password:                    set.Password,
+		Endpoint:                    set.Endpoint,
+		MaxResponseSize:             c.MaxResponseSize,
+		HTTPClient:                  c.HTTPClient,
+		MaxConcurrentSends:          c.MaxConcurrentSends,
+		StatusRetry:                 c.StatusRetry,
+		TempDir:                     c.TempDir,
+		TreatAlreadyVoidedAsSuccess: c.TreatAlreadyVoidedAsSuccess,
+		PreSubmitSchemaCheck:        c.PreSubmitSchemaCheck,
+		CheckEncoding:               c.CheckEncoding,
+		Language:                    c.Language,
+		Clock:                       c.Clock,
+		signer:                      c.signer,
+		certificates:                c.certificates,
+		validator:                   c.validator,
+		rucService:                  c.rucService,
+		extraHeaders:                c.extraHeaders,
+	}
+	derived.SetEndpoints(set.Endpoints)
+
+	return derived, nil
+}
@@ -0,0 +1,75 @@
+package sunatlib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSUNATClient_Using(t *testing.T) {
+	newServer := func(usernames *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			*usernames = append(*usernames, between(string(body), "<wsse:Username>", "</wsse:Username>"))
+			io.WriteString(w, sendBillSuccessResponse)
+		}))
+	}
+
+	var prodUsers, betaUsers []string
+	prod := newServer(&prodUsers)
+	defer prod.Close()
+	beta := newServer(&betaUsers)
+	defer beta.Close()
+
+	client := NewSUNATClient(testRUC, "DEFAULT", "default", "http://127.0.0.1:0")
+	if err := client.AddCredentialSet("prod", CredentialSet{Username: "PRODUSER", Password: "prod", Endpoint: prod.URL}); err != nil {
+		t.Fatalf("AddCredentialSet(prod) error = %v", err)
+	}
+	if err := client.AddCredentialSet("beta", CredentialSet{Username: "MODDATOS", Password: "MODDATOS", Endpoints: map[ServiceType]string{ServiceBill: beta.URL}}); err != nil {
+		t.Fatalf("AddCredentialSet(beta) error = %v", err)
+	}
+	if got, want := client.CredentialSets(), []string{"beta", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CredentialSets() = %v, want %v", got, want)
+	}
+
+	for _, name := range []string{"prod", "beta"} {
+		derived, err := client.Using(name)
+		if err != nil {
+			t.Fatalf("Using(%s) error = %v", name, err)
+		}
+		response, err := derived.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+		if err != nil {
+			t.Fatalf("Using(%s).SendToSUNAT() error = %v", name, err)
+		}
+		if !response.Success {
+			t.Errorf("Using(%s).SendToSUNAT() = %+v, want success", name, response)
+		}
+	}
+
+	if want := []string{testRUC + "PRODUSER"}; !reflect.DeepEqual(prodUsers, want) {
+		t.Errorf("prod requests = %v, want %v", prodUsers, want)
+	}
+	if want := []string{testRUC + "MODDATOS"}; !reflect.DeepEqual(betaUsers, want) {
+		t.Errorf("beta requests = %v, want %v", betaUsers, want)
+	}
+	if client.Username != "DEFAULT" || client.Metrics().SendsAttempted != 0 {
+		t.Errorf("the base client should be unaffected, got user %s and %d sends", client.Username, client.Metrics().SendsAttempted)
+	}
+}
+
+func TestSUNATClient_Using_Invalid(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	if _, err := client.Using("prod"); !errors.Is(err, ErrUnknownCredentialSet) {
+		t.Errorf("Using() error = %v, want ErrUnknownCredentialSet", err)
+	}
+	if err := client.AddCredentialSet("", CredentialSet{Endpoint: "http://127.0.0.1:0"}); err == nil {
+		t.Error("AddCredentialSet() should reject an empty name")
+	}
+	if err := client.AddCredentialSet("prod", CredentialSet{Username: "PRODUSER"}); err == nil {
+		t.Error("AddCredentialSet() should require an endpoint")
+	}
+}
@@ -0,0 +1,127 @@
+// Package sunatlib provides diagnostic dumps of SUNAT responses for support reports
+package sunatlib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// maxDebugBodySize is the maximum number of response bytes included in a debug dump
+const maxDebugBodySize = 4096
+
+// debugDump formats a response for support reports, redacting credentials and truncating the raw body
+func debugDump(kind string, success, unrecognized bool, message string, responseXML []byte) string {
+	body := utils.RedactCredentials(string(responseXML))
+	if len(body) > maxDebugBodySize {
+		body = fmt.Sprintf("%s... [truncated %d bytes]", body[:maxDebugBodySize], len(body)-maxDebugBodySize)
+	}
+
+	return fmt.Sprintf("%s{Success: %t, Unrecognized: %t, Message: %q}\n%s",
+		kind, success, unrecognized, utils.RedactCredentials(message), body)
+}
+
+// summaryLine joins the parts of a one-line summary, redacting credentials from the message
+func summaryLine(outcome, message string) string {
+	message = utils.RedactCredentials(strings.Join(strings.Fields(message), " "))
+	if message == "" {
+		return outcome
+	}
+	return outcome + ": " + message
+}
+
+// Summary returns a one-line, credential-free description of the response for logs, including
+// the CDR response code when the CDR can be parsed (e.g., "aceptado, CDR 0: La Factura numero
+// F001-1, ha sido aceptada")
+func (r *SUNATResponse) Summary() string {
+	switch {
+	case r.Unrecognized:
+		return summaryLine("respuesta no reconocida", r.Message)
+	case !r.Success:
+		return summaryLine("error", r.Message)
+	}
+
+	if cdr, err := ParseCDR(r.ApplicationResponse); err == nil {
+		return summaryLine(fmt.Sprintf("%s, CDR %s", cdrOutcome(cdr), cdr.ResponseCode), cdr.Description)
+	}
+	return summaryLine("enviado", r.Message)
+}
+
+// String returns Summary
+func (r *SUNATResponse) String() string {
+	return r.Summary()
+}
+
+// cdrOutcome describes the response code of a CDR
+func cdrOutcome(cdr *CDR) string {
+	switch {
+	case cdr.IsRejected():
+		return "rechazado"
+	case cdr.HasObservations():
+		return "aceptado con observaciones"
+	case cdr.IsAccepted():
+		return "aceptado"
+	default:
+		return "excepción"
+	}
+}
+
+// Summary returns a one-line, credential-free description of the ticket status for logs
+// (e.g., "ticket 202600000000123 procesado con errores (99)")
+func (r *TicketStatusResponse) Summary() string {
+	prefix := "ticket " + r.Ticket
+	switch {
+	case r.Unrecognized:
+		return summaryLine(prefix+" respuesta no reconocida", r.Message)
+	case !r.Success && r.FaultCode != "":
+		return summaryLine(fmt.Sprintf("%s error %s", prefix, r.FaultCode), r.Message)
+	case !r.Success:
+		return summaryLine(prefix+" error", r.Message)
+	}
+	return fmt.Sprintf("%s %s (%s)", prefix, strings.ToLower(r.GetTicketStatusDescription()), r.StatusCode)
+}
+
+// String returns Summary
+func (r *TicketStatusResponse) String() string {
+	return r.Summary()
+}
+
+// Summary returns a one-line, credential-free description of the sendSummary or sendPack
+// response for logs (e.g., "ticket 202600000000123: Comunicación de baja enviada exitosamente")
+func (r *VoidedDocumentsResponse) Summary() string {
+	switch {
+	case r.AlreadyVoided:
+		return summaryLine(fmt.Sprintf("ya anulado (%s)", r.FaultCode), r.Message)
+	case r.Unrecognized:
+		return summaryLine("respuesta no reconocida", r.Message)
+	case !r.Success && r.FaultCode != "":
+		return summaryLine("error "+r.FaultCode, r.Message)
+	case !r.Success:
+		return summaryLine("error", r.Message)
+	}
+	return summaryLine("ticket "+r.Ticket, r.Message)
+}
+
+// String returns Summary
+func (r *VoidedDocumentsResponse) String() string {
+	return r.Summary()
+}
+
+// Summary returns a one-line description of the validation result for logs
+// (e.g., "VALIDO (code 0001): El comprobante F001-123 es un comprobante de pago válido.")
+func (r *ValidationResult) Summary() string {
+	state := r.State
+	if state == "" {
+		state = "UNKNOWN"
+	}
+	if r.StatusCode != "" {
+		state = fmt.Sprintf("%s (code %s)", state, r.StatusCode)
+	}
+	return summaryLine(state, r.StatusMessage)
+}
+
+// String returns Summary
+func (r *ValidationResult) String() string {
+	return r.Summary()
+}
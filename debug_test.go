@@ -0,0 +1,59 @@
+package sunatlib
+
+import "testing"
+
+func TestResponseSummaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{ Summary() string }
+		want     string
+	}{
+		{
+			name: "accepted invoice",
+			response: &SUNATResponse{
+				Success:             true,
+				Message:             "Documento enviado exitosamente",
+				ApplicationResponse: readTestCDR(t, "R-20000000001-01-F001-00000001_aceptado.xml", true),
+			},
+			want: "aceptado, CDR 0: La Factura numero F001-00000001, ha sido aceptada",
+		},
+		{
+			name:     "sendBill fault",
+			response: &SUNATResponse{Message: "El usuario <wsse:Password>S3cr3t</wsse:Password> no tiene\n perfil"},
+			want:     "error: El usuario <wsse:Password>***</wsse:Password> no tiene perfil",
+		},
+		{
+			name:     "ticket processed with errors",
+			response: &TicketStatusResponse{Success: true, Ticket: "202600000000123", StatusCode: "99"},
+			want:     "ticket 202600000000123 procesado con errores (99)",
+		},
+		{
+			name:     "ticket not found",
+			response: &TicketStatusResponse{Ticket: "202600000000123", FaultCode: "0127", Message: "El ticket no existe"},
+			want:     "ticket 202600000000123 error 0127: El ticket no existe",
+		},
+		{
+			name:     "voided documents sent",
+			response: &VoidedDocumentsResponse{Success: true, Ticket: "202600000000123", Message: "Comunicación de baja enviada exitosamente"},
+			want:     "ticket 202600000000123: Comunicación de baja enviada exitosamente",
+		},
+		{
+			name:     "already voided",
+			response: &VoidedDocumentsResponse{Success: true, AlreadyVoided: true, FaultCode: AlreadyVoidedCode, Message: "El comprobante fue informado previamente"},
+			want:     "ya anulado (1032): El comprobante fue informado previamente",
+		},
+		{
+			name:     "valid document",
+			response: &ValidationResult{Success: true, IsValid: true, State: "VALIDO", StatusCode: "0001", StatusMessage: "El comprobante F001-123 es un comprobante de pago válido."},
+			want:     "VALIDO (code 0001): El comprobante F001-123 es un comprobante de pago válido.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.response.Summary(); got != tt.want {
+				t.Errorf("Summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
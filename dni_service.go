@@ -3,10 +3,11 @@ package sunatlib
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // DNIResponse represents the response from EsSalud DNI validation service
@@ -43,20 +44,30 @@ type EsSaludResponse struct {
 
 // DNIService handles DNI consultation operations
 type DNIService struct {
-	BaseURL    string
-	HTTPClient *http.Client
+	BaseURL         string
+	HTTPClient      *http.Client
+	MaxResponseSize int64 // Maximum response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+	extraHeaders    map[string]string
 }
 
 // NewDNIService creates a new DNI service instance
 func NewDNIService() *DNIService {
 	return &DNIService{
 		BaseURL: "https://viva.essalud.gob.pe/viva/validar-ws-reniec",
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		HTTPClient: utils.NewHTTPClient(30*time.Second, utils.TransportTimeouts{}),
 	}
 }
 
+// SetTransportTimeouts configures the per-phase timeouts of DNI requests, see SUNATClient.SetTransportTimeouts
+func (ds *DNIService) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	ds.HTTPClient = utils.WithTransportTimeouts(ds.HTTPClient, timeouts)
+}
+
+// SetExtraHeaders sets headers added to every DNI and CE request, see SUNATClient.SetExtraHeaders
+func (ds *DNIService) SetExtraHeaders(headers map[string]string) {
+	ds.extraHeaders = utils.CopyHeaders(headers)
+}
+
 // ConsultDNI performs a DNI consultation using EsSalud service
 func (ds *DNIService) ConsultDNI(dni string) (*DNIResponse, error) {
 	if !IsValidDNI(dni) {
@@ -79,6 +90,7 @@ func (ds *DNIService) ConsultDNI(dni string) (*DNIResponse, error) {
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "es-ES,es;q=0.9")
 	req.Header.Set("Referer", "https://viva.essalud.gob.pe/")
+	utils.ApplyExtraHeaders(req, ds.extraHeaders)
 
 	resp, err := ds.HTTPClient.Do(req)
 	if err != nil {
@@ -89,7 +101,7 @@ func (ds *DNIService) ConsultDNI(dni string) (*DNIResponse, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp, ds.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
 	}
@@ -168,6 +180,7 @@ func (ds *DNIService) ConsultCE(ce string) (*DNIResponse, error) {
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "es-ES,es;q=0.9")
 	req.Header.Set("Referer", "https://viva.essalud.gob.pe/")
+	utils.ApplyExtraHeaders(req, ds.extraHeaders)
 
 	resp, err := ds.HTTPClient.Do(req)
 	if err != nil {
@@ -178,7 +191,7 @@ func (ds *DNIService) ConsultCE(ce string) (*DNIResponse, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp, ds.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
 	}
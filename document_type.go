@@ -0,0 +1,110 @@
+// Package sunatlib provides detection of the SUNAT document type of UBL documents
+package sunatlib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// ErrUnsupportedDocumentType is returned by the generators for document types they cannot produce
+type ErrUnsupportedDocumentType = utils.ErrUnsupportedDocumentType
+
+// seriesDocumentType returns the document type given by the prefix of a summary or voided
+// documents identifier (RC-YYYYMMDD-### or RA-YYYYMMDD-###), or an empty string
+func seriesDocumentType(seriesNumber string) string {
+	prefix, _, found := strings.Cut(seriesNumber, "-")
+	if !found {
+		return ""
+	}
+	if _, ok := utils.GetDocumentVersion(prefix); !ok {
+		return ""
+	}
+	return prefix
+}
+
+// rootDocumentTypes maps UBL root elements with a single SUNAT document type to its code
+var rootDocumentTypes = map[string]string{
+	"CreditNote":       "07",
+	"DebitNote":        "08",
+	"VoidedDocuments":  "RA",
+	"SummaryDocuments": "RC",
+}
+
+// typeCodeElements maps root elements shared by several document types to the child
+// element holding the SUNAT type code
+var typeCodeElements = map[string]string{
+	"Invoice":        "InvoiceTypeCode",        // 01 Factura, 03 Boleta
+	"DespatchAdvice": "DespatchAdviceTypeCode", // 09 GRE remitente, 31 GRE transportista
+}
+
+// DetectDocumentType returns the SUNAT document type code (01, 03, 07, 08, 09, 31, RA, RC)
+// of a UBL document, based on its root element and, for invoices and despatch advices, on
+// cbc:InvoiceTypeCode or cbc:DespatchAdviceTypeCode
+func DetectDocumentType(xmlContent []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xmlContent))
+	// Documents may declare ISO-8859-1; only element names and codes are needed
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	root, err := nextStartElement(decoder)
+	if err != nil {
+		return "", fmt.Errorf("failed to read root element: %w", err)
+	}
+
+	if code, ok := rootDocumentTypes[root.Name.Local]; ok {
+		return code, nil
+	}
+
+	typeCodeElement, ok := typeCodeElements[root.Name.Local]
+	if !ok {
+		return "", fmt.Errorf("unknown document root element: %s", root.Name.Local)
+	}
+
+	// Look for the type code among the direct children of the root element
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && t.Name.Local == typeCodeElement {
+				var code string
+				if err := decoder.DecodeElement(&code, &t); err != nil {
+					return "", fmt.Errorf("failed to read %s: %w", typeCodeElement, err)
+				}
+				return strings.TrimSpace(code), nil
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	return "", fmt.Errorf("%s document without %s", root.Name.Local, typeCodeElement)
+}
+
+// nextStartElement returns the first start element of the document
+func nextStartElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
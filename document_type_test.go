@@ -0,0 +1,108 @@
+package sunatlib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectDocumentType(t *testing.T) {
+	const cbc = `xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"`
+
+	tests := []struct {
+		name     string
+		xml      string
+		expected string
+		wantErr  bool
+	}{
+		{"Factura", `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" ` + cbc + `><cbc:ID>F001-1</cbc:ID><cbc:InvoiceTypeCode listID="0101">01</cbc:InvoiceTypeCode></Invoice>`, "01", false},
+		{"Boleta", `<?xml version="1.0" encoding="ISO-8859-1"?><Invoice ` + cbc + `><cbc:InvoiceTypeCode> 03 </cbc:InvoiceTypeCode></Invoice>`, "03", false},
+		{"Credit Note", `<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"/>`, "07", false},
+		{"Debit Note", `<DebitNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:DebitNote-2"/>`, "08", false},
+		{"Voided Documents", `<VoidedDocuments xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:VoidedDocuments-1"/>`, "RA", false},
+		{"Summary Documents", `<SummaryDocuments xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:SummaryDocuments-1"/>`, "RC", false},
+		{"GRE Remitente", `<DespatchAdvice ` + cbc + `><cbc:DespatchAdviceTypeCode>09</cbc:DespatchAdviceTypeCode></DespatchAdvice>`, "09", false},
+		{"GRE Transportista", `<DespatchAdvice ` + cbc + `><cbc:DespatchAdviceTypeCode>31</cbc:DespatchAdviceTypeCode></DespatchAdvice>`, "31", false},
+		{"Nested Type Code Ignored", `<Invoice ` + cbc + `><cac:BillingReference><cbc:InvoiceTypeCode>01</cbc:InvoiceTypeCode></cac:BillingReference></Invoice>`, "", true},
+		{"Unknown Root", `<Order/>`, "", true},
+		{"Not XML", `not xml`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := DetectDocumentType([]byte(tt.xml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectDocumentType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.expected {
+				t.Errorf("DetectDocumentType() = %q, want %q", code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectDocumentType_TestData(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "F001-00000001_grabado_oneroso.xml"))
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+
+	code, err := DetectDocumentType(content)
+	if err != nil {
+		t.Fatalf("DetectDocumentType() error = %v", err)
+	}
+	if code != "01" {
+		t.Errorf("DetectDocumentType() = %q, want 01", code)
+	}
+}
+
+func TestGenerators_UnsupportedDocumentType(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	creditNote := newTestInvoice()
+	creditNote.DocumentType = "07"
+
+	despatch := newTestInvoice()
+	despatch.DocumentType = "09"
+
+	summaryAsVoided := newTestVoidedDocumentsRequest()
+	summaryAsVoided.SeriesNumber = "RC-20260427-001"
+
+	voidedAsSummary := newTestSummaryDocumentsRequest()
+	voidedAsSummary.SeriesNumber = "RA-20260428-001"
+
+	tests := []struct {
+		name            string
+		generate        func() ([]byte, error)
+		wantCode        string
+		wantGenerator   string
+		wantAlternative string
+	}{
+		{"Credit Note In Invoice Generator", func() ([]byte, error) { return GenerateInvoiceXML(creditNote) }, "07", "GenerateInvoiceXML", ""},
+		{"Guia In Invoice Generator", func() ([]byte, error) { return GenerateInvoiceXML(despatch) }, "09", "GenerateInvoiceXML", "gre.GenerateXML"},
+		{"Summary In Voided Generator", func() ([]byte, error) { return client.GenerateVoidedDocumentsXML(summaryAsVoided) }, "RC", "GenerateVoidedDocumentsXML", "SUNATClient.GenerateSummaryDocumentsXML"},
+		{"Voided In Summary Generator", func() ([]byte, error) { return client.GenerateSummaryDocumentsXML(voidedAsSummary) }, "RA", "GenerateSummaryDocumentsXML", "SUNATClient.GenerateVoidedDocumentsXML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlContent, err := tt.generate()
+			if xmlContent != nil {
+				t.Error("generator should not produce XML for an unsupported type")
+			}
+
+			var unsupported *ErrUnsupportedDocumentType
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("error = %v, want *ErrUnsupportedDocumentType", err)
+			}
+			if unsupported.Code != tt.wantCode || unsupported.Generator != tt.wantGenerator || unsupported.Alternative != tt.wantAlternative {
+				t.Errorf("error = %+v, want code %s, generator %s and alternative %q", unsupported, tt.wantCode, tt.wantGenerator, tt.wantAlternative)
+			}
+			if len(unsupported.Supported) == 0 || !strings.Contains(err.Error(), "supported: ") {
+				t.Errorf("error %q should list the supported types", err)
+			}
+		})
+	}
+}
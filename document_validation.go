@@ -3,12 +3,14 @@ package sunatlib
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // DocumentValidationClient handles document validation requests to SUNAT
@@ -18,6 +20,9 @@ type DocumentValidationClient struct {
 	Password string
 	Endpoint string
 	Client   *http.Client
+	MaxResponseSize int64 // Maximum response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+	metrics  clientMetrics
+	extraHeaders map[string]string
 }
 
 // ValidationRequest represents a document validation request
@@ -42,6 +47,34 @@ type ValidationResponse struct {
 	ResponseXML   []byte
 }
 
+// ToResult converts the response to the ValidationResult returned by ValidationClient, deriving
+// the state from the status message. Responses without a status message (communication errors,
+// SOAP faults) have state UNKNOWN and the error message as ErrorDetails
+func (r *ValidationResponse) ToResult() *ValidationResult {
+	result := &ValidationResult{
+		Success:       r.Success,
+		IsValid:       r.IsValid,
+		StatusCode:    "UNKNOWN",
+		StatusMessage: r.StatusMessage,
+		State:         "UNKNOWN",
+		ResponseXML:   string(r.ResponseXML),
+	}
+
+	switch {
+	case r.IsValid:
+		result.State = "VALIDO"
+	case r.StatusMessage != "":
+		result.State = validationStateFromMessage(r.StatusMessage)
+	}
+
+	result.ErrorDetails = validationStateDetails(result.State)
+	if r.ErrorMessage != "" {
+		result.ErrorDetails = r.ErrorMessage
+	}
+
+	return result
+}
+
 // ValidationSOAPResponse represents the SOAP response structure
 type ValidationSOAPResponse struct {
 	XMLName xml.Name `xml:"Envelope"`
@@ -65,9 +98,7 @@ func NewDocumentValidationClientWithCredentials(ruc, username, password string)
 		Username: username,
 		Password: password,
 		Endpoint: GetValidationServiceEndpoint(Production),
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		Client: utils.NewHTTPClient(30*time.Second, utils.TransportTimeouts{}),
 	}
 }
 
@@ -78,14 +109,36 @@ func NewDocumentValidationClientBeta(ruc, username, password string) *DocumentVa
 		Username: username,
 		Password: password,
 		Endpoint: GetValidationServiceEndpoint(Beta),
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		Client: utils.NewHTTPClient(30*time.Second, utils.TransportTimeouts{}),
 	}
 }
 
+// SetEndpoints overrides the validation endpoint (e.g., for OSE providers) using the ServiceValidation entry
+func (c *DocumentValidationClient) SetEndpoints(endpoints map[ServiceType]string) {
+	if endpoint, ok := endpoints[ServiceValidation]; ok && endpoint != "" {
+		c.Endpoint = endpoint
+	}
+}
+
+// SetTransportTimeouts configures the per-phase timeouts of validation requests, see SUNATClient.SetTransportTimeouts
+func (c *DocumentValidationClient) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	c.Client = utils.WithTransportTimeouts(c.Client, timeouts)
+}
+
+// SetExtraHeaders sets headers added to every validation request, see SUNATClient.SetExtraHeaders
+func (c *DocumentValidationClient) SetExtraHeaders(headers map[string]string) {
+	c.extraHeaders = utils.CopyHeaders(headers)
+}
+
 // ValidateDocument validates an electronic document with SUNAT using SOAP
 func (c *DocumentValidationClient) ValidateDocument(req *ValidationRequest) (*ValidationResponse, error) {
+	return c.ValidateDocumentContext(context.Background(), req)
+}
+
+// ValidateDocumentContext is like ValidateDocument, cancelling the request when ctx is done
+func (c *DocumentValidationClient) ValidateDocumentContext(ctx context.Context, req *ValidationRequest) (response *ValidationResponse, err error) {
+	defer func() { c.metrics.recordValidation(err) }()
+
 	// Set default values for optional fields
 	recipientDocType := req.RecipientDocumentType
 	if recipientDocType == "" {
@@ -134,7 +187,7 @@ xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-s
 		authNumber)
 
 	// Send HTTP request
-	httpReq, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -145,14 +198,15 @@ xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-s
 	httpReq.Header.Set("Pragma", "no-cache")
 	httpReq.Header.Set("SOAPAction", "")
 	httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", len(soapBody)))
+	utils.ApplyExtraHeaders(httpReq, c.extraHeaders)
 
 	resp, err := c.Client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, utils.RedactError(fmt.Errorf("failed to send HTTP request: %w", err), c.Password)
 	}
 	defer resp.Body.Close()
 
-	responseData, err := io.ReadAll(resp.Body)
+	responseData, err := readResponseBody(resp, c.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -182,7 +236,7 @@ func (c *DocumentValidationClient) parseValidationResponse(responseData []byte,
 		if start := strings.Index(responseStr, "<faultstring>"); start != -1 {
 			start += 13
 			if end := strings.Index(responseStr[start:], "</faultstring>"); end != -1 {
-				response.ErrorMessage = responseStr[start : start+end]
+				response.ErrorMessage = utils.Redact(responseStr[start:start+end], c.Password)
 			}
 		}
 
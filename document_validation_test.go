@@ -0,0 +1,39 @@
+package sunatlib
+
+import "testing"
+
+func TestValidationResponse_ToResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		response ValidationResponse
+		want     ValidationResult
+	}{
+		{
+			name:     "Valid Document",
+			response: ValidationResponse{Success: true, IsValid: true, StatusMessage: "El comprobante F001-1 es un comprobante de pago válido."},
+			want:     ValidationResult{Success: true, IsValid: true, StatusCode: "UNKNOWN", StatusMessage: "El comprobante F001-1 es un comprobante de pago válido.", ErrorDetails: "Documento válido en SUNAT", State: "VALIDO"},
+		},
+		{
+			name:     "Voided Document",
+			response: ValidationResponse{Success: true, StatusMessage: "El comprobante F001-1 ha sido informado a SUNAT y se encuentra de BAJA."},
+			want:     ValidationResult{Success: true, StatusCode: "UNKNOWN", StatusMessage: "El comprobante F001-1 ha sido informado a SUNAT y se encuentra de BAJA.", ErrorDetails: "Documento anulado o dado de baja", State: "ANULADO"},
+		},
+		{
+			name:     "Communication Error",
+			response: ValidationResponse{ErrorMessage: "Se ha perdido la comunicación con la SUNAT"},
+			want:     ValidationResult{StatusCode: "UNKNOWN", ErrorDetails: "Se ha perdido la comunicación con la SUNAT", State: "UNKNOWN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.response.ToResult()
+			if *got != tt.want {
+				t.Errorf("ToResult() = %+v, want %+v", *got, tt.want)
+			}
+			if got.IsDefinitive() != tt.want.IsDefinitive() {
+				t.Errorf("IsDefinitive() = %v, want %v", got.IsDefinitive(), tt.want.IsDefinitive())
+			}
+		})
+	}
+}
@@ -47,6 +47,17 @@ const (
 	Beta
 )
 
+// ServiceType identifies a SUNAT (or OSE) service operation whose endpoint can be overridden
+type ServiceType int
+
+const (
+	ServiceBill       ServiceType = iota // sendBill operation
+	ServiceSummary                       // sendSummary operation (voided documents, summaries)
+	ServiceStatus                        // getStatus operation (ticket queries)
+	ServiceValidation                    // validaCDPcriterios operation (document validation)
+	ServicePack                          // sendPack operation (batch transmission)
+)
+
 // GetBillServiceEndpoint returns the appropriate billService endpoint based on environment
 func GetBillServiceEndpoint(env Environment) string {
 	switch env {
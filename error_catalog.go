@@ -0,0 +1,80 @@
+// Package sunatlib provides a catalog of SUNAT response codes with suggested fixes
+package sunatlib
+
+import "strings"
+
+// ErrorCatalogEntry describes a SUNAT response code
+type ErrorCatalogEntry struct {
+	Description string // Description published by SUNAT
+	Hint        string // Suggested action to fix the problem
+}
+
+// ErrorCatalog maps SUNAT response codes (exceptions 0100-1999, rejections 2000-3999,
+// observations 4000+) to their description and a suggested action
+type ErrorCatalog map[string]ErrorCatalogEntry
+
+// DefaultErrorCatalog contains the response codes most commonly returned by SUNAT
+var DefaultErrorCatalog = ErrorCatalog{
+	// Exceptions (0100-1999): the document was not processed
+	"0100": {"El sistema no puede responder su solicitud. Intente nuevamente o comuníquese con su Administrador", "Error temporal de SUNAT: reintente el envío más tarde."},
+	"0102": {"Usuario o contraseña incorrectos", "Verifique el usuario SOL (RUC + usuario) y la clave SOL configurados."},
+	"0103": {"El Usuario ingresado no existe", "Verifique que el usuario secundario SOL exista para el RUC emisor."},
+	"0104": {"La Clave ingresada es incorrecta", "Actualice la clave SOL configurada en el cliente."},
+	"0105": {"El Usuario no está activo", "Active el usuario secundario SOL desde SUNAT Operaciones en Línea."},
+	"0109": {"El sistema no puede responder su solicitud. (El servicio de autenticación no está disponible)", "Error temporal de SUNAT: reintente el envío más tarde."},
+	"0111": {"No tiene el perfil para enviar comprobantes electrónicos", "Asigne al usuario SOL el perfil de emisor electrónico en SUNAT Operaciones en Línea."},
+	"0127": {"El ticket no existe", "Verifique el número de ticket y que la consulta se haga con el mismo RUC que realizó el envío."},
+	"0130": {"El sistema no puede responder su solicitud. (No se pudo obtener el ticket de proceso)", "Error temporal de SUNAT: reintente el envío más tarde."},
+	"0151": {"El nombre del archivo ZIP es incorrecto", "Use el formato RUC-TIPO-SERIE-NUMERO.zip para el archivo enviado."},
+	"0154": {"El RUC del archivo no corresponde al RUC del usuario", "El RUC del nombre del archivo debe coincidir con el RUC del usuario SOL."},
+	"0155": {"El archivo ZIP está vacío", "Verifique que el ZIP contenga el XML firmado."},
+	"0156": {"El archivo ZIP está corrupto", "Regenere el ZIP y verifique que el contenido en base64 no se haya truncado."},
+	"0160": {"El archivo XML está vacío", "Verifique que el XML firmado tenga contenido antes de comprimirlo."},
+	"0161": {"El nombre del archivo XML no coincide con el nombre del archivo ZIP", "Use el mismo nombre base para el XML y el ZIP."},
+	"1032": {"El comprobante fue informado previamente en una comunicación de baja", "El comprobante ya fue anulado: no debe reenviarse."},
+	"1033": {"El comprobante fue registrado previamente con otros datos", "El número ya fue usado: emita el comprobante con el siguiente correlativo."},
+
+	// Rejections (2000-3999): the document was processed and rejected
+	"2017": {"El número de documento de identidad del receptor debe ser RUC", "Las facturas requieren un cliente identificado con RUC (tipo de documento 6)."},
+	"2072": {"CustomizationID - La versión del documento no es la correcta", "Use CustomizationID 2.0 para comprobantes UBL 2.1."},
+	"2108": {"Presentación fuera de fecha", "El plazo de envío venció: emita un nuevo comprobante o regularice con SUNAT."},
+	"2325": {"El certificado usado no es el comunicado a SUNAT", "Registre el certificado digital en SUNAT Operaciones en Línea o firme con el certificado registrado."},
+	"2326": {"El certificado usado se encuentra de baja", "Firme con un certificado vigente registrado en SUNAT."},
+	"2327": {"El certificado usado no se encuentra vigente", "Renueve el certificado digital y regístrelo en SUNAT Operaciones en Línea."},
+	"2328": {"El certificado usado se encuentra revocado", "Obtenga un nuevo certificado digital y regístrelo en SUNAT Operaciones en Línea."},
+	"2335": {"El documento ya fue informado", "No reenvíe el documento: consulte su estado y recupere el CDR existente."},
+	"2800": {"El dato ingresado en el tipo de documento de identidad del receptor no está permitido", "Verifique el tipo de documento del cliente (Catálogo 06) según el tipo de comprobante."},
+	"3024": {"El XML contiene más de un tag como elemento de primer nivel cac:TaxTotal", "Agrupe todos los tributos en un único cac:TaxTotal a nivel de documento."},
+	"3105": {"El XML no contiene el tag o no existe información del total de tributos de la línea", "Cada cac:InvoiceLine debe incluir su cac:TaxTotal."},
+	"3244": {"Debe consignar la información del tipo de transacción del comprobante", "Incluya cac:PaymentTerms con FormaPago Contado o Crédito."},
+	"3277": {"El monto total de la base imponible no coincide con la sumatoria de los valores de venta", "Revise que la base imponible del IGV sea la suma de los valores de venta gravados."},
+
+	// Observations (4000+): the document was accepted with observations
+	"4252": {"El dato ingresado como atributo @listName es incorrecto", "Corrija el atributo listName según el catálogo SUNAT correspondiente."},
+	"4287": {"El precio unitario de la operación que está informando difiere de los cálculos realizados en base a la cantidad, valor unitario y tributos del ítem", "Recalcule el precio unitario incluyendo tributos a partir del valor unitario y el IGV de la línea."},
+}
+
+// Lookup returns the description and suggested action for a SUNAT response code. The code may
+// include the SOAP fault prefix (e.g., "soap-env:Client.0111")
+func (c ErrorCatalog) Lookup(code string) (description, hint string, ok bool) {
+	code = strings.TrimSpace(code)
+	if i := strings.LastIndex(code, "."); i != -1 {
+		code = code[i+1:]
+	}
+
+	// Exception codes are published with four digits (e.g., "0111")
+	if len(code) > 0 && len(code) < 4 {
+		code = strings.Repeat("0", 4-len(code)) + code
+	}
+
+	entry, ok := c[code]
+	if !ok {
+		return "", "", false
+	}
+	return entry.Description, entry.Hint, true
+}
+
+// Guidance returns the catalog description and suggested action for the CDR response code
+func (c *CDR) Guidance() (description, hint string, ok bool) {
+	return DefaultErrorCatalog.Lookup(c.ResponseCode)
+}
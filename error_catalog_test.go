@@ -0,0 +1,50 @@
+package sunatlib
+
+import "testing"
+
+func TestErrorCatalog_Lookup(t *testing.T) {
+	tests := []struct {
+		code        string
+		description string
+		found       bool
+	}{
+		{"2335", "El documento ya fue informado", true},
+		{"0111", "No tiene el perfil para enviar comprobantes electrónicos", true},
+		{"soap-env:Client.0102", "Usuario o contraseña incorrectos", true},
+		{"127", "El ticket no existe", true},
+		{" 3244 ", "Debe consignar la información del tipo de transacción del comprobante", true},
+		{"4252", "El dato ingresado como atributo @listName es incorrecto", true},
+		{"9999", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			description, hint, ok := DefaultErrorCatalog.Lookup(tt.code)
+			if ok != tt.found {
+				t.Fatalf("Lookup(%q) ok = %v, want %v", tt.code, ok, tt.found)
+			}
+			if description != tt.description {
+				t.Errorf("Lookup(%q) description = %q, want %q", tt.code, description, tt.description)
+			}
+			if tt.found && hint == "" {
+				t.Errorf("Lookup(%q) should return a suggested action", tt.code)
+			}
+		})
+	}
+}
+
+func TestCDR_Guidance(t *testing.T) {
+	cdr, err := ParseCDR(readTestCDR(t, "R-20000000001-01-F001-00000004_rechazado.xml", true))
+	if err != nil {
+		t.Fatalf("ParseCDR() error = %v", err)
+	}
+
+	description, hint, ok := cdr.Guidance()
+	if !ok {
+		t.Fatalf("Expected guidance for response code %s", cdr.ResponseCode)
+	}
+	if description == "" || hint == "" {
+		t.Errorf("Expected description and hint, got %q / %q", description, hint)
+	}
+}
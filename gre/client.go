@@ -7,12 +7,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // GreClient is the client for SUNAT's New GRE REST API
@@ -25,6 +26,26 @@ type GreClient struct {
 	ApiURL       string
 	Token        *OAuthToken
 	HttpClient   *http.Client
+
+	// MaxResponseSize limits the response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+	MaxResponseSize int64
+
+	extraHeaders map[string]string
+}
+
+// SetExtraHeaders sets headers added to every token and API request (e.g., keys required by a
+// gateway or proxy). They never replace Authorization or Content-Type
+func (c *GreClient) SetExtraHeaders(headers map[string]string) {
+	c.extraHeaders = utils.CopyHeaders(headers)
+}
+
+// SetTransportTimeouts configures the connection, TLS handshake and response header timeouts
+// of the HTTP client, independently of its total timeout
+func (c *GreClient) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	if c.HttpClient == nil {
+		c.HttpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	c.HttpClient = utils.WithTransportTimeouts(c.HttpClient, timeouts)
 }
 
 // GetToken requests a new OAuth token from SUNAT
@@ -44,6 +65,7 @@ func (c *GreClient) GetToken(ctx context.Context) (*OAuthToken, error) {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
 
 	client := c.HttpClient
 	if client == nil {
@@ -55,13 +77,17 @@ func (c *GreClient) GetToken(ctx context.Context) (*OAuthToken, error) {
 	}
 	defer resp.Body.Close()
 
+	body, err := utils.ReadLimitedBody(resp.Body, c.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OAuth error: %d - %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("OAuth error: %d - %s", resp.StatusCode, c.redact(string(body)))
 	}
 
 	var token OAuthToken
-	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+	if err := json.Unmarshal(body, &token); err != nil {
 		return nil, err
 	}
 	token.IssuedAt = time.Now()
@@ -95,6 +121,7 @@ func (c *GreClient) SendGuide(ctx context.Context, fileName string, zipContent [
 	}
 	req.Header.Set("Authorization", "Bearer "+c.Token.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
 
 	client := c.HttpClient
 	if client == nil {
@@ -106,13 +133,17 @@ func (c *GreClient) SendGuide(ctx context.Context, fileName string, zipContent [
 	}
 	defer resp.Body.Close()
 
+	body, err := utils.ReadLimitedBody(resp.Body, c.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GRE API error: %d - %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("GRE API error: %d - %s", resp.StatusCode, c.redact(string(body)))
 	}
 
 	var greResp GreResponse
-	if err := json.NewDecoder(resp.Body).Decode(&greResp); err != nil {
+	if err := json.Unmarshal(body, &greResp); err != nil {
 		return nil, err
 	}
 
@@ -132,6 +163,7 @@ func (c *GreClient) GetStatus(ctx context.Context, ticket string) (*GreStatusRes
 		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+c.Token.AccessToken)
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
 
 	client := c.HttpClient
 	if client == nil {
@@ -143,15 +175,24 @@ func (c *GreClient) GetStatus(ctx context.Context, ticket string) (*GreStatusRes
 	}
 	defer resp.Body.Close()
 
+	body, err := utils.ReadLimitedBody(resp.Body, c.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GRE API error: %d - %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("GRE API error: %d - %s", resp.StatusCode, c.redact(string(body)))
 	}
 
 	var statusResp GreStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
+	if err := json.Unmarshal(body, &statusResp); err != nil {
 		return nil, err
 	}
 
 	return &statusResp, nil
 }
+
+// redact removes the SOL password and client secret from text included in errors
+func (c *GreClient) redact(s string) string {
+	return utils.Redact(s, c.Password, c.ClientSecret)
+}
@@ -3,9 +3,13 @@ package gre
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 func TestGreClient_GetToken(t *testing.T) {
@@ -98,3 +102,21 @@ func TestGreClient_GetStatus(t *testing.T) {
 		t.Error("Expected ArcCdr to be not empty")
 	}
 }
+
+func TestGreClient_GetStatus_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("A", 2048)))
+	}))
+	defer server.Close()
+
+	client := &GreClient{
+		ApiURL:          server.URL,
+		Token:           &OAuthToken{AccessToken: "valid-token"},
+		MaxResponseSize: 1024,
+	}
+
+	_, err := client.GetStatus(context.Background(), "123456789")
+	if !errors.Is(err, utils.ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge, got %v", err)
+	}
+}
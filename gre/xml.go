@@ -2,6 +2,8 @@ package gre
 
 import (
 	"fmt"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 const despatchAdviceTemplate = `<?xml version="1.0" encoding="UTF-8"?>
@@ -15,8 +17,8 @@ const despatchAdviceTemplate = `<?xml version="1.0" encoding="UTF-8"?>
             <ext:ExtensionContent/>
         </ext:UBLExtension>
     </ext:UBLExtensions>
-    <cbc:UBLVersionID>2.1</cbc:UBLVersionID>
-    <cbc:CustomizationID>2.0</cbc:CustomizationID>
+    <cbc:UBLVersionID>%s</cbc:UBLVersionID>
+    <cbc:CustomizationID>%s</cbc:CustomizationID>
     <cbc:ID>%s</cbc:ID>
     <cbc:IssueDate>%s</cbc:IssueDate>
     <cbc:IssueTime>%s</cbc:IssueTime>
@@ -88,6 +90,15 @@ const despatchAdviceTemplate = `<?xml version="1.0" encoding="UTF-8"?>
 
 // GenerateXML generates the UBL 2.1 DespatchAdvice XML using a template for precision
 func GenerateXML(guide *DespatchAdvice) ([]byte, error) {
+	// An empty type code defaults to the guía de remisión remitente
+	typeCode := guide.TypeCode
+	if typeCode == "" {
+		typeCode = "09"
+	}
+	if typeCode != "09" && typeCode != "31" {
+		return nil, utils.NewUnsupportedDocumentTypeError(typeCode, "gre.GenerateXML", "09", "31")
+	}
+
 	stagesXML := ""
 	for _, stage := range guide.Shipment.ShipmentStages {
 		carrierXML := ""
@@ -168,11 +179,15 @@ func GenerateXML(guide *DespatchAdvice) ([]byte, error) {
 		)
 	}
 
+	version, _ := utils.GetDocumentVersion(typeCode)
+
 	xmlContent := fmt.Sprintf(despatchAdviceTemplate,
+		version.UBLVersionID,
+		version.CustomizationID,
 		guide.ID,
 		guide.IssueDate,
 		guide.IssueTime,
-		guide.TypeCode,
+		typeCode,
 		guide.Signature.ID,
 		guide.Signature.SignatoryParty.PartyIdentification.ID,
 		guide.Signature.SignatoryParty.PartyName.Name,
@@ -1,9 +1,12 @@
 package gre
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 func TestGenerateExampleXMLs(t *testing.T) {
@@ -171,6 +174,8 @@ func TestGenerateXML(t *testing.T) {
 			},
 			wantErr: false,
 			checks: []string{
+				"<cbc:UBLVersionID>2.1</cbc:UBLVersionID>",
+				"<cbc:CustomizationID>2.0</cbc:CustomizationID>",
 				"<cbc:ID>T001-1</cbc:ID>",
 				"<cbc:HandlingCode>02</cbc:HandlingCode>",
 				"<cbc:LicensePlateID>ABC-123</cbc:LicensePlateID>",
@@ -223,3 +228,57 @@ func TestGenerateXML(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateXML_Deterministic(t *testing.T) {
+	guide := &DespatchAdvice{
+		ID:                    "T001-00000001",
+		IssueDate:             "2023-10-27",
+		IssueTime:             "12:00:00",
+		TypeCode:              "09",
+		Signature:             Signature{ID: "IDSignKG", SignatoryParty: Party{PartyIdentification: ID{ID: "20600000000"}, PartyName: Name{Name: "MI EMPRESA SAC"}}},
+		DespatchSupplierParty: SupplierParty{CustomerAssignedAccountID: "20600000000", AdditionalAccountID: "6", Party: Party{PartyIdentification: ID{ID: "20600000000"}, PartyName: Name{Name: "MI EMPRESA SAC"}}},
+		DeliveryCustomerParty: CustomerParty{CustomerAssignedAccountID: "20400000000", AdditionalAccountID: "6", Party: Party{PartyIdentification: ID{ID: "20400000000"}, PartyName: Name{Name: "CLIENTE SAC"}}},
+		Shipment: Shipment{
+			HandlingCode:       "02",
+			GrossWeightMeasure: Measure{Value: 150.5, UnitCode: "KGM"},
+			ShipmentStages: []ShipmentStage{
+				{ID: "1", TransportModeCode: "02", TransitPeriod: Period{StartDate: "2023-10-28"}, DriverPerson: &Person{ID: ID{ID: "10203040"}}},
+				{ID: "2", TransportModeCode: "01", TransitPeriod: Period{StartDate: "2023-10-29"}, CarrierParty: &CarrierParty{PartyIdentification: ID{ID: "20601234567"}, PartyName: Name{Name: "TRANSPORTES EXPRESS SAC"}}},
+			},
+		},
+		DespatchLines: []DespatchLine{
+			{ID: "1", DeliveredQuantity: Measure{Value: 10, UnitCode: "NIU"}, Item: Item{Description: "PRODUCTO 1", ID: ID{ID: "PROD001"}}},
+			{ID: "2", DeliveredQuantity: Measure{Value: 5, UnitCode: "NIU"}, Item: Item{Description: "PRODUCTO 2", ID: ID{ID: "PROD002"}}},
+		},
+	}
+
+	first, err := GenerateXML(guide)
+	if err != nil {
+		t.Fatalf("GenerateXML() error = %v", err)
+	}
+
+	for i := 1; i < 20; i++ {
+		next, err := GenerateXML(guide)
+		if err != nil {
+			t.Fatalf("GenerateXML() error = %v", err)
+		}
+		if string(first) != string(next) {
+			t.Fatalf("run %d produced different output for identical input", i+1)
+		}
+	}
+}
+
+func TestGenerateXML_UnsupportedDocumentType(t *testing.T) {
+	_, err := GenerateXML(&DespatchAdvice{ID: "F001-1", TypeCode: "01"})
+
+	var unsupported *utils.ErrUnsupportedDocumentType
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("GenerateXML() error = %v, want *utils.ErrUnsupportedDocumentType", err)
+	}
+	if unsupported.Code != "01" || unsupported.Alternative != "GenerateInvoiceXML" {
+		t.Errorf("error = %+v, want code 01 pointing to GenerateInvoiceXML", unsupported)
+	}
+	if want := `gre.GenerateXML does not support document type "01" (supported: 09, 31); use GenerateInvoiceXML`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
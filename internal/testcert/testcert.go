@@ -0,0 +1,83 @@
+// Package testcert provides RSA certificates for the sunatlib tests
+package testcert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Certificate is a generated certificate with its private key
+type Certificate struct {
+	Cert *x509.Certificate // Parsed certificate
+	Key  *rsa.PrivateKey   // Private key of the certificate
+}
+
+// New creates a certificate for subject valid between notBefore and notAfter,
+// signed by issuer or self-signed when issuer is nil
+func New(t testing.TB, subject pkix.Name, notBefore, notAfter time.Time, issuer *Certificate) *Certificate {
+	t.Helper()
+	return create(t, subject, notBefore, notAfter, false, issuer)
+}
+
+// NewCA is like New but the certificate can sign other certificates
+func NewCA(t testing.TB, subject pkix.Name, notBefore, notAfter time.Time, issuer *Certificate) *Certificate {
+	t.Helper()
+	return create(t, subject, notBefore, notAfter, true, issuer)
+}
+
+func create(t testing.TB, subject pkix.Name, notBefore, notAfter time.Time, isCA bool, issuer *Certificate) *Certificate {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("failed to generate serial number: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	parent, parentKey := template, key
+	if issuer != nil {
+		parent, parentKey = issuer.Cert, issuer.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &Certificate{Cert: cert, Key: key}
+}
+
+// KeyPEM returns the private key PEM encoded in PKCS#1 form
+func (c *Certificate) KeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.Key)})
+}
+
+// CertPEM returns the certificate PEM encoded
+func (c *Certificate) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Cert.Raw})
+}
@@ -0,0 +1,610 @@
+// Package sunatlib provides the invoice (factura/boleta) model for SUNAT Peru
+package sunatlib
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// IGVRate is the general sales tax rate applied to taxed operations
+const IGVRate = 0.18
+
+// DefaultOperationType is the operation type used when none is set (Catálogo 51: venta interna)
+const DefaultOperationType = "0101"
+
+// Payment means (FormaPago) of an invoice
+const (
+	PaymentMeansContado = "Contado"
+	PaymentMeansCredito = "Credito"
+)
+
+// Placeholder recipient of boletas for low-value sales ("Varios - Ventas menores")
+const (
+	VariousCustomersDNI  = "00000000"
+	VariousCustomersName = "CLIENTES VARIOS"
+
+	// VariousCustomersMaxAmount is the boleta amount (in soles) above which the customer must be identified
+	VariousCustomersMaxAmount = 700.0
+)
+
+// Maximum lengths of the optional B2B references
+const (
+	MaxPurchaseOrderLength = 20
+	MaxInvoiceNoteLength   = 200
+)
+
+// Invoice represents an electronic invoice (01) or receipt (03) to be issued
+type Invoice struct {
+	DocumentType  string        // Document type code (01=Factura, 03=Boleta)
+	OperationType string        // Operation type (Catálogo 51: 0101=Venta interna, 0200=Exportación de bienes, ...); defaults to 0101
+	Series        string        // Document series (e.g., "F001", "B001")
+	Number        string        // Document correlative number
+	IssueDate     time.Time     // Issue date
+	DueDate       time.Time     // Due date (cbc:DueDate), required for Credito; empty or the issue date for Contado
+	Currency      string        // ISO 4217 currency code (PEN, USD, EUR)
+	ExchangeRate  float64       // Optional PEN per unit of Currency (tipo de cambio) for foreign-currency documents (cac:TaxExchangeRate)
+	Supplier      InvoiceParty  // Issuer of the document
+	Customer      InvoiceParty  // Recipient of the document
+	Items         []InvoiceItem // Document lines
+
+	TotalTaxed      float64 // Sum of taxed operations (gravadas)
+	TotalExonerated float64 // Sum of exonerated operations (exoneradas)
+	TotalUnaffected float64 // Sum of unaffected operations (inafectas)
+	TotalExport     float64 // Sum of export operations (exportación)
+	TotalIGV        float64 // Total IGV
+	TotalDiscounts  float64 // Optional global discounts that do not affect the tax base (cbc:AllowanceTotalAmount)
+	TotalPrepaid    float64 // Optional prepayments (anticipos) applied to the document (cbc:PrepaidAmount)
+	TotalAmount     float64 // Total payable amount (importe total), net of discounts and prepayments
+	NoIGV           bool    // Issued without IGV (Nuevo RUS, fully inafecto sales): no line may carry IGV and no IGV subtotal is emitted
+
+	PaymentMeans string        // FormaPago: Contado or Credito (defaults to Credito with installments, Contado otherwise)
+	Installments []Installment // Payment installments (cuotas), required for Credito
+	SignatureID  string        // Signature reference ID (defaults to signer.DefaultSignatureID)
+
+	PurchaseOrder    string         // Optional purchase order number (cac:OrderReference/cbc:ID)
+	Note             string         // Optional free-text observation (cbc:Note)
+	RelatedDocuments []DocReference // Optional related documents, such as the guías de remisión of the shipment
+
+	DeliveryAddress *InvoiceAddress // Optional delivery address when different from the customer's fiscal address (cac:Delivery)
+}
+
+// InvoiceAddress represents an address in Peru or abroad
+type InvoiceAddress struct {
+	Ubigeo      string // Optional INEI ubigeo code (e.g., "150101")
+	AddressLine string // Full address (street, number, district)
+	CountryCode string // ISO 3166-1 country code (defaults to PE)
+}
+
+// DocReference is a document related to an invoice. Guías de remisión (09 and 31, Catálogo 01)
+// are rendered as cac:DespatchDocumentReference, other documents (Catálogo 12) as
+// cac:AdditionalDocumentReference
+type DocReference struct {
+	Type string // Document type code (e.g., "09" for a guía de remisión remitente)
+	ID   string // Document identifier (e.g., "T001-123")
+}
+
+// despatchReferenceTypes are the guía de remisión types referenced with cac:DespatchDocumentReference (Catálogo 01)
+var despatchReferenceTypes = map[string]bool{
+	"09": true, // Guía de remisión remitente
+	"31": true, // Guía de remisión transportista
+}
+
+// additionalReferenceTypes are the related document types referenced with cac:AdditionalDocumentReference (Catálogo 12)
+var additionalReferenceTypes = map[string]bool{
+	"01": true, // Factura - emitida para corregir error en el RUC
+	"02": true, // Factura - emitida por anticipos
+	"03": true, // Boleta de venta - emitida por anticipos
+	"04": true, // Ticket de salida - ENAPU
+	"05": true, // Código SCOP
+	"99": true, // Otros
+}
+
+// MaxDocReferenceIDLength is the maximum length of a related document identifier
+const MaxDocReferenceIDLength = 30
+
+// IsDespatch returns true if the reference is a guía de remisión
+func (r DocReference) IsDespatch() bool {
+	return despatchReferenceTypes[r.Type]
+}
+
+// Validate checks the reference type and identifier. Guías must use the SERIE-NUMERO format
+func (r DocReference) Validate() error {
+	if !r.IsDespatch() && !additionalReferenceTypes[r.Type] {
+		return fmt.Errorf("invalid related document type: %s (expected 09 or 31, or a Catálogo 12 code)", r.Type)
+	}
+
+	id := strings.TrimSpace(r.ID)
+	if id == "" {
+		return fmt.Errorf("related document %s: ID is required", r.Type)
+	}
+	if len(id) > MaxDocReferenceIDLength {
+		return fmt.Errorf("related document %s: ID must be at most %d characters", r.Type, MaxDocReferenceIDLength)
+	}
+	if r.IsDespatch() {
+		if _, _, err := utils.SplitSerieNumero(id); err != nil {
+			return fmt.Errorf("related document %s: %w", r.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// Installment represents a payment installment (cuota) of a credit sale
+type Installment struct {
+	Amount  float64   // Installment amount
+	DueDate time.Time // Installment due date
+}
+
+// InvoiceParty represents the supplier or customer of an invoice
+type InvoiceParty struct {
+	DocumentType   string // Identity document type (Catálogo 06: 6=RUC, 1=DNI, 4=CE, 7=Pasaporte, 0=No domiciliado)
+	DocumentNumber string // Identity document number
+	Name           string // Registration name (razón social or full name)
+}
+
+// InvoiceItem represents a line of an invoice
+type InvoiceItem struct {
+	Description     string  // Item description
+	Quantity        float64 // Quantity sold
+	UnitCode        string  // Unit of measure (e.g., "NIU", "ZZ")
+	UnitValue       float64 // Unit value without taxes
+	AffectationCode string  // IGV affectation code (Catálogo 07)
+	IGVAmount       float64 // IGV amount of the line
+}
+
+// LineExtensionAmount returns the line value without taxes (quantity * unit value)
+func (item *InvoiceItem) LineExtensionAmount() float64 {
+	return roundAmount(item.Quantity * item.UnitValue)
+}
+
+// IsFree returns true if the line is a free transfer (operación gratuita)
+func (item *InvoiceItem) IsFree() bool {
+	switch item.AffectationCode {
+	case "11", "12", "13", "14", "15", "16", "21", "31", "32", "33", "34", "35", "36", "37":
+		return true
+	default:
+		return false
+	}
+}
+
+// VariousCustomers returns the placeholder recipient used for low-value boletas
+func VariousCustomers() InvoiceParty {
+	return InvoiceParty{DocumentType: "1", DocumentNumber: VariousCustomersDNI, Name: VariousCustomersName}
+}
+
+// IsVariousCustomers returns true if the party is the placeholder recipient of low-value boletas
+func (p *InvoiceParty) IsVariousCustomers() bool {
+	return p.DocumentType == "1" && p.DocumentNumber == VariousCustomersDNI
+}
+
+// SetVariousCustomers sets the placeholder recipient for a low-value boleta ("Varios - Ventas menores")
+func (inv *Invoice) SetVariousCustomers() {
+	inv.Customer = VariousCustomers()
+}
+
+// foreignIdentityTypes lists the identity document types (Catálogo 06) accepted for foreign customers
+var foreignIdentityTypes = map[string]bool{
+	"0": true, // Doc. trib. no dom. sin RUC
+	"4": true, // Carnet de extranjería
+	"6": true, // RUC
+	"7": true, // Pasaporte
+	"A": true, // Cédula diplomática de identidad
+	"B": true, // Doc. identidad país residencia - no domiciliado
+	"C": true, // Tax Identification Number - TIN
+	"D": true, // Identification Number - IN
+	"E": true, // TAM - Tarjeta Andina de Migración
+	"F": true, // Permiso temporal de permanencia - PTP
+	"G": true, // Salvoconducto
+}
+
+// EffectiveOperationType returns the operation type, defaulting to DefaultOperationType
+func (inv *Invoice) EffectiveOperationType() string {
+	if inv.OperationType == "" {
+		return DefaultOperationType
+	}
+	return inv.OperationType
+}
+
+// IsExport returns true for export operations (Catálogo 51: 0200-0208)
+func (inv *Invoice) IsExport() bool {
+	return strings.HasPrefix(inv.EffectiveOperationType(), "02")
+}
+
+// FullNumber returns the document identifier in SERIE-NUMERO format
+func (inv *Invoice) FullNumber() string {
+	return fmt.Sprintf("%s-%s", inv.Series, inv.Number)
+}
+
+// Validate validates the invoice before XML generation
+func (inv *Invoice) Validate() error {
+	if inv.DocumentType != "01" && inv.DocumentType != "03" {
+		return fmt.Errorf("invalid document type for invoice: %s (expected 01 or 03)", inv.DocumentType)
+	}
+
+	if !utils.ValidateDocumentSeries(inv.Series) {
+		return fmt.Errorf("invalid document series format: %s", inv.Series)
+	}
+
+	expectedPrefix := "F"
+	if inv.DocumentType == "03" {
+		expectedPrefix = "B"
+	}
+	if !strings.HasPrefix(inv.Series, expectedPrefix) {
+		return fmt.Errorf("series %s must start with %s for document type %s", inv.Series, expectedPrefix, inv.DocumentType)
+	}
+
+	if !utils.ValidateDocumentNumber(inv.Number) {
+		return fmt.Errorf("invalid document number format: %s", inv.Number)
+	}
+
+	if inv.IssueDate.IsZero() {
+		return fmt.Errorf("issue date is required")
+	}
+
+	if !inv.DueDate.IsZero() && inv.DueDate.Before(inv.IssueDate) {
+		return fmt.Errorf("due date %s is before issue date %s", inv.DueDate.Format("2006-01-02"), inv.IssueDate.Format("2006-01-02"))
+	}
+
+	if !utils.ValidateOperationType(inv.EffectiveOperationType()) {
+		return fmt.Errorf("invalid operation type: %s (Catálogo 51)", inv.OperationType)
+	}
+
+	if !utils.ValidateCurrencyCode(inv.Currency) {
+		return fmt.Errorf("invalid currency code: %s", inv.Currency)
+	}
+
+	if inv.ExchangeRate < 0 {
+		return fmt.Errorf("exchange rate must not be negative, got %v", inv.ExchangeRate)
+	}
+	if inv.ExchangeRate > 0 && inv.Currency == "PEN" {
+		return fmt.Errorf("exchange rate only applies to foreign-currency documents")
+	}
+
+	if utf8.RuneCountInString(inv.PurchaseOrder) > MaxPurchaseOrderLength {
+		return fmt.Errorf("purchase order must be at most %d characters", MaxPurchaseOrderLength)
+	}
+
+	for i, reference := range inv.RelatedDocuments {
+		if err := reference.Validate(); err != nil {
+			return fmt.Errorf("related document %d: %w", i+1, err)
+		}
+	}
+
+	if utf8.RuneCountInString(inv.Note) > MaxInvoiceNoteLength {
+		return fmt.Errorf("note must be at most %d characters", MaxInvoiceNoteLength)
+	}
+
+	if inv.Supplier.DocumentType != "6" {
+		return fmt.Errorf("supplier must be identified with RUC (document type 6)")
+	}
+
+	if !utils.ValidateRUC(inv.Supplier.DocumentNumber) {
+		return fmt.Errorf("invalid supplier RUC: %s", inv.Supplier.DocumentNumber)
+	}
+
+	if inv.Supplier.Name == "" {
+		return fmt.Errorf("supplier name is required")
+	}
+
+	if err := inv.validateCustomer(); err != nil {
+		return err
+	}
+
+	if inv.DeliveryAddress != nil {
+		if err := inv.DeliveryAddress.Validate(); err != nil {
+			return fmt.Errorf("delivery address: %w", err)
+		}
+	}
+
+	if len(inv.Items) == 0 {
+		return fmt.Errorf("at least one item is required")
+	}
+
+	// Validate each item
+	for i := range inv.Items {
+		if err := inv.Items[i].Validate(); err != nil {
+			return fmt.Errorf("item %d: %w", i+1, err)
+		}
+		if inv.IsExport() && inv.Items[i].AffectationCode != "40" {
+			return fmt.Errorf("item %d: export operations require affectation code 40, got %s", i+1, inv.Items[i].AffectationCode)
+		}
+		if inv.NoIGV && (taxPercent(inv.Items[i].AffectationCode) > 0 || inv.Items[i].IGVAmount != 0) {
+			return fmt.Errorf("item %d: documents without IGV cannot have IGV lines, got affectation code %s", i+1, inv.Items[i].AffectationCode)
+		}
+	}
+
+	if err := inv.validateTotals(); err != nil {
+		return err
+	}
+
+	return inv.validateInstallments()
+}
+
+// Validate validates the address
+func (a *InvoiceAddress) Validate() error {
+	if strings.TrimSpace(a.AddressLine) == "" {
+		return fmt.Errorf("address line is required")
+	}
+
+	if a.Ubigeo != "" && !utils.ValidateUbigeo(a.Ubigeo) {
+		return fmt.Errorf("invalid ubigeo: %s", a.Ubigeo)
+	}
+
+	if a.CountryCode != "" && len(a.CountryCode) != 2 {
+		return fmt.Errorf("invalid country code: %s", a.CountryCode)
+	}
+
+	return nil
+}
+
+// EffectiveCountryCode returns the country code, defaulting to PE
+func (a *InvoiceAddress) EffectiveCountryCode() string {
+	if a.CountryCode == "" {
+		return "PE"
+	}
+	return strings.ToUpper(a.CountryCode)
+}
+
+// EffectivePaymentMeans returns the payment means, defaulting to Credito when installments
+// are present and to Contado otherwise
+func (inv *Invoice) EffectivePaymentMeans() string {
+	if inv.PaymentMeans != "" {
+		return inv.PaymentMeans
+	}
+	if len(inv.Installments) > 0 {
+		return PaymentMeansCredito
+	}
+	return PaymentMeansContado
+}
+
+// CreditAmount returns the total amount payable in installments
+func (inv *Invoice) CreditAmount() float64 {
+	var total float64
+	for _, installment := range inv.Installments {
+		total += installment.Amount
+	}
+	return roundAmount(total)
+}
+
+// validateInstallments checks the installments against the payment means and that together
+// they cover the payable amount
+func (inv *Invoice) validateInstallments() error {
+	switch inv.EffectivePaymentMeans() {
+	case PaymentMeansContado:
+		if len(inv.Installments) > 0 {
+			return fmt.Errorf("installments are not allowed for payment means %s", PaymentMeansContado)
+		}
+		// SUNAT expects no due date, or the issue date, for cash sales
+		if !inv.DueDate.IsZero() && !sameDay(inv.DueDate, inv.IssueDate) {
+			return fmt.Errorf("due date %s must be the issue date for payment means %s", inv.DueDate.Format("2006-01-02"), PaymentMeansContado)
+		}
+		return nil
+	case PaymentMeansCredito:
+		if len(inv.Installments) == 0 {
+			return fmt.Errorf("at least one installment is required for payment means %s", PaymentMeansCredito)
+		}
+	default:
+		return fmt.Errorf("invalid payment means: %s (expected %s or %s)", inv.PaymentMeans, PaymentMeansContado, PaymentMeansCredito)
+	}
+
+	for i, installment := range inv.Installments {
+		if installment.Amount <= 0 {
+			return fmt.Errorf("installment %d: amount must be positive", i+1)
+		}
+		if err := utils.ValidateAmount(installment.Amount); err != nil {
+			return fmt.Errorf("installment %d: %w", i+1, err)
+		}
+		if installment.DueDate.IsZero() {
+			return fmt.Errorf("installment %d: due date is required", i+1)
+		}
+		if installment.DueDate.Before(inv.IssueDate) {
+			return fmt.Errorf("installment %d: due date %s is before issue date %s", i+1,
+				installment.DueDate.Format("2006-01-02"), inv.IssueDate.Format("2006-01-02"))
+		}
+	}
+
+	if !utils.AmountsMatch(inv.CreditAmount(), inv.TotalAmount) {
+		return fmt.Errorf("installments total %.2f does not match payable amount %.2f", inv.CreditAmount(), inv.TotalAmount)
+	}
+
+	if inv.DueDate.IsZero() {
+		return fmt.Errorf("due date is required for payment means %s", PaymentMeansCredito)
+	}
+
+	return nil
+}
+
+// sameDay returns true if both times fall on the same calendar date
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// validateCustomer validates the recipient according to the document type
+func (inv *Invoice) validateCustomer() error {
+	if inv.Customer.Name == "" {
+		return fmt.Errorf("customer name is required")
+	}
+
+	if inv.IsExport() {
+		return inv.validateForeignCustomer()
+	}
+
+	if inv.DocumentType == "01" {
+		if inv.Customer.DocumentType != "6" {
+			return fmt.Errorf("invoice customer must be identified with RUC (document type 6)")
+		}
+		if !utils.ValidateRUC(inv.Customer.DocumentNumber) {
+			return fmt.Errorf("invalid customer RUC: %s", inv.Customer.DocumentNumber)
+		}
+		return nil
+	}
+
+	if inv.Customer.DocumentType == "" {
+		return fmt.Errorf("customer document type is required")
+	}
+
+	if inv.Customer.DocumentType == "6" && !utils.ValidateRUC(inv.Customer.DocumentNumber) {
+		return fmt.Errorf("invalid customer RUC: %s", inv.Customer.DocumentNumber)
+	}
+
+	if inv.Customer.IsVariousCustomers() {
+		if inv.Currency != "PEN" {
+			return fmt.Errorf("various customers recipient requires currency PEN, got %s", inv.Currency)
+		}
+		if inv.TotalAmount > VariousCustomersMaxAmount {
+			return fmt.Errorf("boletas above %.2f must identify the customer (total %.2f)", VariousCustomersMaxAmount, inv.TotalAmount)
+		}
+		return nil
+	}
+
+	if inv.Customer.DocumentType == "1" && !IsValidDNI(inv.Customer.DocumentNumber) {
+		return fmt.Errorf("invalid customer DNI: %s", inv.Customer.DocumentNumber)
+	}
+
+	return nil
+}
+
+// validateForeignCustomer validates the recipient of an export, who may lack a Peruvian document
+func (inv *Invoice) validateForeignCustomer() error {
+	if !foreignIdentityTypes[inv.Customer.DocumentType] {
+		return fmt.Errorf("invalid customer document type for export: %s", inv.Customer.DocumentType)
+	}
+
+	if inv.Customer.DocumentType == "6" && !utils.ValidateRUC(inv.Customer.DocumentNumber) {
+		return fmt.Errorf("invalid customer RUC: %s", inv.Customer.DocumentNumber)
+	}
+
+	// Only "0" (no domiciliado sin RUC) may omit the document number
+	if inv.Customer.DocumentType != "0" && inv.Customer.DocumentNumber == "" {
+		return fmt.Errorf("customer document number is required for document type %s", inv.Customer.DocumentType)
+	}
+
+	return nil
+}
+
+// validateTotals checks that the declared totals match the totals computed from the items
+func (inv *Invoice) validateTotals() error {
+	if err := utils.ValidateAmount(inv.TotalDiscounts); err != nil {
+		return fmt.Errorf("invalid discounts total: %w", err)
+	}
+	if err := utils.ValidateAmount(inv.TotalPrepaid); err != nil {
+		return fmt.Errorf("invalid prepaid total: %w", err)
+	}
+
+	computed := inv.ComputeTotals()
+
+	checks := []struct {
+		name     string
+		declared float64
+		computed float64
+	}{
+		{"taxed total", inv.TotalTaxed, computed.TotalTaxed},
+		{"exonerated total", inv.TotalExonerated, computed.TotalExonerated},
+		{"unaffected total", inv.TotalUnaffected, computed.TotalUnaffected},
+		{"export total", inv.TotalExport, computed.TotalExport},
+		{"IGV total", inv.TotalIGV, computed.TotalIGV},
+		{"total amount", inv.TotalAmount, computed.TotalAmount},
+	}
+
+	for _, check := range checks {
+		if err := utils.ValidateAmount(check.declared); err != nil {
+			return fmt.Errorf("invalid %s: %w", check.name, err)
+		}
+		if !utils.AmountsMatch(check.declared, check.computed) {
+			return fmt.Errorf("inconsistent %s: declared %.2f, computed %.2f", check.name, check.declared, check.computed)
+		}
+	}
+
+	return nil
+}
+
+// InvoiceTotals holds the totals of an invoice computed from its items
+type InvoiceTotals struct {
+	TotalTaxed      float64
+	TotalExonerated float64
+	TotalUnaffected float64
+	TotalExport     float64
+	TotalIGV        float64
+	TotalAmount     float64
+}
+
+// ComputeTotals computes the invoice totals from its items; the total amount is net of the
+// declared global discounts and prepayments
+func (inv *Invoice) ComputeTotals() InvoiceTotals {
+	var totals InvoiceTotals
+
+	for i := range inv.Items {
+		item := &inv.Items[i]
+		if item.IsFree() {
+			continue
+		}
+
+		amount := item.LineExtensionAmount()
+		switch item.AffectationCode {
+		case "10", "17":
+			totals.TotalTaxed += amount
+			totals.TotalIGV += item.IGVAmount
+		case "20":
+			totals.TotalExonerated += amount
+		case "30":
+			totals.TotalUnaffected += amount
+		case "40":
+			totals.TotalExport += amount
+		}
+	}
+
+	totals.TotalTaxed = roundAmount(totals.TotalTaxed)
+	totals.TotalExonerated = roundAmount(totals.TotalExonerated)
+	totals.TotalUnaffected = roundAmount(totals.TotalUnaffected)
+	totals.TotalExport = roundAmount(totals.TotalExport)
+	totals.TotalIGV = roundAmount(totals.TotalIGV)
+	totals.TotalAmount = roundAmount(totals.TotalTaxed + totals.TotalExonerated + totals.TotalUnaffected + totals.TotalExport + totals.TotalIGV -
+		inv.TotalDiscounts - inv.TotalPrepaid)
+
+	return totals
+}
+
+// Validate validates a single invoice item
+func (item *InvoiceItem) Validate() error {
+	if item.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+
+	if item.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+
+	if item.UnitValue <= 0 {
+		return fmt.Errorf("unit value must be positive")
+	}
+
+	if item.IGVAmount < 0 {
+		return fmt.Errorf("IGV amount cannot be negative")
+	}
+
+	for _, amount := range []float64{item.UnitValue, item.IGVAmount, item.LineExtensionAmount()} {
+		if err := utils.ValidateAmount(amount); err != nil {
+			return err
+		}
+	}
+
+	if item.UnitCode == "" {
+		return fmt.Errorf("unit code is required")
+	}
+
+	if !utils.ValidateAffectationCode(item.AffectationCode) {
+		return fmt.Errorf("invalid affectation code: %s", item.AffectationCode)
+	}
+
+	return nil
+}
+
+// roundAmount rounds an amount to two decimals
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,978 @@
+package sunatlib
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// newTestInvoice returns a valid invoice with one taxed line for tests
+func newTestInvoice() *Invoice {
+	return &Invoice{
+		DocumentType: "01",
+		Series:       "F001",
+		Number:       "1",
+		IssueDate:    time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC),
+		Currency:     "PEN",
+		Supplier:     InvoiceParty{DocumentType: "6", DocumentNumber: "20000000001", Name: "MI EMPRESA S.A.C."},
+		Customer:     InvoiceParty{DocumentType: "6", DocumentNumber: "20100070970", Name: "CLIENTE S.A."},
+		Items: []InvoiceItem{
+			{Description: "PRODUCTO DE PRUEBA", Quantity: 2, UnitCode: "NIU", UnitValue: 50, AffectationCode: "10", IGVAmount: 18},
+		},
+		TotalTaxed:  100,
+		TotalIGV:    18,
+		TotalAmount: 118,
+	}
+}
+
+// setTestExport turns the test invoice into an export of goods with a single export line
+func setTestExport(inv *Invoice) {
+	inv.OperationType = "0200"
+	inv.Currency = "USD"
+	inv.Items = []InvoiceItem{
+		{Description: "CAFE ORGANICO DE EXPORTACION", Quantity: 2, UnitCode: "KGM", UnitValue: 50, AffectationCode: "40"},
+	}
+	inv.TotalTaxed = 0
+	inv.TotalIGV = 0
+	inv.TotalExport = 100
+	inv.TotalAmount = 100
+}
+
+func TestInvoice_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(inv *Invoice)
+		wantErr bool
+		msg     string
+	}{
+		{
+			name:   "Valid Invoice",
+			mutate: func(inv *Invoice) {},
+		},
+		{
+			name: "Valid Boleta With DNI",
+			mutate: func(inv *Invoice) {
+				inv.DocumentType = "03"
+				inv.Series = "B001"
+				inv.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+			},
+		},
+		{
+			name: "Valid Boleta For Various Customers",
+			mutate: func(inv *Invoice) {
+				inv.DocumentType = "03"
+				inv.Series = "B001"
+				inv.SetVariousCustomers()
+			},
+		},
+		{
+			name: "Various Customers Above Threshold",
+			mutate: func(inv *Invoice) {
+				inv.DocumentType = "03"
+				inv.Series = "B001"
+				inv.SetVariousCustomers()
+				inv.Items[0].Quantity = 12
+				inv.Items[0].IGVAmount = 108
+				inv.TotalTaxed = 600
+				inv.TotalIGV = 108
+				inv.TotalAmount = 708
+			},
+			wantErr: true,
+			msg:     "must identify the customer",
+		},
+		{
+			name: "Various Customers In Foreign Currency",
+			mutate: func(inv *Invoice) {
+				inv.DocumentType = "03"
+				inv.Series = "B001"
+				inv.Currency = "USD"
+				inv.SetVariousCustomers()
+			},
+			wantErr: true,
+			msg:     "requires currency PEN",
+		},
+		{
+			name:    "Various Customers On Factura",
+			mutate:  func(inv *Invoice) { inv.SetVariousCustomers() },
+			wantErr: true,
+			msg:     "must be identified with RUC",
+		},
+		{
+			name:    "Invalid Document Type",
+			mutate:  func(inv *Invoice) { inv.DocumentType = "07" },
+			wantErr: true,
+			msg:     "invalid document type",
+		},
+		{
+			name:    "Invoice Series With Boleta Prefix",
+			mutate:  func(inv *Invoice) { inv.Series = "B001" },
+			wantErr: true,
+			msg:     "must start with F",
+		},
+		{
+			name:    "Invalid Series Format",
+			mutate:  func(inv *Invoice) { inv.Series = "f1" },
+			wantErr: true,
+			msg:     "invalid document series format",
+		},
+		{
+			name:    "Invalid Number",
+			mutate:  func(inv *Invoice) { inv.Number = "ABC" },
+			wantErr: true,
+			msg:     "invalid document number format",
+		},
+		{
+			name:    "Missing Issue Date",
+			mutate:  func(inv *Invoice) { inv.IssueDate = time.Time{} },
+			wantErr: true,
+			msg:     "issue date is required",
+		},
+		{
+			name:    "Invalid Currency",
+			mutate:  func(inv *Invoice) { inv.Currency = "XXX" },
+			wantErr: true,
+			msg:     "invalid currency code",
+		},
+		{
+			name:    "Missing Supplier Document Type",
+			mutate:  func(inv *Invoice) { inv.Supplier.DocumentType = "" },
+			wantErr: true,
+			msg:     "supplier must be identified with RUC",
+		},
+		{
+			name:    "Supplier Without RUC",
+			mutate:  func(inv *Invoice) { inv.Supplier.DocumentType = "1" },
+			wantErr: true,
+			msg:     "supplier must be identified with RUC",
+		},
+		{
+			name:    "Invalid Supplier RUC",
+			mutate:  func(inv *Invoice) { inv.Supplier.DocumentNumber = "20123456789" },
+			wantErr: true,
+			msg:     "invalid supplier RUC",
+		},
+		{
+			name:    "Invoice Customer Without RUC",
+			mutate:  func(inv *Invoice) { inv.Customer.DocumentType = "1" },
+			wantErr: true,
+			msg:     "must be identified with RUC",
+		},
+		{
+			name:    "Invalid Customer RUC",
+			mutate:  func(inv *Invoice) { inv.Customer.DocumentNumber = "20123456789" },
+			wantErr: true,
+			msg:     "invalid customer RUC",
+		},
+		{
+			name:    "No Items",
+			mutate:  func(inv *Invoice) { inv.Items = nil },
+			wantErr: true,
+			msg:     "at least one item is required",
+		},
+		{
+			name:    "Non Positive Quantity",
+			mutate:  func(inv *Invoice) { inv.Items[0].Quantity = 0 },
+			wantErr: true,
+			msg:     "quantity must be positive",
+		},
+		{
+			name:    "Negative Unit Value",
+			mutate:  func(inv *Invoice) { inv.Items[0].UnitValue = -50 },
+			wantErr: true,
+			msg:     "unit value must be positive",
+		},
+		{
+			name:    "Invalid Affectation Code",
+			mutate:  func(inv *Invoice) { inv.Items[0].AffectationCode = "99" },
+			wantErr: true,
+			msg:     "invalid affectation code",
+		},
+		{
+			name:    "Inconsistent IGV Total",
+			mutate:  func(inv *Invoice) { inv.TotalIGV = 17 },
+			wantErr: true,
+			msg:     "inconsistent IGV total",
+		},
+		{
+			name: "Valid Installments",
+			mutate: func(inv *Invoice) {
+				inv.DueDate = time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)
+				inv.Installments = []Installment{
+					{Amount: 59, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)},
+					{Amount: 59, DueDate: time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)},
+				}
+			},
+		},
+		{
+			name: "Credito Without Due Date",
+			mutate: func(inv *Invoice) {
+				inv.Installments = []Installment{{Amount: 118, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)}}
+			},
+			wantErr: true,
+			msg:     "due date is required for payment means Credito",
+		},
+		{
+			name:   "Contado Without Due Date",
+			mutate: func(inv *Invoice) { inv.PaymentMeans = PaymentMeansContado },
+		},
+		{
+			name:   "Contado Due Date On Issue Date",
+			mutate: func(inv *Invoice) { inv.DueDate = time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC) },
+		},
+		{
+			name:    "Contado Due Date After Issue Date",
+			mutate:  func(inv *Invoice) { inv.DueDate = time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC) },
+			wantErr: true,
+			msg:     "due date 2026-05-27 must be the issue date for payment means Contado",
+		},
+		{
+			name: "Installments Not Matching Payable Amount",
+			mutate: func(inv *Invoice) {
+				inv.Installments = []Installment{
+					{Amount: 59, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)},
+					{Amount: 50, DueDate: time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)},
+				}
+			},
+			wantErr: true,
+			msg:     "installments total 109.00 does not match payable amount 118.00",
+		},
+		{
+			name: "Installment Without Due Date",
+			mutate: func(inv *Invoice) {
+				inv.Installments = []Installment{{Amount: 118}}
+			},
+			wantErr: true,
+			msg:     "installment 1: due date is required",
+		},
+		{
+			name: "Installment Due Before Issue Date",
+			mutate: func(inv *Invoice) {
+				inv.Installments = []Installment{{Amount: 118, DueDate: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)}}
+			},
+			wantErr: true,
+			msg:     "is before issue date",
+		},
+		{
+			name: "Export To Foreign Customer Without Document",
+			mutate: func(inv *Invoice) {
+				setTestExport(inv)
+				inv.Customer = InvoiceParty{DocumentType: "0", Name: "FOREIGN BUYER LLC"}
+			},
+		},
+		{
+			name:    "Foreign Customer Outside Export",
+			mutate:  func(inv *Invoice) { inv.Customer = InvoiceParty{DocumentType: "0", Name: "FOREIGN BUYER LLC"} },
+			wantErr: true,
+			msg:     "must be identified with RUC",
+		},
+		{
+			name: "Export Passport Without Number",
+			mutate: func(inv *Invoice) {
+				setTestExport(inv)
+				inv.Customer = InvoiceParty{DocumentType: "7", Name: "JOHN SMITH"}
+			},
+			wantErr: true,
+			msg:     "customer document number is required",
+		},
+		{
+			name: "Export With Taxed Line",
+			mutate: func(inv *Invoice) {
+				inv.OperationType = "0200"
+			},
+			wantErr: true,
+			msg:     "export operations require affectation code 40",
+		},
+		{
+			name:    "Credito Without Installments",
+			mutate:  func(inv *Invoice) { inv.PaymentMeans = PaymentMeansCredito },
+			wantErr: true,
+			msg:     "at least one installment is required for payment means Credito",
+		},
+		{
+			name: "Contado With Installments",
+			mutate: func(inv *Invoice) {
+				inv.PaymentMeans = PaymentMeansContado
+				inv.Installments = []Installment{{Amount: 118, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)}}
+			},
+			wantErr: true,
+			msg:     "installments are not allowed for payment means Contado",
+		},
+		{
+			name: "Invalid Related Document Type",
+			mutate: func(inv *Invoice) {
+				inv.RelatedDocuments = []DocReference{{Type: "01", ID: "F001-1"}, {Type: "12", ID: "X-1"}}
+			},
+			wantErr: true,
+			msg:     "related document 2: invalid related document type: 12",
+		},
+		{
+			name:    "Related Guia Without Number",
+			mutate:  func(inv *Invoice) { inv.RelatedDocuments = []DocReference{{Type: "09", ID: "T001"}} },
+			wantErr: true,
+			msg:     "related document 1: related document 09",
+		},
+		{
+			name:    "Amount Over Limit",
+			mutate:  func(inv *Invoice) { inv.Items[0].UnitValue = 2e12 },
+			wantErr: true,
+			msg:     "item 1: amount 2000000000000.00 exceeds the maximum",
+		},
+		{
+			name:    "IGV Line Without IGV",
+			mutate:  func(inv *Invoice) { inv.NoIGV = true },
+			wantErr: true,
+			msg:     "item 1: documents without IGV cannot have IGV lines",
+		},
+		{
+			name:    "Invalid Operation Type",
+			mutate:  func(inv *Invoice) { inv.OperationType = "0999" },
+			wantErr: true,
+			msg:     "invalid operation type: 0999",
+		},
+		{
+			name:    "Invalid Payment Means",
+			mutate:  func(inv *Invoice) { inv.PaymentMeans = "Cheque" },
+			wantErr: true,
+			msg:     "invalid payment means",
+		},
+		{
+			name:    "Due Date Before Issue Date",
+			mutate:  func(inv *Invoice) { inv.DueDate = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC) },
+			wantErr: true,
+			msg:     "due date 2026-04-01 is before issue date 2026-04-27",
+		},
+		{
+			name:    "Inconsistent Total Amount",
+			mutate:  func(inv *Invoice) { inv.TotalAmount = 120 },
+			wantErr: true,
+			msg:     "inconsistent total amount",
+		},
+		{
+			name: "Discounts And Prepayments",
+			mutate: func(inv *Invoice) {
+				inv.TotalDiscounts = 10
+				inv.TotalPrepaid = 50
+				inv.TotalAmount = 58
+			},
+			wantErr: false,
+		},
+		{
+			name: "Total Amount Without Discounts",
+			mutate: func(inv *Invoice) {
+				inv.TotalDiscounts = 10
+			},
+			wantErr: true,
+			msg:     "inconsistent total amount: declared 118.00, computed 108.00",
+		},
+		{
+			name:    "Negative Prepaid Total",
+			mutate:  func(inv *Invoice) { inv.TotalPrepaid = -5 },
+			wantErr: true,
+			msg:     "invalid prepaid total",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := newTestInvoice()
+			tt.mutate(inv)
+
+			err := inv.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && tt.msg != "" {
+				if !contains(err.Error(), tt.msg) {
+					t.Errorf("Validate() error = %v, want msg containing %v", err, tt.msg)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateInvoiceXML_Installments(t *testing.T) {
+	inv := newTestInvoice()
+	inv.DueDate = time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)
+	inv.Installments = []Installment{
+		{Amount: 59, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{Amount: 59, DueDate: time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)},
+	}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"<cbc:PaymentMeansID>Credito</cbc:PaymentMeansID>\n    <cbc:Amount currencyID=\"PEN\">118.00</cbc:Amount>",
+		"<cbc:PaymentMeansID>Cuota001</cbc:PaymentMeansID>\n    <cbc:Amount currencyID=\"PEN\">59.00</cbc:Amount>\n    <cbc:PaymentDueDate>2026-05-27</cbc:PaymentDueDate>",
+		"<cbc:PaymentMeansID>Cuota002</cbc:PaymentMeansID>\n    <cbc:Amount currencyID=\"PEN\">59.00</cbc:Amount>\n    <cbc:PaymentDueDate>2026-06-27</cbc:PaymentDueDate>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+
+	if strings.Contains(string(xmlContent), "Contado") {
+		t.Error("GenerateInvoiceXML() should not declare a cash sale when installments are present")
+	}
+}
+
+func TestGenerateInvoiceXML_VariousCustomers(t *testing.T) {
+	inv := newTestInvoice()
+	inv.DocumentType = "03"
+	inv.Series = "B001"
+	inv.SetVariousCustomers()
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	customer := string(xmlContent)
+	start := strings.Index(customer, "<cac:AccountingCustomerParty>")
+	end := strings.Index(customer, "</cac:AccountingCustomerParty>")
+	if start == -1 || end == -1 {
+		t.Fatal("GenerateInvoiceXML() missing cac:AccountingCustomerParty")
+	}
+	customer = customer[start:end]
+
+	for _, expected := range []string{
+		`schemeID="1"`,
+		">00000000</cbc:ID>",
+		"CLIENTES VARIOS",
+	} {
+		if !strings.Contains(customer, expected) {
+			t.Errorf("AccountingCustomerParty missing expected string: %s", expected)
+		}
+	}
+}
+
+func TestGenerateInvoiceXML_NoIGV(t *testing.T) {
+	inv := newTestInvoice()
+	inv.DocumentType = "03"
+	inv.Series = "B001"
+	inv.OperationType = "0113"
+	inv.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+	inv.NoIGV = true
+	inv.Items = []InvoiceItem{
+		{Description: "ABARROTES", Quantity: 4, UnitCode: "NIU", UnitValue: 12.5, AffectationCode: "30"},
+		{Description: "LIBRO", Quantity: 1, UnitCode: "NIU", UnitValue: 20, AffectationCode: "20"},
+	}
+	inv.TotalTaxed = 0
+	inv.TotalIGV = 0
+	inv.TotalUnaffected = 50
+	inv.TotalExonerated = 20
+	inv.TotalAmount = 70
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		`<cbc:InvoiceTypeCode listID="0113"`,
+		`<cbc:TaxAmount currencyID="PEN">0.00</cbc:TaxAmount>`,
+		`<cbc:Name>INA</cbc:Name>`,
+		`<cbc:Name>EXO</cbc:Name>`,
+		`<cbc:PayableAmount currencyID="PEN">70.00</cbc:PayableAmount>`,
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+
+	for _, unexpected := range []string{">1000</cbc:ID>", "<cbc:Name>IGV</cbc:Name>"} {
+		if strings.Contains(string(xmlContent), unexpected) {
+			t.Errorf("GenerateInvoiceXML() should not emit an IGV subtotal, found %s", unexpected)
+		}
+	}
+
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("generated boleta failed UBL validation: %v", err)
+	}
+}
+
+func TestGenerateInvoiceXML_Contado(t *testing.T) {
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"<cbc:ID>F001-1</cbc:ID>",
+		"<cbc:PaymentMeansID>Contado</cbc:PaymentMeansID>",
+		"<cbc:PayableAmount currencyID=\"PEN\">118.00</cbc:PayableAmount>",
+		"<cbc:URI>#SignatureSP</cbc:URI>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+
+	if strings.Contains(string(xmlContent), "Cuota") {
+		t.Error("GenerateInvoiceXML() should not render installments for a cash sale")
+	}
+}
+
+func TestGenerateInvoiceXML_DiscountsAndPrepayments(t *testing.T) {
+	inv := newTestInvoice()
+	inv.TotalDiscounts = 8
+	inv.TotalPrepaid = 60
+	inv.TotalAmount = 50
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	expected := `<cbc:TaxInclusiveAmount currencyID="PEN">118.00</cbc:TaxInclusiveAmount>
+    <cbc:AllowanceTotalAmount currencyID="PEN">8.00</cbc:AllowanceTotalAmount>
+    <cbc:PrepaidAmount currencyID="PEN">60.00</cbc:PrepaidAmount>
+    <cbc:PayableAmount currencyID="PEN">50.00</cbc:PayableAmount>`
+	if !strings.Contains(string(xmlContent), expected) {
+		t.Errorf("GenerateInvoiceXML() missing expected LegalMonetaryTotal:\n%s", expected)
+	}
+
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("generated invoice failed UBL validation: %v", err)
+	}
+
+	inv.TotalAmount = 118
+	if _, err := GenerateInvoiceXML(inv); err == nil || !contains(err.Error(), "inconsistent total amount") {
+		t.Errorf("GenerateInvoiceXML() error = %v, want an inconsistent total amount", err)
+	}
+}
+
+func TestGenerateInvoiceXML_InstallmentsSumMismatch(t *testing.T) {
+	inv := newTestInvoice()
+	inv.Installments = []Installment{{Amount: 100, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)}}
+
+	if _, err := GenerateInvoiceXML(inv); err == nil {
+		t.Fatal("Expected error when installments do not sum to the payable amount")
+	}
+}
+
+func TestGenerateInvoiceXML_Export(t *testing.T) {
+	inv := newTestInvoice()
+	setTestExport(inv)
+	inv.Customer = InvoiceParty{DocumentType: "7", DocumentNumber: "X1234567", Name: "JOHN SMITH"}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		`catalogo51">0200</cbc:ProfileID>`,
+		`<cbc:InvoiceTypeCode listID="0200"`,
+		`catalogo06">X1234567</cbc:ID>`,
+		`<cbc:TaxableAmount currencyID="USD">100.00</cbc:TaxableAmount>`,
+		`<cbc:Name>EXP</cbc:Name>`,
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("generated export invoice failed UBL validation: %v", err)
+	}
+}
+
+func TestGenerateInvoiceXML_ForeignCurrencyExchangeRate(t *testing.T) {
+	inv := newTestInvoice()
+	inv.Currency = "USD"
+	inv.ExchangeRate = 3.752
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	xmlStr := string(xmlContent)
+
+	for _, expected := range []string{
+		`<cbc:SourceCurrencyCode>USD</cbc:SourceCurrencyCode>`,
+		`<cbc:SourceCurrencyBaseRate>1</cbc:SourceCurrencyBaseRate>`,
+		`<cbc:TargetCurrencyCode>PEN</cbc:TargetCurrencyCode>`,
+		`<cbc:CalculationRate>3.752</cbc:CalculationRate>`,
+		`<cbc:Date>2026-04-27</cbc:Date>`,
+		`<cbc:TaxAmount currencyID="USD">18.00</cbc:TaxAmount>`,
+		`<cbc:TaxableAmount currencyID="USD">100.00</cbc:TaxableAmount>`,
+	} {
+		if !strings.Contains(xmlStr, expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+	if strings.Contains(xmlStr, `currencyID="PEN"`) {
+		t.Error("GenerateInvoiceXML() should express every amount in the document currency")
+	}
+
+	// UBL requires cac:TaxExchangeRate before the document cac:TaxTotal
+	if strings.Index(xmlStr, "<cac:TaxExchangeRate>") > strings.Index(xmlStr, "<cac:TaxTotal>") {
+		t.Error("cac:TaxExchangeRate must come before cac:TaxTotal")
+	}
+
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("generated USD invoice failed UBL validation: %v", err)
+	}
+}
+
+func TestGenerateInvoiceXML_ExchangeRateRules(t *testing.T) {
+	inv := newTestInvoice()
+	if xmlContent, err := GenerateInvoiceXML(inv); err != nil || strings.Contains(string(xmlContent), "TaxExchangeRate") {
+		t.Errorf("GenerateInvoiceXML() should not render an exchange rate for PEN documents (err = %v)", err)
+	}
+
+	inv.ExchangeRate = 3.75
+	if _, err := GenerateInvoiceXML(inv); err == nil {
+		t.Error("Expected error for an exchange rate on a PEN document")
+	}
+
+	inv.Currency = "USD"
+	inv.ExchangeRate = -1
+	if _, err := GenerateInvoiceXML(inv); err == nil {
+		t.Error("Expected error for a negative exchange rate")
+	}
+}
+
+func TestGenerateInvoiceXML_OperationType(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(inv *Invoice)
+		expected string
+	}{
+		{
+			name:     "default internal sale",
+			mutate:   func(inv *Invoice) {},
+			expected: `<cbc:InvoiceTypeCode listID="0101" listAgencyName="PE:SUNAT" listName="Tipo de Documento"` + "\n" + `    listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo01">01</cbc:InvoiceTypeCode>`,
+		},
+		{
+			name: "boleta internal sale",
+			mutate: func(inv *Invoice) {
+				inv.DocumentType = "03"
+				inv.Series = "B001"
+				inv.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+			},
+			expected: `listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo01">03</cbc:InvoiceTypeCode>`,
+		},
+		{
+			name: "export of goods",
+			mutate: func(inv *Invoice) {
+				setTestExport(inv)
+				inv.Customer = InvoiceParty{DocumentType: "7", DocumentNumber: "X1234567", Name: "JOHN SMITH"}
+			},
+			expected: `<cbc:InvoiceTypeCode listID="0200"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := newTestInvoice()
+			tt.mutate(inv)
+
+			xmlContent, err := GenerateInvoiceXML(inv)
+			if err != nil {
+				t.Fatalf("GenerateInvoiceXML() error = %v", err)
+			}
+			if !strings.Contains(string(xmlContent), tt.expected) {
+				t.Errorf("GenerateInvoiceXML() missing expected string: %s", tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateInvoiceXML_ExportWithoutDocument(t *testing.T) {
+	inv := newTestInvoice()
+	setTestExport(inv)
+	inv.Customer = InvoiceParty{DocumentType: "0", Name: "FOREIGN BUYER LLC"}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	expected := `<cbc:ID schemeID="0" schemeName="Documento de Identidad"`
+	if !strings.Contains(string(xmlContent), expected) {
+		t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+	}
+	if !strings.Contains(string(xmlContent), `catalogo06">-</cbc:ID>`) {
+		t.Error("GenerateInvoiceXML() should identify a customer without document as \"-\"")
+	}
+}
+
+func TestGenerateInvoiceXML_PaymentMeans(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(inv *Invoice)
+		expected []string
+	}{
+		{
+			name:   "Contado Without Due Date",
+			mutate: func(inv *Invoice) {},
+			expected: []string{
+				"<cbc:PaymentMeansID>Contado</cbc:PaymentMeansID>",
+			},
+		},
+		{
+			name: "Contado With Due Date",
+			mutate: func(inv *Invoice) {
+				inv.PaymentMeans = PaymentMeansContado
+				inv.DueDate = inv.IssueDate
+			},
+			expected: []string{
+				"<cbc:IssueTime>00:00:00</cbc:IssueTime>\n  <cbc:DueDate>2026-04-27</cbc:DueDate>",
+				"<cbc:PaymentMeansID>Contado</cbc:PaymentMeansID>",
+			},
+		},
+		{
+			name: "Credito",
+			mutate: func(inv *Invoice) {
+				inv.PaymentMeans = PaymentMeansCredito
+				inv.DueDate = time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)
+				inv.Installments = []Installment{{Amount: 118, DueDate: inv.DueDate}}
+			},
+			expected: []string{
+				"<cbc:DueDate>2026-05-27</cbc:DueDate>",
+				"<cbc:PaymentMeansID>Credito</cbc:PaymentMeansID>",
+				"<cbc:PaymentMeansID>Cuota001</cbc:PaymentMeansID>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := newTestInvoice()
+			tt.mutate(inv)
+
+			xmlContent, err := GenerateInvoiceXML(inv)
+			if err != nil {
+				t.Fatalf("GenerateInvoiceXML() error = %v", err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(string(xmlContent), expected) {
+					t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+				}
+			}
+			if inv.DueDate.IsZero() && strings.Contains(string(xmlContent), "<cbc:DueDate>") {
+				t.Error("GenerateInvoiceXML() should not emit cbc:DueDate when none is set")
+			}
+		})
+	}
+}
+
+func TestGenerateInvoiceXML_OrderReferenceAndNote(t *testing.T) {
+	inv := newTestInvoice()
+	inv.PurchaseOrder = "OC-2026-0042"
+	inv.Note = "Entrega en almacén central"
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"<cac:OrderReference>\n    <cbc:ID>OC-2026-0042</cbc:ID>\n  </cac:OrderReference>",
+		"<cbc:Note><![CDATA[Entrega en almacén central]]></cbc:Note>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("UBL validation error = %v", err)
+	}
+
+	xmlContent, err = GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	for _, unexpected := range []string{"cac:OrderReference", "cbc:Note"} {
+		if strings.Contains(string(xmlContent), unexpected) {
+			t.Errorf("GenerateInvoiceXML() should omit %s when empty", unexpected)
+		}
+	}
+
+	inv.PurchaseOrder = strings.Repeat("9", MaxPurchaseOrderLength+1)
+	if err := inv.Validate(); err == nil || !strings.Contains(err.Error(), "purchase order") {
+		t.Errorf("Expected purchase order length error, got %v", err)
+	}
+
+	// The limit is in characters, not bytes
+	inv.PurchaseOrder = strings.Repeat("Ñ", MaxPurchaseOrderLength)
+	if err := inv.Validate(); err != nil {
+		t.Errorf("Validate() error = %v for a purchase order of %d characters", err, MaxPurchaseOrderLength)
+	}
+}
+
+func TestGenerateInvoiceXML_CDATAEnd(t *testing.T) {
+	inv := newTestInvoice()
+	inv.Note = "Ver cláusula ]]> del contrato"
+	inv.Customer.Name = "CLIENTE ]]> S.A."
+	inv.Items[0].Description = "PRODUCTO ]]>"
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	var parsed struct {
+		Note     string `xml:"Note"`
+		Customer struct {
+			Name string `xml:"Party>PartyLegalEntity>RegistrationName"`
+		} `xml:"AccountingCustomerParty"`
+		Lines []struct {
+			Description string `xml:"Item>Description"`
+		} `xml:"InvoiceLine"`
+	}
+	if err := xml.Unmarshal(xmlContent, &parsed); err != nil {
+		t.Fatalf("generated XML is malformed: %v", err)
+	}
+	if parsed.Note != inv.Note || parsed.Customer.Name != inv.Customer.Name || parsed.Lines[0].Description != inv.Items[0].Description {
+		t.Errorf("parsed note %q, customer %q and description %q, want the original text", parsed.Note, parsed.Customer.Name, parsed.Lines[0].Description)
+	}
+}
+
+func TestGenerateInvoiceXML_DeliveryAddress(t *testing.T) {
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	if strings.Contains(string(xmlContent), "cac:Delivery") {
+		t.Error("GenerateInvoiceXML() should omit cac:Delivery without a delivery address")
+	}
+
+	inv := newTestInvoice()
+	inv.DeliveryAddress = &InvoiceAddress{Ubigeo: "150131", AddressLine: "Av. Javier Prado Este 123, San Isidro"}
+
+	xmlContent, err = GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	for _, expected := range []string{
+		`<cbc:ID schemeAgencyName="PE:INEI" schemeName="Ubigeos">150131</cbc:ID>`,
+		"<cbc:Line><![CDATA[Av. Javier Prado Este 123, San Isidro]]></cbc:Line>",
+		">PE</cbc:IdentificationCode>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+	if strings.Index(string(xmlContent), "<cac:Delivery>") < strings.Index(string(xmlContent), "</cac:AccountingCustomerParty>") {
+		t.Error("cac:Delivery must follow cac:AccountingCustomerParty")
+	}
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("UBL validation error = %v", err)
+	}
+
+	inv.DeliveryAddress.AddressLine = "Lote ]]> Mz. B"
+	xmlContent, err = GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	var parsed struct {
+		Line string `xml:"Delivery>DeliveryLocation>Address>AddressLine>Line"`
+	}
+	if err := xml.Unmarshal(xmlContent, &parsed); err != nil || parsed.Line != inv.DeliveryAddress.AddressLine {
+		t.Errorf("delivery line = %q (%v), want %q", parsed.Line, err, inv.DeliveryAddress.AddressLine)
+	}
+
+	for _, ubigeo := range []string{"15013", "990101", "15O131"} {
+		inv.DeliveryAddress.Ubigeo = ubigeo
+		if err := inv.Validate(); err == nil || !strings.Contains(err.Error(), "ubigeo") {
+			t.Errorf("Validate() with ubigeo %q error = %v, want invalid ubigeo", ubigeo, err)
+		}
+	}
+}
+
+func TestGenerateInvoiceXML_DeclaredPayableAmount(t *testing.T) {
+	// A total rounded one cent below its components must be emitted as declared, so that it
+	// still equals the sum of the installments
+	inv := newTestInvoice()
+	inv.TotalAmount = 117.99
+	inv.DueDate = time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)
+	inv.Installments = []Installment{
+		{Amount: 59, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{Amount: 58.99, DueDate: time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)},
+	}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	for _, expected := range []string{
+		`<cbc:Amount currencyID="PEN">117.99</cbc:Amount>`,
+		`<cbc:PayableAmount currencyID="PEN">117.99</cbc:PayableAmount>`,
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", expected)
+		}
+	}
+}
+
+func TestGenerateMonetaryTotalXML(t *testing.T) {
+	total, err := utils.BuildMonetaryTotal(utils.InvoiceTotals{TotalTaxed: 100, TotalTaxes: 18, TotalDiscounts: 8, TotalPrepaid: 50})
+	if err != nil {
+		t.Fatalf("BuildMonetaryTotal() error = %v", err)
+	}
+
+	got := generateMonetaryTotalXML("PEN", total)
+	want := `
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="PEN">100.00</cbc:LineExtensionAmount>
+    <cbc:TaxInclusiveAmount currencyID="PEN">118.00</cbc:TaxInclusiveAmount>
+    <cbc:AllowanceTotalAmount currencyID="PEN">8.00</cbc:AllowanceTotalAmount>
+    <cbc:PrepaidAmount currencyID="PEN">50.00</cbc:PrepaidAmount>
+    <cbc:PayableAmount currencyID="PEN">60.00</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>`
+	if got != want {
+		t.Errorf("generateMonetaryTotalXML() =%s\nwant%s", got, want)
+	}
+}
+
+func TestGenerateInvoiceXML_RelatedDocuments(t *testing.T) {
+	inv := newTestInvoice()
+	inv.PurchaseOrder = "OC-2026-0042"
+	inv.RelatedDocuments = []DocReference{
+		{Type: "99", ID: "CONTRATO-15"},
+		{Type: "09", ID: "t001-123"},
+		{Type: "31", ID: "V001-9"},
+	}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	content := string(xmlContent)
+
+	expected := []string{
+		"<cac:DespatchDocumentReference>\n    <cbc:ID>T001-123</cbc:ID>",
+		"catalogo01\">09</cbc:DocumentTypeCode>",
+		"<cac:DespatchDocumentReference>\n    <cbc:ID>V001-9</cbc:ID>",
+		"catalogo01\">31</cbc:DocumentTypeCode>",
+		"<cac:AdditionalDocumentReference>\n    <cbc:ID>CONTRATO-15</cbc:ID>",
+		"catalogo12\">99</cbc:DocumentTypeCode>",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("GenerateInvoiceXML() missing expected string: %s", want)
+		}
+	}
+
+	// UBL order: OrderReference, DespatchDocumentReference, AdditionalDocumentReference, Signature
+	order := []string{"<cac:OrderReference>", "<cac:DespatchDocumentReference>", "<cac:AdditionalDocumentReference>", "<cac:Signature>"}
+	for i := 1; i < len(order); i++ {
+		if strings.Index(content, order[i-1]) > strings.Index(content, order[i]) {
+			t.Errorf("%s should come before %s", order[i-1], order[i])
+		}
+	}
+
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("generated invoice failed UBL validation: %v", err)
+	}
+}
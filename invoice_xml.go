@@ -0,0 +1,492 @@
+// Package sunatlib provides UBL 2.1 XML generation for invoices and receipts
+package sunatlib
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// taxCategory describes how an IGV affectation code (Catálogo 07) maps to UBL tax elements
+type taxCategory struct {
+	CategoryID string // UN/ECE 5305 tax category (S, E, O, Z, G)
+	SchemeID   string // SUNAT tax scheme code (Catálogo 05)
+	SchemeName string // SUNAT tax scheme name
+	TypeCode   string // UN/ECE 5153 tax type code
+}
+
+// documentTaxSchemes lists the tax schemes in the order they are emitted at document level
+var documentTaxSchemes = []taxCategory{
+	{CategoryID: "S", SchemeID: "1000", SchemeName: "IGV", TypeCode: "VAT"},
+	{CategoryID: "S", SchemeID: "1016", SchemeName: "IVAP", TypeCode: "VAT"},
+	{CategoryID: "G", SchemeID: "9995", SchemeName: "EXP", TypeCode: "FRE"},
+	{CategoryID: "Z", SchemeID: "9996", SchemeName: "GRA", TypeCode: "FRE"},
+	{CategoryID: "E", SchemeID: "9997", SchemeName: "EXO", TypeCode: "VAT"},
+	{CategoryID: "O", SchemeID: "9998", SchemeName: "INA", TypeCode: "FRE"},
+}
+
+// taxSchemeIndex returns the position of a tax scheme in documentTaxSchemes
+func taxSchemeIndex(schemeID string) int {
+	for i, category := range documentTaxSchemes {
+		if category.SchemeID == schemeID {
+			return i
+		}
+	}
+	return -1
+}
+
+// taxCategoryFor returns the tax category for an IGV affectation code
+func taxCategoryFor(affectationCode string) taxCategory {
+	var schemeID string
+	switch affectationCode {
+	case "10":
+		schemeID = "1000"
+	case "17":
+		schemeID = "1016"
+	case "20":
+		schemeID = "9997"
+	case "30":
+		schemeID = "9998"
+	case "40":
+		schemeID = "9995"
+	default:
+		schemeID = "9996" // Free transfers (gratuitas)
+	}
+
+	return documentTaxSchemes[taxSchemeIndex(schemeID)]
+}
+
+// taxPercent returns the IGV percent applicable to an affectation code
+func taxPercent(affectationCode string) float64 {
+	switch affectationCode {
+	case "10", "11", "12", "13", "14", "15", "16":
+		return IGVRate * 100
+	case "17":
+		return 4
+	default:
+		return 0
+	}
+}
+
+// GenerateInvoiceXML generates the UBL 2.1 XML for an invoice (01) or receipt (03)
+func GenerateInvoiceXML(inv *Invoice) ([]byte, error) {
+	if inv.DocumentType != "01" && inv.DocumentType != "03" {
+		return nil, utils.NewUnsupportedDocumentTypeError(inv.DocumentType, "GenerateInvoiceXML", "01", "03")
+	}
+
+	if err := inv.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+
+	version, _ := utils.GetDocumentVersion(inv.DocumentType)
+
+	signatureID := inv.SignatureID
+	if signatureID == "" {
+		signatureID = signer.DefaultSignatureID
+	}
+
+	// Foreign customers without a document (type 0) are identified with "-"
+	customerDocumentNumber := inv.Customer.DocumentNumber
+	if customerDocumentNumber == "" {
+		customerDocumentNumber = "-"
+	}
+
+	dueDateXML := ""
+	if !inv.DueDate.IsZero() {
+		dueDateXML = fmt.Sprintf(`
+  <cbc:DueDate>%s</cbc:DueDate>`, inv.DueDate.Format("2006-01-02"))
+	}
+
+	noteXML := ""
+	if note := strings.TrimSpace(inv.Note); note != "" {
+		noteXML = fmt.Sprintf(`
+  <cbc:Note><![CDATA[%s]]></cbc:Note>`, utils.EscapeCDATA(utils.StripControlCharacters(note)))
+	}
+
+	orderReferenceXML := ""
+	if purchaseOrder := strings.TrimSpace(inv.PurchaseOrder); purchaseOrder != "" {
+		orderReferenceXML = fmt.Sprintf(`
+  <cac:OrderReference>
+    <cbc:ID>%s</cbc:ID>
+  </cac:OrderReference>`, html.EscapeString(purchaseOrder))
+	}
+	orderReferenceXML += generateRelatedDocumentsXML(inv)
+
+	xmlContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+  xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+  xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+  xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+  xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>%s</cbc:UBLVersionID>
+  <cbc:CustomizationID schemeAgencyName="PE:SUNAT">%s</cbc:CustomizationID>
+  <cbc:ProfileID schemeName="Tipo de Operacion" schemeAgencyName="PE:SUNAT"
+    schemeURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo51">%s</cbc:ProfileID>
+  <cbc:ID>%s</cbc:ID>
+  <cbc:IssueDate>%s</cbc:IssueDate>
+  <cbc:IssueTime>%s</cbc:IssueTime>%s
+  <cbc:InvoiceTypeCode listID="%s" listAgencyName="PE:SUNAT" listName="Tipo de Documento"
+    listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo01">%s</cbc:InvoiceTypeCode>%s
+  <cbc:DocumentCurrencyCode listID="ISO 4217 Alpha" listName="Currency"
+    listAgencyName="United Nations Economic Commission for Europe">%s</cbc:DocumentCurrencyCode>
+  <cbc:LineCountNumeric>%d</cbc:LineCountNumeric>%s
+  <cac:Signature>
+    <cbc:ID>%s</cbc:ID>
+    <cac:SignatoryParty>
+      <cac:PartyIdentification><cbc:ID>%s</cbc:ID></cac:PartyIdentification>
+      <cac:PartyName><cbc:Name><![CDATA[%s]]></cbc:Name></cac:PartyName>
+    </cac:SignatoryParty>
+    <cac:DigitalSignatureAttachment>
+      <cac:ExternalReference><cbc:URI>#%s</cbc:URI></cac:ExternalReference>
+    </cac:DigitalSignatureAttachment>
+  </cac:Signature>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyIdentification>
+        <cbc:ID schemeID="%s" schemeName="Documento de Identidad"
+          schemeAgencyName="PE:SUNAT"
+          schemeURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo06">%s</cbc:ID>
+      </cac:PartyIdentification>
+      <cac:PartyLegalEntity>
+        <cbc:RegistrationName><![CDATA[%s]]></cbc:RegistrationName>
+        <cac:RegistrationAddress>
+          <cbc:AddressTypeCode listAgencyName="PE:SUNAT" listName="Establecimientos anexos">0000</cbc:AddressTypeCode>
+        </cac:RegistrationAddress>
+      </cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cac:Party>
+      <cac:PartyIdentification>
+        <cbc:ID schemeID="%s" schemeName="Documento de Identidad"
+          schemeAgencyName="PE:SUNAT"
+          schemeURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo06">%s</cbc:ID>
+      </cac:PartyIdentification>
+      <cac:PartyLegalEntity>
+        <cbc:RegistrationName><![CDATA[%s]]></cbc:RegistrationName>
+      </cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingCustomerParty>`,
+		version.UBLVersionID,
+		version.CustomizationID,
+		inv.EffectiveOperationType(),
+		inv.FullNumber(),
+		inv.IssueDate.Format("2006-01-02"),
+		inv.IssueDate.Format("15:04:05"),
+		dueDateXML,
+		inv.EffectiveOperationType(),
+		inv.DocumentType,
+		noteXML,
+		inv.Currency,
+		len(inv.Items),
+		orderReferenceXML,
+		signatureID,
+		inv.Supplier.DocumentNumber,
+		utils.EscapeCDATA(inv.Supplier.Name),
+		signatureID,
+		inv.Supplier.DocumentType,
+		inv.Supplier.DocumentNumber,
+		utils.EscapeCDATA(inv.Supplier.Name),
+		inv.Customer.DocumentType,
+		customerDocumentNumber,
+		utils.EscapeCDATA(inv.Customer.Name))
+
+	xmlContent += generateDeliveryXML(inv)
+	xmlContent += generatePaymentTermsXML(inv)
+	xmlContent += generateTaxExchangeRateXML(inv)
+	xmlContent += generateDocumentTaxTotalXML(inv)
+
+	monetaryTotal, err := utils.BuildMonetaryTotal(utils.InvoiceTotals{
+		TotalTaxed:      inv.TotalTaxed,
+		TotalExonerated: inv.TotalExonerated,
+		TotalUnaffected: inv.TotalUnaffected,
+		TotalExport:     inv.TotalExport,
+		TotalTaxes:      inv.TotalIGV,
+		TotalDiscounts:  inv.TotalDiscounts,
+		TotalPrepaid:    inv.TotalPrepaid,
+		PayableAmount:   inv.TotalAmount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+	xmlContent += generateMonetaryTotalXML(inv.Currency, monetaryTotal)
+
+	// Add invoice lines
+	for i := range inv.Items {
+		xmlContent += generateInvoiceLineXML(i+1, &inv.Items[i], inv.Currency)
+	}
+
+	xmlContent += `
+</Invoice>`
+
+	return []byte(xmlContent), nil
+}
+
+// generateRelatedDocumentsXML renders the related documents: guías de remisión first, as UBL
+// requires cac:DespatchDocumentReference before cac:AdditionalDocumentReference
+func generateRelatedDocumentsXML(inv *Invoice) string {
+	despatchXML, additionalXML := "", ""
+	for _, reference := range inv.RelatedDocuments {
+		id := html.EscapeString(strings.ToUpper(strings.TrimSpace(reference.ID)))
+		if reference.IsDespatch() {
+			despatchXML += fmt.Sprintf(`
+  <cac:DespatchDocumentReference>
+    <cbc:ID>%s</cbc:ID>
+    <cbc:DocumentTypeCode listAgencyName="PE:SUNAT" listName="Tipo de Documento"
+      listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo01">%s</cbc:DocumentTypeCode>
+  </cac:DespatchDocumentReference>`, id, reference.Type)
+			continue
+		}
+		additionalXML += fmt.Sprintf(`
+  <cac:AdditionalDocumentReference>
+    <cbc:ID>%s</cbc:ID>
+    <cbc:DocumentTypeCode listAgencyName="PE:SUNAT" listName="Documento Relacionado"
+      listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo12">%s</cbc:DocumentTypeCode>
+  </cac:AdditionalDocumentReference>`, id, reference.Type)
+	}
+	return despatchXML + additionalXML
+}
+
+// generateMonetaryTotalXML renders cac:LegalMonetaryTotal. Discounts and prepayments are
+// only rendered when present
+func generateMonetaryTotalXML(currency string, total utils.MonetaryTotal) string {
+	monetaryXML := fmt.Sprintf(`
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="%s">%.2f</cbc:LineExtensionAmount>
+    <cbc:TaxInclusiveAmount currencyID="%s">%.2f</cbc:TaxInclusiveAmount>`,
+		currency, total.LineExtensionAmount,
+		currency, total.TaxInclusiveAmount)
+
+	if total.AllowanceTotalAmount > 0 {
+		monetaryXML += fmt.Sprintf(`
+    <cbc:AllowanceTotalAmount currencyID="%s">%.2f</cbc:AllowanceTotalAmount>`, currency, total.AllowanceTotalAmount)
+	}
+	if total.PrepaidAmount > 0 {
+		monetaryXML += fmt.Sprintf(`
+    <cbc:PrepaidAmount currencyID="%s">%.2f</cbc:PrepaidAmount>`, currency, total.PrepaidAmount)
+	}
+
+	return monetaryXML + fmt.Sprintf(`
+    <cbc:PayableAmount currencyID="%s">%.2f</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>`, currency, total.PayableAmount)
+}
+
+// generateDeliveryXML renders the optional cac:Delivery block with the delivery address
+func generateDeliveryXML(inv *Invoice) string {
+	address := inv.DeliveryAddress
+	if address == nil {
+		return ""
+	}
+
+	ubigeoXML := ""
+	if address.Ubigeo != "" {
+		ubigeoXML = fmt.Sprintf(`
+        <cbc:ID schemeAgencyName="PE:INEI" schemeName="Ubigeos">%s</cbc:ID>`, address.Ubigeo)
+	}
+
+	return fmt.Sprintf(`
+  <cac:Delivery>
+    <cac:DeliveryLocation>
+      <cac:Address>%s
+        <cac:AddressLine>
+          <cbc:Line><![CDATA[%s]]></cbc:Line>
+        </cac:AddressLine>
+        <cac:Country>
+          <cbc:IdentificationCode listID="ISO 3166-1" listAgencyName="United Nations Economic Commission for Europe"
+            listName="Country">%s</cbc:IdentificationCode>
+        </cac:Country>
+      </cac:Address>
+    </cac:DeliveryLocation>
+  </cac:Delivery>`,
+		ubigeoXML,
+		utils.EscapeCDATA(utils.StripControlCharacters(strings.TrimSpace(address.AddressLine))),
+		address.EffectiveCountryCode())
+}
+
+// generatePaymentTermsXML renders the FormaPago block, with one cuota per installment on credit sales
+func generatePaymentTermsXML(inv *Invoice) string {
+	if inv.EffectivePaymentMeans() == PaymentMeansContado {
+		return fmt.Sprintf(`
+  <cac:PaymentTerms>
+    <cbc:ID>FormaPago</cbc:ID>
+    <cbc:PaymentMeansID>%s</cbc:PaymentMeansID>
+  </cac:PaymentTerms>`, PaymentMeansContado)
+	}
+
+	paymentTerms := fmt.Sprintf(`
+  <cac:PaymentTerms>
+    <cbc:ID>FormaPago</cbc:ID>
+    <cbc:PaymentMeansID>%s</cbc:PaymentMeansID>
+    <cbc:Amount currencyID="%s">%.2f</cbc:Amount>
+  </cac:PaymentTerms>`, PaymentMeansCredito, inv.Currency, inv.CreditAmount())
+
+	for i, installment := range inv.Installments {
+		paymentTerms += fmt.Sprintf(`
+  <cac:PaymentTerms>
+    <cbc:ID>FormaPago</cbc:ID>
+    <cbc:PaymentMeansID>Cuota%03d</cbc:PaymentMeansID>
+    <cbc:Amount currencyID="%s">%.2f</cbc:Amount>
+    <cbc:PaymentDueDate>%s</cbc:PaymentDueDate>
+  </cac:PaymentTerms>`,
+			i+1,
+			inv.Currency,
+			installment.Amount,
+			installment.DueDate.Format("2006-01-02"))
+	}
+
+	return paymentTerms
+}
+
+// generateTaxExchangeRateXML renders the cac:TaxExchangeRate block of a foreign-currency document:
+// tax amounts stay in the document currency and the exchange rate gives their PEN reference
+func generateTaxExchangeRateXML(inv *Invoice) string {
+	if inv.ExchangeRate <= 0 || inv.Currency == "PEN" {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+  <cac:TaxExchangeRate>
+    <cbc:SourceCurrencyCode>%s</cbc:SourceCurrencyCode>
+    <cbc:SourceCurrencyBaseRate>1</cbc:SourceCurrencyBaseRate>
+    <cbc:TargetCurrencyCode>PEN</cbc:TargetCurrencyCode>
+    <cbc:TargetCurrencyBaseRate>1</cbc:TargetCurrencyBaseRate>
+    <cbc:CalculationRate>%s</cbc:CalculationRate>
+    <cbc:Date>%s</cbc:Date>
+  </cac:TaxExchangeRate>`,
+		inv.Currency,
+		strconv.FormatFloat(inv.ExchangeRate, 'f', -1, 64),
+		inv.IssueDate.Format("2006-01-02"))
+}
+
+// generateDocumentTaxTotalXML renders the single document-level cac:TaxTotal (SUNAT 3024)
+func generateDocumentTaxTotalXML(inv *Invoice) string {
+	// Accumulate per scheme in documentTaxSchemes order so the output never depends on map iteration
+	taxable := make([]float64, len(documentTaxSchemes))
+	taxes := make([]float64, len(documentTaxSchemes))
+	present := make([]bool, len(documentTaxSchemes))
+	for i := range inv.Items {
+		item := &inv.Items[i]
+		index := taxSchemeIndex(taxCategoryFor(item.AffectationCode).SchemeID)
+		taxable[index] += item.LineExtensionAmount()
+		taxes[index] += item.IGVAmount
+		present[index] = true
+	}
+
+	taxTotal := fmt.Sprintf(`
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>`, inv.Currency, inv.TotalIGV)
+
+	for index, category := range documentTaxSchemes {
+		if !present[index] {
+			continue
+		}
+		taxTotal += fmt.Sprintf(`
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="%s">%.2f</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:ID schemeID="UN/ECE 5305" schemeName="Tax Category Identifier"
+          schemeAgencyName="United Nations Economic Commission for Europe">%s</cbc:ID>
+        <cac:TaxScheme>
+          <cbc:ID schemeID="UN/ECE 5153" schemeName="Codigo de tributos" schemeAgencyName="PE:SUNAT">%s</cbc:ID>
+          <cbc:Name>%s</cbc:Name>
+          <cbc:TaxTypeCode>%s</cbc:TaxTypeCode>
+        </cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>`,
+			inv.Currency, roundAmount(taxable[index]),
+			inv.Currency, roundAmount(taxes[index]),
+			category.CategoryID,
+			category.SchemeID,
+			category.SchemeName,
+			category.TypeCode)
+	}
+
+	taxTotal += `
+  </cac:TaxTotal>`
+
+	return taxTotal
+}
+
+// generateInvoiceLineXML renders a single cac:InvoiceLine
+func generateInvoiceLineXML(lineID int, item *InvoiceItem, currency string) string {
+	category := taxCategoryFor(item.AffectationCode)
+	lineExtension := item.LineExtensionAmount()
+
+	// Onerous lines declare the unit price including taxes (01), free lines the referential value (02)
+	priceTypeCode := "01"
+	referencePrice := roundAmount((lineExtension + item.IGVAmount) / item.Quantity)
+	unitValue := item.UnitValue
+	if item.IsFree() {
+		priceTypeCode = "02"
+		referencePrice = item.UnitValue
+		unitValue = 0
+	}
+
+	return fmt.Sprintf(`
+  <cac:InvoiceLine>
+    <cbc:ID>%d</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="%s" unitCodeListID="UN/ECE rec 20"
+      unitCodeListAgencyName="United Nations Economic Commission for Europe">%.2f</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="%s">%.2f</cbc:LineExtensionAmount>
+    <cac:PricingReference>
+      <cac:AlternativeConditionPrice>
+        <cbc:PriceAmount currencyID="%s">%.2f</cbc:PriceAmount>
+        <cbc:PriceTypeCode listName="Tipo de Precio" listAgencyName="PE:SUNAT"
+          listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo16">%s</cbc:PriceTypeCode>
+      </cac:AlternativeConditionPrice>
+    </cac:PricingReference>
+    <cac:TaxTotal>
+      <cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>
+      <cac:TaxSubtotal>
+        <cbc:TaxableAmount currencyID="%s">%.2f</cbc:TaxableAmount>
+        <cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>
+        <cac:TaxCategory>
+          <cbc:ID schemeID="UN/ECE 5305" schemeName="Tax Category Identifier"
+            schemeAgencyName="United Nations Economic Commission for Europe">%s</cbc:ID>
+          <cbc:Percent>%.2f</cbc:Percent>
+          <cbc:TaxExemptionReasonCode listAgencyName="PE:SUNAT" listName="Afectacion del IGV"
+            listURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo07">%s</cbc:TaxExemptionReasonCode>
+          <cac:TaxScheme>
+            <cbc:ID schemeID="UN/ECE 5153" schemeName="Codigo de tributos" schemeAgencyName="PE:SUNAT">%s</cbc:ID>
+            <cbc:Name>%s</cbc:Name>
+            <cbc:TaxTypeCode>%s</cbc:TaxTypeCode>
+          </cac:TaxScheme>
+        </cac:TaxCategory>
+      </cac:TaxSubtotal>
+    </cac:TaxTotal>
+    <cac:Item>
+      <cbc:Description><![CDATA[%s]]></cbc:Description>
+    </cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="%s">%.2f</cbc:PriceAmount>
+    </cac:Price>
+  </cac:InvoiceLine>`,
+		lineID,
+		item.UnitCode,
+		item.Quantity,
+		currency, lineExtension,
+		currency, referencePrice,
+		priceTypeCode,
+		currency, item.IGVAmount,
+		currency, lineExtension,
+		currency, item.IGVAmount,
+		category.CategoryID,
+		taxPercent(item.AffectationCode),
+		item.AffectationCode,
+		category.SchemeID,
+		category.SchemeName,
+		category.TypeCode,
+		utils.EscapeCDATA(item.Description),
+		currency, unitValue)
+}
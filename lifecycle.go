@@ -0,0 +1,88 @@
+// Package sunatlib provides tracking of a document's validation state over time
+package sunatlib
+
+import "time"
+
+// LifecycleStateSent is the first state of a DocumentLifecycle, recorded when the document is sent
+const LifecycleStateSent = "SENT"
+
+// LifecycleEntry is a state observed for a document, from the first to the last time it was seen
+type LifecycleEntry struct {
+	State    string    `json:"state"`             // SENT, NO_INFORMADO, VALIDO, ANULADO, RECHAZADO or UNKNOWN
+	Since    time.Time `json:"since"`             // First time the state was observed
+	LastSeen time.Time `json:"last_seen"`         // Last time the state was observed
+	Message  string    `json:"message,omitempty"` // SUNAT status message of the last observation
+	Observed int       `json:"observed"`          // Number of consecutive observations of the state
+}
+
+// DocumentLifecycle records the validation states of a document as it is polled with
+// ValidationClient, one entry per state change. It can be persisted as JSON between polls.
+// It is not safe for concurrent use
+type DocumentLifecycle struct {
+	Timeline []LifecycleEntry `json:"timeline"`
+}
+
+// NewDocumentLifecycle starts the timeline of a document sent at sentAt
+func NewDocumentLifecycle(sentAt time.Time) *DocumentLifecycle {
+	return &DocumentLifecycle{
+		Timeline: []LifecycleEntry{{State: LifecycleStateSent, Since: sentAt, LastSeen: sentAt, Observed: 1}},
+	}
+}
+
+// Record adds a validation result observed at the given time. Repeated observations of the
+// current state extend its entry instead of adding a new one
+func (l *DocumentLifecycle) Record(result *ValidationResult, observedAt time.Time) {
+	if result == nil {
+		return
+	}
+
+	if n := len(l.Timeline); n > 0 && l.Timeline[n-1].State == result.State {
+		last := &l.Timeline[n-1]
+		last.LastSeen = observedAt
+		last.Message = result.StatusMessage
+		last.Observed++
+		return
+	}
+
+	l.Timeline = append(l.Timeline, LifecycleEntry{
+		State:    result.State,
+		Since:    observedAt,
+		LastSeen: observedAt,
+		Message:  result.StatusMessage,
+		Observed: 1,
+	})
+}
+
+// Poll validates the document with vc and records the result
+func (l *DocumentLifecycle) Poll(vc *ValidationClient, params *ValidationParams) (*ValidationResult, error) {
+	result, err := vc.ValidateDocument(params)
+	if err != nil {
+		return nil, err
+	}
+	l.Record(result, time.Now())
+	return result, nil
+}
+
+// CurrentState returns the last observed state, or an empty string for an empty timeline
+func (l *DocumentLifecycle) CurrentState() string {
+	if len(l.Timeline) == 0 {
+		return ""
+	}
+	return l.Timeline[len(l.Timeline)-1].State
+}
+
+// IsDefinitive returns true if the last observed state can no longer change
+func (l *DocumentLifecycle) IsDefinitive() bool {
+	result := ValidationResult{State: l.CurrentState()}
+	return result.IsDefinitive()
+}
+
+// IsStuck returns true if the document is still pending (SENT, NO_INFORMADO or UNKNOWN) more than
+// maxPending after it was sent. Documents that stay NO_INFORMADO that long usually signal a problem
+// with the submission
+func (l *DocumentLifecycle) IsStuck(maxPending time.Duration, now time.Time) bool {
+	if len(l.Timeline) == 0 || l.IsDefinitive() {
+		return false
+	}
+	return now.Sub(l.Timeline[0].Since) > maxPending
+}
@@ -0,0 +1,60 @@
+package sunatlib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocumentLifecycle_Record(t *testing.T) {
+	sentAt := time.Date(2026, 4, 27, 10, 0, 0, 0, time.UTC)
+	lifecycle := NewDocumentLifecycle(sentAt)
+
+	observations := []string{"NO_INFORMADO", "NO_INFORMADO", "NO_INFORMADO", "VALIDO", "VALIDO"}
+	for i, state := range observations {
+		lifecycle.Record(&ValidationResult{State: state, StatusMessage: state}, sentAt.Add(time.Duration(i+1)*time.Minute))
+	}
+
+	want := []LifecycleEntry{
+		{State: LifecycleStateSent, Since: sentAt, LastSeen: sentAt, Observed: 1},
+		{State: "NO_INFORMADO", Since: sentAt.Add(time.Minute), LastSeen: sentAt.Add(3 * time.Minute), Message: "NO_INFORMADO", Observed: 3},
+		{State: "VALIDO", Since: sentAt.Add(4 * time.Minute), LastSeen: sentAt.Add(5 * time.Minute), Message: "VALIDO", Observed: 2},
+	}
+	if !reflect.DeepEqual(lifecycle.Timeline, want) {
+		t.Errorf("Timeline = %+v, want %+v", lifecycle.Timeline, want)
+	}
+	if lifecycle.CurrentState() != "VALIDO" || !lifecycle.IsDefinitive() {
+		t.Errorf("CurrentState() = %q, want definitive VALIDO", lifecycle.CurrentState())
+	}
+
+	data, err := json.Marshal(lifecycle)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var restored DocumentLifecycle
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(restored.Timeline, want) {
+		t.Errorf("restored Timeline = %+v, want %+v", restored.Timeline, want)
+	}
+}
+
+func TestDocumentLifecycle_IsStuck(t *testing.T) {
+	sentAt := time.Date(2026, 4, 27, 10, 0, 0, 0, time.UTC)
+	lifecycle := NewDocumentLifecycle(sentAt)
+	lifecycle.Record(&ValidationResult{State: "NO_INFORMADO"}, sentAt.Add(time.Hour))
+
+	if lifecycle.IsStuck(24*time.Hour, sentAt.Add(2*time.Hour)) {
+		t.Error("Expected document pending for 2 hours not to be stuck")
+	}
+	if !lifecycle.IsStuck(24*time.Hour, sentAt.Add(25*time.Hour)) {
+		t.Error("Expected document pending for 25 hours to be stuck")
+	}
+
+	lifecycle.Record(&ValidationResult{State: "VALIDO"}, sentAt.Add(26*time.Hour))
+	if lifecycle.IsStuck(24*time.Hour, sentAt.Add(27*time.Hour)) {
+		t.Error("Expected a definitive document not to be stuck")
+	}
+}
@@ -0,0 +1,73 @@
+// Package sunatlib provides the catalog of messages generated by the library
+package sunatlib
+
+// Language selects the language of the messages generated by the library. Messages returned
+// by SUNAT (fault strings, CDR descriptions) are never translated
+type Language string
+
+const (
+	LanguageSpanish Language = "es" // Default language
+	LanguageEnglish Language = "en"
+)
+
+// messageKey identifies a library-generated message in the catalog
+type messageKey int
+
+const (
+	msgDocumentSent messageKey = iota
+	msgVoidedDocumentsSent
+	msgSummarySent
+	msgPackSent
+	msgUnrecognizedResponse
+	msgUnrecognizedTicketResponse
+	msgVoidedDocumentsProcessed
+	msgVoidedDocumentsInProgress
+	msgVoidedDocumentsProcessedWithErrors
+	msgTicketWaitTimeout
+	msgTicketProcessed
+	msgTicketInProgress
+	msgTicketProcessedWithErrors
+)
+
+// messageCatalog holds the library-generated messages of each language
+var messageCatalog = map[Language]map[messageKey]string{
+	LanguageSpanish: {
+		msgDocumentSent:                       "Documento enviado exitosamente",
+		msgVoidedDocumentsSent:                "Comunicación de baja enviada exitosamente",
+		msgSummarySent:                        "Resumen diario enviado exitosamente",
+		msgPackSent:                           "Lote enviado exitosamente",
+		msgUnrecognizedResponse:               "Respuesta no reconocida de SUNAT",
+		msgUnrecognizedTicketResponse:         "Respuesta no reconocida de SUNAT para consulta de ticket",
+		msgVoidedDocumentsProcessed:           "Comunicación de baja procesada exitosamente",
+		msgVoidedDocumentsInProgress:          "Comunicación de baja en proceso de validación",
+		msgVoidedDocumentsProcessedWithErrors: "Comunicación de baja procesada con errores",
+		msgTicketWaitTimeout:                  "Timeout esperando procesamiento del ticket",
+		msgTicketProcessed:                    "Procesado correctamente",
+		msgTicketInProgress:                   "En proceso",
+		msgTicketProcessedWithErrors:          "Procesado con errores",
+	},
+	LanguageEnglish: {
+		msgDocumentSent:                       "Document sent successfully",
+		msgVoidedDocumentsSent:                "Voided documents communication sent successfully",
+		msgSummarySent:                        "Daily summary sent successfully",
+		msgPackSent:                           "Pack sent successfully",
+		msgUnrecognizedResponse:               "Unrecognized SUNAT response",
+		msgUnrecognizedTicketResponse:         "Unrecognized SUNAT response to the ticket query",
+		msgVoidedDocumentsProcessed:           "Voided documents communication processed successfully",
+		msgVoidedDocumentsInProgress:          "Voided documents communication being validated",
+		msgVoidedDocumentsProcessedWithErrors: "Voided documents communication processed with errors",
+		msgTicketWaitTimeout:                  "Timed out waiting for the ticket to be processed",
+		msgTicketProcessed:                    "Processed successfully",
+		msgTicketInProgress:                   "In progress",
+		msgTicketProcessedWithErrors:          "Processed with errors",
+	},
+}
+
+// message returns the text of key in the language, falling back to Spanish for an empty or
+// unknown language
+func (l Language) message(key messageKey) string {
+	if text, ok := messageCatalog[l][key]; ok {
+		return text
+	}
+	return messageCatalog[LanguageSpanish][key]
+}
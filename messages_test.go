@@ -0,0 +1,76 @@
+package sunatlib
+
+import "testing"
+
+func TestLanguage_Messages(t *testing.T) {
+	tests := []struct {
+		name            string
+		language        Language
+		wantSent        string
+		wantMessage     string
+		wantDescription string
+	}{
+		{"Default", "", "Comunicación de baja enviada exitosamente", "Comunicación de baja procesada exitosamente", "Procesado correctamente"},
+		{"Spanish", LanguageSpanish, "Comunicación de baja enviada exitosamente", "Comunicación de baja procesada exitosamente", "Procesado correctamente"},
+		{"English", LanguageEnglish, "Voided documents communication sent successfully", "Voided documents communication processed successfully", "Processed successfully"},
+		{"Unknown", Language("fr"), "Comunicación de baja enviada exitosamente", "Comunicación de baja procesada exitosamente", "Procesado correctamente"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSOAPTestServer(t, map[string]func() string{
+				"sendSummary": func() string { return sendSummaryResponse(testTicket) },
+				"getStatus":   func() string { return getStatusResponse("0", "") },
+			})
+			defer server.Close()
+
+			client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+			client.Language = tt.language
+
+			sent, err := client.SendVoidedDocuments(newTestVoidedDocumentsRequest())
+			if err != nil {
+				t.Fatalf("SendVoidedDocuments() error = %v", err)
+			}
+			if sent.Message != tt.wantSent {
+				t.Errorf("send Message = %q, want %q", sent.Message, tt.wantSent)
+			}
+
+			status, err := client.QueryVoidedDocumentsTicket(testTicket)
+			if err != nil {
+				t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+			}
+			if status.Message != tt.wantMessage {
+				t.Errorf("status Message = %q, want %q", status.Message, tt.wantMessage)
+			}
+			if got := status.GetTicketStatusDescription(); got != tt.wantDescription {
+				t.Errorf("GetTicketStatusDescription() = %q, want %q", got, tt.wantDescription)
+			}
+		})
+	}
+}
+
+func TestLanguage_SOAPFaultNotTranslated(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return soapFaultResponse("0127", "El ticket no existe") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.Language = LanguageEnglish
+
+	status, err := client.QueryVoidedDocumentsTicket(testTicket)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+	if status.Message != "El ticket no existe" {
+		t.Errorf("Message = %q, want SUNAT's fault string", status.Message)
+	}
+}
+
+func TestLanguage_CatalogComplete(t *testing.T) {
+	for language, messages := range messageCatalog {
+		if len(messages) != len(messageCatalog[LanguageSpanish]) {
+			t.Errorf("catalog %s has %d messages, want %d", language, len(messages), len(messageCatalog[LanguageSpanish]))
+		}
+	}
+}
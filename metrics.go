@@ -0,0 +1,92 @@
+// Package sunatlib provides in-process operation counters for the SUNAT clients
+package sunatlib
+
+import "sync/atomic"
+
+// MetricsSnapshot is a point-in-time copy of a client's operation counters.
+// Each client only updates the counters of the operations it performs
+type MetricsSnapshot struct {
+	SendsAttempted      int64 // sendBill, sendSummary and sendPack requests attempted (local validation or ZIP failures are not sends)
+	SendsSucceeded      int64 // Sends accepted by SUNAT
+	SendsFailed         int64 // Sends that failed or were rejected
+	Validations         int64 // Document validations performed
+	ValidationsFailed   int64 // Validations that returned an error
+	ValidationCacheHits int64 // Validations answered from the cache or by an identical query in flight
+	Consultations       int64 // RUC/DNI/CE consultations performed
+	ConsultationsFailed int64 // Consultations that returned an error
+	Retries             int64 // Requests retried automatically (e.g., getStatus)
+}
+
+// clientMetrics holds the thread-safe counters behind MetricsSnapshot
+type clientMetrics struct {
+	sendsAttempted      atomic.Int64
+	sendsSucceeded      atomic.Int64
+	sendsFailed         atomic.Int64
+	validations         atomic.Int64
+	validationsFailed   atomic.Int64
+	validationCacheHits atomic.Int64
+	consultations       atomic.Int64
+	consultationsFailed atomic.Int64
+	retries             atomic.Int64
+}
+
+// recordSend counts a send and its outcome
+func (m *clientMetrics) recordSend(succeeded bool) {
+	m.sendsAttempted.Add(1)
+	if succeeded {
+		m.sendsSucceeded.Add(1)
+	} else {
+		m.sendsFailed.Add(1)
+	}
+}
+
+// recordValidation counts a validation and whether it failed
+func (m *clientMetrics) recordValidation(err error) {
+	m.validations.Add(1)
+	if err != nil {
+		m.validationsFailed.Add(1)
+	}
+}
+
+// recordConsultation counts a consultation and whether it failed
+func (m *clientMetrics) recordConsultation(err error) {
+	m.consultations.Add(1)
+	if err != nil {
+		m.consultationsFailed.Add(1)
+	}
+}
+
+// snapshot returns the current value of the counters
+func (m *clientMetrics) snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		SendsAttempted:      m.sendsAttempted.Load(),
+		SendsSucceeded:      m.sendsSucceeded.Load(),
+		SendsFailed:         m.sendsFailed.Load(),
+		Validations:         m.validations.Load(),
+		ValidationsFailed:   m.validationsFailed.Load(),
+		ValidationCacheHits: m.validationCacheHits.Load(),
+		Consultations:       m.consultations.Load(),
+		ConsultationsFailed: m.consultationsFailed.Load(),
+		Retries:             m.retries.Load(),
+	}
+}
+
+// Metrics returns a snapshot of the send and retry counters
+func (c *SUNATClient) Metrics() MetricsSnapshot {
+	return c.metrics.snapshot()
+}
+
+// Metrics returns a snapshot of the validation counters
+func (vc *ValidationClient) Metrics() MetricsSnapshot {
+	return vc.metrics.snapshot()
+}
+
+// Metrics returns a snapshot of the validation counters
+func (c *DocumentValidationClient) Metrics() MetricsSnapshot {
+	return c.metrics.snapshot()
+}
+
+// Metrics returns a snapshot of the consultation counters
+func (c *ConsultationClient) Metrics() MetricsSnapshot {
+	return c.metrics.snapshot()
+}
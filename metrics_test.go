@@ -0,0 +1,93 @@
+package sunatlib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSUNATClient_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml")
+		if strings.Contains(string(body), "F001-2") {
+			io.WriteString(w, soapFaultResponse("2800", "Rechazado"))
+			return
+		}
+		io.WriteString(w, sendBillSuccessResponse)
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+		}()
+	}
+	wg.Wait()
+
+	if _, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-2"); err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+
+	flaky, _ := newFlakyStatusServer(t, 1, getStatusResponse("98", ""))
+	defer flaky.Close()
+	client.SetEndpoints(map[ServiceType]string{ServiceStatus: flaky.URL})
+	client.StatusRetry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	if _, err := client.QueryVoidedDocumentsTicket(testTicket); err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+
+	got := client.Metrics()
+	want := MetricsSnapshot{SendsAttempted: 11, SendsSucceeded: 10, SendsFailed: 1, Retries: 1}
+	if got != want {
+		t.Errorf("Metrics() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSUNATClient_MetricsIgnoreLocalFailures(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	if _, err := client.SendToSUNAT(make([]byte, MaxDocumentSize+1), "01", "F001-1"); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("Expected ErrDocumentTooLarge, got %v", err)
+	}
+	request := newTestVoidedDocumentsRequest()
+	request.Documents = nil
+	if _, err := client.SendVoidedDocuments(request); err == nil {
+		t.Error("Expected error for a request without documents")
+	}
+	if _, err := client.SendPackWithSeries("LT-20260427-00001", nil); err == nil {
+		t.Error("Expected error for an empty pack")
+	}
+
+	if got := client.Metrics(); got != (MetricsSnapshot{}) {
+		t.Errorf("Metrics() = %+v, want no sends for local failures", got)
+	}
+}
+
+func TestConsultationClient_Metrics(t *testing.T) {
+	server := newTestRUCServer(t, "ACTIVO", "HABIDO")
+	defer server.Close()
+
+	client := NewRUCConsultationClient("")
+	client.rucService.BaseURL = server.URL
+
+	client.ConsultRUC("20100070970")
+	client.ConsultRUCFull("20100070970")
+	client.ConsultRUC("123")
+	client.ConsultDNI("12345678") // DNI service not configured
+
+	got := client.Metrics()
+	if got.Consultations != 3 || got.ConsultationsFailed != 1 {
+		t.Errorf("Metrics() = %+v, want 3 consultations and 1 failure", got)
+	}
+}
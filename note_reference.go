@@ -0,0 +1,52 @@
+// Package sunatlib provides validation of the documents referenced by credit and debit notes
+package sunatlib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// BillingReference identifies the document modified by a credit (07) or debit (08) note
+// (cac:BillingReference/cac:InvoiceDocumentReference)
+type BillingReference struct {
+	DocumentType string // Referenced document type (01=Factura, 03=Boleta)
+	SeriesNumber string // Referenced document in SERIE-NUMERO format (e.g., F001-123)
+}
+
+// noteSeriesPrefixes maps the document types a note can reference to the series prefix
+// shared by the note and the referenced document
+var noteSeriesPrefixes = map[string]string{
+	"01": "F",
+	"03": "B",
+}
+
+// ValidateNoteReference checks that a note series is consistent with the referenced document:
+// notes modifying a factura use an F series and notes modifying a boleta use a B series.
+// SUNAT rejects notes whose series does not match the referenced document type
+func ValidateNoteReference(noteSeries string, ref BillingReference) error {
+	prefix, ok := noteSeriesPrefixes[ref.DocumentType]
+	if !ok {
+		return fmt.Errorf("invalid referenced document type: %s (expected 01 or 03)", ref.DocumentType)
+	}
+
+	if !utils.ValidateDocumentSeries(noteSeries) {
+		return fmt.Errorf("invalid note series format: %s", noteSeries)
+	}
+
+	referencedSeries, _, err := utils.SplitSerieNumero(ref.SeriesNumber)
+	if err != nil {
+		return fmt.Errorf("invalid referenced document: %w", err)
+	}
+
+	if !strings.HasPrefix(referencedSeries, prefix) {
+		return fmt.Errorf("referenced document %s must have a series starting with %s for document type %s", ref.SeriesNumber, prefix, ref.DocumentType)
+	}
+
+	if !strings.HasPrefix(noteSeries, prefix) {
+		return fmt.Errorf("note series %s must start with %s to modify document %s (type %s)", noteSeries, prefix, ref.SeriesNumber, ref.DocumentType)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package sunatlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNoteReference(t *testing.T) {
+	tests := []struct {
+		name       string
+		noteSeries string
+		ref        BillingReference
+		msg        string
+	}{
+		{"Invoice Note", "FC01", BillingReference{DocumentType: "01", SeriesNumber: "F001-123"}, ""},
+		{"Boleta Note", "BC01", BillingReference{DocumentType: "03", SeriesNumber: "B001-45"}, ""},
+		{"Boleta Series For Invoice", "BC01", BillingReference{DocumentType: "01", SeriesNumber: "F001-123"}, "must start with F"},
+		{"Invoice Series For Boleta", "FC01", BillingReference{DocumentType: "03", SeriesNumber: "B001-45"}, "must start with B"},
+		{"Referenced Type Mismatch", "FC01", BillingReference{DocumentType: "01", SeriesNumber: "B001-45"}, "series starting with F"},
+		{"Unsupported Referenced Type", "FC01", BillingReference{DocumentType: "07", SeriesNumber: "FC01-1"}, "invalid referenced document type"},
+		{"Malformed Reference", "FC01", BillingReference{DocumentType: "01", SeriesNumber: "F001"}, "invalid referenced document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNoteReference(tt.noteSeries, tt.ref)
+			if tt.msg == "" {
+				if err != nil {
+					t.Errorf("ValidateNoteReference() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("ValidateNoteReference() error = %v, want msg containing %q", err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package sunatlib provides batch transmission of signed documents (sendPack)
+package sunatlib
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// ErrEmptyPack is returned by SendPack when there are no documents to send
+var ErrEmptyPack = errors.New("pack without documents")
+
+// GeneratePackSeries generates the identifier of a pack (lote) of documents
+// Format: LT-YYYYMMDD-##### where ##### is a sequential number
+func GeneratePackSeries(date time.Time, sequential int) string {
+	return fmt.Sprintf("LT-%s-%05d", date.Format("20060102"), sequential)
+}
+
+// SendPack sends several signed documents in a single pack ZIP (a ZIP containing the ZIP of each
+// document) and returns the ticket to poll with QueryVoidedDocumentsTicket or WaitForTicketProcessing.
+// The pack is named after the current date and second of the day; use SendPackWithSeries to choose it
+func (c *SUNATClient) SendPack(documents []SignedDoc) (*VoidedDocumentsResponse, error) {
+	return c.SendPackContext(context.Background(), documents)
+}
+
+// SendPackContext is like SendPack, cancelling the request when ctx is done
+func (c *SUNATClient) SendPackContext(ctx context.Context, documents []SignedDoc) (*VoidedDocumentsResponse, error) {
+	now := c.clock().Now()
+	secondOfDay := now.Hour()*3600 + now.Minute()*60 + now.Second()
+	return c.SendPackWithSeriesContext(ctx, GeneratePackSeries(now, secondOfDay), documents)
+}
+
+// SendPackWithSeries is like SendPack using the given pack identifier (see GeneratePackSeries)
+func (c *SUNATClient) SendPackWithSeries(seriesNumber string, documents []SignedDoc) (*VoidedDocumentsResponse, error) {
+	return c.SendPackWithSeriesContext(context.Background(), seriesNumber, documents)
+}
+
+// SendPackWithSeriesContext is like SendPackWithSeries, cancelling the request when ctx is done
+func (c *SUNATClient) SendPackWithSeriesContext(ctx context.Context, seriesNumber string, documents []SignedDoc) (response *VoidedDocumentsResponse, err error) {
+	attempted := false
+	defer func() {
+		if attempted {
+			c.metrics.recordSend(err == nil && response != nil && response.Success)
+		}
+	}()
+
+	zipData, zipName, err := c.createPackZIP(seriesNumber, documents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pack ZIP: %w", err)
+	}
+
+	// Encode to base64
+	zipB64 := base64.StdEncoding.EncodeToString(zipData)
+
+	// Build SOAP envelope for sendPack
+	soapBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.sunat.gob.pe" xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+  <soapenv:Header>
+    <wsse:Security>
+      <wsse:UsernameToken>
+        <wsse:Username>%s%s</wsse:Username>
+        <wsse:Password>%s</wsse:Password>
+      </wsse:UsernameToken>
+    </wsse:Security>
+  </soapenv:Header>
+  <soapenv:Body>
+    <ser:sendPack>
+      <fileName>%s</fileName>
+      <contentFile>%s</contentFile>
+    </ser:sendPack>
+  </soapenv:Body>
+</soapenv:Envelope>`, c.senderRUC(), c.Username, c.Password, zipName, zipB64)
+
+	// Send HTTP request
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpointFor(ServicePack), bytes.NewBuffer([]byte(soapBody)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", "urn:sendPack")
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
+
+	attempted = true
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, c.redactError(fmt.Errorf("failed to send HTTP request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	responseData, err := readResponseBody(resp, c.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return c.parseTicketResponse(responseData, "sendPackResponse", msgPackSent)
+}
+
+// createPackZIP creates the pack ZIP ({RUC}-{seriesNumber}.zip) holding the ZIP of each document,
+// named as it would be for sendBill
+func (c *SUNATClient) createPackZIP(seriesNumber string, documents []SignedDoc) ([]byte, string, error) {
+	if len(documents) == 0 {
+		return nil, "", ErrEmptyPack
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	seen := make(map[string]bool, len(documents))
+	for i, doc := range documents {
+		docZIP, docZIPName, err := c.createZIP(doc.SignedXML, doc.DocumentType, doc.SeriesNumber)
+		if err != nil {
+			return nil, "", fmt.Errorf("document %d: %w", i+1, err)
+		}
+		if seen[docZIPName] {
+			return nil, "", fmt.Errorf("document %d: duplicate document %s", i+1, docZIPName)
+		}
+		seen[docZIPName] = true
+
+		fw, err := zipWriter.Create(docZIPName)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := fw.Write(docZIP); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, "", err
+	}
+
+	zipName := fmt.Sprintf("%s-%s.zip", c.RUC, seriesNumber)
+	if err := validateZIPSize(zipName, buf.Bytes()); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), zipName, nil
+}
@@ -0,0 +1,123 @@
+package sunatlib
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratePackSeries(t *testing.T) {
+	got := GeneratePackSeries(time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC), 12)
+	if want := "LT-20260427-00012"; got != want {
+		t.Errorf("GeneratePackSeries() = %q, want %q", got, want)
+	}
+}
+
+func TestSendPackWithSeries(t *testing.T) {
+	var fileName, contentFile, soapAction string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		soapAction = r.Header.Get("SOAPAction")
+		fileName = between(string(body), "<fileName>", "</fileName>")
+		contentFile = between(string(body), "<contentFile>", "</contentFile>")
+		w.Write([]byte(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:sendPackResponse xmlns:br="http://service.sunat.gob.pe"><ticket>202600000000123</ticket></br:sendPackResponse></soap-env:Body></soap-env:Envelope>`))
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	documents := []SignedDoc{
+		{SignedXML: []byte("<Invoice>1</Invoice>"), DocumentType: "01", SeriesNumber: "F001-1"},
+		{SignedXML: []byte("<Invoice>2</Invoice>"), DocumentType: "01", SeriesNumber: "f001-2"},
+		{SignedXML: []byte("<CreditNote/>"), DocumentType: "07", SeriesNumber: "FC01-1"},
+	}
+
+	response, err := client.SendPackWithSeries("LT-20260427-00001", documents)
+	if err != nil {
+		t.Fatalf("SendPackWithSeries() error = %v", err)
+	}
+	if !response.Success || response.Ticket != "202600000000123" {
+		t.Errorf("response = %+v, want success with ticket", response)
+	}
+	if soapAction != "urn:sendPack" {
+		t.Errorf("SOAPAction = %q, want urn:sendPack", soapAction)
+	}
+	if want := testRUC + "-LT-20260427-00001.zip"; fileName != want {
+		t.Errorf("fileName = %q, want %q", fileName, want)
+	}
+
+	packData, err := base64.StdEncoding.DecodeString(contentFile)
+	if err != nil {
+		t.Fatalf("contentFile is not base64: %v", err)
+	}
+	pack, err := zip.NewReader(bytes.NewReader(packData), int64(len(packData)))
+	if err != nil {
+		t.Fatalf("contentFile is not a ZIP: %v", err)
+	}
+
+	wantEntries := []string{
+		testRUC + "-01-F001-1",
+		testRUC + "-01-F001-2",
+		testRUC + "-07-FC01-1",
+	}
+	if len(pack.File) != len(wantEntries) {
+		t.Fatalf("pack has %d entries, want %d", len(pack.File), len(wantEntries))
+	}
+	for i, entry := range pack.File {
+		if entry.Name != wantEntries[i]+".zip" {
+			t.Errorf("entry %d = %q, want %q", i, entry.Name, wantEntries[i]+".zip")
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", entry.Name, err)
+		}
+		inner, _ := io.ReadAll(rc)
+		rc.Close()
+
+		innerZIP, err := zip.NewReader(bytes.NewReader(inner), int64(len(inner)))
+		if err != nil {
+			t.Fatalf("%s is not a ZIP: %v", entry.Name, err)
+		}
+		if len(innerZIP.File) != 1 || innerZIP.File[0].Name != wantEntries[i]+".xml" {
+			t.Errorf("%s should contain only %s.xml", entry.Name, wantEntries[i])
+		}
+	}
+}
+
+func TestSendPackWithSeries_InvalidDocuments(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	if _, err := client.SendPackWithSeries("LT-20260427-00001", nil); !errors.Is(err, ErrEmptyPack) {
+		t.Errorf("error = %v, want ErrEmptyPack", err)
+	}
+
+	duplicated := []SignedDoc{
+		{SignedXML: []byte("<Invoice/>"), DocumentType: "01", SeriesNumber: "F001-1"},
+		{SignedXML: []byte("<Invoice/>"), DocumentType: "01", SeriesNumber: "f001-1"},
+	}
+	_, err := client.SendPackWithSeries("LT-20260427-00001", duplicated)
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("error = %v, want duplicate document error", err)
+	}
+}
+
+// between returns the text of s between the first start and the following end
+func between(s, start, end string) string {
+	i := strings.Index(s, start)
+	if i == -1 {
+		return ""
+	}
+	s = s[i+len(start):]
+	if j := strings.Index(s, end); j != -1 {
+		return s[:j]
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+// Package sunatlib provides lightweight extraction of party names from UBL documents
+package sunatlib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExtractSupplierName returns the supplier's razón social (cac:AccountingSupplierParty
+// cac:PartyLegalEntity/cbc:RegistrationName) of an invoice, boleta or note without
+// parsing the rest of the document
+func ExtractSupplierName(xmlContent []byte) (string, error) {
+	return extractPartyName(xmlContent, "AccountingSupplierParty")
+}
+
+// ExtractCustomerName returns the customer's name (cac:AccountingCustomerParty
+// cac:PartyLegalEntity/cbc:RegistrationName) of an invoice, boleta or note without
+// parsing the rest of the document
+func ExtractCustomerName(xmlContent []byte) (string, error) {
+	return extractPartyName(xmlContent, "AccountingCustomerParty")
+}
+
+// extractPartyName decodes only the given party element among the direct children of the
+// root element, skipping every other subtree (including the signature)
+func extractPartyName(xmlContent []byte, partyElement string) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xmlContent))
+	decoder.CharsetReader = latin1CharsetReader
+
+	if _, err := nextStartElement(decoder); err != nil {
+		return "", fmt.Errorf("failed to read root element: %w", err)
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if start.Name.Local != partyElement {
+			if err := decoder.Skip(); err != nil {
+				return "", fmt.Errorf("failed to parse XML: %w", err)
+			}
+			continue
+		}
+
+		var party struct {
+			RegistrationName string `xml:"Party>PartyLegalEntity>RegistrationName"`
+		}
+		if err := decoder.DecodeElement(&party, &start); err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", partyElement, err)
+		}
+
+		name := strings.TrimSpace(party.RegistrationName)
+		if name == "" {
+			return "", fmt.Errorf("%s without RegistrationName", partyElement)
+		}
+		return name, nil
+	}
+
+	return "", fmt.Errorf("document without %s", partyElement)
+}
+
+// latin1CharsetReader decodes ISO-8859-1 documents, the encoding used by some issuers;
+// other charsets are read as is
+func latin1CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	default:
+		return input, nil
+	}
+}
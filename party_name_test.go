@@ -0,0 +1,54 @@
+package sunatlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractPartyNames(t *testing.T) {
+	inv := newTestInvoice()
+	inv.Supplier.Name = "COMERCIAL PEÑA S.A.C."
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	supplier, err := ExtractSupplierName(xmlContent)
+	if err != nil {
+		t.Fatalf("ExtractSupplierName() error = %v", err)
+	}
+	if supplier != "COMERCIAL PEÑA S.A.C." {
+		t.Errorf("ExtractSupplierName() = %q, want %q", supplier, "COMERCIAL PEÑA S.A.C.")
+	}
+
+	customer, err := ExtractCustomerName(xmlContent)
+	if err != nil {
+		t.Fatalf("ExtractCustomerName() error = %v", err)
+	}
+	if customer != "CLIENTE S.A." {
+		t.Errorf("ExtractCustomerName() = %q, want %q", customer, "CLIENTE S.A.")
+	}
+}
+
+func TestExtractSupplierName_Latin1(t *testing.T) {
+	xmlContent := []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" ` +
+		`xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" ` +
+		`xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">` +
+		`<cac:AccountingSupplierParty><cac:Party><cac:PartyLegalEntity>` +
+		"<cbc:RegistrationName>COMERCIAL PE\xd1A S.A.C.</cbc:RegistrationName>" +
+		`</cac:PartyLegalEntity></cac:Party></cac:AccountingSupplierParty></Invoice>`)
+
+	supplier, err := ExtractSupplierName(xmlContent)
+	if err != nil {
+		t.Fatalf("ExtractSupplierName() error = %v", err)
+	}
+	if supplier != "COMERCIAL PEÑA S.A.C." {
+		t.Errorf("ExtractSupplierName() = %q, want %q", supplier, "COMERCIAL PEÑA S.A.C.")
+	}
+
+	if _, err := ExtractCustomerName(xmlContent); err == nil || !strings.Contains(err.Error(), "without AccountingCustomerParty") {
+		t.Errorf("Expected missing customer error, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package sunatlib provides customer checks before issuing documents
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrRUCServiceNotConfigured is returned by PrecheckCustomer when no RUCService is attached
+	ErrRUCServiceNotConfigured = errors.New("RUC service not configured - use SetRUCService() first")
+
+	// ErrCustomerNotActive is returned when the customer RUC is not ACTIVO
+	ErrCustomerNotActive = errors.New("customer RUC is not active")
+
+	// ErrCustomerNotHabido is returned when the customer RUC condition is not HABIDO
+	ErrCustomerNotHabido = errors.New("customer RUC is not habido")
+)
+
+// SetRUCService attaches the RUC service used by PrecheckCustomer (nil detaches it)
+func (c *SUNATClient) SetRUCService(rs *RUCService) {
+	c.rucService = rs
+}
+
+// PrecheckCustomer consults a customer RUC before invoicing and returns an error if the taxpayer
+// is not ACTIVO or not HABIDO. The consulted data is returned along with those errors
+func (c *SUNATClient) PrecheckCustomer(ruc string) (*RUCBasicData, error) {
+	if c.rucService == nil {
+		return nil, ErrRUCServiceNotConfigured
+	}
+
+	response, err := c.rucService.ConsultBasic(ruc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consult customer RUC %s: %w", ruc, err)
+	}
+
+	if !response.Success || response.Data == nil {
+		return nil, fmt.Errorf("failed to consult customer RUC %s: %s", ruc, response.Message)
+	}
+
+	data := response.Data
+	if !strings.EqualFold(strings.TrimSpace(data.Estado), "ACTIVO") {
+		return data, fmt.Errorf("%w: %s is %s", ErrCustomerNotActive, ruc, data.Estado)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(data.Condicion), "HABIDO") {
+		return data, fmt.Errorf("%w: %s is %s", ErrCustomerNotHabido, ruc, data.Condicion)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,69 @@
+package sunatlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestRUCServer serves a RUC consultation with the given status and condition
+func newTestRUCServer(t *testing.T, estado, condicion string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "success",
+			"lista": []map[string]string{{
+				"apenomdenunciado": "CLIENTE S.A.",
+				"estado":           estado,
+				"condicion":        condicion,
+			}},
+		})
+	}))
+}
+
+func TestPrecheckCustomer(t *testing.T) {
+	tests := []struct {
+		name      string
+		estado    string
+		condicion string
+		wantErr   error
+	}{
+		{"Active Habido", "ACTIVO", "HABIDO", nil},
+		{"Status Not Reported", "", "", nil},
+		{"Inactive", "BAJA DE OFICIO", "HABIDO", ErrCustomerNotActive},
+		{"Not Habido", "ACTIVO", "NO HABIDO", ErrCustomerNotHabido},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRUCServer(t, tt.estado, tt.condicion)
+			defer server.Close()
+
+			rucService := NewRUCService("")
+			rucService.BaseURL = server.URL
+
+			client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+			client.SetRUCService(rucService)
+
+			data, err := client.PrecheckCustomer("20100070970")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PrecheckCustomer() error = %v, want %v", err, tt.wantErr)
+			}
+			if data == nil || data.RazonSocial != "CLIENTE S.A." {
+				t.Errorf("Expected consulted data to be returned, got %+v", data)
+			}
+		})
+	}
+}
+
+func TestPrecheckCustomer_NoRUCService(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+
+	_, err := client.PrecheckCustomer("20100070970")
+	if !errors.Is(err, ErrRUCServiceNotConfigured) {
+		t.Fatalf("Expected ErrRUCServiceNotConfigured, got %v", err)
+	}
+}
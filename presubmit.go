@@ -0,0 +1,98 @@
+// Package sunatlib provides pre-flight checks of signed documents before they are sent
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// Names of the PreSubmitCheck sub-checks, in the order they run
+const (
+	PreSubmitStructure   = "structure"   // Well-formed signed document (signer.ErrInvalidSignedXML)
+	PreSubmitSignature   = "signature"   // Signature matches the content (signer.ErrSignatureVerification)
+	PreSubmitCertificate = "certificate" // Signing certificate within its validity period (utils.ErrCertificateExpired)
+	PreSubmitFileName    = "filename"    // SUNAT file name derivable and consistent with the client (ErrDocumentNameMismatch)
+	PreSubmitSchema      = "schema"      // UBL structural validation, only with PreSubmitSchemaCheck
+)
+
+// ErrDocumentNameMismatch is returned when a document's type or issuer differs from the one it is sent as
+var ErrDocumentNameMismatch = errors.New("document does not match the expected file name")
+
+// PreSubmitError is returned by PreSubmitCheck for the first sub-check that fails. Err keeps the
+// error of the sub-check, so errors.Is works with its sentinel errors
+type PreSubmitError struct {
+	Check string // Failed sub-check (PreSubmitStructure, PreSubmitSignature, ...)
+	Err   error  // Error of the sub-check
+}
+
+func (e *PreSubmitError) Error() string {
+	return fmt.Sprintf("pre-submit %s check failed: %v", e.Check, e.Err)
+}
+
+// Unwrap returns the error of the failed sub-check
+func (e *PreSubmitError) Unwrap() error {
+	return e.Err
+}
+
+// PreSubmitCheck runs every local check on a signed document before it is sent as docType:
+// structure, signature verification (requires xmlsec1), certificate validity, file name
+// derivation and, with PreSubmitSchemaCheck, UBL validation. It returns a *PreSubmitError for
+// the first failing check, or nil when the document is ready to send. The issuer may be the
+// client RUC or any RUC registered with SetCertificateRegistry
+func (c *SUNATClient) PreSubmitCheck(signedXML []byte, docType string) error {
+	if err := utils.CheckXMLEncoding(signedXML); err != nil {
+		return &PreSubmitError{Check: PreSubmitStructure, Err: err}
+	}
+	if err := signer.PostSignValidate(signedXML); err != nil {
+		return &PreSubmitError{Check: PreSubmitStructure, Err: err}
+	}
+
+	certificate, err := signer.VerifyXML(signedXML)
+	if err != nil {
+		return &PreSubmitError{Check: PreSubmitSignature, Err: err}
+	}
+
+	now := c.clock().Now()
+	if now.Before(certificate.NotBefore) || now.After(certificate.NotAfter) {
+		return &PreSubmitError{Check: PreSubmitCertificate, Err: fmt.Errorf("%w: valid from %s to %s",
+			utils.ErrCertificateExpired, certificate.NotBefore.Format(time.RFC3339), certificate.NotAfter.Format(time.RFC3339))}
+	}
+
+	if err := c.checkDocumentName(signedXML, docType); err != nil {
+		return &PreSubmitError{Check: PreSubmitFileName, Err: err}
+	}
+
+	if c.PreSubmitSchemaCheck {
+		if err := c.validator.Validate(signedXML); err != nil {
+			return &PreSubmitError{Check: PreSubmitSchema, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// checkDocumentName verifies that the SUNAT file name can be derived from the document and that
+// its type is the one the client sends it as, issued by the client RUC or a RUC registered in
+// its CertificateRegistry
+func (c *SUNATClient) checkDocumentName(signedXML []byte, docType string) error {
+	if _, err := DeriveFileName(signedXML); err != nil {
+		return err
+	}
+
+	documentType, issuerRUC, id, err := parseDocumentIdentity(signedXML)
+	if err != nil {
+		return err
+	}
+	if documentType != docType {
+		return fmt.Errorf("%w: %s is a document of type %s, not %s", ErrDocumentNameMismatch, id, documentType, docType)
+	}
+	if issuerRUC != c.RUC && (c.certificates == nil || !c.certificates.has(issuerRUC)) {
+		return fmt.Errorf("%w: %s is issued by %s, not by %s or a RUC of the certificate registry", ErrDocumentNameMismatch, id, issuerRUC, c.RUC)
+	}
+
+	return nil
+}
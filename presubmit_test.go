@@ -0,0 +1,134 @@
+package sunatlib
+
+import (
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// newTestPreSubmitDocument returns an invoice of testRUC carrying a signature block whose digest
+// is accepted by installTestXMLSec1Verify, signed with a certificate valid until notAfter.
+// mutate changes the unsigned XML before the digest is computed
+func newTestPreSubmitDocument(t *testing.T, notAfter time.Time, mutate func(string) string) []byte {
+	t.Helper()
+
+	inv := newTestInvoice()
+	inv.Supplier.DocumentNumber = testRUC
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	unsigned := string(xmlContent)
+	if mutate != nil {
+		unsigned = mutate(unsigned)
+	}
+
+	certificate := testcert.New(t, pkix.Name{CommonName: testRUC}, notAfter.Add(-24*time.Hour), notAfter, nil)
+
+	digest := sha1.Sum([]byte(unsigned))
+	signature := `        <ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Id="SignatureSP">
+          <ds:SignedInfo>
+            <ds:Reference URI="">
+              <ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>
+            </ds:Reference>
+          </ds:SignedInfo>
+          <ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>
+          <ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(certificate.Cert.Raw) + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+        </ds:Signature>
+`
+	anchor := "<ext:ExtensionContent>\n"
+	if !strings.Contains(unsigned, anchor) {
+		t.Fatal("generated invoice without ext:ExtensionContent")
+	}
+	return []byte(strings.Replace(unsigned, anchor, anchor+signature, 1))
+}
+
+func TestPreSubmitCheck(t *testing.T) {
+	installTestXMLSec1Verify(t)
+
+	valid := time.Now().Add(24 * time.Hour)
+	document := newTestPreSubmitDocument(t, valid, nil)
+
+	tests := []struct {
+		name      string
+		document  []byte
+		docType   string
+		schema    bool
+		wantCheck string
+		wantErr   error
+	}{
+		{"Valid", document, "01", true, "", nil},
+		{"Structure", []byte(strings.Replace(string(document), "<ds:SignatureValue>c2lnbmF0dXJl", "<ds:SignatureValue>", 1)), "01", false, PreSubmitStructure, signer.ErrInvalidSignedXML},
+		{"Signature", []byte(strings.Replace(string(document), "CLIENTE S.A.", "OTRO CLIENTE S.A.", 1)), "01", false, PreSubmitSignature, signer.ErrSignatureVerification},
+		{"Certificate", newTestPreSubmitDocument(t, time.Now().Add(-time.Hour), nil), "01", false, PreSubmitCertificate, utils.ErrCertificateExpired},
+		{"File Name", document, "03", false, PreSubmitFileName, ErrDocumentNameMismatch},
+		{"Schema Disabled", newTestPreSubmitDocument(t, valid, withoutUBLVersion), "01", false, "", nil},
+		{"Schema", newTestPreSubmitDocument(t, valid, withoutUBLVersion), "01", true, PreSubmitSchema, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+			client.PreSubmitSchemaCheck = tt.schema
+
+			err := client.PreSubmitCheck(tt.document, tt.docType)
+			if tt.wantCheck == "" {
+				if err != nil {
+					t.Fatalf("PreSubmitCheck() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var preSubmitErr *PreSubmitError
+			if !errors.As(err, &preSubmitErr) {
+				t.Fatalf("PreSubmitCheck() error = %v, want *PreSubmitError", err)
+			}
+			if preSubmitErr.Check != tt.wantCheck {
+				t.Errorf("Check = %q, want %q (%v)", preSubmitErr.Check, tt.wantCheck, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("PreSubmitCheck() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// withoutUBLVersion removes cbc:UBLVersionID, which UBLValidator requires
+func withoutUBLVersion(xmlContent string) string {
+	start := strings.Index(xmlContent, "<cbc:UBLVersionID>")
+	end := strings.Index(xmlContent, "</cbc:UBLVersionID>")
+	if start == -1 || end == -1 {
+		return xmlContent
+	}
+	return xmlContent[:start] + xmlContent[end+len("</cbc:UBLVersionID>"):]
+}
+
+func TestPreSubmitCheck_RegisteredRUC(t *testing.T) {
+	installTestXMLSec1Verify(t)
+
+	document := newTestPreSubmitDocument(t, time.Now().Add(24*time.Hour), nil)
+	client := NewSUNATClient("20000000001", "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	if err := client.PreSubmitCheck(document, "01"); !errors.Is(err, ErrDocumentNameMismatch) {
+		t.Fatalf("PreSubmitCheck() without registry error = %v, want %v", err, ErrDocumentNameMismatch)
+	}
+
+	registry := NewCertificateRegistry()
+	pfxData, _ := newTestPFX(t, "secret")
+	if err := registry.RegisterPFX(testRUC, pfxData, "secret"); err != nil {
+		t.Fatalf("RegisterPFX() error = %v", err)
+	}
+	client.SetCertificateRegistry(registry)
+
+	if err := client.PreSubmitCheck(document, "01"); err != nil {
+		t.Errorf("PreSubmitCheck() with %s registered error = %v, want nil", testRUC, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package sunatlib provides the QR code data printed on electronic documents
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// ErrInvalidQR is returned by ParseQRData when the scanned text is not a SUNAT QR
+var ErrInvalidQR = errors.New("invalid QR data")
+
+// qrMinFields is the number of mandatory fields of the QR: RUC, type, series, number, IGV, total and date
+const qrMinFields = 7
+
+// BuildQRData builds the text of the QR code printed on the representation of an invoice:
+// RUC|TIPO|SERIE|NUMERO|IGV|TOTAL|FECHA|TIPO DOC ADQUIRENTE|NUMERO DOC ADQUIRENTE|VALOR RESUMEN|
+// digestValue is the ds:DigestValue of the signed document
+func BuildQRData(inv *Invoice, digestValue string) string {
+	fields := []string{
+		inv.Supplier.DocumentNumber,
+		inv.DocumentType,
+		inv.Series,
+		inv.Number,
+		fmt.Sprintf("%.2f", inv.TotalIGV),
+		fmt.Sprintf("%.2f", inv.TotalAmount),
+		inv.IssueDate.Format("2006-01-02"),
+		inv.Customer.DocumentType,
+		inv.Customer.DocumentNumber,
+		digestValue,
+	}
+	return strings.Join(fields, "|") + "|"
+}
+
+// ParseQRData parses the text of a scanned QR code into the parameters to validate the document.
+// The issue date is accepted as YYYY-MM-DD or DD/MM/YYYY
+func ParseQRData(qrData string) (*ValidationParams, error) {
+	fields := strings.Split(strings.TrimSpace(qrData), "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if len(fields) < qrMinFields {
+		return nil, fmt.Errorf("%w: expected at least %d fields separated by |, got %d", ErrInvalidQR, qrMinFields, len(fields))
+	}
+
+	params := &ValidationParams{
+		IssuerRUC:      fields[0],
+		DocumentType:   fields[1],
+		SeriesNumber:   strings.ToUpper(fields[2]),
+		DocumentNumber: fields[3],
+	}
+
+	if !utils.ValidateRUC(params.IssuerRUC) {
+		return nil, fmt.Errorf("%w: invalid RUC %q", ErrInvalidQR, params.IssuerRUC)
+	}
+	if len(params.DocumentType) != 2 {
+		return nil, fmt.Errorf("%w: invalid document type %q", ErrInvalidQR, params.DocumentType)
+	}
+	if !utils.ValidateDocumentSeries(params.SeriesNumber) {
+		return nil, fmt.Errorf("%w: invalid series %q", ErrInvalidQR, params.SeriesNumber)
+	}
+	if !utils.ValidateDocumentNumber(params.DocumentNumber) {
+		return nil, fmt.Errorf("%w: invalid number %q", ErrInvalidQR, params.DocumentNumber)
+	}
+
+	total, err := strconv.ParseFloat(fields[5], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid total %q", ErrInvalidQR, fields[5])
+	}
+	params.TotalAmount = total
+
+	issueDate, err := parseQRDate(fields[6])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid issue date %q", ErrInvalidQR, fields[6])
+	}
+	params.IssueDate = issueDate
+
+	if len(fields) > 8 {
+		params.RecipientDocType = fields[7]
+		params.RecipientDocNumber = fields[8]
+	}
+
+	return params, nil
+}
+
+// parseQRDate returns a QR issue date in YYYY-MM-DD format
+func parseQRDate(value string) (string, error) {
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported date format")
+}
+
+// ValidateFromQR validates the document described by a scanned QR code
+func (vc *ValidationClient) ValidateFromQR(qrData string) (*ValidationResult, error) {
+	params, err := ParseQRData(qrData)
+	if err != nil {
+		return nil, err
+	}
+	return vc.ValidateDocument(params)
+}
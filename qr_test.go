@@ -0,0 +1,76 @@
+package sunatlib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildQRData(t *testing.T) {
+	inv := newTestInvoice()
+
+	got := BuildQRData(inv, "qZk+NkcGgWq6PiVxeFDCbJzQ2J0=")
+	want := "20000000001|01|F001|1|18.00|118.00|2026-04-27|6|20100070970|qZk+NkcGgWq6PiVxeFDCbJzQ2J0=|"
+	if got != want {
+		t.Errorf("BuildQRData() = %q, want %q", got, want)
+	}
+
+	params, err := ParseQRData(got)
+	if err != nil {
+		t.Fatalf("ParseQRData() error = %v", err)
+	}
+	if params.IssuerRUC != "20000000001" || params.SeriesNumber != "F001" || params.DocumentNumber != "1" ||
+		params.IssueDate != "2026-04-27" || params.TotalAmount != 118 || params.RecipientDocNumber != "20100070970" {
+		t.Errorf("ParseQRData() = %+v, want the invoice data", params)
+	}
+}
+
+func TestParseQRData_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		qr   string
+	}{
+		{"not a SUNAT QR", "https://example.com/invoice/1"},
+		{"missing fields", testRUC + "|01|F001|1|18.00"},
+		{"invalid RUC", "123|01|F001|1|18.00|118.00|2026-04-27|"},
+		{"invalid total", testRUC + "|01|F001|1|18.00|S/ 118|2026-04-27|"},
+		{"invalid date", testRUC + "|01|F001|1|18.00|118.00|27-04-2026|"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQRData(tt.qr); !errors.Is(err, ErrInvalidQR) {
+				t.Errorf("ParseQRData() error = %v, want ErrInvalidQR", err)
+			}
+		})
+	}
+}
+
+func TestValidateFromQR(t *testing.T) {
+	var requestBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		io.WriteString(w, validationResponse("El comprobante F001-123 es un comprobante de pago válido."))
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+
+	result, err := client.ValidateFromQR(testRUC + "|01|F001|123|18.00|118.00|27/04/2026|6|20100070970|qZk+NkcGgWq6PiVxeFDCbJzQ2J0=|")
+	if err != nil {
+		t.Fatalf("ValidateFromQR() error = %v", err)
+	}
+	if result.State != "VALIDO" {
+		t.Errorf("State = %q, want VALIDO", result.State)
+	}
+	for _, want := range []string{"<serieCDP>F001</serieCDP>", "<numeroCDP>123</numeroCDP>", "<fechaEmision>27/04/2026</fechaEmision>", "<importeTotal>118.00</importeTotal>"} {
+		if !strings.Contains(requestBody, want) {
+			t.Errorf("request is missing %s", want)
+		}
+	}
+}
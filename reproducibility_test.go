@@ -0,0 +1,63 @@
+package sunatlib
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// generateTimes is the number of times each generator runs in the reproducibility tests
+const generateTimes = 20
+
+func TestGenerators_Deterministic(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+
+	// An invoice mixing every tax bucket plus installments exercises all aggregation paths
+	inv := newTestInvoice()
+	inv.Items = []InvoiceItem{
+		{Description: "GRAVADO", Quantity: 1, UnitCode: "NIU", UnitValue: 100, AffectationCode: "10", IGVAmount: 18},
+		{Description: "EXONERADO", Quantity: 1, UnitCode: "NIU", UnitValue: 50, AffectationCode: "20"},
+		{Description: "INAFECTO", Quantity: 1, UnitCode: "NIU", UnitValue: 30, AffectationCode: "30"},
+		{Description: "BONIFICACION", Quantity: 1, UnitCode: "NIU", UnitValue: 10, AffectationCode: "15", IGVAmount: 1.8},
+	}
+	inv.TotalTaxed = 100
+	inv.TotalExonerated = 50
+	inv.TotalUnaffected = 30
+	inv.TotalIGV = 18
+	inv.TotalAmount = 198
+	inv.DueDate = time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)
+	inv.Installments = []Installment{
+		{Amount: 99, DueDate: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{Amount: 99, DueDate: time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC)},
+	}
+
+	voided := newTestVoidedDocumentsRequest()
+	voided.Documents = append(voided.Documents,
+		VoidedDocument{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "7", VoidedReason: "ERROR EN MONTO"},
+		VoidedDocument{DocumentTypeCode: "07", DocumentSeries: "F001", DocumentNumber: "9", VoidedReason: "ERROR EN FECHA"},
+	)
+
+	generators := map[string]func() ([]byte, error){
+		"Invoice":         func() ([]byte, error) { return GenerateInvoiceXML(inv) },
+		"VoidedDocuments": func() ([]byte, error) { return client.GenerateVoidedDocumentsXML(voided) },
+	}
+
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			first, err := generate()
+			if err != nil {
+				t.Fatalf("generator error = %v", err)
+			}
+
+			for i := 1; i < generateTimes; i++ {
+				next, err := generate()
+				if err != nil {
+					t.Fatalf("generator error = %v", err)
+				}
+				if !bytes.Equal(first, next) {
+					t.Fatalf("run %d produced different output for identical input", i+1)
+				}
+			}
+		})
+	}
+}
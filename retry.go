@@ -0,0 +1,93 @@
+// Package sunatlib provides automatic retries for idempotent SUNAT queries
+package sunatlib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// RetryPolicy configures the retries of idempotent requests such as getStatus.
+// Write operations (sendBill, sendSummary) are never retried automatically
+type RetryPolicy struct {
+	MaxAttempts int           // Maximum number of attempts, including the first one (0 uses DefaultStatusRetryPolicy)
+	Backoff     time.Duration // Delay before the first retry, doubled on each further retry
+}
+
+// DefaultStatusRetryPolicy is the retry policy applied to getStatus queries
+var DefaultStatusRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 500 * time.Millisecond}
+
+// statusRetryPolicy returns the retry policy for getStatus queries
+func (c *SUNATClient) statusRetryPolicy() RetryPolicy {
+	if c.StatusRetry == nil || c.StatusRetry.MaxAttempts <= 0 {
+		return DefaultStatusRetryPolicy
+	}
+	return *c.StatusRetry
+}
+
+// isRetryableStatus returns true for HTTP statuses caused by a temporary gateway problem.
+// SOAP faults are returned with status 500 and are not retried
+func isRetryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// postStatusQuery sends a getStatus SOAP request, retrying network errors and gateway errors
+// according to the status retry policy until ctx is done. It returns the response body and the
+// attempts made
+func (c *SUNATClient) postStatusQuery(ctx context.Context, soapBody string) ([]byte, int, error) {
+	policy := c.statusRetryPolicy()
+	backoff := policy.Backoff
+
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			c.metrics.retries.Add(1)
+			if !c.sleepOrStop(backoff, ctx.Done()) {
+				return nil, attempt - 1, ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", c.endpointFor(ServiceStatus), bytes.NewBufferString(soapBody))
+		if err != nil {
+			return nil, attempt, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+		req.Header.Set("SOAPAction", "urn:getStatus")
+		utils.ApplyExtraHeaders(req, c.extraHeaders)
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			lastErr = c.redactError(fmt.Errorf("failed to send HTTP request: %w", err))
+			if ctx.Err() != nil {
+				return nil, attempt, lastErr
+			}
+			continue
+		}
+
+		responseData, err := readResponseBody(resp, c.MaxResponseSize)
+		resp.Body.Close()
+		if err != nil {
+			if errors.Is(err, ErrResponseTooLarge) {
+				return nil, attempt, fmt.Errorf("failed to read response: %w", err)
+			}
+			lastErr = fmt.Errorf("failed to read response: %w", err)
+			continue
+		}
+
+		if isRetryableStatus(resp.StatusCode) && attempt < policy.MaxAttempts {
+			lastErr = fmt.Errorf("SUNAT returned HTTP %d", resp.StatusCode)
+			continue
+		}
+
+		return responseData, attempt, nil
+	}
+
+	return nil, policy.MaxAttempts, fmt.Errorf("getStatus failed after %d attempts: %w", policy.MaxAttempts, lastErr)
+}
@@ -0,0 +1,73 @@
+package sunatlib
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyStatusServer returns a getStatus server that drops the first failures connections
+func newFlakyStatusServer(t *testing.T, failures int32, body string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&requests, 1); n <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Hijack() error = %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, body)
+	}))
+
+	return server, &requests
+}
+
+func TestQueryVoidedDocumentsTicket_RetriesNetworkErrors(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString([]byte(testCDRContents))
+	server, requests := newFlakyStatusServer(t, 1, getStatusResponse("0", cdr))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.StatusRetry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	response, err := client.QueryVoidedDocumentsTicket(testTicket)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+	if !response.IsProcessed() {
+		t.Errorf("Expected processed ticket, got status %q", response.StatusCode)
+	}
+	if response.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", response.Attempts)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestGetVoidedDocumentsStatus_RetriesExhausted(t *testing.T) {
+	server, requests := newFlakyStatusServer(t, 5, getStatusResponse("0", ""))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.StatusRetry = &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+
+	_, err := client.GetVoidedDocumentsStatus(testTicket)
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("Expected error after 2 attempts, got %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
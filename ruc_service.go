@@ -2,11 +2,15 @@ package sunatlib
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // SunatRawResponse represents the direct response from SUNAT service
@@ -21,14 +25,25 @@ type SunatRawResponse struct {
 		DesDistrito        string `json:"desdistrito"`
 		DesProvincia       string `json:"desprovincia"`
 		DesDepartamento    string `json:"desdepartamento"`
+		Estado             string `json:"estado"`    // Taxpayer status, when reported
+		Condicion          string `json:"condicion"` // Domicile condition, when reported
 	} `json:"lista"`
 }
 
 // RUCBasicResponse represents the standard response format
 type RUCBasicResponse struct {
-	Success bool          `json:"success"`
-	Data    *RUCBasicData `json:"data,omitempty"`
-	Message string        `json:"message,omitempty"`
+	Success   bool          `json:"success"`
+	Data      *RUCBasicData `json:"data,omitempty"`
+	Message   string        `json:"message,omitempty"`
+	RateLimit *RateLimit    `json:"rate_limit,omitempty"` // Rate-limit headers returned by the provider, if any
+}
+
+// RateLimit holds the X-RateLimit-* headers returned by consultation providers (e.g., DeColecta)
+// so bulk callers can pace themselves
+type RateLimit struct {
+	Limit     int       `json:"limit"`     // Requests allowed in the current window
+	Remaining int       `json:"remaining"` // Requests left in the current window
+	Reset     time.Time `json:"reset"`     // When the window resets (zero if not reported)
 }
 
 // RUCBasicData contains basic company information
@@ -54,9 +69,10 @@ type RUCBasicData struct {
 
 // RUCFullResponse represents the response with full data (if available)
 type RUCFullResponse struct {
-	Success bool         `json:"success"`
-	Data    *RUCFullData `json:"data,omitempty"`
-	Message string       `json:"message,omitempty"`
+	Success   bool         `json:"success"`
+	Data      *RUCFullData `json:"data,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	RateLimit *RateLimit   `json:"rate_limit,omitempty"` // Rate-limit headers returned by the provider, if any
 }
 
 // RUCFullData contains complete company information
@@ -70,22 +86,74 @@ type RUCFullData struct {
 	FechaInscripcion   string `json:"fecha_inscripcion"`
 }
 
+// ErrHistoryNotSupported is returned by ConsultHistory when the provider has no RUC history endpoint
+var ErrHistoryNotSupported = errors.New("RUC history not supported by provider")
+
+// ErrRUCNotFound is returned when a provider endpoint answers HTTP 404 for the queried RUC
+var ErrRUCNotFound = errors.New("RUC no encontrado")
+
+// RUCStateChange is a change of the taxpayer status or domicile condition of a RUC
+type RUCStateChange struct {
+	Date      time.Time `json:"fecha"`     // Date of the change
+	Estado    string    `json:"estado"`    // Taxpayer status from that date (ACTIVO, BAJA DE OFICIO, ...)
+	Condicion string    `json:"condicion"` // Domicile condition from that date (HABIDO, NO HABIDO, ...)
+}
+
+// rucHistoryResponse is the payload of a RUC history endpoint (DeColecta format)
+type rucHistoryResponse struct {
+	RUC       string `json:"numero_documento"`
+	Historial []struct {
+		Fecha     string `json:"fecha"`
+		Estado    string `json:"estado"`
+		Condicion string `json:"condicion"`
+	} `json:"historial"`
+}
+
+// ErrSeriesNotSupported is returned by ConsultAuthorizedSeries when the provider has no authorized series endpoint
+var ErrSeriesNotSupported = errors.New("authorized series not supported by provider")
+
+// SeriesInfo is an electronic document series registered by a RUC
+type SeriesInfo struct {
+	Series       string `json:"serie"`          // Series (e.g., "F001")
+	DocumentType string `json:"tipo_documento"` // Document type code (Catálogo 01)
+	Status       string `json:"estado"`         // Series status as reported (ACTIVO, BAJA, ...)
+}
+
+// rucSeriesResponse is the payload of an authorized series endpoint (DeColecta format)
+type rucSeriesResponse struct {
+	RUC    string       `json:"numero_documento"`
+	Series []SeriesInfo `json:"series"`
+}
+
 // RUCService handles RUC consultation operations
 type RUCService struct {
-	BaseURL    string
-	HTTPClient *http.Client
+	BaseURL         string
+	HistoryURL      string // RUC history endpoint queried with ?numero=RUC (empty if the provider has none)
+	SeriesURL       string // Authorized series endpoint queried with ?numero=RUC (empty if the provider has none)
+	FullURL         string // Full RUC data endpoint (DeColecta format) queried with ?numero=RUC; empty limits ConsultFull to basic data
+	HTTPClient      *http.Client
+	MaxResponseSize int64 // Maximum response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+	extraHeaders    map[string]string
 }
 
 // NewRUCService creates a new RUC service instance (apiKey is kept for backward compatibility but unused)
 func NewRUCService(apiKey string) *RUCService {
 	return &RUCService{
 		BaseURL: "https://ww1.sunat.gob.pe/ol-ti-itfisdenreg/itfisdenreg.htm",
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		HTTPClient: utils.NewHTTPClient(30*time.Second, utils.TransportTimeouts{}),
 	}
 }
 
+// SetTransportTimeouts configures the per-phase timeouts of RUC requests, see SUNATClient.SetTransportTimeouts
+func (rs *RUCService) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	rs.HTTPClient = utils.WithTransportTimeouts(rs.HTTPClient, timeouts)
+}
+
+// SetExtraHeaders sets headers added to every RUC request, see SUNATClient.SetExtraHeaders
+func (rs *RUCService) SetExtraHeaders(headers map[string]string) {
+	rs.extraHeaders = utils.CopyHeaders(headers)
+}
+
 // ConsultBasic performs a basic RUC consultation using SUNAT's direct API
 func (rs *RUCService) ConsultBasic(ruc string) (*RUCBasicResponse, error) {
 	if !IsValidRUC(ruc) {
@@ -105,6 +173,7 @@ func (rs *RUCService) ConsultBasic(ruc string) (*RUCBasicResponse, error) {
 	// Realistic headers to avoid blocks
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 	req.Header.Set("Accept", "application/json, text/plain, */*")
+	utils.ApplyExtraHeaders(req, rs.extraHeaders)
 
 	resp, err := rs.HTTPClient.Do(req)
 	if err != nil {
@@ -115,35 +184,50 @@ func (rs *RUCService) ConsultBasic(ruc string) (*RUCBasicResponse, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	rateLimit := parseRateLimit(resp.Header, time.Now())
+
+	body, err := readResponseBody(resp, rs.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		return &RUCBasicResponse{
-			Success: false,
-			Message: fmt.Sprintf("Error HTTP %d", resp.StatusCode),
+			Success:   false,
+			Message:   fmt.Sprintf("Error HTTP %d", resp.StatusCode),
+			RateLimit: rateLimit,
 		}, fmt.Errorf("error HTTP %d", resp.StatusCode)
 	}
 
 	var sunatResp SunatRawResponse
 	if err := json.Unmarshal(body, &sunatResp); err != nil {
 		return &RUCBasicResponse{
-			Success: false,
-			Message: "Error parseando respuesta de SUNAT",
+			Success:   false,
+			Message:   "Error parseando respuesta de SUNAT",
+			RateLimit: rateLimit,
 		}, fmt.Errorf("error parseando JSON: %w", err)
 	}
 
 	if sunatResp.Message != "success" || len(sunatResp.Lista) == 0 {
 		return &RUCBasicResponse{
-			Success: false,
-			Message: "RUC no encontrado o error en SUNAT",
+			Success:   false,
+			Message:   "RUC no encontrado o error en SUNAT",
+			RateLimit: rateLimit,
 		}, fmt.Errorf("RUC no encontrado")
 	}
 
 	data := sunatResp.Lista[0]
-	
+
+	// The service usually omits status and condition, and only returns active taxpayers
+	estado := strings.TrimSpace(data.Estado)
+	if estado == "" {
+		estado = "ACTIVO"
+	}
+	condicion := strings.TrimSpace(data.Condicion)
+	if condicion == "" {
+		condicion = "HABIDO"
+	}
+
 	result := &RUCBasicResponse{
 		Success: true,
 		Data: &RUCBasicData{
@@ -154,29 +238,39 @@ func (rs *RUCService) ConsultBasic(ruc string) (*RUCBasicResponse, error) {
 			Provincia:    strings.TrimSpace(data.DesProvincia),
 			Departamento: strings.TrimSpace(data.DesDepartamento),
 			Ubigeo:       data.IdDepartamento + data.IdProvincia + data.IdDistrito,
-			Estado:       "ACTIVO", // This API doesn't provide status, but it usually returns active ones
-			Condicion:    "HABIDO",
+			Estado:       estado,
+			Condicion:    condicion,
 		},
-		Message: "Consulta exitosa",
+		Message:   "Consulta exitosa",
+		RateLimit: rateLimit,
 	}
 
 	return result, nil
 }
 
-// ConsultFull performs a RUC consultation (limited data due to simplified API)
+// ConsultFull performs a RUC consultation using FullURL, or ConsultBasic (limited data) when it is not configured
 func (rs *RUCService) ConsultFull(ruc string) (*RUCFullResponse, error) {
+	if rs.FullURL != "" {
+		return rs.consultFullEndpoint(ruc)
+	}
+
 	basic, err := rs.ConsultBasic(ruc)
 	if err != nil {
-		return &RUCFullResponse{
+		response := &RUCFullResponse{
 			Success: false,
 			Message: err.Error(),
-		}, err
+		}
+		if basic != nil {
+			response.RateLimit = basic.RateLimit
+		}
+		return response, err
 	}
 
 	if !basic.Success {
 		return &RUCFullResponse{
-			Success: false,
-			Message: basic.Message,
+			Success:   false,
+			Message:   basic.Message,
+			RateLimit: basic.RateLimit,
 		}, nil
 	}
 
@@ -185,10 +279,307 @@ func (rs *RUCService) ConsultFull(ruc string) (*RUCFullResponse, error) {
 		Data: &RUCFullData{
 			RUCBasicData: *basic.Data,
 		},
-		Message: "Consulta exitosa (datos limitados)",
+		Message:   "Consulta exitosa (datos limitados)",
+		RateLimit: basic.RateLimit,
+	}, nil
+}
+
+// consultFullEndpoint queries FullURL, whose response holds the RUCFullData fields
+func (rs *RUCService) consultFullEndpoint(ruc string) (*RUCFullResponse, error) {
+	if !IsValidRUC(ruc) {
+		return &RUCFullResponse{
+			Success: false,
+			Message: "RUC debe tener 11 dígitos y empezar con 10, 20 o 15",
+		}, fmt.Errorf("RUC inválido: debe tener 11 dígitos")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?numero=%s", rs.FullURL, ruc), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creando request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	utils.ApplyExtraHeaders(req, rs.extraHeaders)
+
+	resp, err := rs.HTTPClient.Do(req)
+	if err != nil {
+		return &RUCFullResponse{
+			Success: false,
+			Message: fmt.Sprintf("Error de conexión: %v", err),
+		}, fmt.Errorf("error ejecutando request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	rateLimit := parseRateLimit(resp.Header, time.Now())
+
+	body, err := readResponseBody(resp, rs.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &RUCFullResponse{
+			Success:   false,
+			Message:   fmt.Sprintf("Error HTTP %d", resp.StatusCode),
+			RateLimit: rateLimit,
+		}, fmt.Errorf("error HTTP %d", resp.StatusCode)
+	}
+
+	var data RUCFullData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return &RUCFullResponse{
+			Success:   false,
+			Message:   "Error parseando respuesta",
+			RateLimit: rateLimit,
+		}, fmt.Errorf("error parseando JSON: %w", err)
+	}
+	if data.RUC == "" {
+		data.RUC = ruc
+	}
+
+	return &RUCFullResponse{
+		Success:   true,
+		Data:      &data,
+		Message:   "Consulta exitosa",
+		RateLimit: rateLimit,
 	}, nil
 }
 
+// RUCFullOrBasicResponse is the result of ConsultFullOrBasic. Data always holds the basic fields
+// when Success is true; the full fields are only set when HasFullData is true
+type RUCFullOrBasicResponse struct {
+	Success     bool         `json:"success"`
+	Data        *RUCFullData `json:"data,omitempty"`
+	HasFullData bool         `json:"has_full_data"` // True when the full fields (actividad económica, ...) were returned
+	Message     string       `json:"message,omitempty"`
+	RateLimit   *RateLimit   `json:"rate_limit,omitempty"` // Rate-limit headers of the last request made
+}
+
+// ConsultFullOrBasic returns basic and, when available, full RUC data with a single full
+// consultation. It falls back to ConsultBasic only when the full consultation fails or lacks the
+// basic fields, so UIs can show the basic data first and the full data on demand
+func (rs *RUCService) ConsultFullOrBasic(ruc string) (*RUCFullOrBasicResponse, error) {
+	full, err := rs.ConsultFull(ruc)
+	if err == nil && full.Success && full.Data != nil && strings.TrimSpace(full.Data.RazonSocial) != "" {
+		return &RUCFullOrBasicResponse{
+			Success:     true,
+			Data:        full.Data,
+			HasFullData: hasFullRUCData(full.Data),
+			Message:     full.Message,
+			RateLimit:   full.RateLimit,
+		}, nil
+	}
+
+	// Without FullURL, ConsultFull already was a basic consultation
+	if rs.FullURL == "" {
+		response := &RUCFullOrBasicResponse{Success: false}
+		if full != nil {
+			response.Message = full.Message
+			response.RateLimit = full.RateLimit
+		}
+		return response, err
+	}
+
+	basic, err := rs.ConsultBasic(ruc)
+	if err != nil {
+		response := &RUCFullOrBasicResponse{Success: false, Message: err.Error()}
+		if basic != nil {
+			response.RateLimit = basic.RateLimit
+		}
+		return response, err
+	}
+	if !basic.Success || basic.Data == nil {
+		return &RUCFullOrBasicResponse{Success: false, Message: basic.Message, RateLimit: basic.RateLimit}, nil
+	}
+
+	return &RUCFullOrBasicResponse{
+		Success:   true,
+		Data:      &RUCFullData{RUCBasicData: *basic.Data},
+		Message:   basic.Message,
+		RateLimit: basic.RateLimit,
+	}, nil
+}
+
+// hasFullRUCData returns true if any field beyond the basic data is set
+func hasFullRUCData(data *RUCFullData) bool {
+	return data.ActividadEconomica != "" || data.NumeroTrabajadores != "" || data.TipoFacturacion != "" ||
+		data.TipoContabilidad != "" || data.ComercioExterior != "" || data.FechaInscripcion != ""
+}
+
+// ConsultHistory returns the status and condition changes of a RUC, oldest first. SUNAT's direct
+// API has no history, so it returns ErrHistoryNotSupported unless HistoryURL is configured, or
+// when the provider answers HTTP 501. An unknown RUC (HTTP 404) returns ErrRUCNotFound
+func (rs *RUCService) ConsultHistory(ruc string) ([]RUCStateChange, error) {
+	if !IsValidRUC(ruc) {
+		return nil, fmt.Errorf("RUC inválido: debe tener 11 dígitos")
+	}
+	if rs.HistoryURL == "" {
+		return nil, ErrHistoryNotSupported
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?numero=%s", rs.HistoryURL, ruc), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creando request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	utils.ApplyExtraHeaders(req, rs.extraHeaders)
+
+	resp, err := rs.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error ejecutando request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp, rs.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrRUCNotFound, ruc)
+	case http.StatusNotImplemented:
+		return nil, fmt.Errorf("%w: HTTP %d", ErrHistoryNotSupported, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("error HTTP %d", resp.StatusCode)
+	}
+
+	return parseRUCHistory(body)
+}
+
+// parseRUCHistory parses a RUC history payload, sorting the changes by date
+func parseRUCHistory(body []byte) ([]RUCStateChange, error) {
+	var history rucHistoryResponse
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("error parseando JSON: %w", err)
+	}
+
+	changes := make([]RUCStateChange, 0, len(history.Historial))
+	for i, entry := range history.Historial {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(entry.Fecha))
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid date %q", i+1, entry.Fecha)
+		}
+		changes = append(changes, RUCStateChange{
+			Date:      date,
+			Estado:    strings.TrimSpace(entry.Estado),
+			Condicion: strings.TrimSpace(entry.Condicion),
+		})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Date.Before(changes[j].Date)
+	})
+
+	return changes, nil
+}
+
+// ConsultAuthorizedSeries returns the electronic document series registered by ruc, queried from
+// SeriesURL. SUNAT's public consultation does not expose them, so it returns ErrSeriesNotSupported
+// when no provider endpoint is configured or the provider answers HTTP 501. An unknown RUC
+// (HTTP 404) returns ErrRUCNotFound
+func (rs *RUCService) ConsultAuthorizedSeries(ruc string) ([]SeriesInfo, error) {
+	if !IsValidRUC(ruc) {
+		return nil, fmt.Errorf("RUC inválido: debe tener 11 dígitos")
+	}
+	if rs.SeriesURL == "" {
+		return nil, ErrSeriesNotSupported
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?numero=%s", rs.SeriesURL, ruc), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creando request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	utils.ApplyExtraHeaders(req, rs.extraHeaders)
+
+	resp, err := rs.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error ejecutando request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp, rs.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrRUCNotFound, ruc)
+	case http.StatusNotImplemented:
+		return nil, fmt.Errorf("%w: HTTP %d", ErrSeriesNotSupported, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("error HTTP %d", resp.StatusCode)
+	}
+
+	return parseAuthorizedSeries(body)
+}
+
+// parseAuthorizedSeries parses an authorized series payload, sorted by document type and series
+func parseAuthorizedSeries(body []byte) ([]SeriesInfo, error) {
+	var payload rucSeriesResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("error parseando JSON: %w", err)
+	}
+
+	series := make([]SeriesInfo, 0, len(payload.Series))
+	for i, entry := range payload.Series {
+		info := SeriesInfo{
+			Series:       strings.ToUpper(strings.TrimSpace(entry.Series)),
+			DocumentType: strings.TrimSpace(entry.DocumentType),
+			Status:       strings.ToUpper(strings.TrimSpace(entry.Status)),
+		}
+		if info.Series == "" {
+			return nil, fmt.Errorf("entry %d: missing series", i+1)
+		}
+		series = append(series, info)
+	}
+
+	sort.SliceStable(series, func(i, j int) bool {
+		if series[i].DocumentType != series[j].DocumentType {
+			return series[i].DocumentType < series[j].DocumentType
+		}
+		return series[i].Series < series[j].Series
+	})
+
+	return series, nil
+}
+
+// parseRateLimit reads the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// headers. Reset may be a Unix timestamp or a number of seconds from now. It returns nil when
+// the provider does not report rate limits
+func parseRateLimit(header http.Header, now time.Time) *RateLimit {
+	limit, hasLimit := headerInt(header, "X-RateLimit-Limit")
+	remaining, hasRemaining := headerInt(header, "X-RateLimit-Remaining")
+	reset, hasReset := headerInt(header, "X-RateLimit-Reset")
+	if !hasLimit && !hasRemaining && !hasReset {
+		return nil
+	}
+
+	rateLimit := &RateLimit{Limit: limit, Remaining: remaining}
+	if hasReset {
+		// Values this large are Unix timestamps rather than delays in seconds
+		if reset > 1000000000 {
+			rateLimit.Reset = time.Unix(int64(reset), 0)
+		} else {
+			rateLimit.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	return rateLimit
+}
+
+// headerInt returns the integer value of a header and whether it was present and valid
+func headerInt(header http.Header, name string) (int, bool) {
+	value, err := strconv.Atoi(strings.TrimSpace(header.Get(name)))
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
 // IsValidRUC validates if a RUC number has the correct format
 func IsValidRUC(ruc string) bool {
 	if len(ruc) != 11 {
@@ -0,0 +1,307 @@
+package sunatlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRUCService_RateLimitHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Header().Set("X-RateLimit-Reset", "1777334400")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "success",
+			"lista":   []map[string]string{{"apenomdenunciado": "CLIENTE S.A."}},
+		})
+	}))
+	defer server.Close()
+
+	rucService := NewRUCService("")
+	rucService.BaseURL = server.URL
+
+	response, err := rucService.ConsultFull("20100070970")
+	if err != nil {
+		t.Fatalf("ConsultFull() error = %v", err)
+	}
+
+	rateLimit := response.RateLimit
+	if rateLimit == nil {
+		t.Fatal("Expected rate limit on the response")
+	}
+	if rateLimit.Limit != 100 || rateLimit.Remaining != 42 {
+		t.Errorf("Expected limit 100 and remaining 42, got %+v", rateLimit)
+	}
+	if !rateLimit.Reset.Equal(time.Unix(1777334400, 0)) {
+		t.Errorf("Expected reset at Unix 1777334400, got %v", rateLimit.Reset)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2026, 4, 27, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    *RateLimit
+	}{
+		{
+			name:    "Reset In Seconds",
+			headers: map[string]string{"X-RateLimit-Limit": "60", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "30"},
+			want:    &RateLimit{Limit: 60, Remaining: 0, Reset: now.Add(30 * time.Second)},
+		},
+		{
+			name:    "Without Reset",
+			headers: map[string]string{"X-RateLimit-Remaining": "5"},
+			want:    &RateLimit{Remaining: 5},
+		},
+		{
+			name:    "Not Reported",
+			headers: map[string]string{"X-RateLimit-Remaining": "unknown"},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.headers {
+				header.Set(name, value)
+			}
+
+			got := parseRateLimit(header, now)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("parseRateLimit() = %+v, want %+v", got, tt.want)
+			}
+			if got != nil && (got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining || !got.Reset.Equal(tt.want.Reset)) {
+				t.Errorf("parseRateLimit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRUCService_ConsultHistory(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("numero")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"numero_documento": "20100070970",
+			"historial": [
+				{"fecha": "2023-08-15", "estado": "SUSPENSION TEMPORAL", "condicion": "HABIDO"},
+				{"fecha": "2010-03-01", "estado": "ACTIVO", "condicion": "HABIDO"},
+				{"fecha": "2024-01-10", "estado": "ACTIVO", "condicion": " NO HABIDO "}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	rucService := NewRUCService("")
+	rucService.HistoryURL = server.URL
+
+	changes, err := rucService.ConsultHistory("20100070970")
+	if err != nil {
+		t.Fatalf("ConsultHistory() error = %v", err)
+	}
+	if query != "20100070970" {
+		t.Errorf("numero = %q, want 20100070970", query)
+	}
+
+	want := []RUCStateChange{
+		{Date: time.Date(2010, 3, 1, 0, 0, 0, 0, time.UTC), Estado: "ACTIVO", Condicion: "HABIDO"},
+		{Date: time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC), Estado: "SUSPENSION TEMPORAL", Condicion: "HABIDO"},
+		{Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Estado: "ACTIVO", Condicion: "NO HABIDO"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(changes), len(want))
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestRUCService_ConsultHistory_NotSupported(t *testing.T) {
+	rucService := NewRUCService("")
+	if _, err := rucService.ConsultHistory("20100070970"); !errors.Is(err, ErrHistoryNotSupported) {
+		t.Errorf("error = %v, want ErrHistoryNotSupported", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+	}))
+	defer server.Close()
+	rucService.HistoryURL = server.URL
+	if _, err := rucService.ConsultHistory("20100070970"); !errors.Is(err, ErrHistoryNotSupported) {
+		t.Errorf("error = %v, want ErrHistoryNotSupported for HTTP 501", err)
+	}
+}
+
+func TestRUCService_ConsultHistory_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	rucService := NewRUCService("")
+	rucService.HistoryURL = server.URL
+	_, err := rucService.ConsultHistory("20100070970")
+	if !errors.Is(err, ErrRUCNotFound) || errors.Is(err, ErrHistoryNotSupported) {
+		t.Errorf("error = %v, want ErrRUCNotFound for HTTP 404", err)
+	}
+}
+
+func TestRUCService_ConsultAuthorizedSeries(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("numero")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"numero_documento": "20100070970",
+			"series": [
+				{"serie": "F002", "tipo_documento": "01", "estado": "activo"},
+				{"serie": "B001", "tipo_documento": "03", "estado": "ACTIVO"},
+				{"serie": " f001 ", "tipo_documento": "01", "estado": "BAJA"}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	rucService := NewRUCService("")
+	rucService.SeriesURL = server.URL
+
+	series, err := rucService.ConsultAuthorizedSeries("20100070970")
+	if err != nil {
+		t.Fatalf("ConsultAuthorizedSeries() error = %v", err)
+	}
+	if query != "20100070970" {
+		t.Errorf("numero = %q, want 20100070970", query)
+	}
+
+	want := []SeriesInfo{
+		{Series: "F001", DocumentType: "01", Status: "BAJA"},
+		{Series: "F002", DocumentType: "01", Status: "ACTIVO"},
+		{Series: "B001", DocumentType: "03", Status: "ACTIVO"},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("got %d series, want %d", len(series), len(want))
+	}
+	for i := range want {
+		if series[i] != want[i] {
+			t.Errorf("series %d = %+v, want %+v", i, series[i], want[i])
+		}
+	}
+}
+
+func TestRUCService_ConsultAuthorizedSeries_NotSupported(t *testing.T) {
+	rucService := NewRUCService("")
+	if _, err := rucService.ConsultAuthorizedSeries("20100070970"); !errors.Is(err, ErrSeriesNotSupported) {
+		t.Errorf("error = %v, want ErrSeriesNotSupported", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+	}))
+	defer server.Close()
+	rucService.SeriesURL = server.URL
+	if _, err := rucService.ConsultAuthorizedSeries("20100070970"); !errors.Is(err, ErrSeriesNotSupported) {
+		t.Errorf("error = %v, want ErrSeriesNotSupported for HTTP 501", err)
+	}
+}
+
+func TestRUCService_ConsultAuthorizedSeries_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	rucService := NewRUCService("")
+	rucService.SeriesURL = server.URL
+	_, err := rucService.ConsultAuthorizedSeries("20100070970")
+	if !errors.Is(err, ErrRUCNotFound) || errors.Is(err, ErrSeriesNotSupported) {
+		t.Errorf("error = %v, want ErrRUCNotFound for HTTP 404", err)
+	}
+}
+
+func TestRUCService_ConsultFullOrBasic(t *testing.T) {
+	var basicCalls, fullCalls int
+	basicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		basicCalls++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "success",
+			"lista":   []map[string]string{{"apenomdenunciado": "CLIENTE S.A."}},
+		})
+	}))
+	defer basicServer.Close()
+
+	tests := []struct {
+		name           string
+		fullHandler    http.HandlerFunc
+		wantFullData   bool
+		wantBasicCalls int
+		wantActividad  string
+	}{
+		{
+			name: "Full Succeeds",
+			fullHandler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]string{
+					"numero_documento":    r.URL.Query().Get("numero"),
+					"razon_social":        "CLIENTE S.A.",
+					"estado":              "ACTIVO",
+					"condicion":           "HABIDO",
+					"actividad_economica": "VENTA AL POR MAYOR",
+				})
+			},
+			wantFullData:   true,
+			wantBasicCalls: 0,
+			wantActividad:  "VENTA AL POR MAYOR",
+		},
+		{
+			name: "Falls Back To Basic",
+			fullHandler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantFullData:   false,
+			wantBasicCalls: 1,
+		},
+		{
+			name: "Full Without Basic Fields",
+			fullHandler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{}`))
+			},
+			wantFullData:   false,
+			wantBasicCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basicCalls, fullCalls = 0, 0
+			fullServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fullCalls++
+				tt.fullHandler(w, r)
+			}))
+			defer fullServer.Close()
+
+			rucService := NewRUCService("")
+			rucService.BaseURL = basicServer.URL
+			rucService.FullURL = fullServer.URL
+
+			response, err := rucService.ConsultFullOrBasic("20100070970")
+			if err != nil {
+				t.Fatalf("ConsultFullOrBasic() error = %v", err)
+			}
+			if !response.Success || response.Data == nil || response.Data.RazonSocial != "CLIENTE S.A." {
+				t.Fatalf("response = %+v, want basic data of CLIENTE S.A.", response)
+			}
+			if response.HasFullData != tt.wantFullData || response.Data.ActividadEconomica != tt.wantActividad {
+				t.Errorf("HasFullData = %v with actividad %q, want %v with %q", response.HasFullData, response.Data.ActividadEconomica, tt.wantFullData, tt.wantActividad)
+			}
+			if fullCalls != 1 || basicCalls != tt.wantBasicCalls {
+				t.Errorf("made %d full and %d basic calls, want 1 and %d", fullCalls, basicCalls, tt.wantBasicCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package sunatlib provides a sample invoice for integration tests against SUNAT beta
+package sunatlib
+
+import "time"
+
+// GenerateSampleInvoice returns a complete, valid invoice (factura F001-1) issued today by ruc
+// to SUNAT's own RUC, with a single taxed line of 100.00 plus IGV. It can be generated with
+// GenerateInvoiceXML, signed and sent to the beta environment to test the full pipeline;
+// change Number to send it more than once
+func GenerateSampleInvoice(ruc string) *Invoice {
+	inv := &Invoice{
+		DocumentType:  "01",
+		OperationType: DefaultOperationType,
+		Series:        "F001",
+		Number:        "1",
+		IssueDate:     time.Now(),
+		Currency:      "PEN",
+		Supplier:      InvoiceParty{DocumentType: "6", DocumentNumber: ruc, Name: "EMPRESA DE PRUEBA S.A.C."},
+		Customer:      InvoiceParty{DocumentType: "6", DocumentNumber: SUNATRUC, Name: "SUPERINTENDENCIA NACIONAL DE ADUANAS Y DE ADMINISTRACION TRIBUTARIA"},
+		Items: []InvoiceItem{
+			{Description: "PRODUCTO DE PRUEBA", Quantity: 1, UnitCode: "NIU", UnitValue: 100, AffectationCode: "10", IGVAmount: 100 * IGVRate},
+		},
+		PaymentMeans: PaymentMeansContado,
+		Note:         "COMPROBANTE DE PRUEBA",
+	}
+
+	totals := inv.ComputeTotals()
+	inv.TotalTaxed = totals.TotalTaxed
+	inv.TotalIGV = totals.TotalIGV
+	inv.TotalAmount = totals.TotalAmount
+
+	return inv
+}
@@ -0,0 +1,33 @@
+package sunatlib
+
+import "testing"
+
+func TestGenerateSampleInvoice(t *testing.T) {
+	inv := GenerateSampleInvoice(testRUC)
+
+	if err := inv.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if inv.Supplier.DocumentNumber != testRUC {
+		t.Errorf("Supplier = %s, want %s", inv.Supplier.DocumentNumber, testRUC)
+	}
+	if inv.TotalTaxed != 100 || inv.TotalIGV != 18 || inv.TotalAmount != 118 {
+		t.Errorf("totals = %v/%v/%v, want 100/18/118", inv.TotalTaxed, inv.TotalIGV, inv.TotalAmount)
+	}
+
+	xmlContent, err := GenerateInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	if err := NewUBLValidator().Validate(xmlContent); err != nil {
+		t.Errorf("UBLValidator.Validate() error = %v", err)
+	}
+
+	fileName, err := DeriveFileName(xmlContent)
+	if err != nil {
+		t.Fatalf("DeriveFileName() error = %v", err)
+	}
+	if fileName != testRUC+"-01-F001-1" {
+		t.Errorf("DeriveFileName() = %s, want %s-01-F001-1", fileName, testRUC)
+	}
+}
@@ -0,0 +1,116 @@
+// Package sunatlib provides a single entry point that routes signed documents to the right SOAP operation
+package sunatlib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// SendOutcome is the result of Send. Documents sent with sendBill (invoices, boletas, notes)
+// are answered synchronously with a CDR in Response; summaries and voided documents sent with
+// sendSummary are answered with a ticket, see Ticket and TicketResponse
+type SendOutcome struct {
+	DocumentType   string                   // Detected SUNAT document type (01, 03, 07, 08, RA, RC)
+	FileName       string                   // SUNAT file name without extension
+	Operation      string                   // SOAP operation used: sendBill or sendSummary
+	Response       *SUNATResponse           // sendBill response, nil for asynchronous documents
+	TicketResponse *VoidedDocumentsResponse // sendSummary response, nil for synchronous documents
+}
+
+// IsAsync returns true if the document was sent with sendSummary and must be followed up by ticket
+func (o *SendOutcome) IsAsync() bool {
+	return o.TicketResponse != nil
+}
+
+// Success returns true if SUNAT accepted the submission
+func (o *SendOutcome) Success() bool {
+	if o.IsAsync() {
+		return o.TicketResponse.Success
+	}
+	return o.Response != nil && o.Response.Success
+}
+
+// Ticket returns the ticket to poll with QueryVoidedDocumentsTicket or WaitForTicketProcessing,
+// or an empty string for synchronous documents
+func (o *SendOutcome) Ticket() string {
+	if o.IsAsync() {
+		return o.TicketResponse.Ticket
+	}
+	return ""
+}
+
+// Send sends a signed document to SUNAT choosing the SOAP operation from its content: invoices,
+// boletas and credit/debit notes go through sendBill, summaries (RC) and voided documents (RA)
+// through sendSummary. The document must be issued by the client's RUC. Despatch advices (GRE)
+// are sent with the gre package
+func (c *SUNATClient) Send(signedXML []byte) (*SendOutcome, error) {
+	return c.SendContext(context.Background(), signedXML)
+}
+
+// SendContext is like Send, cancelling the request when ctx is done
+func (c *SUNATClient) SendContext(ctx context.Context, signedXML []byte) (*SendOutcome, error) {
+	documentType, issuerRUC, id, err := parseDocumentIdentity(signedXML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify document: %w", err)
+	}
+	if issuerRUC != c.RUC {
+		return nil, fmt.Errorf("document %s is issued by %s, not by the client RUC %s", id, issuerRUC, c.RUC)
+	}
+
+	outcome := &SendOutcome{DocumentType: documentType}
+
+	switch documentType {
+	case "01", "03", "07", "08":
+		serie, numero, err := utils.SplitSerieNumero(id)
+		if err != nil {
+			return nil, err
+		}
+		seriesNumber := utils.JoinSerieNumero(serie, numero)
+
+		outcome.Operation = "sendBill"
+		outcome.FileName = BuildDocumentName(c.RUC, documentType, seriesNumber)
+		outcome.Response, err = c.SendToSUNATContext(ctx, signedXML, documentType, seriesNumber)
+		if err != nil {
+			return nil, err
+		}
+	case "RA", "RC":
+		outcome.Operation = "sendSummary"
+		outcome.FileName = fmt.Sprintf("%s-%s", c.RUC, id)
+		outcome.TicketResponse, err = c.sendSummaryDocument(ctx, signedXML, documentType, id)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("document type %s cannot be sent with sendBill or sendSummary", documentType)
+	}
+
+	return outcome, nil
+}
+
+// sendSummaryDocument sends a signed summary (RC) or voided documents communication (RA)
+func (c *SUNATClient) sendSummaryDocument(ctx context.Context, signedXML []byte, documentType, seriesNumber string) (response *VoidedDocumentsResponse, err error) {
+	attempted := false
+	defer func() {
+		if attempted {
+			c.metrics.recordSend(err == nil && response != nil && response.Success)
+		}
+	}()
+
+	zipData, zipName, err := c.createVoidedDocumentsZIP(signedXML, seriesNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ZIP: %w", err)
+	}
+
+	attempted = true
+	responseData, err := c.postSendSummary(ctx, zipName, zipData)
+	if err != nil {
+		return nil, err
+	}
+
+	if documentType == "RA" {
+		return c.parseVoidedDocumentsResponse(responseData)
+	}
+	return c.parseTicketResponse(responseData, "sendSummaryResponse", msgSummarySent)
+}
@@ -0,0 +1,127 @@
+package sunatlib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSend_Routing(t *testing.T) {
+	invoice := newTestInvoice()
+	invoice.Supplier.DocumentNumber = testRUC
+	invoiceXML, err := GenerateInvoiceXML(invoice)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	generator := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	summaryXML, err := generator.GenerateSummaryDocumentsXML(newTestSummaryDocumentsRequest())
+	if err != nil {
+		t.Fatalf("GenerateSummaryDocumentsXML() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		document      []byte
+		wantType      string
+		wantOperation string
+		wantFileName  string
+		wantAsync     bool
+	}{
+		{"Invoice", invoiceXML, "01", "sendBill", testRUC + "-01-F001-1", false},
+		{"Summary", summaryXML, "RC", "sendSummary", testRUC + "-RC-20260428-001", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operation, fileName, path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				path = r.URL.Path
+				fileName = between(string(body), "<fileName>", "</fileName>")
+				switch {
+				case strings.Contains(string(body), "<ser:sendBill>"):
+					operation = "sendBill"
+					io.WriteString(w, sendBillSuccessResponse)
+				case strings.Contains(string(body), "<ser:sendSummary>"):
+					operation = "sendSummary"
+					io.WriteString(w, sendSummaryResponse(testTicket))
+				default:
+					t.Errorf("unexpected SOAP request: %s", body)
+				}
+			}))
+			defer server.Close()
+
+			client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+			client.SetEndpoints(map[ServiceType]string{
+				ServiceBill:    server.URL + "/bill",
+				ServiceSummary: server.URL + "/summary",
+			})
+
+			outcome, err := client.Send(tt.document)
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if operation != tt.wantOperation || outcome.Operation != tt.wantOperation {
+				t.Errorf("operation = %q (outcome %q), want %q", operation, outcome.Operation, tt.wantOperation)
+			}
+			wantPath := "/bill"
+			if tt.wantAsync {
+				wantPath = "/summary"
+			}
+			if path != wantPath {
+				t.Errorf("endpoint path = %q, want %q", path, wantPath)
+			}
+			if outcome.DocumentType != tt.wantType {
+				t.Errorf("DocumentType = %q, want %q", outcome.DocumentType, tt.wantType)
+			}
+			if outcome.FileName != tt.wantFileName || fileName != tt.wantFileName+".zip" {
+				t.Errorf("file name = %q (sent %q), want %q", outcome.FileName, fileName, tt.wantFileName)
+			}
+			if !outcome.Success() {
+				t.Errorf("Success() = false, want true")
+			}
+			if outcome.IsAsync() != tt.wantAsync {
+				t.Errorf("IsAsync() = %v, want %v", outcome.IsAsync(), tt.wantAsync)
+			}
+
+			if tt.wantAsync {
+				if outcome.Ticket() != testTicket || outcome.Response != nil {
+					t.Errorf("outcome = %+v, want ticket %s only", outcome, testTicket)
+				}
+			} else if outcome.Ticket() != "" || outcome.Response == nil {
+				t.Errorf("outcome = %+v, want a sendBill response only", outcome)
+			}
+		})
+	}
+}
+
+func TestSend_Invalid(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	otherIssuer, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document []byte
+		msg      string
+	}{
+		{"Not XML", []byte("not xml"), "failed to identify document"},
+		{"Other Issuer", otherIssuer, "not by the client RUC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Send(tt.document)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Send() error = %v, want %q", err, tt.msg)
+			}
+		})
+	}
+}
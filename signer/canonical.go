@@ -0,0 +1,352 @@
+// Package signer provides the canonical form of signed documents for debugging digest mismatches
+package signer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// xmlNamespace is the namespace bound to the reserved xml prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// ErrSignatureNotFound is returned by CanonicalSignedContent when the document has no ds:Signature
+var ErrSignatureNotFound = errors.New("ds:Signature not found")
+
+// CanonicalSignedContent returns the exact bytes covered by the ds:DigestValue of a document
+// signed with Reference URI="": the document without its ds:Signature (enveloped-signature
+// transform) in inclusive C14N without comments. Hashing them with the ds:DigestMethod must give
+// the embedded DigestValue, so they can be diffed against what SUNAT recomputes
+func CanonicalSignedContent(signedXML []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(signedXML))
+
+	var (
+		out           bytes.Buffer
+		scopes        = []*c14nScope{{declared: map[string]string{"xml": xmlNamespace}, rendered: map[string]string{}}}
+		depth         int
+		skipDepth     int
+		signatureSeen bool
+		rootClosed    bool
+	)
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		// Inside the removed ds:Signature only the depth is tracked
+		if skipDepth > 0 {
+			switch token.(type) {
+			case xml.StartElement:
+				skipDepth++
+			case xml.EndElement:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			scope := scopes[len(scopes)-1].child(t.Attr)
+			if !signatureSeen && t.Name.Local == "Signature" && scope.declared[t.Name.Space] == xmldsigNamespace {
+				signatureSeen = true
+				skipDepth = 1
+				continue
+			}
+			scopes = append(scopes, scope)
+			depth++
+			writeC14NStartElement(&out, t, scope)
+
+		case xml.EndElement:
+			if depth == 0 {
+				return nil, fmt.Errorf("%w: unexpected end element %s", ErrInvalidSignedXML, t.Name.Local)
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+			scopes = scopes[:len(scopes)-1]
+			depth--
+			rootClosed = depth == 0
+
+		case xml.CharData:
+			// Text outside the document element is not part of the canonical form
+			if depth > 0 {
+				writeC14NText(&out, string(t))
+			}
+
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			if depth == 0 && rootClosed {
+				out.WriteByte('\n')
+			}
+			out.WriteString("<?" + t.Target)
+			if inst := strings.TrimSpace(string(t.Inst)); inst != "" {
+				out.WriteString(" " + inst)
+			}
+			out.WriteString("?>")
+			if depth == 0 && !rootClosed {
+				out.WriteByte('\n')
+			}
+		}
+		// Comments and the DOCTYPE are dropped by C14N without comments
+	}
+
+	if !signatureSeen {
+		return nil, ErrSignatureNotFound
+	}
+	if !rootClosed {
+		return nil, fmt.Errorf("%w: missing document element end", ErrInvalidSignedXML)
+	}
+
+	return out.Bytes(), nil
+}
+
+// canonicalElement returns the inclusive C14N (without comments) of the first element named
+// local in namespace, as a document subset: the element renders every namespace in scope
+func canonicalElement(data []byte, namespace, local string) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		out    bytes.Buffer
+		scopes = []*c14nScope{{declared: map[string]string{"xml": xmlNamespace}, rendered: map[string]string{}}}
+		depth  int // Depth inside the element, 0 before reaching it
+	)
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			parent := scopes[len(scopes)-1]
+			scope := parent.child(t.Attr)
+			if depth == 0 {
+				if t.Name.Local != local || scope.declared[t.Name.Space] != namespace {
+					scopes = append(scopes, scope)
+					continue
+				}
+				scope = parent.apex(t.Attr)
+			}
+			scopes = append(scopes, scope)
+			depth++
+			writeC14NStartElement(&out, t, scope)
+
+		case xml.EndElement:
+			if len(scopes) == 1 {
+				return nil, fmt.Errorf("%w: unexpected end element %s", ErrInvalidSignedXML, t.Name.Local)
+			}
+			scopes = scopes[:len(scopes)-1]
+			if depth == 0 {
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+			depth--
+			if depth == 0 {
+				return out.Bytes(), nil
+			}
+
+		case xml.CharData:
+			if depth > 0 {
+				writeC14NText(&out, string(t))
+			}
+
+		case xml.ProcInst:
+			if depth > 0 {
+				out.WriteString("<?" + t.Target)
+				if inst := strings.TrimSpace(string(t.Inst)); inst != "" {
+					out.WriteString(" " + inst)
+				}
+				out.WriteString("?>")
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: element %s not found", ErrInvalidSignedXML, local)
+}
+
+// c14nScope holds the namespaces of an element: declared are the bindings in scope, rendered the
+// ones already output by the element or its ancestors
+type c14nScope struct {
+	declared map[string]string
+	rendered map[string]string
+	newNS    map[string]string // Declarations output on this element
+}
+
+// child returns the scope of an element with the given attributes nested in s
+func (s *c14nScope) child(attrs []xml.Attr) *c14nScope {
+	child := &c14nScope{
+		declared: make(map[string]string, len(s.declared)),
+		rendered: make(map[string]string, len(s.rendered)),
+		newNS:    make(map[string]string),
+	}
+	for prefix, uri := range s.declared {
+		child.declared[prefix] = uri
+	}
+	for prefix, uri := range s.rendered {
+		child.rendered[prefix] = uri
+	}
+
+	for _, attr := range attrs {
+		prefix, isNS := namespaceDeclaration(attr.Name)
+		if !isNS {
+			continue
+		}
+		child.declared[prefix] = attr.Value
+		if prefix == "xml" {
+			continue
+		}
+		// A declaration is superfluous when the same binding was already output; an empty
+		// default namespace only needs output to undo a rendered non-empty one
+		if rendered, ok := s.rendered[prefix]; ok && rendered == attr.Value {
+			continue
+		}
+		if prefix == "" && attr.Value == "" && s.rendered[""] == "" {
+			continue
+		}
+		child.rendered[prefix] = attr.Value
+		child.newNS[prefix] = attr.Value
+	}
+
+	return child
+}
+
+// apex returns the scope of the first element of a document subset nested in s: having no
+// rendered ancestor, it outputs every namespace in scope
+func (s *c14nScope) apex(attrs []xml.Attr) *c14nScope {
+	apex := s.child(attrs)
+	apex.rendered = make(map[string]string, len(apex.declared))
+	apex.newNS = make(map[string]string, len(apex.declared))
+	for prefix, uri := range apex.declared {
+		if prefix == "xml" || (prefix == "" && uri == "") {
+			continue
+		}
+		apex.rendered[prefix] = uri
+		apex.newNS[prefix] = uri
+	}
+	return apex
+}
+
+// namespaceDeclaration returns the prefix declared by an xmlns attribute ("" for the default namespace)
+func namespaceDeclaration(name xml.Name) (string, bool) {
+	if name.Space == "" && name.Local == "xmlns" {
+		return "", true
+	}
+	if name.Space == "xmlns" {
+		return name.Local, true
+	}
+	return "", false
+}
+
+// qualifiedName returns the name as written in the document (prefix:local)
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// writeC14NStartElement writes a start tag with its namespace declarations sorted by prefix
+// followed by its attributes sorted by namespace URI and local name
+func writeC14NStartElement(out *bytes.Buffer, element xml.StartElement, scope *c14nScope) {
+	out.WriteString("<" + qualifiedName(element.Name))
+
+	prefixes := make([]string, 0, len(scope.newNS))
+	for prefix := range scope.newNS {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			out.WriteString(` xmlns="`)
+		} else {
+			out.WriteString(" xmlns:" + prefix + `="`)
+		}
+		writeC14NAttributeValue(out, scope.newNS[prefix])
+		out.WriteByte('"')
+	}
+
+	type c14nAttr struct {
+		uri   string
+		local string
+		name  string
+		value string
+	}
+	attrs := make([]c14nAttr, 0, len(element.Attr))
+	for _, attr := range element.Attr {
+		if _, isNS := namespaceDeclaration(attr.Name); isNS {
+			continue
+		}
+		uri := ""
+		if attr.Name.Space != "" {
+			uri = scope.declared[attr.Name.Space]
+		}
+		attrs = append(attrs, c14nAttr{uri: uri, local: attr.Name.Local, name: qualifiedName(attr.Name), value: attr.Value})
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	for _, attr := range attrs {
+		out.WriteString(" " + attr.name + `="`)
+		// Attribute value normalization turns literal whitespace into spaces
+		writeC14NAttributeValue(out, strings.NewReplacer("\t", " ", "\n", " ").Replace(attr.value))
+		out.WriteByte('"')
+	}
+
+	out.WriteByte('>')
+}
+
+// writeC14NText writes character data with the C14N text escapes
+func writeC14NText(out *bytes.Buffer, text string) {
+	for _, r := range text {
+		switch r {
+		case '&':
+			out.WriteString("&amp;")
+		case '<':
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		case '\r':
+			out.WriteString("&#xD;")
+		default:
+			out.WriteRune(r)
+		}
+	}
+}
+
+// writeC14NAttributeValue writes an attribute value with the C14N attribute escapes
+func writeC14NAttributeValue(out *bytes.Buffer, value string) {
+	for _, r := range value {
+		switch r {
+		case '&':
+			out.WriteString("&amp;")
+		case '<':
+			out.WriteString("&lt;")
+		case '"':
+			out.WriteString("&quot;")
+		case '\t':
+			out.WriteString("&#x9;")
+		case '\n':
+			out.WriteString("&#xA;")
+		case '\r':
+			out.WriteString("&#xD;")
+		default:
+			out.WriteRune(r)
+		}
+	}
+}
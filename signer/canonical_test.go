@@ -0,0 +1,106 @@
+package signer
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testCanonicalDocument is a signed invoice whose DigestValue was computed with an independent
+// C14N implementation (libxml2) over the document without its ds:Signature
+const testCanonicalDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+  xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+  xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+  xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+  xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <ds:Signature Id="SignatureSP">
+          <ds:SignedInfo>
+            <ds:CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"/>
+            <ds:SignatureMethod Algorithm="http://www.w3.org/2000/09/xmldsig#rsa-sha1"/>
+            <ds:Reference URI="">
+              <ds:Transforms>
+                <ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/>
+              </ds:Transforms>
+              <ds:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/>
+              <ds:DigestValue>zMP/9ABuDz2RnQlMf2OsTnL1KhI=</ds:DigestValue>
+            </ds:Reference>
+          </ds:SignedInfo>
+          <ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>
+        </ds:Signature>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>2.1</cbc:UBLVersionID>
+  <cbc:ID>F001-1</cbc:ID>
+  <!-- comments are not signed -->
+  <cbc:Note languageLocaleID="1000"><![CDATA[CIENTO DIECIOCHO & 00/100 SOLES]]></cbc:Note>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyIdentification>
+        <cbc:ID schemeURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo06" schemeID="6"
+          schemeAgencyName="PE:SUNAT">20000000001</cbc:ID>
+      </cac:PartyIdentification>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:TaxTotal/>
+</Invoice>`
+
+func TestCanonicalSignedContent_MatchesDigestValue(t *testing.T) {
+	content, err := CanonicalSignedContent([]byte(testCanonicalDocument))
+	if err != nil {
+		t.Fatalf("CanonicalSignedContent() error = %v", err)
+	}
+
+	digestValue, err := ExtractDigestValue([]byte(testCanonicalDocument))
+	if err != nil {
+		t.Fatalf("ExtractDigestValue() error = %v", err)
+	}
+
+	sum := sha1.Sum(content)
+	if got := base64.StdEncoding.EncodeToString(sum[:]); got != digestValue {
+		t.Errorf("digest of canonical content = %s, want embedded DigestValue %s\n%s", got, digestValue, content)
+	}
+}
+
+func TestCanonicalSignedContent_Form(t *testing.T) {
+	content, err := CanonicalSignedContent([]byte(testCanonicalDocument))
+	if err != nil {
+		t.Fatalf("CanonicalSignedContent() error = %v", err)
+	}
+	canonical := string(content)
+
+	for _, expected := range []string{
+		`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac=`,
+		`<cbc:Note languageLocaleID="1000">CIENTO DIECIOCHO &amp; 00/100 SOLES</cbc:Note>`,
+		`<cbc:ID schemeAgencyName="PE:SUNAT" schemeID="6" schemeURI="urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo06">`,
+		`<cac:TaxTotal></cac:TaxTotal>`,
+	} {
+		if !strings.Contains(canonical, expected) {
+			t.Errorf("canonical content missing %s", expected)
+		}
+	}
+
+	for _, unexpected := range []string{"<?xml", "ds:Signature", "DigestValue", "<!--"} {
+		if strings.Contains(canonical, unexpected) {
+			t.Errorf("canonical content should not contain %s", unexpected)
+		}
+	}
+}
+
+func TestCanonicalSignedContent_Errors(t *testing.T) {
+	unsigned := `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"><ID>F001-1</ID></Invoice>`
+	if _, err := CanonicalSignedContent([]byte(unsigned)); !errors.Is(err, ErrSignatureNotFound) {
+		t.Errorf("error = %v, want ErrSignatureNotFound", err)
+	}
+
+	truncated := testCanonicalDocument[:len(testCanonicalDocument)-len("</Invoice>")]
+	if _, err := CanonicalSignedContent([]byte(truncated)); !errors.Is(err, ErrInvalidSignedXML) {
+		t.Errorf("error = %v, want ErrInvalidSignedXML for a truncated document", err)
+	}
+}
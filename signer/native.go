@@ -0,0 +1,172 @@
+// Package signer provides pure Go XML signing with an in-memory key, without xmlsec1
+package signer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// signatureMethodHashes are the ds:SignatureMethod algorithms the in-memory signer supports
+var signatureMethodHashes = map[string]crypto.Hash{
+	rsaSHA1Method:   crypto.SHA1,
+	rsaSHA256Method: crypto.SHA256,
+}
+
+// digestMethodHashes are the ds:DigestMethod algorithms the in-memory signer supports
+var digestMethodHashes = map[string]crypto.Hash{
+	sha1DigestMethod:   crypto.SHA1,
+	sha256DigestMethod: crypto.SHA256,
+}
+
+// NewInMemorySigner creates a signer that signs in pure Go with an already parsed RSA key and
+// certificate, so xmlsec1 is not needed and no key material is written to disk. It produces the
+// same enveloped signature as the xmlsec1 signer, with inclusive C14N
+func NewInMemorySigner(key *rsa.PrivateKey, cert *x509.Certificate) (*XMLSigner, error) {
+	if key == nil || cert == nil {
+		return nil, errors.New("private key and certificate are required")
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("certificate public key is %T, expected RSA", cert.PublicKey)
+	}
+	if publicKey.N.Cmp(key.PublicKey.N) != 0 || publicKey.E != key.PublicKey.E {
+		return nil, errors.New("private key does not match the certificate")
+	}
+
+	return &XMLSigner{
+		privateKey:  key,
+		certificate: cert,
+	}, nil
+}
+
+// NewInMemorySignerFromPEM is like NewInMemorySigner but parses a PEM encoded RSA private key
+// (PKCS#1 or PKCS#8) and certificate, so key material never touches the disk
+func NewInMemorySignerFromPEM(keyPEM, certPEM []byte) (*XMLSigner, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PEM key pair: %w", err)
+	}
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, expected RSA", pair.PrivateKey)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return NewInMemorySigner(key, cert)
+}
+
+// InMemory returns true for signers created with NewInMemorySigner, which do not use xmlsec1
+func (s *XMLSigner) InMemory() bool {
+	return s.privateKey != nil
+}
+
+// signInMemory fills the DigestValue, SignatureValue and X509Certificate of a signature template
+func (s *XMLSigner) signInMemory(template []byte) ([]byte, error) {
+	switch s.CanonicalizationMethod() {
+	case C14N, C14NWithComments:
+		// ds:SignedInfo has no comments, so both give the same bytes
+	default:
+		return nil, fmt.Errorf("canonicalization method %s is not supported by the in-memory signer", s.CanonicalizationMethod())
+	}
+
+	signatureMethod, digestMethod, err := signatureAlgorithms(template)
+	if err != nil {
+		return nil, err
+	}
+	signatureHash, ok := signatureMethodHashes[signatureMethod]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature method: %s", signatureMethod)
+	}
+	digestHash, ok := digestMethodHashes[digestMethod]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest method: %s", digestMethod)
+	}
+
+	content, err := CanonicalSignedContent(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize document: %w", err)
+	}
+	signed := fillSignatureValue(template, "DigestValue", base64.StdEncoding.EncodeToString(hashSum(digestHash, content)))
+
+	signedInfo, err := canonicalElement(signed, xmldsigNamespace, "SignedInfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize ds:SignedInfo: %w", err)
+	}
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, signatureHash, hashSum(signatureHash, signedInfo))
+	if err != nil {
+		return nil, fmt.Errorf("RSA signing failed: %w", err)
+	}
+
+	signed = fillSignatureValue(signed, "SignatureValue", base64.StdEncoding.EncodeToString(signature))
+	signed = fillSignatureValue(signed, "X509Certificate", base64.StdEncoding.EncodeToString(s.certificate.Raw))
+
+	if err := PostSignValidate(signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// signatureAlgorithms returns the ds:SignatureMethod and ds:DigestMethod algorithms of a template
+func signatureAlgorithms(template []byte) (signatureMethod, digestMethod string, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(template))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xmldsigNamespace {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local != "Algorithm" {
+				continue
+			}
+			switch start.Name.Local {
+			case "SignatureMethod":
+				signatureMethod = attr.Value
+			case "DigestMethod":
+				digestMethod = attr.Value
+			}
+		}
+		if signatureMethod != "" && digestMethod != "" {
+			return signatureMethod, digestMethod, nil
+		}
+	}
+	return "", "", errors.New("signature template without SignatureMethod or DigestMethod")
+}
+
+// fillSignatureValue replaces the empty <ds:name/> placeholder of the template with value
+func fillSignatureValue(template []byte, name, value string) []byte {
+	placeholder := "<ds:" + name + "/>"
+	return []byte(strings.Replace(string(template), placeholder, "<ds:"+name+">"+value+"</ds:"+name+">", 1))
+}
+
+// hashSum returns the digest of data with a SHA-1 or SHA-256 hash
+func hashSum(hash crypto.Hash, data []byte) []byte {
+	if hash == crypto.SHA256 {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+	sum := sha1.Sum(data)
+	return sum[:]
+}
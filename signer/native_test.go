@@ -0,0 +1,162 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+)
+
+// newTestKeyPair returns a parsed RSA key and a self-signed certificate for it
+func newTestKeyPair(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	certificate := testcert.New(t, pkix.Name{CommonName: "20100070970 EMPRESA DE PRUEBA"}, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), nil)
+	return certificate.Key, certificate.Cert
+}
+
+// signatureValuePattern extracts the ds:SignatureValue of a signed document
+var signatureValuePattern = regexp.MustCompile(`<ds:SignatureValue>([^<]+)</ds:SignatureValue>`)
+
+func TestNewInMemorySigner_SignXML(t *testing.T) {
+	// Without xmlsec1 on PATH the signature can only come from the in-memory signer
+	t.Setenv("PATH", t.TempDir())
+
+	key, cert := newTestKeyPair(t)
+	xmlSigner, err := NewInMemorySigner(key, cert)
+	if err != nil {
+		t.Fatalf("NewInMemorySigner() error = %v", err)
+	}
+	if !xmlSigner.InMemory() {
+		t.Error("Expected InMemory() to be true")
+	}
+
+	signedXML, err := xmlSigner.SignXML([]byte(fmt.Sprintf(testDocument, "SignRA", "SignRA")))
+	if err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+	if !strings.Contains(string(signedXML), `<ds:Signature Id="SignRA">`) {
+		t.Error("Expected the signature Id referenced by the document")
+	}
+
+	// DigestValue covers the canonical document without its signature
+	content, err := CanonicalSignedContent(signedXML)
+	if err != nil {
+		t.Fatalf("CanonicalSignedContent() error = %v", err)
+	}
+	digestValue, err := ExtractDigestValue(signedXML)
+	if err != nil {
+		t.Fatalf("ExtractDigestValue() error = %v", err)
+	}
+	sum := sha1.Sum(content)
+	if got := base64.StdEncoding.EncodeToString(sum[:]); got != digestValue {
+		t.Errorf("DigestValue = %s, want %s", digestValue, got)
+	}
+
+	// SignatureValue signs the canonical ds:SignedInfo, which carries the namespaces in scope
+	signedInfo, err := canonicalElement(signedXML, xmldsigNamespace, "SignedInfo")
+	if err != nil {
+		t.Fatalf("canonicalElement() error = %v", err)
+	}
+	if want := `<ds:SignedInfo xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:VoidedDocuments-1" xmlns:cac=`; !strings.HasPrefix(string(signedInfo), want) {
+		t.Errorf("canonical SignedInfo starts with %.120s, want %s", signedInfo, want)
+	}
+	match := signatureValuePattern.FindSubmatch(signedXML)
+	if match == nil {
+		t.Fatal("SignatureValue not found")
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(match[1]))
+	if err != nil {
+		t.Fatalf("invalid SignatureValue: %v", err)
+	}
+	signedInfoSum := sha1.Sum(signedInfo)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, signedInfoSum[:], signature); err != nil {
+		t.Errorf("SignatureValue does not verify: %v", err)
+	}
+
+	embedded, err := EmbeddedCertificate(signedXML)
+	if err != nil {
+		t.Fatalf("EmbeddedCertificate() error = %v", err)
+	}
+	if !embedded.Equal(cert) {
+		t.Error("Expected the signer certificate in ds:X509Certificate")
+	}
+}
+
+func TestNewInMemorySigner_SignXMLSHA256(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+	xmlSigner, err := NewInMemorySigner(key, cert)
+	if err != nil {
+		t.Fatalf("NewInMemorySigner() error = %v", err)
+	}
+	if err := xmlSigner.SetSignatureAlgorithm(SignatureSHA256); err != nil {
+		t.Fatalf("SetSignatureAlgorithm() error = %v", err)
+	}
+
+	signedXML, err := xmlSigner.SignXML([]byte(fmt.Sprintf(testDocument, "SignRA", "SignRA")))
+	if err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+
+	content, err := CanonicalSignedContent(signedXML)
+	if err != nil {
+		t.Fatalf("CanonicalSignedContent() error = %v", err)
+	}
+	digestValue, err := ExtractDigestValue(signedXML)
+	if err != nil {
+		t.Fatalf("ExtractDigestValue() error = %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if got := base64.StdEncoding.EncodeToString(sum[:]); got != digestValue {
+		t.Errorf("DigestValue = %s, want the SHA-256 digest %s", digestValue, got)
+	}
+
+	signedInfo, err := canonicalElement(signedXML, xmldsigNamespace, "SignedInfo")
+	if err != nil {
+		t.Fatalf("canonicalElement() error = %v", err)
+	}
+	match := signatureValuePattern.FindSubmatch(signedXML)
+	if match == nil {
+		t.Fatal("SignatureValue not found")
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(match[1]))
+	if err != nil {
+		t.Fatalf("invalid SignatureValue: %v", err)
+	}
+	signedInfoSum := sha256.Sum256(signedInfo)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, signedInfoSum[:], signature); err != nil {
+		t.Errorf("SignatureValue does not verify with RSA-SHA256: %v", err)
+	}
+}
+
+func TestNewInMemorySigner_Errors(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+	otherKey, _ := newTestKeyPair(t)
+
+	if _, err := NewInMemorySigner(nil, cert); err == nil {
+		t.Error("Expected error without a private key")
+	}
+	if _, err := NewInMemorySigner(otherKey, cert); err == nil {
+		t.Error("Expected error for a key that does not match the certificate")
+	}
+
+	xmlSigner, err := NewInMemorySigner(key, cert)
+	if err != nil {
+		t.Fatalf("NewInMemorySigner() error = %v", err)
+	}
+	if err := xmlSigner.SetCanonicalizationMethod(ExclusiveC14N); err != nil {
+		t.Fatalf("SetCanonicalizationMethod() error = %v", err)
+	}
+	if _, err := xmlSigner.SignXML([]byte(fmt.Sprintf(testDocument, "SignRA", "SignRA"))); err == nil {
+		t.Error("Expected error for exclusive C14N, which the in-memory signer does not implement")
+	}
+}
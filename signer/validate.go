@@ -0,0 +1,158 @@
+// Package signer provides a sanity check for signed documents before submission
+package signer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmldsigNamespace is the namespace of the ds:Signature element
+const xmldsigNamespace = "http://www.w3.org/2000/09/xmldsig#"
+
+// ErrInvalidSignedXML is returned by PostSignValidate when the signed document is corrupted
+var ErrInvalidSignedXML = errors.New("invalid signed XML")
+
+// requiredSignatureValues are the ds:Signature elements that must carry a base64 value
+var requiredSignatureValues = []string{"DigestValue", "SignatureValue", "X509Certificate"}
+
+// PostSignValidate checks that a signed document is still well-formed XML with a single root
+// element and a complete ds:Signature (non-empty DigestValue, SignatureValue and X509Certificate).
+// It catches corruption such as double-encoded entities or broken namespaces before submission;
+// it does not verify the signature itself
+func PostSignValidate(signedXML []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(signedXML))
+
+	var (
+		root           string
+		rootClosed     bool
+		depth          int
+		signatureDepth int
+		signatures     int
+		current        string
+		text           strings.Builder
+	)
+	values := make(map[string]string)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if rootClosed {
+					return fmt.Errorf("%w: unexpected element %s after root %s", ErrInvalidSignedXML, t.Name.Local, root)
+				}
+				root = t.Name.Local
+			}
+			depth++
+
+			// encoding/xml leaves undeclared prefixes unresolved instead of failing
+			if t.Name.Space != "" && !strings.Contains(t.Name.Space, ":") {
+				return fmt.Errorf("%w: undeclared namespace prefix %q in %s", ErrInvalidSignedXML, t.Name.Space, t.Name.Local)
+			}
+
+			if t.Name.Local == "Signature" && t.Name.Space == xmldsigNamespace && signatureDepth == 0 {
+				if depth == 1 {
+					return fmt.Errorf("%w: Signature is the root element", ErrInvalidSignedXML)
+				}
+				signatureDepth = depth
+				signatures++
+			}
+			if signatureDepth > 0 && t.Name.Space == xmldsigNamespace {
+				current = t.Name.Local
+				text.Reset()
+			}
+
+		case xml.CharData:
+			if current != "" {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			if signatureDepth > 0 && t.Name.Local == current {
+				if _, seen := values[current]; !seen {
+					values[current] = strings.TrimSpace(text.String())
+				}
+				current = ""
+			}
+			if depth == signatureDepth {
+				signatureDepth = 0
+			}
+			depth--
+			if depth == 0 {
+				rootClosed = true
+			}
+		}
+	}
+
+	if !rootClosed {
+		return fmt.Errorf("%w: missing root element", ErrInvalidSignedXML)
+	}
+	if signatures == 0 {
+		return fmt.Errorf("%w: missing ds:Signature element", ErrInvalidSignedXML)
+	}
+	if signatures > 1 {
+		return fmt.Errorf("%w: found %d ds:Signature elements", ErrInvalidSignedXML, signatures)
+	}
+
+	for _, name := range requiredSignatureValues {
+		value := strings.Join(strings.Fields(values[name]), "")
+		if value == "" {
+			return fmt.Errorf("%w: empty ds:%s", ErrInvalidSignedXML, name)
+		}
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return fmt.Errorf("%w: ds:%s is not valid base64: %v", ErrInvalidSignedXML, name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExtractDigestValue returns the ds:DigestValue of the document's ds:Signature, the value SUNAT
+// prints in the QR code and echoes in the CDR
+func ExtractDigestValue(signedXML []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(signedXML))
+
+	var (
+		inDigest bool
+		text     strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			inDigest = t.Name.Local == "DigestValue" && t.Name.Space == xmldsigNamespace
+		case xml.CharData:
+			if inDigest {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if inDigest {
+				if value := strings.Join(strings.Fields(text.String()), ""); value != "" {
+					return value, nil
+				}
+				inDigest = false
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: empty ds:DigestValue", ErrInvalidSignedXML)
+}
@@ -0,0 +1,142 @@
+package signer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSignedDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">
+<ext:UBLExtensions><ext:UBLExtension><ext:ExtensionContent>
+<ds:Signature Id="SignatureSP">
+<ds:SignedInfo>
+<ds:CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"/>
+<ds:SignatureMethod Algorithm="http://www.w3.org/2000/09/xmldsig#rsa-sha1"/>
+<ds:Reference URI="">
+<ds:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/>
+<ds:DigestValue>qZk+NkcGgWq6PiVxeFDCbJzQ2J0=</ds:DigestValue>
+</ds:Reference>
+</ds:SignedInfo>
+<ds:SignatureValue>
+dGVzdC1zaWduYXR1cmUtdmFsdWU=
+</ds:SignatureValue>
+<ds:KeyInfo><ds:X509Data><ds:X509Certificate>MIIBdGVzdGNlcnQ=</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+</ds:Signature>
+</ext:ExtensionContent></ext:UBLExtension></ext:UBLExtensions>
+<cbc:ID>F001-1</cbc:ID>
+<cac:Signature><cbc:ID>SignatureSP</cbc:ID></cac:Signature>
+</Invoice>`
+
+func TestPostSignValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		corrupt   func(string) string
+		wantError string
+	}{
+		{
+			name:    "valid",
+			corrupt: func(s string) string { return s },
+		},
+		{
+			name:      "truncated",
+			corrupt:   func(s string) string { return s[:len(s)-20] },
+			wantError: "unexpected EOF",
+		},
+		{
+			name: "broken entity",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "<cbc:ID>F001-1</cbc:ID>", "<cbc:ID>F001&amp-1</cbc:ID>", 1)
+			},
+			wantError: "invalid character entity",
+		},
+		{
+			name: "undeclared namespace",
+			corrupt: func(s string) string {
+				return strings.Replace(s, `xmlns:ds="http://www.w3.org/2000/09/xmldsig#"`, "", 1)
+			},
+			wantError: `undeclared namespace prefix "ds"`,
+		},
+		{
+			name: "empty digest value",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "<ds:DigestValue>qZk+NkcGgWq6PiVxeFDCbJzQ2J0=</ds:DigestValue>", "<ds:DigestValue/>", 1)
+			},
+			wantError: "empty ds:DigestValue",
+		},
+		{
+			name: "empty signature value",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "\ndGVzdC1zaWduYXR1cmUtdmFsdWU=\n", "", 1)
+			},
+			wantError: "empty ds:SignatureValue",
+		},
+		{
+			name: "missing certificate",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "<ds:X509Certificate>MIIBdGVzdGNlcnQ=</ds:X509Certificate>", "", 1)
+			},
+			wantError: "empty ds:X509Certificate",
+		},
+		{
+			name: "double encoded signature value",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "dGVzdC1zaWduYXR1cmUtdmFsdWU=", "dGVzdC1zaWduYXR1cmUtdmFsdWU&amp;#61;", 1)
+			},
+			wantError: "ds:SignatureValue is not valid base64",
+		},
+		{
+			name: "missing signature",
+			corrupt: func(s string) string {
+				start := strings.Index(s, "<ds:Signature ")
+				end := strings.Index(s, "</ds:Signature>") + len("</ds:Signature>")
+				return s[:start] + s[end:]
+			},
+			wantError: "missing ds:Signature element",
+		},
+		{
+			name:      "second root",
+			corrupt:   func(s string) string { return s + "\n<Invoice/>" },
+			wantError: "unexpected element Invoice after root Invoice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PostSignValidate([]byte(tt.corrupt(testSignedDocument)))
+
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("PostSignValidate() error = %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidSignedXML) {
+				t.Fatalf("Expected ErrInvalidSignedXML, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("PostSignValidate() error = %v, want containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExtractDigestValue(t *testing.T) {
+	digest, err := ExtractDigestValue([]byte(testSignedDocument))
+	if err != nil {
+		t.Fatalf("ExtractDigestValue() error = %v", err)
+	}
+	if digest != "qZk+NkcGgWq6PiVxeFDCbJzQ2J0=" {
+		t.Errorf("ExtractDigestValue() = %q, want qZk+NkcGgWq6PiVxeFDCbJzQ2J0=", digest)
+	}
+
+	unsigned := strings.Replace(testSignedDocument, "qZk+NkcGgWq6PiVxeFDCbJzQ2J0=", "", 1)
+	if _, err := ExtractDigestValue([]byte(unsigned)); !errors.Is(err, ErrInvalidSignedXML) {
+		t.Errorf("ExtractDigestValue() without digest error = %v, want ErrInvalidSignedXML", err)
+	}
+}
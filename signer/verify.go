@@ -0,0 +1,111 @@
+// Package signer provides verification of signed XML documents
+package signer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSignatureVerification is returned by VerifyXML when the signature does not match the document
+var ErrSignatureVerification = errors.New("signature verification failed")
+
+// VerifyXML verifies the ds:Signature of a signed document with xmlsec1 against the key of the
+// certificate embedded in ds:KeyInfo, and returns that certificate. It proves the document was
+// not modified after signing but not who signed it: callers must verify the certificate chain
+// (e.g., with utils.VerifyCertificate) and that it belongs to the expected signer
+func VerifyXML(signedXML []byte) (*x509.Certificate, error) {
+	if err := PostSignValidate(signedXML); err != nil {
+		return nil, err
+	}
+
+	certificate, err := EmbeddedCertificate(signedXML)
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir, err := os.MkdirTemp("", "sunatlib_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	signedFile := filepath.Join(tempDir, "signed.xml")
+	if err := os.WriteFile(signedFile, signedXML, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write signed XML: %w", err)
+	}
+	certFile := filepath.Join(tempDir, "certificate.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	// The key is loaded from the embedded certificate and ds:X509Data is ignored, so xmlsec1
+	// only checks the signature; the chain is left to the caller
+	cmd := exec.Command("xmlsec1", "verify",
+		"--pubkey-cert-pem", certFile,
+		"--enabled-key-data", "key-name",
+		signedFile)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%w: %s", ErrSignatureVerification, strings.TrimSpace(string(output)))
+		}
+		return nil, fmt.Errorf("xmlsec1 verification failed: %w", err)
+	}
+
+	return certificate, nil
+}
+
+// EmbeddedCertificate returns the certificate in the ds:X509Certificate of a signed document
+func EmbeddedCertificate(signedXML []byte) (*x509.Certificate, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(signedXML))
+
+	var (
+		inCertificate bool
+		text          strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignedXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			inCertificate = t.Name.Local == "X509Certificate" && t.Name.Space == xmldsigNamespace
+		case xml.CharData:
+			if inCertificate {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if inCertificate {
+				der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text.String()), ""))
+				if err != nil {
+					return nil, fmt.Errorf("%w: ds:X509Certificate is not valid base64: %v", ErrInvalidSignedXML, err)
+				}
+				certificate, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid ds:X509Certificate: %v", ErrInvalidSignedXML, err)
+				}
+				return certificate, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: missing ds:X509Certificate", ErrInvalidSignedXML)
+}
@@ -0,0 +1,25 @@
+package signer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedCertificate_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"not a certificate", testSignedDocument},
+		{"missing certificate", strings.Replace(testSignedDocument, "<ds:X509Certificate>MIIBdGVzdGNlcnQ=</ds:X509Certificate>", "", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EmbeddedCertificate([]byte(tt.document)); !errors.Is(err, ErrInvalidSignedXML) {
+				t.Errorf("EmbeddedCertificate() error = %v, want ErrInvalidSignedXML", err)
+			}
+		})
+	}
+}
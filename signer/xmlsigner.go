@@ -2,23 +2,104 @@
 package signer
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
-// XMLSigner handles XML digital signatures using xmlsec1
+// DefaultSignatureID is the ds:Signature Id used when the document does not reference one
+const DefaultSignatureID = "SignatureSP"
+
+// Canonicalization algorithms supported for ds:SignedInfo
+const (
+	C14N                      = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	C14NWithComments          = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	ExclusiveC14N             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	ExclusiveC14NWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+)
+
+// DefaultCanonicalizationMethod is the canonicalization expected by SUNAT (inclusive C14N)
+const DefaultCanonicalizationMethod = C14N
+
+// SignatureAlgorithm selects the hash of the ds:SignatureMethod and ds:DigestMethod
+type SignatureAlgorithm string
+
+// Signature algorithms supported by XMLSigner
+const (
+	SignatureSHA1   SignatureAlgorithm = "SHA1"   // rsa-sha1 signature with sha1 digest
+	SignatureSHA256 SignatureAlgorithm = "SHA256" // rsa-sha256 signature with sha256 digest
+)
+
+// DefaultSignatureAlgorithm is the signature algorithm used unless another is set (RSA-SHA1)
+const DefaultSignatureAlgorithm = SignatureSHA1
+
+// Algorithm URIs of ds:SignatureMethod and ds:DigestMethod
+const (
+	rsaSHA1Method      = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	rsaSHA256Method    = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	sha1DigestMethod   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	sha256DigestMethod = "http://www.w3.org/2001/04/xmlenc#sha256"
+)
+
+// signatureAlgorithmMethods maps each signature algorithm to its SignatureMethod and DigestMethod URIs
+var signatureAlgorithmMethods = map[SignatureAlgorithm][2]string{
+	SignatureSHA1:   {rsaSHA1Method, sha1DigestMethod},
+	SignatureSHA256: {rsaSHA256Method, sha256DigestMethod},
+}
+
+// signatureURIPattern finds the signature reference inside cac:DigitalSignatureAttachment
+var signatureURIPattern = regexp.MustCompile(`(?s)<cac:DigitalSignatureAttachment>.*?<cbc:URI>\s*#([^<\s]+)\s*</cbc:URI>`)
+
+// NewSignatureID generates a unique signature Id for documents processed in bulk
+func NewSignatureID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", DefaultSignatureID, time.Now().UnixNano())
+	}
+	return DefaultSignatureID + "-" + hex.EncodeToString(b)
+}
+
+// signatureReferenceID returns the signature Id referenced by the document's
+// cac:DigitalSignatureAttachment URI, or DefaultSignatureID when absent
+func signatureReferenceID(xmlStr string) string {
+	if match := signatureURIPattern.FindStringSubmatch(xmlStr); match != nil {
+		return match[1]
+	}
+	return DefaultSignatureID
+}
+
+// XMLSigner handles XML digital signatures using xmlsec1, or in pure Go when created with
+// NewInMemorySigner. SignXML is safe for concurrent use: xmlsec1 runs, which share the signer's
+// working files, are serialized
 type XMLSigner struct {
+	mu               sync.Mutex // Guards the working files in tempDir
 	privateKeyPath   string
 	certificatePath  string
 	tempDir         string
+	canonicalizationMethod string
+	signatureAlgorithm SignatureAlgorithm
+	privateKey      *rsa.PrivateKey   // In-memory key (NewInMemorySigner), signs without xmlsec1
+	certificate     *x509.Certificate // Certificate of the in-memory key
 }
 
 // NewXMLSigner creates a new XML signer with private key and certificate paths
 func NewXMLSigner(privateKeyPath, certificatePath string) (*XMLSigner, error) {
+	return NewXMLSignerInDir(privateKeyPath, certificatePath, "")
+}
+
+// NewXMLSignerInDir is like NewXMLSigner but creates the signer's working directory under
+// baseDir instead of os.TempDir()
+func NewXMLSignerInDir(privateKeyPath, certificatePath, baseDir string) (*XMLSigner, error) {
 	// Verify files exist
 	if _, err := os.Stat(privateKeyPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("private key file not found: %s", privateKeyPath)
@@ -28,8 +109,8 @@ func NewXMLSigner(privateKeyPath, certificatePath string) (*XMLSigner, error) {
 	}
 
 	// Create temp directory for operations
-	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("sunatlib_%d", time.Now().UnixNano()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
+	tempDir, err := os.MkdirTemp(baseDir, "sunatlib_")
+	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
@@ -40,6 +121,84 @@ func NewXMLSigner(privateKeyPath, certificatePath string) (*XMLSigner, error) {
 	}, nil
 }
 
+// NewXMLSignerFromPEM creates a new XML signer from a PEM encoded private key and certificate,
+// for deployments that keep key material in environment variables or secret managers.
+// xmlsec1 only reads keys from files, so the PEM blocks are written with 0600 permissions
+// to the signer's private temp directory and removed by Cleanup. NewInMemorySignerFromPEM
+// signs without xmlsec1 and keeps the key off disk
+func NewXMLSignerFromPEM(keyPEM, certPEM []byte) (*XMLSigner, error) {
+	return NewXMLSignerFromPEMInDir(keyPEM, certPEM, "")
+}
+
+// NewXMLSignerFromPEMInDir is like NewXMLSignerFromPEM but creates the signer's private
+// directory under baseDir instead of os.TempDir()
+func NewXMLSignerFromPEMInDir(keyPEM, certPEM []byte, baseDir string) (*XMLSigner, error) {
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, fmt.Errorf("invalid PEM key pair: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp(baseDir, "sunatlib_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	privateKeyPath := filepath.Join(tempDir, "private_key.pem")
+	certificatePath := filepath.Join(tempDir, "certificate.pem")
+
+	if err := os.WriteFile(privateKeyPath, keyPEM, 0600); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(certificatePath, certPEM, 0600); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	return &XMLSigner{
+		privateKeyPath:  privateKeyPath,
+		certificatePath: certificatePath,
+		tempDir:        tempDir,
+	}, nil
+}
+
+// SetCanonicalizationMethod sets the canonicalization algorithm URI declared in ds:SignedInfo.
+// xmlsec1 applies the algorithm declared in the template. Defaults to DefaultCanonicalizationMethod
+func (s *XMLSigner) SetCanonicalizationMethod(algorithm string) error {
+	switch algorithm {
+	case C14N, C14NWithComments, ExclusiveC14N, ExclusiveC14NWithComments:
+		s.canonicalizationMethod = algorithm
+		return nil
+	default:
+		return fmt.Errorf("unsupported canonicalization method: %s", algorithm)
+	}
+}
+
+// CanonicalizationMethod returns the canonicalization algorithm URI used when signing
+func (s *XMLSigner) CanonicalizationMethod() string {
+	if s.canonicalizationMethod == "" {
+		return DefaultCanonicalizationMethod
+	}
+	return s.canonicalizationMethod
+}
+
+// SetSignatureAlgorithm sets the algorithm declared in ds:SignatureMethod and ds:DigestMethod.
+// xmlsec1 applies the algorithms declared in the template. Defaults to DefaultSignatureAlgorithm
+func (s *XMLSigner) SetSignatureAlgorithm(algorithm SignatureAlgorithm) error {
+	if _, ok := signatureAlgorithmMethods[algorithm]; !ok {
+		return fmt.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+	s.signatureAlgorithm = algorithm
+	return nil
+}
+
+// SignatureAlgorithm returns the signature algorithm used when signing
+func (s *XMLSigner) SignatureAlgorithm() SignatureAlgorithm {
+	if s.signatureAlgorithm == "" {
+		return DefaultSignatureAlgorithm
+	}
+	return s.signatureAlgorithm
+}
+
 // SignXML signs an XML document and returns the signed XML bytes
 func (s *XMLSigner) SignXML(xmlContent []byte) ([]byte, error) {
 	// Create template with signature placeholder
@@ -48,14 +207,24 @@ func (s *XMLSigner) SignXML(xmlContent []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to create signature template: %w", err)
 	}
 
+	if s.InMemory() {
+		return s.signInMemory(template)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Write template to temp file
 	templateFile := filepath.Join(s.tempDir, "template.xml")
 	if err := os.WriteFile(templateFile, template, 0644); err != nil {
 		return nil, fmt.Errorf("failed to write template file: %w", err)
 	}
 
-	// Sign using xmlsec1
+	// Sign using xmlsec1, removing any output left by a previous signature
 	outputFile := filepath.Join(s.tempDir, "signed.xml")
+	if err := os.Remove(outputFile); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove previous signed XML: %w", err)
+	}
 	cmd := exec.Command("xmlsec1", "sign",
 		"--lax-key-search",
 		"--privkey-pem", fmt.Sprintf("%s,%s", s.privateKeyPath, s.certificatePath),
@@ -67,15 +236,18 @@ func (s *XMLSigner) SignXML(xmlContent []byte) ([]byte, error) {
 		return nil, fmt.Errorf("xmlsec1 signing failed: %w\nOutput: %s", err, string(output))
 	}
 
-	// Check if signing was successful
-	if !strings.Contains(string(output), "Signature status: OK") {
-		return nil, fmt.Errorf("signing failed - xmlsec1 output: %s", string(output))
-	}
-
 	// Read signed XML
 	signedXML, err := os.ReadFile(outputFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read signed XML: %w", err)
+		return nil, fmt.Errorf("signing failed - xmlsec1 output: %s: %w", string(output), err)
+	}
+
+	// Check if signing was successful. Some xmlsec1 builds and locales do not print the
+	// English marker, so a zero exit code with a complete signature is also a success
+	if !strings.Contains(string(output), "Signature status: OK") {
+		if err := PostSignValidate(signedXML); err != nil {
+			return nil, fmt.Errorf("signing failed - xmlsec1 output: %s: %w", string(output), err)
+		}
 	}
 
 	return signedXML, nil
@@ -85,17 +257,19 @@ func (s *XMLSigner) SignXML(xmlContent []byte) ([]byte, error) {
 func (s *XMLSigner) createSignatureTemplate(xmlContent []byte) ([]byte, error) {
 	// Parse the input XML and inject signature template
 	xmlStr := string(xmlContent)
+	methods := signatureAlgorithmMethods[s.SignatureAlgorithm()]
 	
-	// Find ExtensionContent and inject signature template
-	signatureTemplate := `    <ds:Signature Id="SignatureSP">
+	// Find ExtensionContent and inject signature template, using the Id referenced by the
+	// document so that cac:DigitalSignatureAttachment/cbc:URI matches ds:Signature/@Id
+	signatureTemplate := `    <ds:Signature Id="` + signatureReferenceID(xmlStr) + `">
         <ds:SignedInfo>
-            <ds:CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"/>
-            <ds:SignatureMethod Algorithm="http://www.w3.org/2000/09/xmldsig#rsa-sha1"/>
+            <ds:CanonicalizationMethod Algorithm="` + s.CanonicalizationMethod() + `"/>
+            <ds:SignatureMethod Algorithm="` + methods[0] + `"/>
             <ds:Reference URI="">
                 <ds:Transforms>
                     <ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/>
                 </ds:Transforms>
-                <ds:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/>
+                <ds:DigestMethod Algorithm="` + methods[1] + `"/>
                 <ds:DigestValue/>
             </ds:Reference>
         </ds:SignedInfo>
@@ -145,8 +319,11 @@ func (s *XMLSigner) createSignatureTemplate(xmlContent []byte) ([]byte, error) {
 	return []byte(xmlStr), nil
 }
 
-// Cleanup removes temporary files
+// Cleanup removes temporary files, waiting for a signature in progress to finish
 func (s *XMLSigner) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.tempDir != "" {
 		return os.RemoveAll(s.tempDir)
 	}
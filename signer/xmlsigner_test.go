@@ -0,0 +1,309 @@
+package signer
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+)
+
+const testDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<VoidedDocuments xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:VoidedDocuments-1"
+xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">
+<ext:UBLExtensions><ext:UBLExtension>
+<ext:ExtensionContent>
+    </ext:ExtensionContent>
+</ext:UBLExtension></ext:UBLExtensions>
+<cbc:ID>RA-20260427-001</cbc:ID>
+<cac:Signature>
+<cbc:ID>%s</cbc:ID>
+<cac:DigitalSignatureAttachment>
+<cac:ExternalReference>
+<cbc:URI>#%s</cbc:URI>
+</cac:ExternalReference>
+</cac:DigitalSignatureAttachment>
+</cac:Signature>
+</VoidedDocuments>`
+
+func TestCreateSignatureTemplate_SignatureID(t *testing.T) {
+	s := &XMLSigner{}
+	signatureID := NewSignatureID()
+
+	document := strings.ReplaceAll(testDocument, "%s", signatureID)
+	template, err := s.createSignatureTemplate([]byte(document))
+	if err != nil {
+		t.Fatalf("createSignatureTemplate() error = %v", err)
+	}
+
+	expected := `<ds:Signature Id="` + signatureID + `">`
+	if !strings.Contains(string(template), expected) {
+		t.Errorf("Expected template to contain %s, got:\n%s", expected, template)
+	}
+}
+
+func TestCreateSignatureTemplate_DefaultSignatureID(t *testing.T) {
+	s := &XMLSigner{}
+
+	document := strings.Replace(testDocument, "<cac:DigitalSignatureAttachment>", "<cac:Other>", 1)
+	document = strings.Replace(document, "</cac:DigitalSignatureAttachment>", "</cac:Other>", 1)
+	template, err := s.createSignatureTemplate([]byte(document))
+	if err != nil {
+		t.Fatalf("createSignatureTemplate() error = %v", err)
+	}
+
+	expected := `<ds:Signature Id="` + DefaultSignatureID + `">`
+	if !strings.Contains(string(template), expected) {
+		t.Errorf("Expected template to contain %s", expected)
+	}
+}
+
+func TestNewSignatureID_Unique(t *testing.T) {
+	if NewSignatureID() == NewSignatureID() {
+		t.Error("Expected unique signature IDs")
+	}
+}
+
+// newTestPEMKeyPair returns a PEM encoded RSA private key and self-signed certificate
+func newTestPEMKeyPair(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	certificate := testcert.New(t, pkix.Name{CommonName: "20100070970 EMPRESA DE PRUEBA"}, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), nil)
+	return certificate.KeyPEM(), certificate.CertPEM()
+}
+
+// installFakeXMLSec1 puts on PATH an xmlsec1 stand-in that copies the template to the output,
+// records the key files it was given in argsFile and reports a successful signature
+func installFakeXMLSec1(t *testing.T) (argsFile string) {
+	return installFakeXMLSec1Command(t, `cp "$1" "$output"
+echo "Signature status: OK"`)
+}
+
+// installFakeXMLSec1Command is like installFakeXMLSec1 but runs command to produce the output,
+// with the template in $1 and the output path in $output
+func installFakeXMLSec1Command(t *testing.T, command string) (argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+
+	script := `#!/bin/sh
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --privkey-pem) echo "$2" > "` + argsFile + `"; shift ;;
+    --output) output="$2"; shift ;;
+  esac
+  shift
+done
+` + command + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "xmlsec1"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake xmlsec1: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile
+}
+
+func TestNewXMLSignerFromPEM(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	argsFile := installFakeXMLSec1(t)
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+
+	s, err := NewXMLSignerFromPEM(keyPEM, certPEM)
+	if err != nil {
+		t.Fatalf("NewXMLSignerFromPEM() error = %v", err)
+	}
+	defer s.Cleanup()
+
+	document := strings.ReplaceAll(testDocument, "%s", DefaultSignatureID)
+	signed, err := s.SignXML([]byte(document))
+	if err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+	if !strings.Contains(string(signed), `<ds:Signature Id="`+DefaultSignatureID+`">`) {
+		t.Error("Expected signed XML to contain the signature")
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("failed to read xmlsec1 arguments: %v", err)
+	}
+	paths := strings.Split(strings.TrimSpace(string(args)), ",")
+	if len(paths) != 2 {
+		t.Fatalf("Expected key and certificate paths, got %q", args)
+	}
+
+	keyContent, err := os.ReadFile(paths[0])
+	if err != nil || string(keyContent) != string(keyPEM) {
+		t.Errorf("Expected xmlsec1 to receive the PEM private key, err = %v", err)
+	}
+	info, err := os.Stat(paths[0])
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected private key file with 0600 permissions, got %v (err = %v)", info.Mode().Perm(), err)
+	}
+
+	if err := s.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Error("Expected Cleanup to remove the private key file")
+	}
+}
+
+func TestNewXMLSignerFromPEM_Mismatch(t *testing.T) {
+	keyPEM, _ := newTestPEMKeyPair(t)
+	_, otherCertPEM := newTestPEMKeyPair(t)
+
+	if _, err := NewXMLSignerFromPEM(keyPEM, otherCertPEM); err == nil {
+		t.Fatal("Expected error for a key that does not match the certificate")
+	}
+	if _, err := NewXMLSignerFromPEM([]byte("not a key"), []byte("not a cert")); err == nil {
+		t.Fatal("Expected error for invalid PEM input")
+	}
+}
+
+func TestCreateSignatureTemplate_CanonicalizationMethod(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		expected  string
+	}{
+		{"Default", "", C14N},
+		{"Exclusive", ExclusiveC14N, ExclusiveC14N},
+		{"Exclusive With Comments", ExclusiveC14NWithComments, ExclusiveC14NWithComments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &XMLSigner{}
+			if tt.algorithm != "" {
+				if err := s.SetCanonicalizationMethod(tt.algorithm); err != nil {
+					t.Fatalf("SetCanonicalizationMethod() error = %v", err)
+				}
+			}
+
+			document := strings.ReplaceAll(testDocument, "%s", DefaultSignatureID)
+			template, err := s.createSignatureTemplate([]byte(document))
+			if err != nil {
+				t.Fatalf("createSignatureTemplate() error = %v", err)
+			}
+
+			expected := `<ds:CanonicalizationMethod Algorithm="` + tt.expected + `"/>`
+			if !strings.Contains(string(template), expected) {
+				t.Errorf("Expected template to contain %s", expected)
+			}
+		})
+	}
+}
+
+func TestSetCanonicalizationMethod_Unsupported(t *testing.T) {
+	s := &XMLSigner{}
+
+	if err := s.SetCanonicalizationMethod("http://www.w3.org/2006/12/xml-c14n11"); err == nil {
+		t.Fatal("Expected error for unsupported canonicalization method")
+	}
+	if s.CanonicalizationMethod() != DefaultCanonicalizationMethod {
+		t.Errorf("Expected default method to be kept, got %s", s.CanonicalizationMethod())
+	}
+}
+
+func TestCreateSignatureTemplate_SignatureAlgorithm(t *testing.T) {
+	tests := []struct {
+		name            string
+		algorithm       SignatureAlgorithm
+		signatureMethod string
+		digestMethod    string
+	}{
+		{"Default", "", "http://www.w3.org/2000/09/xmldsig#rsa-sha1", "http://www.w3.org/2000/09/xmldsig#sha1"},
+		{"SHA1", SignatureSHA1, "http://www.w3.org/2000/09/xmldsig#rsa-sha1", "http://www.w3.org/2000/09/xmldsig#sha1"},
+		{"SHA256", SignatureSHA256, "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", "http://www.w3.org/2001/04/xmlenc#sha256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &XMLSigner{}
+			if tt.algorithm != "" {
+				if err := s.SetSignatureAlgorithm(tt.algorithm); err != nil {
+					t.Fatalf("SetSignatureAlgorithm() error = %v", err)
+				}
+			}
+
+			document := strings.ReplaceAll(testDocument, "%s", DefaultSignatureID)
+			template, err := s.createSignatureTemplate([]byte(document))
+			if err != nil {
+				t.Fatalf("createSignatureTemplate() error = %v", err)
+			}
+
+			for _, expected := range []string{
+				`<ds:SignatureMethod Algorithm="` + tt.signatureMethod + `"/>`,
+				`<ds:DigestMethod Algorithm="` + tt.digestMethod + `"/>`,
+			} {
+				if !strings.Contains(string(template), expected) {
+					t.Errorf("Expected template to contain %s", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSetSignatureAlgorithm_Unsupported(t *testing.T) {
+	s := &XMLSigner{}
+
+	if err := s.SetSignatureAlgorithm("SHA512"); err == nil {
+		t.Fatal("Expected error for unsupported signature algorithm")
+	}
+	if s.SignatureAlgorithm() != DefaultSignatureAlgorithm {
+		t.Errorf("Expected default algorithm to be kept, got %s", s.SignatureAlgorithm())
+	}
+}
+
+func TestSignXML_WithoutSuccessMarker(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+	document := []byte(strings.ReplaceAll(testDocument, "%s", DefaultSignatureID))
+
+	t.Run("Signed", func(t *testing.T) {
+		// A localized xmlsec1 build that signs but prints no English marker
+		installFakeXMLSec1Command(t, `sed -e 's|<ds:DigestValue/>|<ds:DigestValue>ZGlnZXN0</ds:DigestValue>|' \
+  -e 's|<ds:SignatureValue/>|<ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>|' \
+  -e 's|<ds:X509Certificate/>|<ds:X509Certificate>Y2VydA==</ds:X509Certificate>|' "$1" > "$output"
+echo "Estado de la firma: correcto"`)
+
+		s, err := NewXMLSignerFromPEM(keyPEM, certPEM)
+		if err != nil {
+			t.Fatalf("NewXMLSignerFromPEM() error = %v", err)
+		}
+		defer s.Cleanup()
+
+		signed, err := s.SignXML(document)
+		if err != nil {
+			t.Fatalf("SignXML() error = %v", err)
+		}
+		if !strings.Contains(string(signed), "<ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>") {
+			t.Errorf("Expected the signed output, got %s", signed)
+		}
+	})
+
+	t.Run("Not Signed", func(t *testing.T) {
+		installFakeXMLSec1Command(t, `cp "$1" "$output"`)
+
+		s, err := NewXMLSignerFromPEM(keyPEM, certPEM)
+		if err != nil {
+			t.Fatalf("NewXMLSignerFromPEM() error = %v", err)
+		}
+		defer s.Cleanup()
+
+		if _, err := s.SignXML(document); !errors.Is(err, ErrInvalidSignedXML) {
+			t.Errorf("Expected ErrInvalidSignedXML for an unsigned output, got %v", err)
+		}
+	})
+}
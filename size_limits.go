@@ -0,0 +1,34 @@
+// Package sunatlib provides the size checks applied to documents before sending them to SUNAT
+package sunatlib
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxDocumentSize is the maximum size in bytes of a signed XML document sent to SUNAT
+const MaxDocumentSize = 10 << 20
+
+// MaxZIPSize is the maximum size in bytes of a ZIP package sent to SUNAT (sendBill, sendSummary
+// and sendPack)
+const MaxZIPSize = 5 << 20
+
+// ErrDocumentTooLarge is returned when a signed document or its ZIP exceeds SUNAT's size limits
+var ErrDocumentTooLarge = errors.New("document exceeds SUNAT size limit")
+
+// ValidateDocumentSize checks that a signed XML document does not exceed MaxDocumentSize.
+// The send methods run it, and check the ZIP against MaxZIPSize, before contacting SUNAT
+func ValidateDocumentSize(signedXML []byte) error {
+	if len(signedXML) > MaxDocumentSize {
+		return fmt.Errorf("%w: XML is %d bytes, limit is %d bytes", ErrDocumentTooLarge, len(signedXML), MaxDocumentSize)
+	}
+	return nil
+}
+
+// validateZIPSize checks that a ZIP package does not exceed MaxZIPSize
+func validateZIPSize(zipName string, zipData []byte) error {
+	if len(zipData) > MaxZIPSize {
+		return fmt.Errorf("%w: %s is %d bytes, limit is %d bytes", ErrDocumentTooLarge, zipName, len(zipData), MaxZIPSize)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sunatlib
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateDocumentSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"Small", 1024, false},
+		{"At Limit", MaxDocumentSize, false},
+		{"Above Limit", MaxDocumentSize + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDocumentSize(bytes.Repeat([]byte("a"), tt.size))
+			if tt.wantErr != errors.Is(err, ErrDocumentTooLarge) {
+				t.Errorf("ValidateDocumentSize(%d bytes) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendToSUNAT_SizeLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("oversized document sent to SUNAT")
+	}))
+	defer server.Close()
+
+	// Random data barely compresses, so this XML is within MaxDocumentSize but its ZIP is not
+	random := make([]byte, MaxZIPSize+MaxZIPSize/8)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("failed to generate random data: %v", err)
+	}
+	incompressible := []byte("<Invoice>" + base64.StdEncoding.EncodeToString(random) + "</Invoice>")
+	if len(incompressible) > MaxDocumentSize {
+		t.Fatalf("test document is %d bytes, want at most %d", len(incompressible), MaxDocumentSize)
+	}
+
+	tests := []struct {
+		name     string
+		document []byte
+	}{
+		{"XML Above Limit", bytes.Repeat([]byte("a"), MaxDocumentSize+1)},
+		{"ZIP Above Limit", incompressible},
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.SendToSUNAT(tt.document, "01", "F001-1"); !errors.Is(err, ErrDocumentTooLarge) {
+				t.Errorf("SendToSUNAT() error = %v, want ErrDocumentTooLarge", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package sunatlib provides streaming submission of signed documents to SUNAT
+package sunatlib
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMaxConcurrentSends is the number of concurrent sends used by SendToSUNATStream by default
+const DefaultMaxConcurrentSends = 4
+
+// SignedDoc is a signed document queued for SendToSUNATStream
+type SignedDoc struct {
+	SignedXML    []byte // Signed UBL XML
+	DocumentType string // Document type code (01, 03, 07, 08)
+	SeriesNumber string // Document identifier in SERIE-NUMERO format
+}
+
+// SendResult is the outcome of sending a SignedDoc
+type SendResult struct {
+	Doc      SignedDoc
+	Response *SUNATResponse
+	Err      error
+}
+
+// SendToSUNATStream sends the documents received from docs with at most MaxConcurrentSends
+// requests in flight, emitting each result as soon as it completes. Results are not ordered.
+// The returned channel is closed once docs is closed and drained, or when ctx is cancelled
+func (c *SUNATClient) SendToSUNATStream(ctx context.Context, docs <-chan SignedDoc) <-chan SendResult {
+	workers := c.MaxConcurrentSends
+	if workers <= 0 {
+		workers = DefaultMaxConcurrentSends
+	}
+
+	results := make(chan SendResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case doc, ok := <-docs:
+					if !ok {
+						return
+					}
+
+					response, err := c.sendToSUNAT(ctx, doc.SignedXML, doc.DocumentType, doc.SeriesNumber)
+					select {
+					case results <- SendResult{Doc: doc, Response: response, Err: err}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
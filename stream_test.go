@@ -0,0 +1,95 @@
+package sunatlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const sendBillSuccessResponse = `<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:sendBillResponse xmlns:br="http://service.sunat.gob.pe"><applicationResponse></applicationResponse></br:sendBillResponse></soap-env:Body></soap-env:Envelope>`
+
+func TestSendToSUNATStream(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "F001-3.zip") {
+			// Oversized response for a single document
+			io.WriteString(w, strings.Repeat("A", 4096))
+			return
+		}
+		io.WriteString(w, sendBillSuccessResponse)
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.MaxConcurrentSends = 2
+	client.MaxResponseSize = 1024
+
+	docs := make(chan SignedDoc)
+	go func() {
+		defer close(docs)
+		for i := 1; i <= 6; i++ {
+			docs <- SignedDoc{SignedXML: []byte("<Invoice/>"), DocumentType: "01", SeriesNumber: fmt.Sprintf("F001-%d", i)}
+		}
+	}()
+
+	results := make(map[string]SendResult)
+	for result := range client.SendToSUNATStream(context.Background(), docs) {
+		results[result.Doc.SeriesNumber] = result
+	}
+
+	if len(results) != 6 {
+		t.Fatalf("Expected 6 results, got %d", len(results))
+	}
+	for seriesNumber, result := range results {
+		if seriesNumber == "F001-3" {
+			if !errors.Is(result.Err, ErrResponseTooLarge) {
+				t.Errorf("Expected ErrResponseTooLarge for %s, got %v", seriesNumber, result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || !result.Response.Success {
+			t.Errorf("Expected success for %s, got err=%v response=%+v", seriesNumber, result.Err, result.Response)
+		}
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent sends, got %d", maxInFlight)
+	}
+}
+
+func TestSendToSUNATStream_Cancelled(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// docs is never closed: the stream must end because the context is cancelled
+	docs := make(chan SignedDoc)
+	results := client.SendToSUNATStream(ctx, docs)
+
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Error("Expected no results after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected results channel to be closed after cancellation")
+	}
+}
@@ -0,0 +1,513 @@
+// Package sunatlib provides functionality for SUNAT summary documents (resumen diario de boletas)
+package sunatlib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+// Status of a summary line (Catálogo 19)
+const (
+	SummaryStatusAdd    = "1" // Adicionar
+	SummaryStatusModify = "2" // Modificar
+	SummaryStatusVoid   = "3" // Anulado
+)
+
+// ErrSummaryNotProcessed is returned by ValidateSummaryBoletas when the summary ticket was not
+// processed successfully, so its boletas cannot be validated yet
+var ErrSummaryNotProcessed = errors.New("summary documents not processed successfully by SUNAT")
+
+// SummaryDocumentLine represents a boleta (or a note referencing one) reported in a summary.
+// Amounts are split in the buckets SUNAT expects for each line (sac:BillingPayment and cac:TaxTotal)
+type SummaryDocumentLine struct {
+	DocumentTypeCode  string            // Document type code (03=Boleta, 07=Credit note, 08=Debit note)
+	DocumentSeries    string            // Document series (e.g., "B001")
+	DocumentNumber    string            // Document correlative number
+	CustomerDocType   string            // Customer identity document type (Catálogo 06)
+	CustomerDocNumber string            // Customer identity document number
+	Currency          string            // Currency code (e.g., "PEN")
+	TotalAmount       float64           // Document total (ImporteTotal)
+	TotalTaxed        float64           // Taxed operations (gravadas, instruction 01)
+	TotalExonerated   float64           // Exonerated operations (exoneradas, instruction 02)
+	TotalUnaffected   float64           // Unaffected operations (inafectas, instruction 03)
+	TotalIGV          float64           // IGV of the document
+	TotalISC          float64           // ISC of the document
+	Reference         *BillingReference // Boleta modified by a note (required for 07 and 08)
+	Status            string            // Line status (SummaryStatusAdd, SummaryStatusModify or SummaryStatusVoid)
+	ExtraElements     map[string]string // Inner XML of schema elements not modeled by the library (see SummaryLineExtraElements)
+}
+
+// SummaryTotals holds the amounts of a summary added up across its lines
+type SummaryTotals struct {
+	TotalAmount     float64
+	TotalTaxed      float64
+	TotalExonerated float64
+	TotalUnaffected float64
+	TotalIGV        float64
+	TotalISC        float64
+}
+
+// SummaryDocumentsRequest represents a daily summary (RC) of boletas
+type SummaryDocumentsRequest struct {
+	RUC           string                // Company RUC
+	CompanyName   string                // Company name/reason social
+	SeriesNumber  string                // Summary series number (RC-YYYYMMDD-###)
+	IssueDate     time.Time             // Issue date
+	ReferenceDate time.Time             // Reference date (issue date of the summarized documents)
+	Lines         []SummaryDocumentLine // Summarized documents
+	SignatureID   string                // Signature Id referenced by the document (defaults to signer.DefaultSignatureID)
+}
+
+// ValidateSummaryBoletas validates with SUNAT every document added or modified by a summary,
+// once its ticket has been processed successfully (ticketStatus from QueryVoidedDocumentsTicket
+// or WaitForTicketProcessing). Voided lines are skipped. The report's Valid entries are the
+// documents SUNAT now recognizes
+func (vc *ValidationClient) ValidateSummaryBoletas(summary *SummaryDocumentsRequest, ticketStatus *TicketStatusResponse) (*ValidationReport, error) {
+	if ticketStatus == nil || !ticketStatus.IsSuccessful() {
+		return nil, ErrSummaryNotProcessed
+	}
+
+	var records []ValidationParams
+	for _, line := range summary.Lines {
+		if line.Status == SummaryStatusVoid {
+			continue
+		}
+		records = append(records, ValidationParams{
+			IssuerRUC:          summary.RUC,
+			DocumentType:       line.DocumentTypeCode,
+			SeriesNumber:       line.DocumentSeries,
+			DocumentNumber:     line.DocumentNumber,
+			RecipientDocType:   line.CustomerDocType,
+			RecipientDocNumber: line.CustomerDocNumber,
+			IssueDate:          summary.ReferenceDate.Format("2006-01-02"),
+			TotalAmount:        line.TotalAmount,
+		})
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("summary %s has no documents to validate", summary.SeriesNumber)
+	}
+
+	return vc.ValidateFromRecords(records)
+}
+
+// SummaryLineExtraElements lists the elements accepted in SummaryDocumentLine.ExtraElements: the
+// sac:SummaryDocumentsLine children of the SummaryDocuments 1.1 schema (UBLPE-SummaryDocuments-1.1.xsd)
+// the library does not model, in schema order. Their values are the inner XML of the element
+var SummaryLineExtraElements = []string{
+	"sac:SUNATPerceptionSummaryDocumentReference", // After cac:BillingReference, before cac:Status
+	"cac:AllowanceCharge",                         // After sac:BillingPayment, before cac:TaxTotal
+}
+
+// BuildSummaryFromBoletas builds the daily summary of the boletas issued on referenceDate, one
+// line per boleta with status SummaryStatusAdd and its tax buckets. All boletas must be valid
+// and share the issuer, which becomes the summary issuer. The summary is issued on issueDate
+// with the given sequence of that day (see GenerateSummaryDocumentsSeries), so every summary
+// sent on the same day needs its own sequence
+func BuildSummaryFromBoletas(boletas []Invoice, referenceDate, issueDate time.Time, sequence int) (*SummaryDocumentsRequest, error) {
+	if len(boletas) == 0 {
+		return nil, fmt.Errorf("at least one boleta is required")
+	}
+	if sequence < 1 {
+		return nil, fmt.Errorf("invalid summary sequence %d: must be at least 1", sequence)
+	}
+
+	summary := &SummaryDocumentsRequest{
+		RUC:           boletas[0].Supplier.DocumentNumber,
+		CompanyName:   boletas[0].Supplier.Name,
+		SeriesNumber:  GenerateSummaryDocumentsSeries(issueDate, sequence),
+		IssueDate:     issueDate,
+		ReferenceDate: referenceDate,
+		Lines:         make([]SummaryDocumentLine, 0, len(boletas)),
+	}
+
+	for i := range boletas {
+		boleta := &boletas[i]
+		if boleta.DocumentType != "03" {
+			return nil, fmt.Errorf("document %s is not a boleta (type %s)", boleta.FullNumber(), boleta.DocumentType)
+		}
+		if err := boleta.Validate(); err != nil {
+			return nil, fmt.Errorf("boleta %s: %w", boleta.FullNumber(), err)
+		}
+		if boleta.Supplier.DocumentNumber != summary.RUC {
+			return nil, fmt.Errorf("boleta %s: issuer %s differs from %s", boleta.FullNumber(), boleta.Supplier.DocumentNumber, summary.RUC)
+		}
+		if !sameDay(boleta.IssueDate, referenceDate) {
+			return nil, fmt.Errorf("boleta %s: issued on %s, not on the reference date %s", boleta.FullNumber(),
+				boleta.IssueDate.Format("2006-01-02"), referenceDate.Format("2006-01-02"))
+		}
+		if boleta.TotalExport != 0 {
+			return nil, fmt.Errorf("boleta %s: export operations cannot be summarized", boleta.FullNumber())
+		}
+		if boleta.TotalDiscounts != 0 || boleta.TotalPrepaid != 0 {
+			return nil, fmt.Errorf("boleta %s: global discounts and prepayments cannot be summarized", boleta.FullNumber())
+		}
+
+		summary.Lines = append(summary.Lines, SummaryDocumentLine{
+			DocumentTypeCode:  boleta.DocumentType,
+			DocumentSeries:    boleta.Series,
+			DocumentNumber:    boleta.Number,
+			CustomerDocType:   boleta.Customer.DocumentType,
+			CustomerDocNumber: boleta.Customer.DocumentNumber,
+			Currency:          boleta.Currency,
+			TotalAmount:       boleta.TotalAmount,
+			TotalTaxed:        boleta.TotalTaxed,
+			TotalExonerated:   boleta.TotalExonerated,
+			TotalUnaffected:   boleta.TotalUnaffected,
+			TotalIGV:          boleta.TotalIGV,
+			Status:            SummaryStatusAdd,
+		})
+	}
+
+	if err := summary.Validate(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// summaryBillingPayments lists the sac:BillingPayment instruction IDs and the line amount of each
+var summaryBillingPayments = []struct {
+	InstructionID string
+	Amount        func(line *SummaryDocumentLine) float64
+}{
+	{InstructionID: "01", Amount: func(line *SummaryDocumentLine) float64 { return line.TotalTaxed }},
+	{InstructionID: "02", Amount: func(line *SummaryDocumentLine) float64 { return line.TotalExonerated }},
+	{InstructionID: "03", Amount: func(line *SummaryDocumentLine) float64 { return line.TotalUnaffected }},
+}
+
+// GenerateSummaryDocumentsXML generates the XML for a summary of boletas (resumen diario).
+// Each line carries its total, one sac:BillingPayment per non-empty bucket (01 gravado,
+// 02 exonerado, 03 inafecto) and its ISC and IGV tax totals
+func (c *SUNATClient) GenerateSummaryDocumentsXML(request *SummaryDocumentsRequest) ([]byte, error) {
+	if docType := seriesDocumentType(request.SeriesNumber); docType != "" && docType != "RC" {
+		return nil, utils.NewUnsupportedDocumentTypeError(docType, "GenerateSummaryDocumentsXML", "RC")
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid summary: %w", err)
+	}
+
+	version, _ := utils.GetDocumentVersion("RC")
+
+	signatureID := request.SignatureID
+	if signatureID == "" {
+		signatureID = signer.DefaultSignatureID
+	}
+
+	xmlContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<SummaryDocuments xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:SummaryDocuments-1"
+xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+xmlns:sac="urn:sunat:names:specification:ubl:peru:schema:xsd:SunatAggregateComponents-1"
+xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<ext:UBLExtensions><ext:UBLExtension>
+<ext:ExtensionContent>
+    </ext:ExtensionContent>
+</ext:UBLExtension></ext:UBLExtensions>
+<cbc:UBLVersionID>%s</cbc:UBLVersionID>
+<cbc:CustomizationID>%s</cbc:CustomizationID>
+<cbc:ID>%s</cbc:ID>
+<cbc:ReferenceDate>%s</cbc:ReferenceDate>
+<cbc:IssueDate>%s</cbc:IssueDate>
+<cac:Signature>
+<cbc:ID>%s</cbc:ID>
+<cac:SignatoryParty>
+<cac:PartyIdentification>
+<cbc:ID>%s</cbc:ID>
+</cac:PartyIdentification>
+<cac:PartyName>
+<cbc:Name><![CDATA[%s]]></cbc:Name>
+</cac:PartyName>
+</cac:SignatoryParty>
+<cac:DigitalSignatureAttachment>
+<cac:ExternalReference>
+<cbc:URI>#%s</cbc:URI>
+</cac:ExternalReference>
+</cac:DigitalSignatureAttachment>
+</cac:Signature>
+<cac:AccountingSupplierParty>
+<cbc:CustomerAssignedAccountID>%s</cbc:CustomerAssignedAccountID>
+<cbc:AdditionalAccountID>6</cbc:AdditionalAccountID>
+<cac:Party>
+<cac:PartyLegalEntity>
+<cbc:RegistrationName><![CDATA[%s]]></cbc:RegistrationName>
+</cac:PartyLegalEntity>
+</cac:Party>
+</cac:AccountingSupplierParty>`,
+		version.UBLVersionID,
+		version.CustomizationID,
+		request.SeriesNumber,
+		request.ReferenceDate.Format("2006-01-02"),
+		request.IssueDate.Format("2006-01-02"),
+		signatureID,
+		request.RUC,
+		utils.ValidateSpecialCharacters(request.CompanyName),
+		signatureID,
+		request.RUC,
+		utils.ValidateSpecialCharacters(request.CompanyName))
+
+	for i := range request.Lines {
+		xmlContent += generateSummaryLineXML(i+1, &request.Lines[i])
+	}
+
+	xmlContent += `
+</SummaryDocuments>`
+
+	return []byte(xmlContent), nil
+}
+
+// generateSummaryLineXML renders a sac:SummaryDocumentsLine
+func generateSummaryLineXML(lineID int, line *SummaryDocumentLine) string {
+	// Customers without a document are reported as "0" / "-"
+	customerDocType := line.CustomerDocType
+	customerDocNumber := line.CustomerDocNumber
+	if customerDocNumber == "" {
+		customerDocType, customerDocNumber = "0", "-"
+	}
+
+	lineXML := fmt.Sprintf(`
+<sac:SummaryDocumentsLine>
+<cbc:LineID>%d</cbc:LineID>
+<cbc:DocumentTypeCode>%s</cbc:DocumentTypeCode>
+<cbc:ID>%s</cbc:ID>
+<cac:AccountingCustomerParty>
+<cbc:CustomerAssignedAccountID>%s</cbc:CustomerAssignedAccountID>
+<cbc:AdditionalAccountID>%s</cbc:AdditionalAccountID>
+</cac:AccountingCustomerParty>`,
+		lineID,
+		line.DocumentTypeCode,
+		utils.JoinSerieNumero(line.DocumentSeries, line.DocumentNumber),
+		customerDocNumber,
+		customerDocType)
+
+	if line.Reference != nil {
+		lineXML += fmt.Sprintf(`
+<cac:BillingReference>
+<cac:InvoiceDocumentReference>
+<cbc:ID>%s</cbc:ID>
+<cbc:DocumentTypeCode>%s</cbc:DocumentTypeCode>
+</cac:InvoiceDocumentReference>
+</cac:BillingReference>`, strings.ToUpper(line.Reference.SeriesNumber), line.Reference.DocumentType)
+	}
+	lineXML += summaryLineExtraXML(line, "sac:SUNATPerceptionSummaryDocumentReference")
+
+	lineXML += fmt.Sprintf(`
+<cac:Status>
+<cbc:ConditionCode>%s</cbc:ConditionCode>
+</cac:Status>
+<sac:TotalAmount currencyID="%s">%.2f</sac:TotalAmount>`, line.Status, line.Currency, line.TotalAmount)
+
+	payments := 0
+	for _, payment := range summaryBillingPayments {
+		amount := payment.Amount(line)
+		if amount == 0 {
+			continue
+		}
+		lineXML += summaryBillingPaymentXML(payment.InstructionID, line.Currency, amount)
+		payments++
+	}
+	// At least one payment is required, documents without amounts report an empty gravado bucket
+	if payments == 0 {
+		lineXML += summaryBillingPaymentXML("01", line.Currency, 0)
+	}
+
+	lineXML += summaryLineExtraXML(line, "cac:AllowanceCharge")
+
+	if line.TotalISC > 0 {
+		lineXML += summaryTaxTotalXML(line.Currency, line.TotalISC, "2000", "ISC", "EXC")
+	}
+	lineXML += summaryTaxTotalXML(line.Currency, line.TotalIGV, "1000", "IGV", "VAT")
+
+	return lineXML + `
+</sac:SummaryDocumentsLine>`
+}
+
+// summaryLineExtraXML renders the extra element name of a line, or nothing when it is not set
+func summaryLineExtraXML(line *SummaryDocumentLine, name string) string {
+	value, ok := line.ExtraElements[name]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("\n<%s>%s</%s>", name, value, name)
+}
+
+// summaryBillingPaymentXML renders a sac:BillingPayment
+func summaryBillingPaymentXML(instructionID, currency string, amount float64) string {
+	return fmt.Sprintf(`
+<sac:BillingPayment>
+<cbc:PaidAmount currencyID="%s">%.2f</cbc:PaidAmount>
+<cbc:InstructionID>%s</cbc:InstructionID>
+</sac:BillingPayment>`, currency, amount, instructionID)
+}
+
+// summaryTaxTotalXML renders the cac:TaxTotal of a tax scheme (Catálogo 05)
+func summaryTaxTotalXML(currency string, amount float64, schemeID, schemeName, typeCode string) string {
+	return fmt.Sprintf(`
+<cac:TaxTotal>
+<cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>
+<cac:TaxSubtotal>
+<cbc:TaxAmount currencyID="%s">%.2f</cbc:TaxAmount>
+<cac:TaxCategory>
+<cac:TaxScheme>
+<cbc:ID>%s</cbc:ID>
+<cbc:Name>%s</cbc:Name>
+<cbc:TaxTypeCode>%s</cbc:TaxTypeCode>
+</cac:TaxScheme>
+</cac:TaxCategory>
+</cac:TaxSubtotal>
+</cac:TaxTotal>`, currency, amount, currency, amount, schemeID, schemeName, typeCode)
+}
+
+// Totals adds up the amounts of the lines that are not voided
+func (req *SummaryDocumentsRequest) Totals() SummaryTotals {
+	var totals SummaryTotals
+	for _, line := range req.Lines {
+		if line.Status == SummaryStatusVoid {
+			continue
+		}
+		totals.TotalAmount += line.TotalAmount
+		totals.TotalTaxed += line.TotalTaxed
+		totals.TotalExonerated += line.TotalExonerated
+		totals.TotalUnaffected += line.TotalUnaffected
+		totals.TotalIGV += line.TotalIGV
+		totals.TotalISC += line.TotalISC
+	}
+
+	totals.TotalAmount = roundAmount(totals.TotalAmount)
+	totals.TotalTaxed = roundAmount(totals.TotalTaxed)
+	totals.TotalExonerated = roundAmount(totals.TotalExonerated)
+	totals.TotalUnaffected = roundAmount(totals.TotalUnaffected)
+	totals.TotalIGV = roundAmount(totals.TotalIGV)
+	totals.TotalISC = roundAmount(totals.TotalISC)
+	return totals
+}
+
+// Validate validates the summary before XML generation
+func (req *SummaryDocumentsRequest) Validate() error {
+	if !utils.ValidateRUC(req.RUC) {
+		return fmt.Errorf("invalid RUC: %s", req.RUC)
+	}
+
+	if req.CompanyName == "" {
+		return fmt.Errorf("company name is required")
+	}
+
+	if req.SeriesNumber == "" {
+		return fmt.Errorf("series number is required")
+	}
+
+	if req.IssueDate.Format("2006-01-02") < req.ReferenceDate.Format("2006-01-02") {
+		return fmt.Errorf("issue date %s is before reference date %s",
+			req.IssueDate.Format("2006-01-02"), req.ReferenceDate.Format("2006-01-02"))
+	}
+
+	if len(req.Lines) == 0 {
+		return fmt.Errorf("at least one line is required")
+	}
+
+	// Validate each line and reject duplicates, SUNAT rejects the whole summary
+	seen := make(map[string]int, len(req.Lines))
+	for i := range req.Lines {
+		line := &req.Lines[i]
+		if err := line.Validate(); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		key := line.DocumentTypeCode + "-" + utils.NormalizeSerieNumero(line.DocumentSeries, line.DocumentNumber)
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("line %d: duplicate of line %d (%s)", i+1, first, key)
+		}
+		seen[key] = i + 1
+	}
+
+	return nil
+}
+
+// Validate validates a single summary line
+func (line *SummaryDocumentLine) Validate() error {
+	switch line.DocumentTypeCode {
+	case "03", "07", "08":
+	default:
+		return fmt.Errorf("invalid document type for summary: %s (expected 03, 07 or 08)", line.DocumentTypeCode)
+	}
+
+	if !utils.ValidateDocumentSeries(line.DocumentSeries) || !strings.HasPrefix(line.DocumentSeries, "B") {
+		return fmt.Errorf("invalid boleta series: %s", line.DocumentSeries)
+	}
+
+	if !utils.ValidateDocumentNumber(line.DocumentNumber) {
+		return fmt.Errorf("invalid document number format: %s", line.DocumentNumber)
+	}
+
+	switch line.Status {
+	case SummaryStatusAdd, SummaryStatusModify, SummaryStatusVoid:
+	default:
+		return fmt.Errorf("invalid status: %s (Catálogo 19)", line.Status)
+	}
+
+	if !utils.ValidateCurrencyCode(line.Currency) {
+		return fmt.Errorf("invalid currency code: %s", line.Currency)
+	}
+
+	if line.DocumentTypeCode != "03" {
+		if line.Reference == nil {
+			return fmt.Errorf("notes require the referenced boleta")
+		}
+		if err := ValidateNoteReference(line.DocumentSeries, *line.Reference); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range line.ExtraElements {
+		if !isSummaryLineExtraElement(name) {
+			return fmt.Errorf("extra element %q is not allowed (see SummaryLineExtraElements)", name)
+		}
+		if err := checkXMLFragment(value); err != nil {
+			return fmt.Errorf("extra element %q is not well-formed XML: %w", name, err)
+		}
+	}
+
+	for _, amount := range []float64{line.TotalAmount, line.TotalTaxed, line.TotalExonerated, line.TotalUnaffected, line.TotalIGV, line.TotalISC} {
+		if err := utils.ValidateAmount(amount); err != nil {
+			return err
+		}
+	}
+
+	computed := line.TotalTaxed + line.TotalExonerated + line.TotalUnaffected + line.TotalIGV + line.TotalISC
+	if !utils.AmountsMatch(computed, line.TotalAmount) {
+		return fmt.Errorf("total amount %.2f does not match the sum of its buckets %.2f", line.TotalAmount, computed)
+	}
+
+	return nil
+}
+
+// checkXMLFragment returns an error if fragment is not well-formed XML content
+func checkXMLFragment(fragment string) error {
+	decoder := xml.NewDecoder(strings.NewReader("<fragment>" + fragment + "</fragment>"))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// isSummaryLineExtraElement returns true if name is one of SummaryLineExtraElements
+func isSummaryLineExtraElement(name string) bool {
+	for _, allowed := range SummaryLineExtraElements {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
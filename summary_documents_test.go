@@ -0,0 +1,369 @@
+package sunatlib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSummaryDocumentsRequest returns a valid summary of two boletas
+func newTestSummaryDocumentsRequest() *SummaryDocumentsRequest {
+	return &SummaryDocumentsRequest{
+		RUC:           testRUC,
+		CompanyName:   "EMPRESA DE PRUEBA S.A.C.",
+		SeriesNumber:  "RC-20260428-001",
+		IssueDate:     time.Date(2026, 4, 28, 0, 0, 0, 0, time.UTC),
+		ReferenceDate: time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC),
+		Lines: []SummaryDocumentLine{
+			{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "1", CustomerDocType: "1", CustomerDocNumber: "12345678", Currency: "PEN", TotalAmount: 118, TotalTaxed: 100, TotalIGV: 18, Status: SummaryStatusAdd},
+			{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "2", CustomerDocType: "1", CustomerDocNumber: "87654321", Currency: "PEN", TotalAmount: 59, TotalTaxed: 50, TotalIGV: 9, Status: SummaryStatusAdd},
+		},
+	}
+}
+
+func TestValidateSummaryBoletas(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		io.WriteString(w, validationResponse("El comprobante es un comprobante de pago válido."))
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+	client.SetMaxConcurrency(1)
+
+	summary := newTestSummaryDocumentsRequest()
+	summary.Lines = append(summary.Lines, SummaryDocumentLine{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "3", TotalAmount: 10, Status: SummaryStatusVoid})
+
+	report, err := client.ValidateSummaryBoletas(summary, &TicketStatusResponse{StatusCode: "0"})
+	if err != nil {
+		t.Fatalf("ValidateSummaryBoletas() error = %v", err)
+	}
+
+	if len(report.Valid) != 2 || report.Total != 2 {
+		t.Fatalf("Expected the 2 summarized boletas as valid, got %+v", report)
+	}
+	for i, number := range []string{"1", "2"} {
+		if report.Valid[i].Params.DocumentNumber != number || report.Valid[i].Params.IssueDate != "2026-04-27" {
+			t.Errorf("Valid[%d] = %+v, want boleta %s issued on the reference date", i, report.Valid[i].Params, number)
+		}
+	}
+	for _, body := range requests {
+		if strings.Contains(body, "<numeroCDP>3</numeroCDP>") {
+			t.Error("Expected voided lines not to be validated")
+		}
+	}
+}
+
+func TestValidateSummaryBoletas_NotProcessed(t *testing.T) {
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+
+	for _, status := range []*TicketStatusResponse{nil, {StatusCode: "98"}, {StatusCode: "99"}} {
+		if _, err := client.ValidateSummaryBoletas(newTestSummaryDocumentsRequest(), status); !errors.Is(err, ErrSummaryNotProcessed) {
+			t.Errorf("ValidateSummaryBoletas(%+v) error = %v, want ErrSummaryNotProcessed", status, err)
+		}
+	}
+}
+
+func TestGenerateSummaryDocumentsXML_Buckets(t *testing.T) {
+	summary := newTestSummaryDocumentsRequest()
+	summary.Lines = []SummaryDocumentLine{
+		{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "1", CustomerDocType: "1", CustomerDocNumber: "12345678", Currency: "PEN", TotalAmount: 118, TotalTaxed: 100, TotalIGV: 18, Status: SummaryStatusAdd},
+		{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "2", Currency: "PEN", TotalAmount: 80, TotalTaxed: 50, TotalExonerated: 21, TotalIGV: 9, Status: SummaryStatusAdd},
+		{DocumentTypeCode: "03", DocumentSeries: "B001", DocumentNumber: "3", Currency: "PEN", TotalAmount: 35, TotalUnaffected: 30, TotalISC: 5, Status: SummaryStatusAdd},
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	xmlData, err := client.GenerateSummaryDocumentsXML(summary)
+	if err != nil {
+		t.Fatalf("GenerateSummaryDocumentsXML() error = %v", err)
+	}
+
+	lines := strings.Split(string(xmlData), "<sac:SummaryDocumentsLine>")[1:]
+	if len(lines) != 3 {
+		t.Fatalf("got %d summary lines, want 3", len(lines))
+	}
+
+	tests := []struct {
+		name     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "gravado",
+			contains: []string{
+				`<cbc:ID>B001-1</cbc:ID>`,
+				`<sac:TotalAmount currencyID="PEN">118.00</sac:TotalAmount>`,
+				`<cbc:PaidAmount currencyID="PEN">100.00</cbc:PaidAmount>
+<cbc:InstructionID>01</cbc:InstructionID>`,
+				`<cbc:TaxAmount currencyID="PEN">18.00</cbc:TaxAmount>`,
+			},
+			excludes: []string{"<cbc:InstructionID>02", "<cbc:InstructionID>03", "<cbc:Name>ISC</cbc:Name>"},
+		},
+		{
+			name: "gravado y exonerado",
+			contains: []string{
+				`<cbc:CustomerAssignedAccountID>-</cbc:CustomerAssignedAccountID>
+<cbc:AdditionalAccountID>0</cbc:AdditionalAccountID>`,
+				`<cbc:PaidAmount currencyID="PEN">50.00</cbc:PaidAmount>
+<cbc:InstructionID>01</cbc:InstructionID>`,
+				`<cbc:PaidAmount currencyID="PEN">21.00</cbc:PaidAmount>
+<cbc:InstructionID>02</cbc:InstructionID>`,
+				`<cbc:TaxAmount currencyID="PEN">9.00</cbc:TaxAmount>`,
+			},
+			excludes: []string{"<cbc:InstructionID>03", "<cbc:Name>ISC</cbc:Name>"},
+		},
+		{
+			name: "inafecto con ISC",
+			contains: []string{
+				`<cbc:PaidAmount currencyID="PEN">30.00</cbc:PaidAmount>
+<cbc:InstructionID>03</cbc:InstructionID>`,
+				`<cbc:TaxAmount currencyID="PEN">5.00</cbc:TaxAmount>`,
+				`<cbc:ID>2000</cbc:ID>
+<cbc:Name>ISC</cbc:Name>
+<cbc:TaxTypeCode>EXC</cbc:TaxTypeCode>`,
+				`<cbc:TaxAmount currencyID="PEN">0.00</cbc:TaxAmount>`,
+			},
+			excludes: []string{"<cbc:InstructionID>01", "<cbc:InstructionID>02"},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := lines[i]
+			for _, want := range tt.contains {
+				if !strings.Contains(line, want) {
+					t.Errorf("line %d is missing %q", i+1, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(line, unwanted) {
+					t.Errorf("line %d should not contain %q", i+1, unwanted)
+				}
+			}
+			if !strings.Contains(line, "<cbc:ID>1000</cbc:ID>\n<cbc:Name>IGV</cbc:Name>") {
+				t.Errorf("line %d is missing the IGV tax total", i+1)
+			}
+		})
+	}
+
+	totals := summary.Totals()
+	want := SummaryTotals{TotalAmount: 233, TotalTaxed: 150, TotalExonerated: 21, TotalUnaffected: 30, TotalIGV: 27, TotalISC: 5}
+	if totals != want {
+		t.Errorf("Totals() = %+v, want %+v", totals, want)
+	}
+}
+
+func TestSummaryDocumentsRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*SummaryDocumentsRequest)
+	}{
+		{"factura en resumen", func(s *SummaryDocumentsRequest) { s.Lines[0].DocumentTypeCode = "01" }},
+		{"serie de factura", func(s *SummaryDocumentsRequest) { s.Lines[0].DocumentSeries = "F001" }},
+		{"total no cuadra", func(s *SummaryDocumentsRequest) { s.Lines[0].TotalIGV = 10 }},
+		{"nota sin referencia", func(s *SummaryDocumentsRequest) { s.Lines[0].DocumentTypeCode = "07" }},
+		{"estado inválido", func(s *SummaryDocumentsRequest) { s.Lines[0].Status = "9" }},
+		{"sin líneas", func(s *SummaryDocumentsRequest) { s.Lines = nil }},
+	}
+
+	if err := newTestSummaryDocumentsRequest().Validate(); err != nil {
+		t.Fatalf("Validate() error = %v for a valid summary", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := newTestSummaryDocumentsRequest()
+			tt.modify(summary)
+			if err := summary.Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestGenerateSummaryDocumentsXML_ExtraElements(t *testing.T) {
+	summary := newTestSummaryDocumentsRequest()
+	summary.Lines[0].ExtraElements = map[string]string{
+		"cac:AllowanceCharge":                         `<cbc:ChargeIndicator>true</cbc:ChargeIndicator><cbc:Amount currencyID="PEN">5.00</cbc:Amount>`,
+		"sac:SUNATPerceptionSummaryDocumentReference": `<sac:SUNATPerceptionSystemCode>01</sac:SUNATPerceptionSystemCode>`,
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	xmlData, err := client.GenerateSummaryDocumentsXML(summary)
+	if err != nil {
+		t.Fatalf("GenerateSummaryDocumentsXML() error = %v", err)
+	}
+
+	line := strings.Split(string(xmlData), "<sac:SummaryDocumentsLine>")[1]
+	for _, want := range []string{
+		`</cac:AccountingCustomerParty>
+<sac:SUNATPerceptionSummaryDocumentReference><sac:SUNATPerceptionSystemCode>01</sac:SUNATPerceptionSystemCode></sac:SUNATPerceptionSummaryDocumentReference>
+<cac:Status>`,
+		`</sac:BillingPayment>
+<cac:AllowanceCharge><cbc:ChargeIndicator>true</cbc:ChargeIndicator><cbc:Amount currencyID="PEN">5.00</cbc:Amount></cac:AllowanceCharge>
+<cac:TaxTotal>`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("extra element not rendered in schema order, want:\n%s\ngot:\n%s", want, line)
+		}
+	}
+
+	for name, extra := range map[string]map[string]string{
+		"cbc:Note":            {"cbc:Note": "x"},
+		"cac:AllowanceCharge": {"cac:AllowanceCharge": "<cbc:Amount>5.00"},
+	} {
+		summary.Lines[0].ExtraElements = extra
+		if _, err := client.GenerateSummaryDocumentsXML(summary); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("error = %v, want an error for the extra element %s", err, name)
+		}
+	}
+}
+
+func TestSummaryDocumentsRequest_Validate_Duplicates(t *testing.T) {
+	summary := newTestSummaryDocumentsRequest()
+	duplicate := summary.Lines[0]
+	duplicate.Status = SummaryStatusModify
+	summary.Lines = append(summary.Lines, duplicate)
+
+	err := summary.Validate()
+	if err == nil {
+		t.Fatal("Expected error for duplicated line")
+	}
+	if !contains(err.Error(), "line 3: duplicate of line 1 (03-B001-1)") {
+		t.Errorf("Validate() error = %v, want duplicate identification", err)
+	}
+
+	padded := newTestSummaryDocumentsRequest()
+	duplicate = padded.Lines[0]
+	duplicate.DocumentNumber = "0000000" + duplicate.DocumentNumber
+	padded.Lines = append(padded.Lines, duplicate)
+	if err := padded.Validate(); err == nil || !contains(err.Error(), "duplicate of line 1") {
+		t.Errorf("Validate() error = %v, want a zero-padded duplicate to be detected", err)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if _, err := client.GenerateSummaryDocumentsXML(summary); err == nil {
+		t.Error("GenerateSummaryDocumentsXML() should reject a summary with duplicated lines")
+	}
+}
+
+func TestBuildSummaryFromBoletas(t *testing.T) {
+	referenceDate := time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC)
+	issueDate := time.Date(2026, 4, 28, 9, 0, 0, 0, time.UTC)
+
+	newBoleta := func(number string) Invoice {
+		boleta := *newTestInvoice()
+		boleta.DocumentType = "03"
+		boleta.Series = "B001"
+		boleta.Number = number
+		boleta.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+		return boleta
+	}
+
+	gravada := newBoleta("1")
+
+	exonerada := newBoleta("2")
+	exonerada.Customer = InvoiceParty{DocumentType: "0", Name: "CLIENTES VARIOS"}
+	exonerada.Items = []InvoiceItem{{Description: "LIBRO", Quantity: 1, UnitCode: "NIU", UnitValue: 40, AffectationCode: "20"}}
+	exonerada.TotalTaxed, exonerada.TotalIGV = 0, 0
+	exonerada.TotalExonerated, exonerada.TotalAmount = 40, 40
+
+	mixta := newBoleta("3")
+	mixta.Items = append(mixta.Items, InvoiceItem{Description: "SERVICIO", Quantity: 1, UnitCode: "ZZ", UnitValue: 25, AffectationCode: "30"})
+	mixta.TotalUnaffected = 25
+	mixta.TotalAmount = 143
+
+	summary, err := BuildSummaryFromBoletas([]Invoice{gravada, exonerada, mixta}, referenceDate, issueDate, 1)
+	if err != nil {
+		t.Fatalf("BuildSummaryFromBoletas() error = %v", err)
+	}
+
+	if summary.RUC != "20000000001" || summary.CompanyName != "MI EMPRESA S.A.C." {
+		t.Errorf("summary issuer = %s %s, want the boletas issuer", summary.RUC, summary.CompanyName)
+	}
+	if summary.SeriesNumber != "RC-20260428-001" || !summary.IssueDate.Equal(issueDate) || !summary.ReferenceDate.Equal(referenceDate) {
+		t.Errorf("SeriesNumber = %s, IssueDate = %s, ReferenceDate = %s", summary.SeriesNumber, summary.IssueDate, summary.ReferenceDate)
+	}
+	if len(summary.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(summary.Lines))
+	}
+	for _, line := range summary.Lines {
+		if line.Status != SummaryStatusAdd {
+			t.Errorf("line %s status = %s, want %s", line.DocumentNumber, line.Status, SummaryStatusAdd)
+		}
+	}
+	if line := summary.Lines[2]; line.TotalTaxed != 100 || line.TotalUnaffected != 25 || line.TotalIGV != 18 || line.TotalAmount != 143 {
+		t.Errorf("mixed line = %+v, want taxed 100, unaffected 25, IGV 18, total 143", line)
+	}
+
+	want := SummaryTotals{TotalAmount: 301, TotalTaxed: 200, TotalExonerated: 40, TotalUnaffected: 25, TotalIGV: 36}
+	if totals := summary.Totals(); totals != want {
+		t.Errorf("Totals() = %+v, want %+v", totals, want)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if _, err := client.GenerateSummaryDocumentsXML(summary); err != nil {
+		t.Errorf("GenerateSummaryDocumentsXML() error = %v", err)
+	}
+}
+
+func TestBuildSummaryFromBoletas_SameDay(t *testing.T) {
+	referenceDate := time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC)
+	issueDate := time.Date(2026, 4, 28, 9, 0, 0, 0, time.UTC)
+
+	boleta := *newTestInvoice()
+	boleta.DocumentType = "03"
+	boleta.Series = "B001"
+	boleta.Customer = InvoiceParty{DocumentType: "1", DocumentNumber: "12345678", Name: "JUAN PEREZ"}
+
+	first, err := BuildSummaryFromBoletas([]Invoice{boleta}, referenceDate, issueDate, 1)
+	if err != nil {
+		t.Fatalf("BuildSummaryFromBoletas() first error = %v", err)
+	}
+	boleta.Number = "2"
+	second, err := BuildSummaryFromBoletas([]Invoice{boleta}, referenceDate, issueDate.Add(2*time.Hour), 2)
+	if err != nil {
+		t.Fatalf("BuildSummaryFromBoletas() second error = %v", err)
+	}
+
+	if first.SeriesNumber != "RC-20260428-001" || second.SeriesNumber != "RC-20260428-002" {
+		t.Errorf("SeriesNumber = %s and %s, want RC-20260428-001 and RC-20260428-002", first.SeriesNumber, second.SeriesNumber)
+	}
+}
+
+func TestBuildSummaryFromBoletas_Invalid(t *testing.T) {
+	referenceDate := time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC)
+
+	factura := *newTestInvoice()
+	if _, err := BuildSummaryFromBoletas([]Invoice{factura}, referenceDate, referenceDate, 1); err == nil || !contains(err.Error(), "is not a boleta") {
+		t.Errorf("error = %v, want an error for a factura", err)
+	}
+
+	boleta := *newTestInvoice()
+	boleta.DocumentType = "03"
+	boleta.Series = "B001"
+	if _, err := BuildSummaryFromBoletas([]Invoice{boleta}, referenceDate.AddDate(0, 0, 1), referenceDate.AddDate(0, 0, 1), 1); err == nil || !contains(err.Error(), "reference date") {
+		t.Errorf("error = %v, want an error for a boleta of another day", err)
+	}
+
+	discounted := boleta
+	discounted.TotalDiscounts = 8
+	discounted.TotalAmount = 110
+	if _, err := BuildSummaryFromBoletas([]Invoice{discounted}, referenceDate, referenceDate, 1); err == nil || !contains(err.Error(), "cannot be summarized") {
+		t.Errorf("error = %v, want an error for a boleta with global discounts", err)
+	}
+
+	if _, err := BuildSummaryFromBoletas(nil, referenceDate, referenceDate, 1); err == nil {
+		t.Error("expected an error without boletas")
+	}
+
+	if _, err := BuildSummaryFromBoletas([]Invoice{boleta}, referenceDate, referenceDate, 0); err == nil || !contains(err.Error(), "sequence") {
+		t.Errorf("error = %v, want an error for sequence 0", err)
+	}
+}
@@ -1,15 +1,22 @@
 // Package sunatlib provides XML digital signature functionality for SUNAT Peru
+//
+// The XML generators (GenerateInvoiceXML, GenerateVoidedDocumentsXML,
+// GenerateSummaryDocumentsXML and gre.GenerateXML) are deterministic: identical input always
+// produces byte-identical XML
 package sunatlib
 
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/henrybravos/sunatlib/signer"
 	"github.com/henrybravos/sunatlib/utils"
@@ -17,14 +24,38 @@ import (
 
 // SUNATClient handles interactions with SUNAT web services for electronic billing
 type SUNATClient struct {
-	RUC      string
+	RUC      string // RUC of the document issuer, used in file names
+	SenderRUC string // RUC of the transmitting party (OSE/PSE) used in the UsernameToken (empty uses RUC)
 	Username string
 	Password string
 	Endpoint string
+	MaxResponseSize int64 // Maximum response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+	HTTPClient *http.Client // HTTP client used for SUNAT requests (nil uses http.DefaultClient)
+	MaxConcurrentSends int // Maximum concurrent sends in SendToSUNATStream (0 uses DefaultMaxConcurrentSends)
+	StatusRetry *RetryPolicy // Retry policy for getStatus queries (nil uses DefaultStatusRetryPolicy)
+	TempDir string // Directory for PFX extraction and signer working files (empty uses os.TempDir()); set before the certificate
+	TreatAlreadyVoidedAsSuccess bool // Report voids of already voided documents as successful (AlreadyVoided) instead of a fault
+	PreSubmitSchemaCheck bool // Also run the UBL structural validation (ValidateUBL) in PreSubmitCheck
+	CheckEncoding bool // Reject documents whose bytes do not match their declared encoding (utils.CheckXMLEncoding) before signing
+	Language Language // Language of library-generated messages (empty uses LanguageSpanish); SUNAT faults are kept as returned
+	Clock Clock // Source of time for polling, retry backoff and certificate checks (nil uses SystemClock)
 	signer   *signer.XMLSigner
 	validator *UBLValidator
+	endpoints map[ServiceType]string
+	rucService *RUCService
+	metrics  clientMetrics
+	extraHeaders map[string]string
+	credentialSets map[string]CredentialSet
+	certificates *CertificateRegistry
+	pfxDirs []string
 }
 
+// ErrResponseTooLarge is returned when a service response exceeds the maximum body size
+var ErrResponseTooLarge = utils.ErrResponseTooLarge
+
+// ErrCertificatePinMismatch is returned when SUNAT's server certificate does not match the configured pins
+var ErrCertificatePinMismatch = utils.ErrCertificatePinMismatch
+
 // NewSUNATClient creates a new SUNAT client for electronic billing
 func NewSUNATClient(ruc, username, password, endpoint string) *SUNATClient {
 	return &SUNATClient{
@@ -36,23 +67,120 @@ func NewSUNATClient(ruc, username, password, endpoint string) *SUNATClient {
 	}
 }
 
+// SetEndpoints overrides the endpoint used for specific services (e.g., OSE endpoints).
+// Services without an override keep using Endpoint
+func (c *SUNATClient) SetEndpoints(endpoints map[ServiceType]string) {
+	c.endpoints = make(map[ServiceType]string, len(endpoints))
+	for service, endpoint := range endpoints {
+		c.endpoints[service] = endpoint
+	}
+}
+
+// endpointFor returns the endpoint configured for a service, falling back to Endpoint
+func (c *SUNATClient) endpointFor(service ServiceType) string {
+	if endpoint, ok := c.endpoints[service]; ok && endpoint != "" {
+		return endpoint
+	}
+	return c.Endpoint
+}
+
+// SetCertificatePEM configures the XML signer with a PEM encoded RSA private key and
+// certificate. It uses the in-memory signer (signer.NewInMemorySignerFromPEM), so the key is
+// never written to disk and xmlsec1 is not required
+func (c *SUNATClient) SetCertificatePEM(keyPEM, certPEM []byte) error {
+	var err error
+	c.signer, err = signer.NewInMemorySignerFromPEM(keyPEM, certPEM)
+	return err
+}
+
+// SetInMemoryCertificate configures a pure Go signer (signer.NewInMemorySigner) with an already
+// parsed key and certificate, so signing does not require xmlsec1 nor writes key files
+func (c *SUNATClient) SetInMemoryCertificate(key *rsa.PrivateKey, cert *x509.Certificate) error {
+	var err error
+	c.signer, err = signer.NewInMemorySigner(key, cert)
+	return err
+}
+
+// SetCanonicalizationMethod sets the signature canonicalization algorithm (see signer.C14N and
+// signer.ExclusiveC14N). The certificate must be configured first
+func (c *SUNATClient) SetCanonicalizationMethod(algorithm string) error {
+	if c.signer == nil {
+		return fmt.Errorf("certificate not configured - use SetCertificate() first")
+	}
+	return c.signer.SetCanonicalizationMethod(algorithm)
+}
+
+// SetSignatureAlgorithm sets the signature algorithm (signer.SignatureSHA1 or
+// signer.SignatureSHA256). The certificate must be configured first
+func (c *SUNATClient) SetSignatureAlgorithm(algorithm signer.SignatureAlgorithm) error {
+	if c.signer == nil {
+		return fmt.Errorf("certificate not configured - use SetCertificate() first")
+	}
+	return c.signer.SetSignatureAlgorithm(algorithm)
+}
+
+// SetCertificatePins enables certificate pinning: the server chain is verified against roots
+// (the system pool when nil) and must contain a certificate whose SHA-256 fingerprint is in pins.
+// Connections that do not match fail with an error wrapping ErrCertificatePinMismatch.
+// The HTTP client's timeouts and transport settings are preserved
+func (c *SUNATClient) SetCertificatePins(roots *x509.CertPool, pins ...string) {
+	c.HTTPClient = utils.WithCertificatePins(c.HTTPClient, roots, pins...)
+}
+
+// SetTransportTimeouts configures the connection, TLS handshake and response header timeouts
+// of the HTTP client, independently of its total timeout. Certificate pins are preserved
+func (c *SUNATClient) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	c.HTTPClient = utils.WithTransportTimeouts(c.HTTPClient, timeouts)
+}
+
+// SetExtraHeaders sets headers added to every SOAP request (e.g., API keys or tenant IDs required
+// by an OSE gateway or proxy). They never replace Content-Type or SOAPAction
+func (c *SUNATClient) SetExtraHeaders(headers map[string]string) {
+	c.extraHeaders = utils.CopyHeaders(headers)
+}
+
+// httpClient returns the HTTP client used for SUNAT requests
+func (c *SUNATClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
 // SetCertificate configures the XML signer with certificate files
 func (c *SUNATClient) SetCertificate(privateKeyPath, certificatePath string) error {
 	var err error
-	c.signer, err = signer.NewXMLSigner(privateKeyPath, certificatePath)
+	c.signer, err = signer.NewXMLSignerInDir(privateKeyPath, certificatePath, c.TempDir)
 	return err
 }
 
-// SetCertificateFromPFX extracts and configures certificate from PFX file
+// SetCertificateFromPFX extracts and configures certificate from PFX file.
+// The PEM files are written with 0600 permissions to a private directory created under tempDir
+// (the client's TempDir when empty) and removed by Cleanup
 func (c *SUNATClient) SetCertificateFromPFX(pfxPath, password, tempDir string) error {
+	if tempDir == "" {
+		tempDir = c.TempDir
+	}
+
+	pfxDir, err := os.MkdirTemp(tempDir, "sunatlib-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
 	// Extract PEM files from PFX
-	privateKeyPath, certPath, err := utils.ExtractPEMFromPFX(pfxPath, password, tempDir)
+	privateKeyPath, certPath, err := utils.ExtractPEMFromPFX(pfxPath, password, pfxDir)
 	if err != nil {
+		os.RemoveAll(pfxDir)
 		return fmt.Errorf("failed to extract PEM from PFX: %w", err)
 	}
 
 	// Set up signer
-	return c.SetCertificate(privateKeyPath, certPath)
+	if err := c.SetCertificate(privateKeyPath, certPath); err != nil {
+		os.RemoveAll(pfxDir)
+		return err
+	}
+	c.pfxDirs = append(c.pfxDirs, pfxDir)
+	return nil
 }
 
 // SignXML signs an XML document and returns the signed XML
@@ -60,10 +188,23 @@ func (c *SUNATClient) SignXML(xmlContent []byte) ([]byte, error) {
 	if c.signer == nil {
 		return nil, fmt.Errorf("certificate not configured - use SetCertificate() first")
 	}
+	return c.signWith(c.signer, xmlContent)
+}
 
-	// Check xmlsec1 availability
-	if err := utils.CheckXMLSec1Available(); err != nil {
-		return nil, err
+// signWith validates an XML document and signs it with the given signer
+func (c *SUNATClient) signWith(xmlSigner *signer.XMLSigner, xmlContent []byte) ([]byte, error) {
+	// Check xmlsec1 availability; in-memory signers do not need it
+	if !xmlSigner.InMemory() {
+		if err := utils.CheckXMLSec1Available(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Content in a different encoding than declared signs fine but fails SUNAT's digest check
+	if c.CheckEncoding {
+		if err := utils.CheckXMLEncoding(xmlContent); err != nil {
+			return nil, err
+		}
 	}
 
 	// Robust Structural Validation: Error 3105 prevention and more
@@ -72,7 +213,7 @@ func (c *SUNATClient) SignXML(xmlContent []byte) ([]byte, error) {
 	}
 
 	// Sign the XML
-	signedXML, err := c.signer.SignXML(xmlContent)
+	signedXML, err := xmlSigner.SignXML(xmlContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign XML: %w", err)
 	}
@@ -87,7 +228,12 @@ func (c *SUNATClient) ValidateUBL(xmlContent []byte) error {
 
 // SendToSUNAT sends a signed XML document to SUNAT
 func (c *SUNATClient) SendToSUNAT(signedXML []byte, documentType, seriesNumber string) (*SUNATResponse, error) {
-	return c.sendToSUNAT(signedXML, documentType, seriesNumber)
+	return c.SendToSUNATContext(context.Background(), signedXML, documentType, seriesNumber)
+}
+
+// SendToSUNATContext is like SendToSUNAT, cancelling the request when ctx is done
+func (c *SUNATClient) SendToSUNATContext(ctx context.Context, signedXML []byte, documentType, seriesNumber string) (*SUNATResponse, error) {
+	return c.sendToSUNAT(ctx, signedXML, documentType, seriesNumber)
 }
 
 // SignAndSendInvoice signs an XML invoice and sends it to SUNAT (convenience method)
@@ -103,18 +249,54 @@ func (c *SUNATClient) SignAndSendInvoice(xmlContent []byte, documentType, series
 }
 
 // sendToSUNAT handles the SOAP communication with SUNAT
-func (c *SUNATClient) sendToSUNAT(signedXML []byte, documentType, seriesNumber string) (*SUNATResponse, error) {
+func (c *SUNATClient) sendToSUNAT(ctx context.Context, signedXML []byte, documentType, seriesNumber string) (response *SUNATResponse, err error) {
+	attempted := false
+	defer func() {
+		if attempted {
+			c.metrics.recordSend(err == nil && response != nil && response.Success)
+		}
+	}()
+
 	// Create ZIP file
 	zipData, zipName, err := c.createZIP(signedXML, documentType, seriesNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ZIP: %w", err)
 	}
 
-	// Encode to base64
+	// Build SOAP envelope
+	soapBody := c.buildSendBillEnvelope(zipName, zipData)
+
+	// Send HTTP request
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpointFor(ServiceBill), bytes.NewBuffer([]byte(soapBody)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", "")
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
+
+	attempted = true
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, c.redactError(fmt.Errorf("failed to send HTTP request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	responseData, err := readResponseBody(resp, c.MaxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return c.parseResponse("sendBill", responseData)
+}
+
+// buildSendBillEnvelope returns the sendBill SOAP envelope for a ZIP package. The envelope
+// carries the SOL credentials
+func (c *SUNATClient) buildSendBillEnvelope(zipName string, zipData []byte) string {
 	zipB64 := base64.StdEncoding.EncodeToString(zipData)
 
-	// Build SOAP envelope
-	soapBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
 <soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.sunat.gob.pe" xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
   <soapenv:Header>
     <wsse:Security>
@@ -130,35 +312,81 @@ func (c *SUNATClient) sendToSUNAT(signedXML []byte, documentType, seriesNumber s
       <contentFile>%s</contentFile>
     </ser:sendBill>
   </soapenv:Body>
-</soapenv:Envelope>`, c.RUC, c.Username, c.Password, zipName, zipB64)
+</soapenv:Envelope>`, c.senderRUC(), c.Username, c.Password, zipName, zipB64)
+}
 
-	// Send HTTP request
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
+// SignAndBuild signs an XML document and packages it exactly as SignAndSendInvoice would,
+// returning the signed XML, the ZIP, its file name and the sendBill SOAP envelope instead
+// of sending them, so they can be inspected, persisted or submitted through another transport.
+// The SOAP envelope contains the SOL password
+func (c *SUNATClient) SignAndBuild(xmlContent []byte, documentType, seriesNumber string) (signedXML, zipData []byte, zipName, soap string, err error) {
+	signedXML, err = c.SignXML(xmlContent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, nil, "", "", fmt.Errorf("failed to sign XML: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	req.Header.Set("SOAPAction", "")
-
-	resp, err := http.DefaultClient.Do(req)
+	zipData, zipName, err = c.createZIP(signedXML, documentType, seriesNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, nil, "", "", fmt.Errorf("failed to create ZIP: %w", err)
 	}
-	defer resp.Body.Close()
 
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	return signedXML, zipData, zipName, c.buildSendBillEnvelope(zipName, zipData), nil
+}
+
+// ValidateCredentials checks the format of the SOL credentials (see utils.ValidateSOLCredentials)
+// without contacting SUNAT. Call it after creating the client to catch typos early
+func (c *SUNATClient) ValidateCredentials() error {
+	return utils.ValidateSOLCredentials(c.senderRUC(), c.Username, c.Password)
+}
+
+// senderRUC returns the RUC that authenticates the SOAP requests
+func (c *SUNATClient) senderRUC() string {
+	if c.SenderRUC != "" {
+		return c.SenderRUC
 	}
+	return c.RUC
+}
+
+// redact removes the SOL password from text that may embed a SOAP envelope
+func (c *SUNATClient) redact(s string) string {
+	return utils.Redact(s, c.Password)
+}
+
+// redactError removes the SOL password from an error message, keeping the error chain
+func (c *SUNATClient) redactError(err error) error {
+	return utils.RedactError(err, c.Password)
+}
 
-	return c.parseResponse(responseData)
+// readResponseBody reads an HTTP response body guarding against oversized responses
+func readResponseBody(resp *http.Response, maxBytes int64) ([]byte, error) {
+	return utils.ReadLimitedBody(resp.Body, maxBytes)
+}
+
+// BuildDocumentName returns the file name, without extension, of a document: the issuer RUC,
+// the document type and the series and number (e.g., 20123456789-01-F001-1). SUNAT requires the
+// issuer RUC even when the document is transmitted by an OSE or PSE with its own RUC
+func BuildDocumentName(issuerRUC, documentType, seriesNumber string) string {
+	return fmt.Sprintf("%s-%s-%s", issuerRUC, documentType, seriesNumber)
 }
 
 // createZIP creates a ZIP file with the signed XML
 func (c *SUNATClient) createZIP(signedXML []byte, documentType, seriesNumber string) ([]byte, string, error) {
-	xmlName := fmt.Sprintf("%s-%s-%s.xml", c.RUC, documentType, seriesNumber)
-	zipName := fmt.Sprintf("%s-%s-%s.zip", c.RUC, documentType, seriesNumber)
+	if err := ValidateDocumentSize(signedXML); err != nil {
+		return nil, "", err
+	}
+
+	// Invoices, boletas and notes are named after their SERIE-NUMERO identifier, normalized when
+	// it can be split; other values are used as given
+	switch documentType {
+	case "01", "03", "07", "08":
+		if serie, numero, err := utils.SplitSerieNumero(seriesNumber); err == nil {
+			seriesNumber = utils.JoinSerieNumero(serie, numero)
+		}
+	}
+
+	documentName := BuildDocumentName(c.RUC, documentType, seriesNumber)
+	xmlName := documentName + ".xml"
+	zipName := documentName + ".zip"
 
 	buf := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buf)
@@ -174,6 +402,9 @@ func (c *SUNATClient) createZIP(signedXML []byte, documentType, seriesNumber str
 
 	zipWriter.Close()
 
+	if err := validateZIPSize(zipName, buf.Bytes()); err != nil {
+		return nil, "", err
+	}
 	return buf.Bytes(), zipName, nil
 }
 
@@ -183,14 +414,29 @@ type SUNATResponse struct {
 	Message          string
 	ResponseXML      []byte
 	ApplicationResponse []byte
-	Error            error
+	Unrecognized     bool // True when the response could not be interpreted; see ResponseXML
+	Attempts         int  // Number of getStatus requests made (set by status queries, which are retried)
+	FaultCode        string // SUNAT error code of a SOAP fault (e.g., "0102"), empty otherwise
+	FaultDetail      string // Text of the fault's <detail> element, often holding the actual error, if any
+	Error            error  // Typed error of the fault, if any (e.g., ErrCredentialLocked or ErrCertificateRejectedBySUNAT)
+	Operation        string    // SOAP operation that returned the response (sendBill or getStatus)
+	ReceivedAt       time.Time // When the response was received, on the client clock
+
+	applicationResponseB64 string // Base64 CDR as received, streamed to disk by SaveApplicationResponse
 }
 
-// parseResponse parses SUNAT's SOAP response
-func (c *SUNATClient) parseResponse(responseData []byte) (*SUNATResponse, error) {
+// DebugString returns a truncated, credential-free dump of the response for support reports
+func (r *SUNATResponse) DebugString() string {
+	return debugDump("SUNATResponse", r.Success, r.Unrecognized, r.Message, r.ResponseXML)
+}
+
+// parseResponse parses SUNAT's SOAP response to operation
+func (c *SUNATClient) parseResponse(operation string, responseData []byte) (*SUNATResponse, error) {
 	responseStr := string(responseData)
 	response := &SUNATResponse{
 		ResponseXML: responseData,
+		Operation:   operation,
+		ReceivedAt:  c.clock().Now(),
 	}
 
 	// Check for SOAP fault
@@ -201,11 +447,14 @@ func (c *SUNATClient) parseResponse(responseData []byte) (*SUNATResponse, error)
 		if start := strings.Index(responseStr, "<faultstring>"); start != -1 {
 			start += 13
 			if end := strings.Index(responseStr[start:], "</faultstring>"); end != -1 {
-				response.Message = responseStr[start : start+end]
+				response.Message = c.redact(responseStr[start : start+end])
 				// Decode HTML entities
 				response.Message = strings.ReplaceAll(response.Message, "&#243;", "ó")
 			}
 		}
+		response.FaultCode = soapFaultCode(responseStr)
+		response.FaultDetail = soapFaultDetail(responseStr)
+		response.Error = faultError(response.FaultCode, response.Message)
 		
 		return response, nil
 	}
@@ -213,7 +462,7 @@ func (c *SUNATClient) parseResponse(responseData []byte) (*SUNATResponse, error)
 	// Check for successful response
 	if strings.Contains(responseStr, "<br:sendBillResponse") {
 		response.Success = true
-		response.Message = "Documento enviado exitosamente"
+		response.Message = c.Language.message(msgDocumentSent)
 
 		// Extract application response (base64 encoded ZIP)
 		if start := strings.Index(responseStr, "<applicationResponse>"); start != -1 {
@@ -223,6 +472,7 @@ func (c *SUNATClient) parseResponse(responseData []byte) (*SUNATResponse, error)
 				appResponse, err := base64.StdEncoding.DecodeString(b64Data)
 				if err == nil {
 					response.ApplicationResponse = appResponse
+					response.applicationResponseB64 = b64Data
 				}
 			}
 		}
@@ -231,20 +481,35 @@ func (c *SUNATClient) parseResponse(responseData []byte) (*SUNATResponse, error)
 	}
 
 	response.Success = false
-	response.Message = "Respuesta no reconocida de SUNAT"
+	response.Unrecognized = true
+	response.Message = c.Language.message(msgUnrecognizedResponse)
 	return response, nil
 }
 
 // Cleanup cleans up temporary files
 func (c *SUNATClient) Cleanup() error {
+	var err error
 	if c.signer != nil {
-		return c.signer.Cleanup()
+		err = c.signer.Cleanup()
 	}
-	return nil
+
+	// PEM files extracted by SetCertificateFromPFX
+	for _, dir := range c.pfxDirs {
+		if removeErr := os.RemoveAll(dir); removeErr != nil && err == nil {
+			err = fmt.Errorf("failed to remove extracted certificate: %w", removeErr)
+		}
+	}
+	c.pfxDirs = nil
+
+	return err
 }
 
-// SaveApplicationResponse saves the CDR (Constancia de Recepción) to a file
+// SaveApplicationResponse saves the CDR (Constancia de Recepción) to a file. A CDR received
+// from SUNAT is streamed from its base64 text with DecodeCDRTo
 func (r *SUNATResponse) SaveApplicationResponse(outputPath string) error {
+	if r.applicationResponseB64 != "" {
+		return SaveCDRBase64(outputPath, r.applicationResponseB64)
+	}
 	if r.ApplicationResponse == nil {
 		return fmt.Errorf("no application response data available")
 	}
@@ -0,0 +1,656 @@
+package sunatlib
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+func TestSendToSUNAT_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("A", 2048)))
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	client.MaxResponseSize = 1024
+
+	_, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestSetEndpoints(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString([]byte(testCDRContents))
+	summaryServer := newSOAPTestServer(t, map[string]func() string{
+		"sendSummary": func() string { return sendSummaryResponse(testTicket) },
+	})
+	defer summaryServer.Close()
+	statusServer := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("0", cdr) },
+	})
+	defer statusServer.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:1/unused")
+	client.SetEndpoints(map[ServiceType]string{
+		ServiceSummary: summaryServer.URL,
+		ServiceStatus:  statusServer.URL,
+	})
+
+	status, err := client.VoidAndWait(newTestVoidedDocumentsRequest(), time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("VoidAndWait() error = %v", err)
+	}
+	if !status.IsSuccessful() {
+		t.Errorf("Expected successful status, got %s", status.StatusCode)
+	}
+
+	if endpoint := client.endpointFor(ServiceBill); endpoint != client.Endpoint {
+		t.Errorf("Expected bill service to fall back to %s, got %s", client.Endpoint, endpoint)
+	}
+}
+
+func TestValidationClient_SetEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap-env:Envelope><soap-env:Body><statusCode>0</statusCode><statusMessage>El comprobante F001-1 es un comprobante de pago válido</statusMessage></soap-env:Body></soap-env:Envelope>`))
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+
+	result, err := client.ValidateInvoice(testRUC, "F001", "1", "2026-04-27", 118)
+	if err != nil {
+		t.Fatalf("ValidateInvoice() error = %v", err)
+	}
+	if result.State != "VALIDO" {
+		t.Errorf("Expected state VALIDO, got %s", result.State)
+	}
+}
+
+func TestSendToSUNAT_RedactsPasswordInFault(t *testing.T) {
+	const password = "S3cr3t&Clave"
+
+	// Some OSE gateways echo the offending request envelope back in the fault string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, soapFaultResponse("0109", "Invalid request: "+html.EscapeString(string(body))))
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", password, server.URL)
+
+	response, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+
+	if response.Success {
+		t.Fatal("Expected SOAP fault response")
+	}
+	if !strings.Contains(response.Message, "wsse:Password") {
+		t.Fatalf("Expected fault message to embed the envelope, got %s", response.Message)
+	}
+	if strings.Contains(response.Message, "S3cr3t") {
+		t.Errorf("Fault message leaks the password: %s", response.Message)
+	}
+	if !strings.Contains(response.Message, utils.RedactedValue) {
+		t.Errorf("Expected redacted placeholder in fault message, got %s", response.Message)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	envelope := "<wsse:Username>20100070970MODDATOS</wsse:Username><wsse:Password>S3cr3t</wsse:Password>"
+	err := utils.RedactError(fmt.Errorf("SOAP request failed: %s: %w", envelope, ErrResponseTooLarge), "S3cr3t")
+
+	if strings.Contains(err.Error(), "S3cr3t") {
+		t.Errorf("RedactError() leaks the password: %v", err)
+	}
+	if !strings.Contains(err.Error(), "<wsse:Password>***</wsse:Password>") {
+		t.Errorf("RedactError() = %v, want redacted password element", err)
+	}
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Error("RedactError() should preserve the wrapped error chain")
+	}
+}
+
+func TestSetCertificatePins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, soapFaultResponse("0111", "No tiene el perfil para enviar comprobantes electronicos"))
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	t.Run("Mismatch", func(t *testing.T) {
+		client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+		client.SetCertificatePins(roots, strings.Repeat("ab", 32))
+
+		_, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+		if !errors.Is(err, ErrCertificatePinMismatch) {
+			t.Fatalf("Expected ErrCertificatePinMismatch, got %v", err)
+		}
+
+		var pinErr *utils.CertificatePinError
+		if !errors.As(err, &pinErr) {
+			t.Fatalf("Expected *utils.CertificatePinError, got %T", err)
+		}
+		if len(pinErr.Fingerprints) == 0 || pinErr.Fingerprints[0] != utils.CertificateFingerprint(server.Certificate()) {
+			t.Errorf("Expected presented fingerprint of the test server, got %v", pinErr.Fingerprints)
+		}
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+		client.SetCertificatePins(roots, utils.CertificateFingerprint(server.Certificate()))
+
+		response, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+		if err != nil {
+			t.Fatalf("SendToSUNAT() error = %v", err)
+		}
+		if response.Success {
+			t.Error("Expected the SOAP fault from the pinned server")
+		}
+	})
+
+	t.Run("Keeps Transport Timeouts", func(t *testing.T) {
+		client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+		client.HTTPClient = &http.Client{Timeout: 7 * time.Second}
+		client.SetTransportTimeouts(utils.TransportTimeouts{ResponseHeaderTimeout: 5 * time.Second})
+		client.SetCertificatePins(roots, strings.Repeat("ab", 32))
+
+		transport, ok := client.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("Expected *http.Transport, got %T", client.HTTPClient.Transport)
+		}
+		if transport.ResponseHeaderTimeout != 5*time.Second || client.HTTPClient.Timeout != 7*time.Second {
+			t.Errorf("Expected timeouts to be preserved, got ResponseHeaderTimeout %v and Timeout %v",
+				transport.ResponseHeaderTimeout, client.HTTPClient.Timeout)
+		}
+		if _, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1"); !errors.Is(err, ErrCertificatePinMismatch) {
+			t.Errorf("Expected ErrCertificatePinMismatch, got %v", err)
+		}
+
+		// Setting the timeouts afterwards keeps the pins
+		client.SetTransportTimeouts(utils.TransportTimeouts{ResponseHeaderTimeout: time.Second})
+		if _, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1"); !errors.Is(err, ErrCertificatePinMismatch) {
+			t.Errorf("Expected pins to survive SetTransportTimeouts, got %v", err)
+		}
+	})
+}
+
+func TestSetTransportTimeouts_TLSHandshake(t *testing.T) {
+	// A server that accepts TCP connections but never answers the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	var conns []net.Conn
+	var mu sync.Mutex
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "https://"+listener.Addr().String())
+	client.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	client.SetTransportTimeouts(utils.TransportTimeouts{TLSHandshakeTimeout: 100 * time.Millisecond})
+
+	if client.HTTPClient.Timeout != 30*time.Second {
+		t.Errorf("Expected the total timeout to be preserved, got %v", client.HTTPClient.Timeout)
+	}
+
+	start := time.Now()
+	_, err = client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "TLS handshake timeout") {
+		t.Fatalf("Expected TLS handshake timeout error, got %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Expected the handshake timeout to trip before the total timeout, took %v", elapsed)
+	}
+}
+
+func TestSendToSUNAT_UnrecognizedResponse(t *testing.T) {
+	const password = "S3cr3tClave"
+
+	// A misbehaving proxy answering with an unexpected body that echoes the request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, "<gateway><request>"+string(body)+"</request></gateway>")
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", password, server.URL)
+
+	response, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+
+	if !response.Unrecognized {
+		t.Error("Expected Unrecognized to be set for an unknown response")
+	}
+	if !strings.Contains(string(response.ResponseXML), "<gateway>") {
+		t.Error("Expected the raw body to be available in ResponseXML")
+	}
+
+	dump := response.DebugString()
+	if strings.Contains(dump, password) {
+		t.Errorf("DebugString() leaks the password: %s", dump)
+	}
+	if !strings.Contains(dump, "<wsse:Password>"+utils.RedactedValue+"</wsse:Password>") {
+		t.Errorf("DebugString() should keep the envelope with the password redacted: %s", dump)
+	}
+}
+
+func TestSUNATResponse_DebugStringTruncates(t *testing.T) {
+	response := &SUNATResponse{ResponseXML: []byte(strings.Repeat("A", maxDebugBodySize+100))}
+
+	dump := response.DebugString()
+	if !strings.Contains(dump, "[truncated 100 bytes]") {
+		t.Errorf("DebugString() should truncate long bodies, got %d bytes", len(dump))
+	}
+}
+
+func TestCreateZIP_SerieNumero(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+
+	tests := []struct {
+		seriesNumber string
+		wantZIP      string
+	}{
+		{" f001-00000123 ", testRUC + "-01-F001-00000123.zip"},
+		// Values that cannot be split are passed through unchanged
+		{"F001", testRUC + "-01-F001.zip"},
+	}
+
+	for _, tt := range tests {
+		_, zipName, err := client.createZIP([]byte("<Invoice/>"), "01", tt.seriesNumber)
+		if err != nil {
+			t.Fatalf("createZIP(%q) error = %v", tt.seriesNumber, err)
+		}
+		if zipName != tt.wantZIP {
+			t.Errorf("createZIP(%q) name = %s, want %s", tt.seriesNumber, zipName, tt.wantZIP)
+		}
+	}
+}
+
+// newTestSigningClient returns a client with a throwaway certificate and an xmlsec1 stand-in on PATH
+func newTestSigningClient(t *testing.T) *SUNATClient {
+	t.Helper()
+	installTestXMLSec1(t)
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if err := client.SetCertificatePEM(keyPEM, certPEM); err != nil {
+		t.Fatalf("SetCertificatePEM() error = %v", err)
+	}
+	t.Cleanup(func() { client.Cleanup() })
+	return client
+}
+
+// installTestXMLSec1 puts on PATH an xmlsec1 stand-in that fills the signature template with
+// base64 placeholder values
+func installTestXMLSec1(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --output) output="$2"; shift ;;
+  esac
+  shift
+done
+sed -e 's|<ds:DigestValue/>|<ds:DigestValue>ZGlnZXN0</ds:DigestValue>|' \
+    -e 's|<ds:SignatureValue/>|<ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>|' \
+    -e 's|<ds:X509Certificate/>|<ds:X509Certificate>Y2VydGlmaWNhdGU=</ds:X509Certificate>|' "$1" > "$output"
+echo "Signature status: OK"
+`
+	if err := os.WriteFile(filepath.Join(dir, "xmlsec1"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake xmlsec1: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// newTestPEMKeyPair returns a PEM encoded RSA private key and self-signed certificate
+func newTestPEMKeyPair(t *testing.T) (keyPEM, certPEM []byte) {
+	t.Helper()
+	certificate := testcert.New(t, pkix.Name{CommonName: testRUC + " EMPRESA DE PRUEBA"}, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), nil)
+	return certificate.KeyPEM(), certificate.CertPEM()
+}
+
+func TestSignAndBuild(t *testing.T) {
+	client := newTestSigningClient(t)
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+
+	signedXML, zipData, zipName, soap, err := client.SignAndBuild(xmlContent, "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SignAndBuild() error = %v", err)
+	}
+
+	if want := testRUC + "-01-F001-1.zip"; zipName != want {
+		t.Errorf("zipName = %q, want %q", zipName, want)
+	}
+
+	_, packaged, err := utils.ExtractXMLFromZip(zipData)
+	if err != nil {
+		t.Fatalf("ExtractXMLFromZip() error = %v", err)
+	}
+	if !bytes.Equal(packaged, signedXML) {
+		t.Error("Expected the ZIP to contain the returned signed XML")
+	}
+	if err := signer.PostSignValidate(packaged); err != nil {
+		t.Errorf("PostSignValidate() error = %v", err)
+	}
+
+	for _, want := range []string{"<ser:sendBill>", "<fileName>" + zipName + "</fileName>", base64.StdEncoding.EncodeToString(zipData)} {
+		if !strings.Contains(soap, want) {
+			t.Errorf("Expected SOAP envelope to contain %q", want)
+		}
+	}
+}
+
+func TestSignXML_CheckEncoding(t *testing.T) {
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if err := client.SetCertificatePEM(keyPEM, certPEM); err != nil {
+		t.Fatalf("SetCertificatePEM() error = %v", err)
+	}
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	if _, err := client.SignXML(xmlContent); err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+
+	// UTF-8 bytes labelled as ISO-8859-1
+	mislabelled := bytes.Replace(xmlContent, []byte(`encoding="UTF-8"`), []byte(`encoding="ISO-8859-1"`), 1)
+	mislabelled = bytes.Replace(mislabelled, []byte("CLIENTE S.A."), []byte("CLIENTE ÑANDÚ S.A."), 1)
+
+	// The check is opt-in
+	if _, err := client.SignXML(mislabelled); errors.Is(err, utils.ErrEncodingMismatch) {
+		t.Errorf("SignXML() error = %v, want no encoding check without CheckEncoding", err)
+	}
+
+	client.CheckEncoding = true
+	if _, err := client.SignXML(mislabelled); !errors.Is(err, utils.ErrEncodingMismatch) {
+		t.Errorf("SignXML() error = %v, want utils.ErrEncodingMismatch", err)
+	}
+	if _, err := client.SignXML(xmlContent); err != nil {
+		t.Errorf("SignXML() error = %v for a UTF-8 document with CheckEncoding", err)
+	}
+}
+
+func TestSetCertificatePEM_NoKeyOnDisk(t *testing.T) {
+	// Without xmlsec1 on PATH signing only works with the in-memory signer
+	t.Setenv("PATH", t.TempDir())
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+
+	tempDir := t.TempDir()
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	client.TempDir = tempDir
+	if err := client.SetCertificatePEM(keyPEM, certPEM); err != nil {
+		t.Fatalf("SetCertificatePEM() error = %v", err)
+	}
+	defer client.Cleanup()
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	signedXML, err := client.SignXML(xmlContent)
+	if err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+	if err := signer.PostSignValidate(signedXML); err != nil {
+		t.Errorf("PostSignValidate() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected nothing written under TempDir, got %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestSetInMemoryCertificate(t *testing.T) {
+	// Without xmlsec1 on PATH signing only works with the in-memory signer
+	t.Setenv("PATH", t.TempDir())
+
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+	keyBlock, _ := pem.Decode(keyPEM)
+	certBlock, _ := pem.Decode(certPEM)
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		t.Fatalf("ParsePKCS1PrivateKey() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	if err := client.SetInMemoryCertificate(key, cert); err != nil {
+		t.Fatalf("SetInMemoryCertificate() error = %v", err)
+	}
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	signedXML, err := client.SignXML(xmlContent)
+	if err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+	if err := signer.PostSignValidate(signedXML); err != nil {
+		t.Errorf("PostSignValidate() error = %v", err)
+	}
+
+	embedded, err := signer.EmbeddedCertificate(signedXML)
+	if err != nil {
+		t.Fatalf("EmbeddedCertificate() error = %v", err)
+	}
+	if !embedded.Equal(cert) {
+		t.Error("Expected the configured certificate in ds:X509Certificate")
+	}
+}
+
+func TestSetExtraHeaders(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Write([]byte(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:sendBillResponse xmlns:br="http://service.sunat.gob.pe"><applicationResponse></applicationResponse></br:sendBillResponse></soap-env:Body></soap-env:Envelope>`))
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	extra := map[string]string{
+		"X-Api-Key":    "ose-key",
+		"X-Tenant-Id":  "tenant-1",
+		"Content-Type": "application/json",
+		"SOAPAction":   "urn:other",
+	}
+	client.SetExtraHeaders(extra)
+	extra["X-Tenant-Id"] = "changed"
+
+	if _, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1"); err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+
+	want := map[string]string{
+		"X-Api-Key":    "ose-key",
+		"X-Tenant-Id":  "tenant-1",
+		"Content-Type": "text/xml; charset=utf-8",
+		"SOAPAction":   "",
+	}
+	for name, value := range want {
+		if got := headers.Get(name); got != value {
+			t.Errorf("header %s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestSUNATClient_TempDir(t *testing.T) {
+	installTestXMLSec1(t)
+	keyPEM, certPEM := newTestPEMKeyPair(t)
+
+	keyDir := t.TempDir()
+	keyPath, certPath := filepath.Join(keyDir, "key.pem"), filepath.Join(keyDir, "cert.pem")
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	client.TempDir = tempDir
+	if err := client.SetCertificate(keyPath, certPath); err != nil {
+		t.Fatalf("SetCertificate() error = %v", err)
+	}
+	defer client.Cleanup()
+
+	xmlContent, err := GenerateInvoiceXML(newTestInvoice())
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML() error = %v", err)
+	}
+	if _, err := client.SignXML(xmlContent); err != nil {
+		t.Fatalf("SignXML() error = %v", err)
+	}
+
+	workDirs, err := filepath.Glob(filepath.Join(tempDir, "sunatlib_*"))
+	if err != nil || len(workDirs) != 1 {
+		t.Fatalf("Expected one signer directory under %s, got %v (%v)", tempDir, workDirs, err)
+	}
+	for _, name := range []string{"template.xml", "signed.xml"} {
+		if _, err := os.Stat(filepath.Join(workDirs[0], name)); err != nil {
+			t.Errorf("Expected %s under the configured TempDir: %v", name, err)
+		}
+	}
+}
+
+func TestSetCertificateFromPFX_PrivateDir(t *testing.T) {
+	pfxData, _ := newTestPFX(t, "secreto")
+	pfxPath := filepath.Join(t.TempDir(), "certificado.pfx")
+	if err := os.WriteFile(pfxPath, pfxData, 0600); err != nil {
+		t.Fatalf("failed to write PFX: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "http://127.0.0.1:0")
+	client.TempDir = tempDir
+	if err := client.SetCertificateFromPFX(pfxPath, "secreto", ""); err != nil {
+		t.Fatalf("SetCertificateFromPFX() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "private_key.pem")); !os.IsNotExist(err) {
+		t.Errorf("Expected no key directly under TempDir, Stat() error = %v", err)
+	}
+	pfxDirs, err := filepath.Glob(filepath.Join(tempDir, "sunatlib-*"))
+	if err != nil || len(pfxDirs) != 1 {
+		t.Fatalf("Expected one private extraction directory under %s, got %v (%v)", tempDir, pfxDirs, err)
+	}
+	for _, name := range []string{"private_key.pem", "certificate.pem"} {
+		info, err := os.Stat(filepath.Join(pfxDirs[0], name))
+		if err != nil {
+			t.Fatalf("Expected %s in the extraction directory: %v", name, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s permissions = %o, want 600", name, perm)
+		}
+	}
+
+	if err := client.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(pfxDirs[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected Cleanup to remove %s, Stat() error = %v", pfxDirs[0], err)
+	}
+}
+
+func TestSendToSUNAT_SenderRUC(t *testing.T) {
+	const senderRUC = "20100070970"
+
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, soapFaultResponse("0111", "No tiene el perfil para enviar comprobantes electronicos"))
+	}))
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "OSEUSER", "secret", server.URL)
+	client.SenderRUC = senderRUC
+
+	if _, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1"); err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+
+	if want := "<wsse:Username>" + senderRUC + "OSEUSER</wsse:Username>"; !strings.Contains(body, want) {
+		t.Errorf("request should authenticate with the sender RUC: missing %s", want)
+	}
+	wantName := BuildDocumentName(testRUC, "01", "F001-1") + ".zip"
+	if got := between(body, "<fileName>", "</fileName>"); got != wantName {
+		t.Errorf("fileName = %q, want %q named after the issuer RUC", got, wantName)
+	}
+
+	client.SenderRUC = ""
+	client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if want := "<wsse:Username>" + testRUC + "OSEUSER</wsse:Username>"; !strings.Contains(body, want) {
+		t.Errorf("without SenderRUC the request should authenticate with RUC: missing %s", want)
+	}
+}
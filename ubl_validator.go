@@ -5,6 +5,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"strings"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // UBLValidator handles structural validation of UBL XML documents
@@ -112,11 +114,12 @@ func (v *UBLValidator) validateHeader(ubl *MinimalUBL) error {
 
 	rootName := strings.ToLower(ubl.XMLName.Local)
 	if rootName == "despatchadvice" {
-		if ubl.UBLVersionID != "2.1" {
-			return fmt.Errorf("UBL error: GRE must use UBLVersionID 2.1")
+		version, _ := utils.GetDocumentVersion("09")
+		if ubl.UBLVersionID != version.UBLVersionID {
+			return fmt.Errorf("UBL error: GRE must use UBLVersionID %s", version.UBLVersionID)
 		}
-		if ubl.CustomizationID != "2.0" {
-			return fmt.Errorf("UBL error: GRE must use CustomizationID 2.0")
+		if ubl.CustomizationID != version.CustomizationID {
+			return fmt.Errorf("UBL error: GRE must use CustomizationID %s", version.CustomizationID)
 		}
 	}
 
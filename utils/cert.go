@@ -2,16 +2,21 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
+
 	"software.sslmate.com/src/go-pkcs12"
 )
 
-// ExtractPEMFromPFX extracts PEM private key and certificate from PFX file
+// ExtractPEMFromPFX extracts PEM private key and certificate from PFX file. The files are
+// written with 0600 permissions, so outputDir should be a private directory
 func ExtractPEMFromPFX(pfxPath, password, outputDir string) (privateKeyPath, certPath string, err error) {
 	// Read PFX file
 	pfxData, err := os.ReadFile(pfxPath)
@@ -37,7 +42,7 @@ func ExtractPEMFromPFX(pfxPath, password, outputDir string) (privateKeyPath, cer
 		return "", "", fmt.Errorf("failed to marshal private key: %w", err)
 	}
 	
-	keyFile, err := os.Create(privateKeyPath)
+	keyFile, err := os.OpenFile(privateKeyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create private key file: %w", err)
 	}
@@ -50,7 +55,7 @@ func ExtractPEMFromPFX(pfxPath, password, outputDir string) (privateKeyPath, cer
 
 	// Write certificate to PEM
 	certPath = filepath.Join(outputDir, "certificate.pem")
-	certFile, err := os.Create(certPath)
+	certFile, err := os.OpenFile(certPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create certificate file: %w", err)
 	}
@@ -64,7 +69,7 @@ func ExtractPEMFromPFX(pfxPath, password, outputDir string) (privateKeyPath, cer
 	// Write CA certificates if present
 	if len(caCerts) > 0 {
 		caPath := filepath.Join(outputDir, "ca_certificates.pem")
-		caFile, err := os.Create(caPath)
+		caFile, err := os.OpenFile(caPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err == nil {
 			defer caFile.Close()
 			for _, caCert := range caCerts {
@@ -76,6 +81,24 @@ func ExtractPEMFromPFX(pfxPath, password, outputDir string) (privateKeyPath, cer
 	return privateKeyPath, certPath, nil
 }
 
+// PEMFromPFXData decodes PFX (PKCS#12) content into a PEM private key (PKCS#8) and certificate,
+// without writing them to disk
+func PEMFromPFXData(pfxData []byte, password string) (keyPEM, certPEM []byte, err error) {
+	privateKey, cert, _, err := pkcs12.DecodeChain(pfxData, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode PFX: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	return keyPEM, certPEM, nil
+}
+
 // ValidateCertificate validates a certificate file
 func ValidateCertificate(certPath string) (*x509.Certificate, error) {
 	certData, err := os.ReadFile(certPath)
@@ -96,6 +119,79 @@ func ValidateCertificate(certPath string) (*x509.Certificate, error) {
 	return cert, nil
 }
 
+// Certificate chain verification errors
+var (
+	ErrCertificateExpired   = errors.New("certificate expired or not yet valid")
+	ErrUntrustedCertificate = errors.New("certificate not issued by a trusted CA")
+)
+
+// VerifyCertificateChain verifies that the signing certificate at certPath chains to a CA in
+// the PEM bundle at caBundlePath (e.g., the accredited RENIEC or Camerfirma roots) and that it
+// is currently valid. Self-signed certificates in the bundle are trusted as roots; the others
+// are used as intermediates
+func VerifyCertificateChain(certPath, caBundlePath string) error {
+	cert, err := ValidateCertificate(certPath)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := os.ReadFile(caBundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for rest := bundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		caCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse CA certificate: %w", err)
+		}
+		if bytes.Equal(caCert.RawIssuer, caCert.RawSubject) {
+			roots.AddCert(caCert)
+		} else {
+			intermediates.AddCert(caCert)
+		}
+	}
+
+	return VerifyCertificate(cert, roots, intermediates)
+}
+
+// VerifyCertificate verifies that cert chains to one of roots, through intermediates when
+// needed, and that it is currently valid. A nil or empty roots pool trusts nothing
+func VerifyCertificate(cert *x509.Certificate, roots, intermediates *x509.CertPool) error {
+	if roots == nil {
+		return fmt.Errorf("%w: no trusted roots given", ErrUntrustedCertificate)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: valid from %s to %s", ErrCertificateExpired,
+			cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+	}
+
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUntrustedCertificate, err)
+	}
+
+	return nil
+}
+
 // CheckXMLSec1Available checks if xmlsec1 is available in the system
 func CheckXMLSec1Available() error {
 	cmd := exec.Command("xmlsec1", "--version")
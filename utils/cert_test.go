@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/internal/testcert"
+)
+
+// writeTestPEM writes the certificates as a PEM file and returns its path
+func writeTestPEM(t *testing.T, name string, certs ...*testcert.Certificate) string {
+	t.Helper()
+
+	var data []byte
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Cert.Raw})...)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestVerifyCertificateChain(t *testing.T) {
+	notBefore, validUntil := time.Now().Add(-48*time.Hour), time.Now().Add(24*time.Hour)
+
+	root := testcert.NewCA(t, pkix.Name{CommonName: "RENIEC Root CA"}, notBefore, validUntil, nil)
+	intermediate := testcert.NewCA(t, pkix.Name{CommonName: "RENIEC Intermediate CA"}, notBefore, validUntil, root)
+	otherRoot := testcert.NewCA(t, pkix.Name{CommonName: "Untrusted Root CA"}, notBefore, validUntil, nil)
+
+	bundle := writeTestPEM(t, "ca_bundle.pem", root, intermediate)
+
+	tests := []struct {
+		name    string
+		cert    *testcert.Certificate
+		wantErr error
+	}{
+		{"Issued By Root", testcert.New(t, pkix.Name{CommonName: "EMPRESA S.A.C."}, notBefore, validUntil, root), nil},
+		{"Issued By Intermediate", testcert.New(t, pkix.Name{CommonName: "EMPRESA S.A.C."}, notBefore, validUntil, intermediate), nil},
+		{"Self Signed", testcert.New(t, pkix.Name{CommonName: "EMPRESA S.A.C."}, notBefore, validUntil, nil), ErrUntrustedCertificate},
+		{"Issued By Other CA", testcert.New(t, pkix.Name{CommonName: "EMPRESA S.A.C."}, notBefore, validUntil, otherRoot), ErrUntrustedCertificate},
+		{"Expired", testcert.New(t, pkix.Name{CommonName: "EMPRESA S.A.C."}, notBefore, time.Now().Add(-time.Hour), root), ErrCertificateExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPath := writeTestPEM(t, "certificate.pem", tt.cert)
+
+			err := VerifyCertificateChain(certPath, bundle)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyCertificateChain() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
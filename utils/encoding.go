@@ -0,0 +1,78 @@
+// Package utils provides detection of XML documents whose bytes do not match their declared encoding
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrEncodingMismatch is returned by CheckXMLEncoding when the content does not match the declared encoding
+var ErrEncodingMismatch = errors.New("XML content does not match its declared encoding")
+
+// xmlEncodingPattern finds the encoding pseudo-attribute of the XML declaration
+var xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]*?\sencoding\s*=\s*["']([^"']+)["']`)
+
+// utf8BOM is the byte order mark some editors prepend to UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DeclaredXMLEncoding returns the encoding declared in the XML declaration, in upper case,
+// or UTF-8 when there is no declaration or it does not specify one
+func DeclaredXMLEncoding(xmlContent []byte) string {
+	content := bytes.TrimPrefix(xmlContent, utf8BOM)
+	if match := xmlEncodingPattern.FindSubmatch(content); match != nil {
+		return strings.ToUpper(string(match[1]))
+	}
+	return "UTF-8"
+}
+
+// CheckXMLEncoding compares the declared encoding of an XML document against its bytes. Documents
+// declared as UTF-8 must be valid UTF-8; documents declared as ISO-8859-1 must not contain UTF-8
+// multi-byte sequences, a sign that UTF-8 text was labelled as Latin-1. A mismatch makes SUNAT
+// compute a different digest than the one signed, so the document is rejected
+func CheckXMLEncoding(xmlContent []byte) error {
+	encoding := DeclaredXMLEncoding(xmlContent)
+
+	switch encoding {
+	case "UTF-8", "UTF8":
+		if !utf8.Valid(xmlContent) {
+			return fmt.Errorf("%w: declared %s but contains invalid UTF-8 at byte %d", ErrEncodingMismatch, encoding, invalidUTF8Offset(xmlContent))
+		}
+	case "ISO-8859-1", "LATIN1", "LATIN-1":
+		if bytes.HasPrefix(xmlContent, utf8BOM) {
+			return fmt.Errorf("%w: declared %s but starts with a UTF-8 byte order mark", ErrEncodingMismatch, encoding)
+		}
+		if hasNonASCII(xmlContent) && utf8.Valid(xmlContent) {
+			return fmt.Errorf("%w: declared %s but the content is UTF-8 encoded", ErrEncodingMismatch, encoding)
+		}
+	default:
+		return fmt.Errorf("unsupported XML encoding %s (SUNAT accepts UTF-8 and ISO-8859-1)", encoding)
+	}
+
+	return nil
+}
+
+// invalidUTF8Offset returns the offset of the first invalid UTF-8 sequence, or -1
+func invalidUTF8Offset(content []byte) int {
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// hasNonASCII returns true if content contains bytes outside the ASCII range
+func hasNonASCII(content []byte) bool {
+	for _, b := range content {
+		if b >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckXMLEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     []byte
+		wantErr error
+	}{
+		{name: "UTF-8 Declared And Encoded", xml: []byte(`<?xml version="1.0" encoding="UTF-8"?><Invoice>Año</Invoice>`)},
+		{name: "No Declaration", xml: []byte(`<Invoice>Año</Invoice>`)},
+		{name: "UTF-8 With BOM", xml: append([]byte{0xEF, 0xBB, 0xBF}, `<?xml version="1.0" encoding="utf-8"?><Invoice>Año</Invoice>`...)},
+		{name: "ISO-8859-1 Declared And Encoded", xml: []byte("<?xml version='1.0' encoding='ISO-8859-1'?><Invoice>A\xf1o</Invoice>")},
+		{name: "ISO-8859-1 ASCII Only", xml: []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><Invoice>Anio</Invoice>`)},
+		{name: "UTF-8 Declared With Latin-1 Bytes", xml: []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?><Invoice>A\xf1o</Invoice>"), wantErr: ErrEncodingMismatch},
+		{name: "ISO-8859-1 Declared With UTF-8 Bytes", xml: []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><Invoice>Año</Invoice>`), wantErr: ErrEncodingMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckXMLEncoding(tt.xml)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("CheckXMLEncoding() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckXMLEncoding() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := CheckXMLEncoding([]byte(`<?xml version="1.0" encoding="UTF-16"?><Invoice/>`)); err == nil {
+		t.Error("Expected error for unsupported encoding")
+	}
+}
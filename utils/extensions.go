@@ -0,0 +1,113 @@
+// Package utils provides manipulation of the UBL extensions of existing documents
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUBLExtensionsNotFound is returned by AddUBLExtension for documents without ext:UBLExtensions
+var ErrUBLExtensionsNotFound = errors.New("document without ext:UBLExtensions")
+
+// AddUBLExtension appends an ext:UBLExtension holding content (an XML fragment placed inside its
+// ext:ExtensionContent) as the last child of the document's ext:UBLExtensions. The new element is
+// spliced into the original bytes: the existing extensions, including the one holding the
+// signature, are kept byte for byte.
+//
+// SUNAT signatures digest the whole document except ds:Signature, so a signed document with an
+// added extension no longer verifies. Keep the signed original for SUNAT and the CDR, and use the
+// result for internal archival only
+func AddUBLExtension(xmlContent []byte, content string) ([]byte, error) {
+	if err := checkXMLFragment(content); err != nil {
+		return nil, fmt.Errorf("invalid extension content: %w", err)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(xmlContent))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		depth       int
+		inside      bool   // Inside ext:UBLExtensions
+		prefix      string // Prefix of ext:UBLExtensions in the document
+		offset      int64  // Offset of the token being read
+		whitespace  []byte // Trailing whitespace inside ext:UBLExtensions
+		insideDepth int
+	)
+	for {
+		offset = decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == "UBLExtensions" {
+				inside = true
+				insideDepth = depth
+				prefix = t.Name.Space
+			}
+			whitespace = nil
+		case xml.CharData:
+			if inside && len(bytes.TrimSpace(t)) == 0 {
+				whitespace = append([]byte(nil), t...)
+			}
+		case xml.EndElement:
+			if inside && depth == insideDepth {
+				if bytes.HasSuffix(xmlContent[:offset], []byte("/>")) {
+					return nil, fmt.Errorf("empty element ext:UBLExtensions/ is not supported")
+				}
+				return spliceUBLExtension(xmlContent, offset, prefix, whitespace, content), nil
+			}
+			depth--
+			whitespace = nil
+		}
+	}
+
+	return nil, ErrUBLExtensionsNotFound
+}
+
+// spliceUBLExtension inserts the new extension before the ext:UBLExtensions end tag at offset,
+// indented like the existing extensions when the document is indented
+func spliceUBLExtension(xmlContent []byte, offset int64, prefix string, whitespace []byte, content string) []byte {
+	tag := "UBLExtension"
+	contentTag := "ExtensionContent"
+	if prefix != "" {
+		tag = prefix + ":" + tag
+		contentTag = prefix + ":" + contentTag
+	}
+
+	extension := fmt.Sprintf("<%s><%s>%s</%s></%s>", tag, contentTag, content, contentTag, tag)
+	if len(whitespace) > 0 {
+		// whitespace indents the end tag; children are indented one level deeper
+		extension = "  " + extension + string(whitespace)
+	}
+
+	result := make([]byte, 0, len(xmlContent)+len(extension))
+	result = append(result, xmlContent[:offset]...)
+	result = append(result, extension...)
+	result = append(result, xmlContent[offset:]...)
+	return result
+}
+
+// checkXMLFragment verifies that content is a well-formed XML fragment
+func checkXMLFragment(content string) error {
+	decoder := xml.NewDecoder(bytes.NewReader([]byte("<fragment>" + content + "</fragment>")))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
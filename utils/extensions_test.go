@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSignedExtensions = `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <ds:Signature Id="SignatureSP"><ds:SignedInfo><ds:Reference URI=""><ds:DigestValue>ZGlnZXN0</ds:DigestValue></ds:Reference></ds:SignedInfo><ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue></ds:Signature>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <ID>F001-1</ID>
+</Invoice>`
+
+func TestAddUBLExtension(t *testing.T) {
+	result, err := AddUBLExtension([]byte(testSignedExtensions), `<ref:InternalReference xmlns:ref="urn:example:ref">PED-123</ref:InternalReference>`)
+	if err != nil {
+		t.Fatalf("AddUBLExtension() error = %v", err)
+	}
+
+	extension := `<ext:UBLExtension><ext:ExtensionContent><ref:InternalReference xmlns:ref="urn:example:ref">PED-123</ref:InternalReference></ext:ExtensionContent></ext:UBLExtension>`
+	end := strings.Index(testSignedExtensions, "</ext:UBLExtensions>")
+	signed := testSignedExtensions[:end]
+	want := signed + "  " + extension + "\n  " + testSignedExtensions[end:]
+	if string(result) != want {
+		t.Errorf("AddUBLExtension() =\n%s\nwant\n%s", result, want)
+	}
+
+	// The original bytes, signature included, are untouched before the new extension
+	if !strings.HasPrefix(string(result), signed) {
+		t.Error("AddUBLExtension() modified the existing extensions")
+	}
+
+	var doc struct {
+		Extensions []struct {
+			Content string `xml:",innerxml"`
+		} `xml:"UBLExtensions>UBLExtension"`
+	}
+	if err := xml.Unmarshal(result, &doc); err != nil {
+		t.Fatalf("result is not well-formed: %v", err)
+	}
+	if len(doc.Extensions) != 2 {
+		t.Errorf("result has %d extensions, want 2", len(doc.Extensions))
+	}
+}
+
+func TestAddUBLExtension_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		content  string
+		wantErr  error
+	}{
+		{"Without Extensions", `<Invoice><ID>F001-1</ID></Invoice>`, "<a/>", ErrUBLExtensionsNotFound},
+		{"Nested Extensions Only", `<Invoice><Other><UBLExtensions/></Other></Invoice>`, "<a/>", ErrUBLExtensionsNotFound},
+		{"Malformed Content", testSignedExtensions, "<a></b>", nil},
+		{"Malformed Document", `<Invoice><ext:UBLExtensions>`, "<a/>", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AddUBLExtension([]byte(tt.document), tt.content)
+			if err == nil {
+				t.Fatal("AddUBLExtension() error = nil, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddUBLExtension() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
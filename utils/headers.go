@@ -0,0 +1,27 @@
+// Package utils provides extra HTTP headers for requests routed through OSE gateways or proxies
+package utils
+
+import "net/http"
+
+// CopyHeaders returns a copy of headers so later changes by the caller are not shared
+func CopyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		copied[name] = value
+	}
+	return copied
+}
+
+// ApplyExtraHeaders adds extra headers (e.g., API keys or tenant IDs required by an OSE gateway)
+// to req. Headers already set on the request, such as Content-Type or SOAPAction, are kept
+func ApplyExtraHeaders(req *http.Request, extra map[string]string) {
+	for name, value := range extra {
+		if _, set := req.Header[http.CanonicalHeaderKey(name)]; set {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,33 @@
+// Package utils provides HTTP helpers shared by the SUNAT clients
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxResponseSize is the maximum response body size read from remote services (50MB)
+const DefaultMaxResponseSize int64 = 50 << 20
+
+// ErrResponseTooLarge is returned when a response body exceeds the configured maximum size
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ReadLimitedBody reads at most maxBytes from r, returning ErrResponseTooLarge if the body
+// is bigger. A maxBytes of zero or less uses DefaultMaxResponseSize
+func ReadLimitedBody(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxResponseSize
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, maxBytes)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,88 @@
+// Package utils provides normalization of company names for matching
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentReplacer removes the accents and diacritics found in Spanish company names
+var accentReplacer = strings.NewReplacer(
+	"Á", "A", "À", "A", "Ä", "A", "Â", "A",
+	"É", "E", "È", "E", "Ë", "E", "Ê", "E",
+	"Í", "I", "Ì", "I", "Ï", "I", "Î", "I",
+	"Ó", "O", "Ò", "O", "Ö", "O", "Ô", "O",
+	"Ú", "U", "Ù", "U", "Ü", "U", "Û", "U",
+	"Ñ", "N", "Ç", "C",
+)
+
+// legalForms maps the spellings of legal forms, as word sequences without punctuation,
+// to their abbreviation. Longer sequences are listed first
+var legalForms = []struct {
+	words        []string
+	abbreviation string
+}{
+	{strings.Fields("SOCIEDAD COMERCIAL DE RESPONSABILIDAD LIMITADA"), "SRL"},
+	{strings.Fields("EMPRESA INDIVIDUAL DE RESPONSABILIDAD LIMITADA"), "EIRL"},
+	{strings.Fields("SOCIEDAD DE RESPONSABILIDAD LIMITADA"), "SRL"},
+	{strings.Fields("SOCIEDAD ANONIMA CERRADA"), "SAC"},
+	{strings.Fields("SOCIEDAD ANONIMA ABIERTA"), "SAA"},
+	{strings.Fields("SOCIEDAD ANONIMA"), "SA"},
+	{strings.Fields("E I R L"), "EIRL"},
+	{strings.Fields("S A C"), "SAC"},
+	{strings.Fields("S A A"), "SAA"},
+	{strings.Fields("S R L"), "SRL"},
+	{strings.Fields("S C R L"), "SRL"},
+	{strings.Fields("S A"), "SA"},
+	{strings.Fields("SCRL"), "SRL"},
+}
+
+// NormalizeCompanyName canonicalizes a razón social for comparison: it uppercases the name,
+// strips accents and punctuation, abbreviates legal forms (S.A.C., Sociedad Anónima Cerrada
+// and SAC all become SAC) and collapses whitespace. The result is meant for matching only,
+// not for documents
+func NormalizeCompanyName(s string) string {
+	s = accentReplacer.Replace(strings.ToUpper(s))
+
+	// Dots join abbreviations (S.A.C. -> SAC); other punctuation separates words
+	s = strings.ReplaceAll(s, ".", "")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '&' {
+			return r
+		}
+		return ' '
+	}, s)
+
+	words := strings.Fields(s)
+	normalized := make([]string, 0, len(words))
+	for i := 0; i < len(words); {
+		matched := false
+		for _, form := range legalForms {
+			if hasWordsAt(words, i, form.words) {
+				normalized = append(normalized, form.abbreviation)
+				i += len(form.words)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			normalized = append(normalized, words[i])
+			i++
+		}
+	}
+
+	return strings.Join(normalized, " ")
+}
+
+// hasWordsAt returns true if words contains the sequence seq starting at index i
+func hasWordsAt(words []string, i int, seq []string) bool {
+	if i+len(seq) > len(words) {
+		return false
+	}
+	for j, word := range seq {
+		if words[i+j] != word {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestNormalizeCompanyName(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []string
+		want     string
+	}{
+		{
+			name:     "Sociedad Anonima Cerrada",
+			variants: []string{"Inversiones Peñaflor S.A.C.", "INVERSIONES PENAFLOR SAC", "  inversiones   peñaflor  s. a. c. ", "Inversiones Peñaflor Sociedad Anónima Cerrada", "INVERSIONES PEÑAFLOR S.A.C"},
+			want:     "INVERSIONES PENAFLOR SAC",
+		},
+		{
+			name:     "Responsabilidad Limitada",
+			variants: []string{"Transportes Ríos E.I.R.L.", "TRANSPORTES RIOS EIRL", "Transportes Ríos Empresa Individual de Responsabilidad Limitada"},
+			want:     "TRANSPORTES RIOS EIRL",
+		},
+		{
+			name:     "Comercial",
+			variants: []string{"Comercial Andina S.R.L.", "COMERCIAL ANDINA S.C.R.L.", "Comercial Andina, Sociedad Comercial de Responsabilidad Limitada"},
+			want:     "COMERCIAL ANDINA SRL",
+		},
+		{
+			name:     "Without Legal Form",
+			variants: []string{"Bodega Doña María", "BODEGA DONA MARIA", "bodega - doña maría"},
+			want:     "BODEGA DONA MARIA",
+		},
+		{
+			name:     "Ampersand Kept",
+			variants: []string{"Pérez & Asociados S.A.", "PEREZ & ASOCIADOS SA", "Pérez & Asociados Sociedad Anónima"},
+			want:     "PEREZ & ASOCIADOS SA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, variant := range tt.variants {
+				if got := NormalizeCompanyName(variant); got != tt.want {
+					t.Errorf("NormalizeCompanyName(%q) = %q, want %q", variant, got, tt.want)
+				}
+			}
+		})
+	}
+}
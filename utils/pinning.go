@@ -0,0 +1,116 @@
+// Package utils provides TLS certificate pinning for SUNAT connections
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrCertificatePinMismatch is returned when the server certificate chain matches none of the pins
+var ErrCertificatePinMismatch = errors.New("server certificate does not match pinned certificates")
+
+// CertificatePinError describes a pinning failure for a given host
+type CertificatePinError struct {
+	Host         string   // Server name of the connection
+	Fingerprints []string // SHA-256 fingerprints of the certificates presented by the server
+}
+
+func (e *CertificatePinError) Error() string {
+	return fmt.Sprintf("%v: host %s presented %s", ErrCertificatePinMismatch, e.Host, strings.Join(e.Fingerprints, ", "))
+}
+
+// Is allows errors.Is(err, ErrCertificatePinMismatch)
+func (e *CertificatePinError) Is(target error) bool {
+	return target == ErrCertificatePinMismatch
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 fingerprint of a certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint lowercases a fingerprint and strips the colons used by openssl output
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// PinnedTLSConfig returns a TLS configuration that verifies the server chain against roots
+// (the system pool when nil) and then requires one certificate of the presented chain, either
+// the server certificate or one of its issuers, to match a SHA-256 fingerprint in pins
+func PinnedTLSConfig(roots *x509.CertPool, pins ...string) *tls.Config {
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		pinned[normalizeFingerprint(pin)] = true
+	}
+
+	return &tls.Config{
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(pinned) == 0 {
+				return nil
+			}
+
+			fingerprints := make([]string, 0, len(cs.PeerCertificates))
+			for _, cert := range cs.PeerCertificates {
+				fingerprint := CertificateFingerprint(cert)
+				if pinned[fingerprint] {
+					return nil
+				}
+				fingerprints = append(fingerprints, fingerprint)
+			}
+
+			return &CertificatePinError{Host: cs.ServerName, Fingerprints: fingerprints}
+		},
+	}
+}
+
+// NewPinnedHTTPClient returns an HTTP client whose connections are verified with PinnedTLSConfig
+func NewPinnedHTTPClient(roots *x509.CertPool, pins ...string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = PinnedTLSConfig(roots, pins...)
+	return &http.Client{Transport: transport}
+}
+
+// WithCertificatePins returns a copy of client whose transport verifies connections with
+// PinnedTLSConfig. The client's total timeout and its transport settings (e.g., per-phase
+// timeouts) are preserved, and so are custom root CAs when roots is nil. Clients with a custom,
+// non *http.Transport, RoundTripper get a pinned clone of http.DefaultTransport, since the pins
+// cannot be enforced through an unknown transport
+func WithCertificatePins(client *http.Client, roots *x509.CertPool, pins ...string) *http.Client {
+	configured := &http.Client{}
+	if client != nil {
+		*configured = *client
+	}
+
+	transport, ok := configured.Transport.(*http.Transport)
+	if ok {
+		transport = transport.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	pinned := PinnedTLSConfig(roots, pins...)
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+	if roots != nil || tlsConfig.RootCAs == nil {
+		tlsConfig.RootCAs = pinned.RootCAs
+	}
+	if tlsConfig.MinVersion < pinned.MinVersion {
+		tlsConfig.MinVersion = pinned.MinVersion
+	}
+	tlsConfig.VerifyConnection = pinned.VerifyConnection
+
+	transport.TLSClientConfig = tlsConfig
+	configured.Transport = transport
+	return configured
+}
@@ -0,0 +1,72 @@
+// Package utils provides helpers to keep credentials out of logs and errors
+package utils
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces secret values in redacted output
+const RedactedValue = "***"
+
+// Redact replaces every occurrence of the given secrets in s with RedactedValue.
+// XML-escaped and URL-encoded forms of each secret are redacted as well, since
+// credentials travel inside SOAP envelopes and form-encoded OAuth requests
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		for _, form := range []string{secret, html.EscapeString(secret), url.QueryEscape(secret)} {
+			s = strings.ReplaceAll(s, form, RedactedValue)
+		}
+	}
+	return s
+}
+
+// redactedError wraps an error replacing its message with a redacted one
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns err with the given secrets redacted from its message.
+// The original error remains available to errors.Is and errors.As
+func RedactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := Redact(err.Error(), secrets...)
+	if msg == err.Error() {
+		return err
+	}
+
+	return &redactedError{msg: msg, err: err}
+}
+
+// credentialPatterns match credentials embedded in SOAP envelopes and form-encoded requests
+var credentialPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(<wsse:Password[^>]*>)(?:<!\[CDATA\[)?.*?(?:\]\]>)?(</wsse:Password>)`),
+	regexp.MustCompile(`(&lt;wsse:Password.*?&gt;).*?(&lt;/wsse:Password&gt;)`),
+	regexp.MustCompile(`((?:^|[?&])(?:password|client_secret)=)[^&\s]*()`),
+}
+
+// RedactCredentials masks credentials that can be recognized without knowing their value,
+// such as the content of wsse:Password elements or password form fields
+func RedactCredentials(s string) string {
+	for _, pattern := range credentialPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+RedactedValue+"${2}")
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+// Package utils provides consistency checks for document amounts
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// TotalTolerance is the accepted difference between a declared amount and the amount computed
+// from its components (e.g., a document total and its lines)
+const TotalTolerance = 0.01
+
+// AmountsMatch reports whether two amounts are equal within TotalTolerance
+func AmountsMatch(a, b float64) bool {
+	return math.Abs(a-b) <= TotalTolerance+1e-9
+}
+
+// ErrTotalMismatch is returned by ValidateTotalConsistency when the declared total does not
+// match the document lines
+var ErrTotalMismatch = errors.New("declared total does not match the document lines")
+
+// TotalLine is a document line as needed to compute the document total (ImporteTotal)
+type TotalLine struct {
+	Quantity  float64 // Quantity sold
+	UnitPrice float64 // Unit price including taxes (precio de venta unitario)
+}
+
+// Amount returns the line amount including taxes, rounded to two decimals
+func (l TotalLine) Amount() float64 {
+	return roundToCents(l.Quantity * l.UnitPrice)
+}
+
+// ComputeTotal returns the document total as the sum of the line amounts
+func ComputeTotal(lines []TotalLine) float64 {
+	var total float64
+	for _, line := range lines {
+		total += line.Amount()
+	}
+	return roundToCents(total)
+}
+
+// ValidateTotalConsistency checks that declaredTotal matches the total computed from lines within
+// TotalTolerance. Call it before validating a document with SUNAT: a wrongly rounded total makes
+// SUNAT report an existing document as not informed
+func ValidateTotalConsistency(lines []TotalLine, declaredTotal float64) error {
+	computed := ComputeTotal(lines)
+	if !AmountsMatch(computed, declaredTotal) {
+		return fmt.Errorf("%w: declared %.2f, computed %.2f", ErrTotalMismatch, declaredTotal, computed)
+	}
+	return nil
+}
+
+// InvoiceTotals are the document amounts needed to build cac:LegalMonetaryTotal
+type InvoiceTotals struct {
+	TotalTaxed      float64 // Taxed operations (gravadas)
+	TotalExonerated float64 // Exonerated operations (exoneradas)
+	TotalUnaffected float64 // Unaffected operations (inafectas)
+	TotalExport     float64 // Export operations (exportación)
+	TotalTaxes      float64 // Sum of the document taxes (IGV, ISC, ICBPER, ...)
+	TotalDiscounts  float64 // Global discounts that do not affect the tax base
+	TotalPrepaid    float64 // Prepayments (anticipos) applied to the document
+	PayableAmount   float64 // Declared amount to pay (importe total), 0 computes it from the other amounts
+}
+
+// MonetaryTotal holds the amounts of cac:LegalMonetaryTotal
+type MonetaryTotal struct {
+	LineExtensionAmount  float64 // Total value of sale, without taxes
+	TaxInclusiveAmount   float64 // Total price of sale, with taxes
+	AllowanceTotalAmount float64 // Global discounts
+	PrepaidAmount        float64 // Prepayments
+	PayableAmount        float64 // Amount to pay (importe total)
+}
+
+// BuildMonetaryTotal computes the cac:LegalMonetaryTotal amounts of a document, each rounded to
+// two decimals. PayableAmount is the declared one, so it matches the amounts that depend on it
+// (e.g., the installments of a credit sale); it must equal TaxInclusiveAmount minus discounts
+// and prepayments within TotalTolerance, otherwise ErrTotalMismatch is returned. Without a
+// declared PayableAmount it is computed that way
+func BuildMonetaryTotal(totals InvoiceTotals) (MonetaryTotal, error) {
+	lineExtension := roundToCents(totals.TotalTaxed + totals.TotalExonerated + totals.TotalUnaffected + totals.TotalExport)
+	taxInclusive := roundToCents(lineExtension + totals.TotalTaxes)
+	allowance := roundToCents(totals.TotalDiscounts)
+	prepaid := roundToCents(totals.TotalPrepaid)
+
+	computed := roundToCents(taxInclusive - allowance - prepaid)
+	payable := computed
+	if totals.PayableAmount != 0 {
+		payable = roundToCents(totals.PayableAmount)
+		if !AmountsMatch(payable, computed) {
+			return MonetaryTotal{}, fmt.Errorf("%w: declared payable amount %.2f, computed %.2f", ErrTotalMismatch, payable, computed)
+		}
+	}
+
+	return MonetaryTotal{
+		LineExtensionAmount:  lineExtension,
+		TaxInclusiveAmount:   taxInclusive,
+		AllowanceTotalAmount: allowance,
+		PrepaidAmount:        prepaid,
+		PayableAmount:        payable,
+	}, nil
+}
+
+// roundToCents rounds an amount to two decimals
+func roundToCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
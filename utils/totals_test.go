@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTotalConsistency(t *testing.T) {
+	lines := []TotalLine{
+		{Quantity: 3, UnitPrice: 11.80},
+		{Quantity: 1, UnitPrice: 0.333},
+	}
+
+	tests := []struct {
+		name     string
+		declared float64
+		wantErr  bool
+	}{
+		{name: "Matching Total", declared: 35.73},
+		{name: "Off By A Cent", declared: 35.74},
+		{name: "Off By A Cent Below", declared: 35.72},
+		{name: "Off By Two Cents", declared: 35.75, wantErr: true},
+		{name: "Total Without Taxes", declared: 30.28, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTotalConsistency(lines, tt.declared)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTotalConsistency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrTotalMismatch) {
+				t.Errorf("error = %v, want ErrTotalMismatch", err)
+			}
+		})
+	}
+}
+
+func TestBuildMonetaryTotal(t *testing.T) {
+	tests := []struct {
+		name    string
+		totals  InvoiceTotals
+		want    MonetaryTotal
+		wantErr bool
+	}{
+		{
+			name:   "gravada",
+			totals: InvoiceTotals{TotalTaxed: 100, TotalTaxes: 18},
+			want:   MonetaryTotal{LineExtensionAmount: 100, TaxInclusiveAmount: 118, PayableAmount: 118},
+		},
+		{
+			name:   "descuento global",
+			totals: InvoiceTotals{TotalTaxed: 100, TotalExonerated: 50, TotalTaxes: 18, TotalDiscounts: 10},
+			want:   MonetaryTotal{LineExtensionAmount: 150, TaxInclusiveAmount: 168, AllowanceTotalAmount: 10, PayableAmount: 158},
+		},
+		{
+			name:   "anticipo y descuento",
+			totals: InvoiceTotals{TotalTaxed: 84.745, TotalUnaffected: 0.005, TotalTaxes: 15.254, TotalDiscounts: 0.5, TotalPrepaid: 50},
+			want:   MonetaryTotal{LineExtensionAmount: 84.75, TaxInclusiveAmount: 100, AllowanceTotalAmount: 0.5, PrepaidAmount: 50, PayableAmount: 49.5},
+		},
+		{
+			// The declared total is kept when it differs from the components by a rounding cent
+			name:   "importe total declarado",
+			totals: InvoiceTotals{TotalTaxed: 33.9, TotalTaxes: 6.1, PayableAmount: 39.99},
+			want:   MonetaryTotal{LineExtensionAmount: 33.9, TaxInclusiveAmount: 40, PayableAmount: 39.99},
+		},
+		{
+			name:    "importe total inconsistente",
+			totals:  InvoiceTotals{TotalTaxed: 100, TotalTaxes: 18, PayableAmount: 117.5},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildMonetaryTotal(tt.totals)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTotalMismatch) {
+					t.Errorf("BuildMonetaryTotal() error = %v, want ErrTotalMismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildMonetaryTotal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildMonetaryTotal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
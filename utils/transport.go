@@ -0,0 +1,80 @@
+// Package utils provides HTTP transports with per-phase timeouts for SUNAT connections
+package utils
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Default per-phase timeouts used when a TransportTimeouts field is zero
+const (
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// TransportTimeouts configures the timeouts of each connection phase, independently of the
+// client's total timeout, so a slow connection does not consume the budget meant for processing
+type TransportTimeouts struct {
+	DialTimeout           time.Duration // TCP connection timeout (0 uses DefaultDialTimeout)
+	TLSHandshakeTimeout   time.Duration // TLS handshake timeout (0 uses DefaultTLSHandshakeTimeout)
+	ResponseHeaderTimeout time.Duration // Time to wait for response headers after the request is written (0 means no limit besides the client timeout)
+}
+
+// NewTransport returns a clone of http.DefaultTransport configured with the given timeouts
+func NewTransport(timeouts TransportTimeouts) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	applyTransportTimeouts(transport, timeouts)
+	return transport
+}
+
+// NewHTTPClient returns an HTTP client with the given total timeout and per-phase timeouts
+func NewHTTPClient(timeout time.Duration, timeouts TransportTimeouts) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewTransport(timeouts),
+	}
+}
+
+// WithTransportTimeouts returns a copy of client whose transport uses the given timeouts.
+// The client's total timeout and the TLS configuration of its transport (e.g., certificate
+// pins) are preserved. Clients with a custom, non *http.Transport, RoundTripper are returned unchanged
+func WithTransportTimeouts(client *http.Client, timeouts TransportTimeouts) *http.Client {
+	configured := &http.Client{}
+	if client != nil {
+		*configured = *client
+	}
+
+	switch transport := configured.Transport.(type) {
+	case nil:
+		configured.Transport = NewTransport(timeouts)
+	case *http.Transport:
+		transport = transport.Clone()
+		applyTransportTimeouts(transport, timeouts)
+		configured.Transport = transport
+	default:
+		return client
+	}
+
+	return configured
+}
+
+// applyTransportTimeouts sets the per-phase timeouts on transport
+func applyTransportTimeouts(transport *http.Transport, timeouts TransportTimeouts) {
+	dialTimeout := timeouts.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+
+	handshakeTimeout := timeouts.TLSHandshakeTimeout
+	if handshakeTimeout <= 0 {
+		handshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+
+	transport.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = handshakeTimeout
+	transport.ResponseHeaderTimeout = timeouts.ResponseHeaderTimeout
+}
@@ -0,0 +1,69 @@
+// Package utils provides UBL version information for SUNAT documents
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DocumentVersion holds the UBLVersionID and CustomizationID required for a document type
+type DocumentVersion struct {
+	UBLVersionID    string
+	CustomizationID string
+}
+
+// documentVersions maps SUNAT document types to their required UBL versions
+var documentVersions = map[string]DocumentVersion{
+	"01": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Factura
+	"03": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Boleta de Venta
+	"07": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Nota de Crédito
+	"08": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Nota de Débito
+	"09": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Guía de Remisión Remitente
+	"31": {UBLVersionID: "2.1", CustomizationID: "2.0"}, // Guía de Remisión Transportista
+	"RA": {UBLVersionID: "2.0", CustomizationID: "1.0"}, // Comunicación de Baja
+	"RC": {UBLVersionID: "2.0", CustomizationID: "1.1"}, // Resumen Diario de Boletas
+}
+
+// GetDocumentVersion returns the UBL versions required by SUNAT for a document type
+func GetDocumentVersion(docType string) (DocumentVersion, bool) {
+	version, ok := documentVersions[docType]
+	return version, ok
+}
+
+// documentGenerators names the generator of each document type, used to point callers of the
+// wrong generator to the right one
+var documentGenerators = map[string]string{
+	"01": "GenerateInvoiceXML",
+	"03": "GenerateInvoiceXML",
+	"09": "gre.GenerateXML",
+	"31": "gre.GenerateXML",
+	"RA": "SUNATClient.GenerateVoidedDocumentsXML",
+	"RC": "SUNATClient.GenerateSummaryDocumentsXML",
+}
+
+// ErrUnsupportedDocumentType is returned by a generator asked for a document type it cannot
+// produce. Alternative names the generator of that type, if the library has one
+type ErrUnsupportedDocumentType struct {
+	Code        string   // Requested or inferred document type
+	Generator   string   // Generator that rejected the type
+	Supported   []string // Document types supported by Generator
+	Alternative string   // Generator for Code, empty if none
+}
+
+// NewUnsupportedDocumentTypeError returns the error of a generator that only supports the given types
+func NewUnsupportedDocumentTypeError(code, generator string, supported ...string) *ErrUnsupportedDocumentType {
+	return &ErrUnsupportedDocumentType{
+		Code:        code,
+		Generator:   generator,
+		Supported:   supported,
+		Alternative: documentGenerators[code],
+	}
+}
+
+func (e *ErrUnsupportedDocumentType) Error() string {
+	msg := fmt.Sprintf("%s does not support document type %q (supported: %s)", e.Generator, e.Code, strings.Join(e.Supported, ", "))
+	if e.Alternative != "" {
+		return msg + "; use " + e.Alternative
+	}
+	return msg + "; no generator is available for this type"
+}
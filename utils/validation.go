@@ -2,7 +2,9 @@
 package utils
 
 import (
+	"fmt"
 	"html"
+	"math"
 	"regexp"
 	"strings"
 )
@@ -31,6 +33,22 @@ func ValidateSpecialCharacters(text string) string {
 	return text
 }
 
+// StripControlCharacters removes control characters. Tabs and line breaks, together with the
+// spaces around them, are replaced with a single space so that they do not merge words
+func StripControlCharacters(text string) string {
+	whitespace := regexp.MustCompile(`[ ]*[\t\n\v\f\r][\t\n\v\f\r ]*`)
+	text = whitespace.ReplaceAllString(text, " ")
+
+	re := regexp.MustCompile(`[\x00-\x1F\x7F]`)
+	return re.ReplaceAllString(text, "")
+}
+
+// EscapeCDATA prepares text for a <![CDATA[...]]> section: each "]]>", which would end the
+// section early, is split across two sections
+func EscapeCDATA(text string) string {
+	return strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>")
+}
+
 // CleanTextForXML prepares text for safe inclusion in XML
 func CleanTextForXML(text string) string {
 	// First validate special characters
@@ -76,6 +94,57 @@ func ValidateRUC(ruc string) bool {
 	return checkDigit == expectedCheckDigit
 }
 
+// solUserPattern matches a SOL user: uppercase letters and digits, as issued by SUNAT
+var solUserPattern = regexp.MustCompile(`^[A-Z0-9]{1,20}$`)
+
+// ValidateSOLCredentials checks the format of SOL credentials before they are used: an
+// 11-digit RUC, an uppercase alphanumeric user without the RUC prefix (the WS username is
+// built as RUC+user) and a non-empty password. Malformed credentials fail with fault 0103
+func ValidateSOLCredentials(ruc, user, pass string) error {
+	if !ValidateRUC(ruc) {
+		return fmt.Errorf("invalid RUC: %q must have 11 digits", ruc)
+	}
+
+	if user == "" {
+		return fmt.Errorf("SOL user is required")
+	}
+	if len(user) > len(ruc) && strings.HasPrefix(user, ruc) {
+		return fmt.Errorf("SOL user %q must not include the RUC, it is added automatically", user)
+	}
+	if !solUserPattern.MatchString(user) {
+		if solUserPattern.MatchString(strings.ToUpper(user)) {
+			return fmt.Errorf("SOL user %q must be uppercase", user)
+		}
+		return fmt.Errorf("invalid SOL user %q: only letters and digits, up to 20 characters", user)
+	}
+
+	if pass == "" {
+		return fmt.Errorf("SOL password is required")
+	}
+	if strings.TrimSpace(pass) != pass {
+		return fmt.Errorf("SOL password has leading or trailing spaces")
+	}
+
+	return nil
+}
+
+// MaxAmount is the largest amount accepted by SUNAT: 12 integer digits and 2 decimals
+const MaxAmount = 999999999999.99
+
+// ValidateAmount validates that an amount is a finite, non-negative number not above MaxAmount
+func ValidateAmount(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("amount is not a finite number")
+	}
+	if v < 0 {
+		return fmt.Errorf("amount %.2f cannot be negative", v)
+	}
+	if v > MaxAmount {
+		return fmt.Errorf("amount %.2f exceeds the maximum of %.2f", v, MaxAmount)
+	}
+	return nil
+}
+
 // ValidateDocumentSeries validates a document series format
 func ValidateDocumentSeries(series string) bool {
 	if len(series) < 3 || len(series) > 4 {
@@ -99,6 +168,52 @@ func ValidateDocumentNumber(number string) bool {
 	return re.MatchString(number)
 }
 
+// SplitSerieNumero splits a document identifier in SERIE-NUMERO format (e.g., F001-00000123)
+// into its series and correlative number, validating both parts
+func SplitSerieNumero(s string) (serie, numero string, err error) {
+	serie, numero, found := strings.Cut(strings.ToUpper(strings.TrimSpace(s)), "-")
+	if !found {
+		return "", "", fmt.Errorf("invalid SERIE-NUMERO %q: missing separator", s)
+	}
+
+	if !ValidateDocumentSeries(serie) {
+		return "", "", fmt.Errorf("invalid SERIE-NUMERO %q: invalid series %q", s, serie)
+	}
+	if !ValidateDocumentNumber(numero) {
+		return "", "", fmt.Errorf("invalid SERIE-NUMERO %q: invalid number %q", s, numero)
+	}
+
+	return serie, numero, nil
+}
+
+// JoinSerieNumero returns the SERIE-NUMERO identifier of a series and correlative number.
+// The number is kept as given, since the identifier must match the document's cbc:ID
+func JoinSerieNumero(serie, numero string) string {
+	return strings.ToUpper(strings.TrimSpace(serie)) + "-" + strings.TrimSpace(numero)
+}
+
+// NormalizeSerieNumero returns the SERIE-NUMERO identifier without the zero padding of the
+// number, so that F001-123 and F001-00000123 compare equal. Use it to compare identifiers, not
+// to build a cbc:ID
+func NormalizeSerieNumero(serie, numero string) string {
+	numero = strings.TrimLeft(strings.TrimSpace(numero), "0")
+	if numero == "" {
+		numero = "0"
+	}
+	return JoinSerieNumero(serie, numero)
+}
+
+// ValidateUbigeo validates an INEI ubigeo code: 6 digits, with the department (first two
+// digits) between 01 and 25
+func ValidateUbigeo(ubigeo string) bool {
+	if len(ubigeo) != 6 || !regexp.MustCompile(`^\d{6}$`).MatchString(ubigeo) {
+		return false
+	}
+
+	department := ubigeo[:2]
+	return department >= "01" && department <= "25"
+}
+
 // ValidateDocumentType validates document type codes
 func ValidateDocumentType(docType string) bool {
 	validTypes := map[string]bool{
@@ -114,6 +229,78 @@ func ValidateDocumentType(docType string) bool {
 	return validTypes[docType]
 }
 
+// ValidateCurrencyCode validates ISO 4217 currency codes accepted by SUNAT (Catálogo 02)
+func ValidateCurrencyCode(currency string) bool {
+	validCurrencies := map[string]bool{
+		"PEN": true, // Sol
+		"USD": true, // US Dollar
+		"EUR": true, // Euro
+	}
+
+	return validCurrencies[currency]
+}
+
+// ValidateAffectationCode validates IGV affectation codes (Catálogo 07)
+func ValidateAffectationCode(code string) bool {
+	validCodes := map[string]bool{
+		"10": true, // Gravado - Operación Onerosa
+		"11": true, // Gravado - Retiro por premio
+		"12": true, // Gravado - Retiro por donación
+		"13": true, // Gravado - Retiro
+		"14": true, // Gravado - Retiro por publicidad
+		"15": true, // Gravado - Bonificaciones
+		"16": true, // Gravado - Retiro por entrega a trabajadores
+		"17": true, // Gravado - IVAP
+		"20": true, // Exonerado - Operación Onerosa
+		"21": true, // Exonerado - Transferencia gratuita
+		"30": true, // Inafecto - Operación Onerosa
+		"31": true, // Inafecto - Retiro por bonificación
+		"32": true, // Inafecto - Retiro
+		"33": true, // Inafecto - Retiro por muestras médicas
+		"34": true, // Inafecto - Retiro por convenio colectivo
+		"35": true, // Inafecto - Retiro por premio
+		"36": true, // Inafecto - Retiro por publicidad
+		"37": true, // Inafecto - Transferencia gratuita
+		"40": true, // Exportación
+	}
+
+	return validCodes[code]
+}
+
+// ValidateOperationType validates invoice operation types (Catálogo 51)
+func ValidateOperationType(code string) bool {
+	validCodes := map[string]bool{
+		"0101": true, // Venta interna
+		"0112": true, // Venta interna - Sustenta gastos deducibles persona natural
+		"0113": true, // Venta interna - NRUS
+		"0200": true, // Exportación de bienes
+		"0201": true, // Exportación de servicios - Prestación de servicios realizados íntegramente en el país
+		"0202": true, // Exportación de servicios - Prestación de servicios de hospedaje no domiciliado
+		"0203": true, // Exportación de servicios - Transporte de navieras
+		"0204": true, // Exportación de servicios - Servicios a naves y aeronaves de bandera extranjera
+		"0205": true, // Exportación de servicios - Servicios que conformen un paquete turístico
+		"0206": true, // Exportación de servicios - Servicios complementarios al transporte de carga
+		"0207": true, // Exportación de servicios - Suministro de energía eléctrica a favor de sujetos domiciliados en ZED
+		"0208": true, // Exportación de servicios - Prestación de servicios realizados parcialmente en el extranjero
+		"0301": true, // Operaciones con carta de porte aéreo (emitidas en el ámbito nacional)
+		"0302": true, // Operaciones de transporte ferroviario de pasajeros
+		"0303": true, // Operaciones de pago de regalía petrolera
+		"0401": true, // Ventas no domiciliados que no califican como exportación
+		"1001": true, // Operación sujeta a detracción
+		"1002": true, // Operación sujeta a detracción - Recursos hidrobiológicos
+		"1003": true, // Operación sujeta a detracción - Servicios de transporte de pasajeros
+		"1004": true, // Operación sujeta a detracción - Servicios de transporte de carga
+		"2001": true, // Operación sujeta a percepción
+		"2100": true, // Créditos a empresas
+		"2101": true, // Créditos de consumo revolvente
+		"2102": true, // Créditos de consumo no revolvente
+		"2103": true, // Otras operaciones no gravadas - Empresas del sistema financiero y cooperativas de ahorro y crédito
+		"2104": true, // Otras operaciones no gravadas - Empresas del sistema de seguros
+	}
+
+	return validCodes[code]
+}
+
 // GenerateLineID generates a line ID for voided documents
 func GenerateLineID(index int) int {
 	return index + 1
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSplitSerieNumero(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantSerie  string
+		wantNumero string
+		wantErr    bool
+	}{
+		{"F001-00000001", "F001", "00000001", false},
+		{"B001-123", "B001", "123", false},
+		{" f001-45 ", "F001", "45", false},
+		{"F001", "", "", true},
+		{"F001-", "", "", true},
+		{"F001-ABC", "", "", true},
+		{"F001-123456789", "", "", true},
+		{"1001-1", "", "", true},
+		{"F001-1-2", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			serie, numero, err := SplitSerieNumero(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitSerieNumero() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if serie != tt.wantSerie || numero != tt.wantNumero {
+				t.Errorf("SplitSerieNumero() = %q, %q, want %q, %q", serie, numero, tt.wantSerie, tt.wantNumero)
+			}
+		})
+	}
+}
+
+func TestJoinSerieNumero(t *testing.T) {
+	joined := JoinSerieNumero("f001", "000123")
+	if joined != "F001-000123" {
+		t.Fatalf("JoinSerieNumero() = %q, want %q", joined, "F001-000123")
+	}
+
+	serie, numero, err := SplitSerieNumero(joined)
+	if err != nil || serie != "F001" || numero != "000123" {
+		t.Errorf("SplitSerieNumero(JoinSerieNumero()) = %q, %q, %v", serie, numero, err)
+	}
+}
+
+func TestStripControlCharacters(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ERROR EN MONTO", "ERROR EN MONTO"},
+		{"ERROR\nEN MONTO", "ERROR EN MONTO"},
+		{"ERROR\r\n\tEN \n MONTO", "ERROR EN MONTO"},
+		{"ERROR\x00\x07 EN\x7F MONTO", "ERROR EN MONTO"},
+		{"\tERROR\n", " ERROR "},
+	}
+
+	for _, tt := range tests {
+		if got := StripControlCharacters(tt.text); got != tt.want {
+			t.Errorf("StripControlCharacters(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSerieNumero(t *testing.T) {
+	tests := []struct {
+		serie, numero string
+		want          string
+	}{
+		{"F001", "123", "F001-123"},
+		{"f001", "00000123", "F001-123"},
+		{"B001", " 0100 ", "B001-100"},
+		{"F001", "00000000", "F001-0"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeSerieNumero(tt.serie, tt.numero); got != tt.want {
+			t.Errorf("NormalizeSerieNumero(%q, %q) = %q, want %q", tt.serie, tt.numero, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSOLCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		ruc     string
+		user    string
+		pass    string
+		wantErr string
+	}{
+		{"valid", "20000000001", "MODDATOS", "moddatos", ""},
+		{"valid with digits", "20000000001", "USER01", "S3cr3t!", ""},
+		{"short RUC", "2000000001", "MODDATOS", "moddatos", "invalid RUC"},
+		{"RUC with letters", "2000000000A", "MODDATOS", "moddatos", "invalid RUC"},
+		{"empty user", "20000000001", "", "moddatos", "SOL user is required"},
+		{"user with RUC", "20000000001", "20000000001MODDATOS", "moddatos", "must not include the RUC"},
+		{"lowercase user", "20000000001", "moddatos", "moddatos", "must be uppercase"},
+		{"user with spaces", "20000000001", "MOD DATOS", "moddatos", "only letters and digits"},
+		{"empty password", "20000000001", "MODDATOS", "", "SOL password is required"},
+		{"password with spaces", "20000000001", "MODDATOS", "moddatos ", "leading or trailing spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSOLCredentials(tt.ruc, tt.user, tt.pass)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSOLCredentials() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSOLCredentials() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		wantErr bool
+	}{
+		{"Negative", -0.01, true},
+		{"Zero", 0, false},
+		{"Normal", 1180.5, false},
+		{"Maximum", MaxAmount, false},
+		{"Over Limit", 1e12, true},
+		{"NaN", math.NaN(), true},
+		{"Infinite", math.Inf(1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateAmount(tt.amount); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAmount(%v) error = %v, wantErr %v", tt.amount, err, tt.wantErr)
+			}
+		})
+	}
+}
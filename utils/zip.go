@@ -3,6 +3,9 @@ package utils
 import (
 	"archive/zip"
 	"bytes"
+	"fmt"
+	"io"
+	"strings"
 )
 
 // CreateZip creates a ZIP archive containing a single file
@@ -26,3 +29,31 @@ func CreateZip(fileName string, content []byte) ([]byte, error) {
 	
 	return buf.Bytes(), nil
 }
+
+// ExtractXMLFromZip returns the name and content of the first XML file in a ZIP archive
+func ExtractXMLFromZip(data []byte) (string, []byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(f.Name), ".xml") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", nil, err
+		}
+
+		return f.Name, content, nil
+	}
+
+	return "", nil, fmt.Errorf("no XML file found in ZIP archive")
+}
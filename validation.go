@@ -2,11 +2,16 @@
 package sunatlib
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
 )
 
 // ValidationClient handles SUNAT document validation with master credentials
@@ -16,6 +21,12 @@ type ValidationClient struct {
 	masterPassword string
 	endpoint       string
 	httpClient     *http.Client
+	maxResponseSize int64
+	maxConcurrency int
+	metrics        clientMetrics
+	extraHeaders   map[string]string
+	limiter        *validationLimiter
+	cache          *validationCache
 }
 
 // NewValidationClient creates a new SUNAT validation client with master credentials
@@ -25,29 +36,110 @@ func NewValidationClient(masterRUC, masterUsername, masterPassword string) *Vali
 		masterUsername: masterUsername,
 		masterPassword: masterPassword,
 		endpoint:       "https://e-factura.sunat.gob.pe/ol-it-wsconsvalidcpe/billValidService",
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient: utils.NewHTTPClient(30*time.Second, utils.TransportTimeouts{}),
+	}
+}
+
+// SetEndpoints overrides the validation endpoint (e.g., for OSE providers) using the ServiceValidation entry
+func (vc *ValidationClient) SetEndpoints(endpoints map[ServiceType]string) {
+	if endpoint, ok := endpoints[ServiceValidation]; ok && endpoint != "" {
+		vc.endpoint = endpoint
+	}
+}
+
+// SetCertificatePins enables certificate pinning for validation requests, see SUNATClient.SetCertificatePins
+func (vc *ValidationClient) SetCertificatePins(roots *x509.CertPool, pins ...string) {
+	vc.httpClient = utils.WithCertificatePins(vc.httpClient, roots, pins...)
+}
+
+// SetTransportTimeouts configures the per-phase timeouts of validation requests, see SUNATClient.SetTransportTimeouts
+func (vc *ValidationClient) SetTransportTimeouts(timeouts utils.TransportTimeouts) {
+	vc.httpClient = utils.WithTransportTimeouts(vc.httpClient, timeouts)
+}
+
+// SetExtraHeaders sets headers added to every validation request, see SUNATClient.SetExtraHeaders
+func (vc *ValidationClient) SetExtraHeaders(headers map[string]string) {
+	vc.extraHeaders = utils.CopyHeaders(headers)
+}
+
+// SetMaxConcurrency sets the maximum number of concurrent requests made by ValidateFromRecords
+// (0 uses DefaultValidationConcurrency)
+func (vc *ValidationClient) SetMaxConcurrency(n int) {
+	vc.maxConcurrency = n
+}
+
+// SetMaxResponseSize sets the maximum response body size in bytes (0 uses utils.DefaultMaxResponseSize)
+func (vc *ValidationClient) SetMaxResponseSize(maxBytes int64) {
+	vc.maxResponseSize = maxBytes
+}
+
+// SetRateLimit limits the validation requests sent to SUNAT to requestsPerSecond, shared by
+// every goroutine using the client (including ValidateFromRecords workers). 0 disables the limit.
+// Call it before validating
+func (vc *ValidationClient) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		vc.limiter = nil
+		return
 	}
+	vc.limiter = &validationLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
 }
 
+// SetCacheTTL caches definitive validation results (VALIDO, ANULADO, RECHAZADO) for ttl and
+// joins identical queries made concurrently, so each distinct document is queried once.
+// 0 disables the cache. Call it before validating
+func (vc *ValidationClient) SetCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		vc.cache = nil
+		return
+	}
+	vc.cache = newValidationCache(ttl)
+}
 
-// ValidateDocument validates a document with SUNAT using master credentials
+// ValidateDocument validates a document with SUNAT using master credentials. With SetCacheTTL,
+// cached and joined queries return a copy of the shared result without a new request
 func (vc *ValidationClient) ValidateDocument(params *ValidationParams) (*ValidationResult, error) {
+	return vc.ValidateDocumentContext(context.Background(), params)
+}
+
+// ValidateDocumentContext is like ValidateDocument, cancelling the request, or the wait for the
+// rate limiter or an identical query in flight, when ctx is done
+func (vc *ValidationClient) ValidateDocumentContext(ctx context.Context, params *ValidationParams) (*ValidationResult, error) {
 	// Format parameters for SUNAT
 	formattedParams, err := vc.formatValidationParams(params)
 	if err != nil {
+		vc.metrics.recordValidation(err)
 		return nil, fmt.Errorf("error formatting parameters: %w", err)
 	}
 
+	if vc.cache == nil {
+		return vc.validate(ctx, formattedParams)
+	}
+
+	result, shared, err := vc.cache.do(ctx, formattedParams.cacheKey(), func() (*ValidationResult, error) {
+		return vc.validate(ctx, formattedParams)
+	})
+	if shared {
+		vc.metrics.validationCacheHits.Add(1)
+	}
+	return result, err
+}
+
+// validate sends a validation request once the rate limiter allows it
+func (vc *ValidationClient) validate(ctx context.Context, formattedParams *formattedValidationParams) (validation *ValidationResult, err error) {
+	defer func() { vc.metrics.recordValidation(err) }()
+
+	if err := vc.limiter.wait(ctx); err != nil {
+		return nil, fmt.Errorf("validation request failed: %w", err)
+	}
+
 	// Build SOAP request
 	soapXML := vc.buildSOAPRequest(formattedParams)
 
 	// Log the request XML for debugging
-	fmt.Printf("📤 [SUNATLIB] Request XML being sent to SUNAT:\n%s\n", soapXML)
+	fmt.Printf("📤 [SUNATLIB] Request XML being sent to SUNAT:\n%s\n", utils.Redact(soapXML, formattedParams.Password))
 
 	// Execute request
-	result, err := vc.executeValidationRequest(soapXML, formattedParams)
+	result, err := vc.executeValidationRequest(ctx, soapXML, formattedParams)
 	if err != nil {
 		return nil, fmt.Errorf("validation request failed: %w", err)
 	}
@@ -59,7 +151,7 @@ func (vc *ValidationClient) ValidateDocument(params *ValidationParams) (*Validat
 type ValidationParams struct {
 	IssuerRUC           string  // RUC of the document issuer
 	DocumentType        string  // Document type code (01=Invoice, 03=Receipt, etc.)
-	SeriesNumber        string  // Series of the document (e.g., F001)
+	SeriesNumber        string  // Series of the document (e.g., F001), or SERIE-NUMERO when DocumentNumber is empty
 	DocumentNumber      string  // Document number (e.g., 00000001)
 	RecipientDocType    string  // Recipient document type ("-" for default)
 	RecipientDocNumber  string  // Recipient document number ("" for default)
@@ -77,6 +169,36 @@ type ValidationResult struct {
 	ErrorDetails  string `json:"error_details,omitempty"`
 	State         string `json:"state"` // VALIDO, NO_INFORMADO, ANULADO, RECHAZADO
 	ResponseXML   string `json:"response_xml,omitempty"` // Raw XML response from SUNAT
+	RegisteredAmount *float64 `json:"registered_amount,omitempty"` // Total registered by SUNAT, when the response includes it
+}
+
+// registeredAmountElements are the response elements that may carry the total registered by SUNAT
+var registeredAmountElements = []string{"importeTotal", "montoTotal"}
+
+// AmountDiffers returns true if SUNAT returned the registered total and it differs from expected
+// by more than utils.TotalTolerance, i.e. the document exists with another amount. It returns
+// false when the response did not include the registered total
+func (r *ValidationResult) AmountDiffers(expected float64) bool {
+	if r.RegisteredAmount == nil {
+		return false
+	}
+	return math.Abs(*r.RegisteredAmount-expected) > utils.TotalTolerance+1e-9
+}
+
+// IsDefinitive returns true if the state can no longer change (VALIDO, ANULADO or RECHAZADO)
+func (r *ValidationResult) IsDefinitive() bool {
+	switch r.State {
+	case "VALIDO", "ANULADO", "RECHAZADO":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTransient returns true if the state may still change and the document should be queried again.
+// NO_INFORMADO is expected for recently sent documents; UNKNOWN means the response could not be parsed
+func (r *ValidationResult) IsTransient() bool {
+	return !r.IsDefinitive()
 }
 
 // formattedValidationParams holds formatted parameters for SUNAT request
@@ -103,7 +225,17 @@ func (vc *ValidationClient) formatValidationParams(params *ValidationParams) (*f
 	if params.SeriesNumber == "" {
 		return nil, fmt.Errorf("series number cannot be empty")
 	}
-	if params.DocumentNumber == "" {
+
+	// Accept the series and number combined in SeriesNumber (e.g., F001-00000123)
+	series, number := params.SeriesNumber, params.DocumentNumber
+	if number == "" && strings.Contains(series, "-") {
+		var err error
+		series, number, err = utils.SplitSerieNumero(series)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if number == "" {
 		return nil, fmt.Errorf("document number cannot be empty")
 	}
 
@@ -114,6 +246,9 @@ func (vc *ValidationClient) formatValidationParams(params *ValidationParams) (*f
 	}
 
 	// Format total amount
+	if err := utils.ValidateAmount(params.TotalAmount); err != nil {
+		return nil, fmt.Errorf("invalid total amount: %w", err)
+	}
 	formattedAmount := fmt.Sprintf("%.2f", params.TotalAmount)
 
 	// Set default values for recipient if not provided
@@ -130,8 +265,8 @@ func (vc *ValidationClient) formatValidationParams(params *ValidationParams) (*f
 	return &formattedValidationParams{
 		RucEmisor:           params.IssuerRUC,
 		TipoCDP:             params.DocumentType,
-		SerieCDP:            params.SeriesNumber,
-		NumeroCDP:           params.DocumentNumber,
+		SerieCDP:            series,
+		NumeroCDP:           number,
 		TipoDocIdReceptor:   recipientDocType,
 		NumeroDocIdReceptor: recipientDocNumber,
 		FechaEmision:        formattedDate,
@@ -207,9 +342,9 @@ func (vc *ValidationClient) buildSOAPRequest(params *formattedValidationParams)
 }
 
 // executeValidationRequest executes the SOAP request to SUNAT
-func (vc *ValidationClient) executeValidationRequest(soapXML string, params *formattedValidationParams) (*ValidationResult, error) {
+func (vc *ValidationClient) executeValidationRequest(ctx context.Context, soapXML string, params *formattedValidationParams) (*ValidationResult, error) {
 	// Create HTTP request
-	req, err := http.NewRequest("POST", vc.endpoint, strings.NewReader(soapXML))
+	req, err := http.NewRequestWithContext(ctx, "POST", vc.endpoint, strings.NewReader(soapXML))
 	if err != nil {
 		return nil, fmt.Errorf("error creating SOAP request: %w", err)
 	}
@@ -218,16 +353,17 @@ func (vc *ValidationClient) executeValidationRequest(soapXML string, params *for
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", "")
 	req.Header.Set("User-Agent", "SUNATLib/1.1.0")
+	utils.ApplyExtraHeaders(req, vc.extraHeaders)
 
 	// Execute request
 	resp, err := vc.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error executing SOAP request: %w", err)
+		return nil, utils.RedactError(fmt.Errorf("error executing SOAP request: %w", err), params.Password)
 	}
 	defer resp.Body.Close()
 
 	// Read response
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := readResponseBody(resp, vc.maxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("error reading SOAP response: %w", err)
 	}
@@ -269,9 +405,32 @@ func (vc *ValidationClient) parseValidationResponse(responseBody string, httpSta
 		}
 	}
 
+	// Extract the registered total, returned only for some documents
+	for _, element := range registeredAmountElements {
+		start := strings.Index(responseBody, "<"+element+">")
+		if start == -1 {
+			continue
+		}
+		start += len(element) + 2
+		end := strings.Index(responseBody[start:], "</"+element+">")
+		if end == -1 {
+			continue
+		}
+		if amount, err := strconv.ParseFloat(strings.TrimSpace(responseBody[start:start+end]), 64); err == nil {
+			result.RegisteredAmount = &amount
+			break
+		}
+	}
+
 	// Determine validity based on message content (ignoring status codes)
-	message := result.StatusMessage
+	result.State = validationStateFromMessage(result.StatusMessage)
+	result.IsValid = result.State == "VALIDO"
+	result.ErrorDetails = validationStateDetails(result.State)
+	return result
+}
 
+// validationStateFromMessage determines the document state from SUNAT's status message
+func validationStateFromMessage(message string) string {
 	// Check message content patterns
 	aux1 := strings.Contains(message, "no existe en los registros de SUNAT")
 	aux2 := strings.Contains(message, "no ha sido informada")
@@ -297,27 +456,23 @@ func (vc *ValidationClient) parseValidationResponse(responseBody string, httpSta
 		state = "VALIDO" // Válido
 	}
 
-	// Set validity and error details based on state
+	return state
+}
+
+// validationStateDetails returns the error details reported for a document state
+func validationStateDetails(state string) string {
 	switch state {
 	case "VALIDO":
-		result.IsValid = true
-		result.ErrorDetails = "Documento válido en SUNAT"
+		return "Documento válido en SUNAT"
 	case "ANULADO":
-		result.IsValid = false
-		result.ErrorDetails = "Documento anulado o dado de baja"
+		return "Documento anulado o dado de baja"
 	case "RECHAZADO":
-		result.IsValid = false
-		result.ErrorDetails = "Documento rechazado por SUNAT"
+		return "Documento rechazado por SUNAT"
 	case "NO_INFORMADO":
-		result.IsValid = false
-		result.ErrorDetails = "Documento no informado a SUNAT"
+		return "Documento no informado a SUNAT"
 	default:
-		result.IsValid = false
-		result.ErrorDetails = "Estado no determinado"
+		return "Estado no determinado"
 	}
-
-	result.State = state
-	return result
 }
 
 // ValidateInvoice is a convenience method for validating invoices
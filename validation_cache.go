@@ -0,0 +1,129 @@
+// Package sunatlib provides the rate limiter and result cache shared by concurrent validations
+package sunatlib
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validationLimiter spaces validation requests evenly so that all the goroutines using a
+// ValidationClient together stay under the configured rate
+type validationLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller may send its request or ctx is done. A nil limiter never blocks
+func (l *validationLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// validationCache keeps definitive validation results (VALIDO, ANULADO, RECHAZADO) for a TTL
+// and joins identical queries that are in flight, so each distinct query reaches SUNAT once
+type validationCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	entries  map[string]validationCacheEntry
+	inFlight map[string]*validationCall
+}
+
+// validationCacheEntry is a cached result and its expiry
+type validationCacheEntry struct {
+	result  *ValidationResult
+	expires time.Time
+}
+
+// validationCall is a query in flight; done is closed once result and err are set
+type validationCall struct {
+	done   chan struct{}
+	result *ValidationResult
+	err    error
+}
+
+// newValidationCache creates an empty cache keeping results for ttl
+func newValidationCache(ttl time.Duration) *validationCache {
+	return &validationCache{
+		ttl:      ttl,
+		entries:  make(map[string]validationCacheEntry),
+		inFlight: make(map[string]*validationCall),
+	}
+}
+
+// do returns the cached result for key, waits for an identical query in flight (until ctx is
+// done), or runs validate. It reports whether the result came from the cache or another caller's query
+func (c *validationCache) do(ctx context.Context, key string, validate func() (*ValidationResult, error)) (*ValidationResult, bool, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if time.Now().Before(entry.expires) {
+			c.mu.Unlock()
+			return copyValidationResult(entry.result), true, nil
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return copyValidationResult(call.result), true, call.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+
+	call := &validationCall{done: make(chan struct{})}
+	c.inFlight[key] = call
+	c.mu.Unlock()
+
+	call.result, call.err = validate()
+
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	if call.err == nil && call.result != nil && call.result.IsDefinitive() {
+		c.entries[key] = validationCacheEntry{result: call.result, expires: time.Now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return copyValidationResult(call.result), false, call.err
+}
+
+// copyValidationResult returns a copy of r so callers sharing a cached result cannot modify it
+func copyValidationResult(r *ValidationResult) *ValidationResult {
+	if r == nil {
+		return nil
+	}
+	result := *r
+	return &result
+}
+
+// cacheKey identifies the document queried, without the credentials
+func (p *formattedValidationParams) cacheKey() string {
+	return strings.Join([]string{
+		p.RucEmisor, p.TipoCDP, p.SerieCDP, p.NumeroCDP, p.TipoDocIdReceptor,
+		p.NumeroDocIdReceptor, p.FechaEmision, p.ImporteTotal, p.NroAutorizacion,
+	}, "|")
+}
@@ -0,0 +1,121 @@
+package sunatlib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidationClient_SharedCacheAndRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	var total atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		number := between(string(body), "<numeroCDP>", "</numeroCDP>")
+		mu.Lock()
+		requests[number]++
+		mu.Unlock()
+		total.Add(1)
+
+		time.Sleep(10 * time.Millisecond) // Keep identical queries in flight together
+		io.WriteString(w, validationResponse("El comprobante F001-"+number+" es un comprobante de pago válido."))
+	}))
+	defer server.Close()
+
+	const requestsPerSecond = 20
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+	client.SetMaxConcurrency(8)
+	client.SetRateLimit(requestsPerSecond)
+	client.SetCacheTTL(time.Minute)
+
+	record := func(number string) ValidationParams {
+		return ValidationParams{IssuerRUC: testRUC, DocumentType: "01", SeriesNumber: "F001", DocumentNumber: number, IssueDate: "2026-04-27", TotalAmount: 118}
+	}
+	distinct := []string{"1", "2", "3", "4", "5", "6"}
+	var records []ValidationParams
+	for i := 0; i < 4; i++ {
+		for _, number := range distinct {
+			records = append(records, record(number))
+		}
+	}
+
+	start := time.Now()
+
+	// Concurrent callers outside the batch share the same cache and limiter
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := record("1")
+			result, err := client.ValidateDocument(&params)
+			if err != nil || !result.IsValid {
+				t.Errorf("ValidateDocument() = %+v, %v, want a valid result", result, err)
+			}
+		}()
+	}
+
+	report, err := client.ValidateFromRecords(records)
+	if err != nil {
+		t.Fatalf("ValidateFromRecords() error = %v", err)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if len(report.Valid) != len(records) {
+		t.Errorf("valid records = %d, want %d", len(report.Valid), len(records))
+	}
+	for _, number := range distinct {
+		if requests[number] != 1 {
+			t.Errorf("document %s queried %d times, want once", number, requests[number])
+		}
+	}
+
+	calls := int64(len(records) + 10)
+	metrics := client.Metrics()
+	if metrics.ValidationCacheHits != calls-total.Load() {
+		t.Errorf("ValidationCacheHits = %d, want %d", metrics.ValidationCacheHits, calls-total.Load())
+	}
+	if metrics.Validations != total.Load() {
+		t.Errorf("Validations = %d, want %d requests", metrics.Validations, total.Load())
+	}
+
+	// The first request is sent at once, each further one waits for its slot
+	minElapsed := time.Duration(len(distinct)-1) * time.Second / requestsPerSecond
+	if elapsed < minElapsed {
+		t.Errorf("%d requests took %v, want at least %v at %d requests per second", total.Load(), elapsed, minElapsed, requestsPerSecond)
+	}
+}
+
+func TestValidationClient_CacheSkipsTransientResults(t *testing.T) {
+	var total atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		total.Add(1)
+		io.WriteString(w, validationResponse("El comprobante F001-1 no existe en los registros de SUNAT."))
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+	client.SetCacheTTL(time.Minute)
+
+	params := ValidationParams{IssuerRUC: testRUC, DocumentType: "01", SeriesNumber: "F001", DocumentNumber: "1", IssueDate: "2026-04-27", TotalAmount: 118}
+	for i := 0; i < 2; i++ {
+		result, err := client.ValidateDocument(&params)
+		if err != nil {
+			t.Fatalf("ValidateDocument() error = %v", err)
+		}
+		if result.State != "NO_INFORMADO" {
+			t.Errorf("State = %s, want NO_INFORMADO", result.State)
+		}
+	}
+	if total.Load() != 2 {
+		t.Errorf("requests = %d, want 2: NO_INFORMADO results must not be cached", total.Load())
+	}
+}
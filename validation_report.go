@@ -0,0 +1,99 @@
+// Package sunatlib provides bulk document reconciliation against SUNAT
+package sunatlib
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultValidationConcurrency is the number of concurrent validations used by ValidateFromRecords by default
+const DefaultValidationConcurrency = 4
+
+// ValidationStateError is the report state of records whose validation request failed
+const ValidationStateError = "ERROR"
+
+// ValidationReportEntry holds the outcome of validating a single record
+type ValidationReportEntry struct {
+	Index  int               // Position of the record in the input
+	Params ValidationParams  // Validated record
+	Result *ValidationResult // SUNAT result (nil if the request failed)
+	Err    error             // Request error, if any
+}
+
+// State returns the SUNAT state of the entry, or ValidationStateError if the request failed
+func (e *ValidationReportEntry) State() string {
+	if e.Err != nil || e.Result == nil {
+		return ValidationStateError
+	}
+	return e.Result.State
+}
+
+// ValidationReport summarizes the validation of a set of records
+type ValidationReport struct {
+	Total       int                     // Number of records validated
+	StateCounts map[string]int          // Number of records per state (VALIDO, NO_INFORMADO, ANULADO, RECHAZADO, UNKNOWN, ERROR)
+	Entries     []ValidationReportEntry // All entries, in input order
+	Valid       []ValidationReportEntry // Documents SUNAT recognizes as valid (VALIDO)
+	NotInformed []ValidationReportEntry // Documents SUNAT has no record of (NO_INFORMADO)
+	Invalid     []ValidationReportEntry // Documents voided, rejected or in an undetermined state
+	Failed      []ValidationReportEntry // Records whose validation request failed
+}
+
+// ValidateFromRecords validates every record against SUNAT with bounded concurrency and
+// returns a reconciliation report. Request failures are reported per record, not as an error
+func (vc *ValidationClient) ValidateFromRecords(records []ValidationParams) (*ValidationReport, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records to validate")
+	}
+
+	workers := vc.maxConcurrency
+	if workers <= 0 {
+		workers = DefaultValidationConcurrency
+	}
+
+	entries := make([]ValidationReportEntry, len(records))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				params := records[i]
+				result, err := vc.ValidateDocument(&params)
+				entries[i] = ValidationReportEntry{Index: i, Params: params, Result: result, Err: err}
+			}
+		}()
+	}
+
+	for i := range records {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	report := &ValidationReport{
+		Total:       len(records),
+		StateCounts: make(map[string]int),
+		Entries:     entries,
+	}
+
+	for _, entry := range entries {
+		state := entry.State()
+		report.StateCounts[state]++
+
+		switch state {
+		case "VALIDO":
+			report.Valid = append(report.Valid, entry)
+		case "NO_INFORMADO":
+			report.NotInformed = append(report.NotInformed, entry)
+		case ValidationStateError:
+			report.Failed = append(report.Failed, entry)
+		default:
+			report.Invalid = append(report.Invalid, entry)
+		}
+	}
+
+	return report, nil
+}
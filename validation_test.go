@@ -0,0 +1,215 @@
+package sunatlib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+func TestValidationResult_IsDefinitive(t *testing.T) {
+	tests := []struct {
+		state      string
+		definitive bool
+	}{
+		{"VALIDO", true},
+		{"ANULADO", true},
+		{"RECHAZADO", true},
+		{"NO_INFORMADO", false},
+		{"UNKNOWN", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			result := &ValidationResult{State: tt.state}
+
+			if got := result.IsDefinitive(); got != tt.definitive {
+				t.Errorf("IsDefinitive() = %v, want %v", got, tt.definitive)
+			}
+			if got := result.IsTransient(); got != !tt.definitive {
+				t.Errorf("IsTransient() = %v, want %v", got, !tt.definitive)
+			}
+		})
+	}
+}
+
+// validationResponse builds a validaCDPcriterios response with the given status message
+func validationResponse(message string) string {
+	return `<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><ns2:validaCDPcriteriosResponse xmlns:ns2="http://service.sunat.gob.pe"><statusCode>0001</statusCode><statusMessage>` + message + `</statusMessage></ns2:validaCDPcriteriosResponse></soap-env:Body></soap-env:Envelope>`
+}
+
+func TestValidateFromRecords(t *testing.T) {
+	messages := map[string]string{
+		"1": "El comprobante F001-1 es un comprobante de pago válido.",
+		"2": "El comprobante F001-2 no existe en los registros de SUNAT.",
+		"3": "El comprobante F001-3 ha sido informado a SUNAT y se encuentra de BAJA.",
+		"4": "El comprobante F001-4 es un comprobante de pago válido.",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for number, message := range messages {
+			if strings.Contains(string(body), "<numeroCDP>"+number+"</numeroCDP>") {
+				io.WriteString(w, validationResponse(message))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+	client.SetMaxConcurrency(2)
+
+	record := func(number, issueDate string) ValidationParams {
+		return ValidationParams{IssuerRUC: testRUC, DocumentType: "01", SeriesNumber: "F001", DocumentNumber: number, IssueDate: issueDate, TotalAmount: 118}
+	}
+	records := []ValidationParams{
+		record("1", "2026-04-27"),
+		record("2", "2026-04-27"),
+		record("3", "2026-04-27"),
+		record("4", "2026-04-27"),
+		record("5", "27-04-2026"), // Unsupported date format
+	}
+
+	report, err := client.ValidateFromRecords(records)
+	if err != nil {
+		t.Fatalf("ValidateFromRecords() error = %v", err)
+	}
+
+	expectedCounts := map[string]int{"VALIDO": 2, "NO_INFORMADO": 1, "ANULADO": 1, ValidationStateError: 1}
+	for state, count := range expectedCounts {
+		if report.StateCounts[state] != count {
+			t.Errorf("Expected %d records in state %s, got %d", count, state, report.StateCounts[state])
+		}
+	}
+
+	if report.Total != 5 || len(report.Entries) != 5 {
+		t.Errorf("Expected 5 entries, got total=%d entries=%d", report.Total, len(report.Entries))
+	}
+	if len(report.NotInformed) != 1 || report.NotInformed[0].Params.DocumentNumber != "2" {
+		t.Errorf("Expected document 2 as not informed, got %+v", report.NotInformed)
+	}
+	if len(report.Invalid) != 1 || report.Invalid[0].Params.DocumentNumber != "3" {
+		t.Errorf("Expected document 3 as invalid, got %+v", report.Invalid)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Index != 4 || report.Failed[0].Err == nil {
+		t.Errorf("Expected record 5 as failed, got %+v", report.Failed)
+	}
+}
+
+func TestValidateFromRecords_Empty(t *testing.T) {
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+
+	if _, err := client.ValidateFromRecords(nil); err == nil {
+		t.Fatal("Expected error for empty records")
+	}
+}
+
+func TestValidationClient_SetCertificatePinsKeepsTimeouts(t *testing.T) {
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+	client.SetTransportTimeouts(utils.TransportTimeouts{ResponseHeaderTimeout: 5 * time.Second})
+	client.SetCertificatePins(nil, strings.Repeat("ab", 32))
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.httpClient.Transport)
+	}
+	if transport.ResponseHeaderTimeout != 5*time.Second {
+		t.Errorf("ResponseHeaderTimeout = %v, want the configured 5s", transport.ResponseHeaderTimeout)
+	}
+	if client.httpClient.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want the default 30s", client.httpClient.Timeout)
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.VerifyConnection == nil {
+		t.Error("Expected the pinning verification on the transport")
+	}
+}
+
+func TestFormatValidationParams_CombinedSerieNumero(t *testing.T) {
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+
+	formatted, err := client.formatValidationParams(&ValidationParams{
+		IssuerRUC:    testRUC,
+		DocumentType: "01",
+		SeriesNumber: "F001-00000123",
+		IssueDate:    "2026-04-27",
+		TotalAmount:  118,
+	})
+	if err != nil {
+		t.Fatalf("formatValidationParams() error = %v", err)
+	}
+	if formatted.SerieCDP != "F001" || formatted.NumeroCDP != "00000123" {
+		t.Errorf("Expected F001 / 00000123, got %s / %s", formatted.SerieCDP, formatted.NumeroCDP)
+	}
+}
+
+func TestFormatValidationParams_InvalidAmount(t *testing.T) {
+	client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+
+	for _, amount := range []float64{-1, 1e13} {
+		_, err := client.formatValidationParams(&ValidationParams{
+			IssuerRUC:    testRUC,
+			DocumentType: "01",
+			SeriesNumber: "F001-00000123",
+			IssueDate:    "2026-04-27",
+			TotalAmount:  amount,
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid total amount") {
+			t.Errorf("formatValidationParams(%v) error = %v, want invalid total amount", amount, err)
+		}
+	}
+}
+
+func TestValidateDocument_RegisteredAmount(t *testing.T) {
+	valid := "El comprobante F001-1 es un comprobante de pago válido."
+	withAmount := strings.Replace(validationResponse(valid), "</statusMessage>", "</statusMessage><importeTotal>150.00</importeTotal>", 1)
+
+	tests := []struct {
+		name        string
+		response    string
+		wantAmount  float64
+		hasAmount   bool
+		wantDiffers bool
+	}{
+		{"Registered Amount Differs", withAmount, 150, true, true},
+		{"Without Registered Amount", validationResponse(valid), 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			client := NewValidationClient(testRUC, "MODDATOS", "MODDATOS")
+			client.SetEndpoints(map[ServiceType]string{ServiceValidation: server.URL})
+
+			result, err := client.ValidateDocument(&ValidationParams{IssuerRUC: testRUC, DocumentType: "01", SeriesNumber: "F001", DocumentNumber: "1", IssueDate: "2026-04-27", TotalAmount: 118})
+			if err != nil {
+				t.Fatalf("ValidateDocument() error = %v", err)
+			}
+			if !result.IsValid {
+				t.Errorf("IsValid = false, want true")
+			}
+			if tt.hasAmount {
+				if result.RegisteredAmount == nil || *result.RegisteredAmount != tt.wantAmount {
+					t.Errorf("RegisteredAmount = %v, want %v", result.RegisteredAmount, tt.wantAmount)
+				}
+			} else if result.RegisteredAmount != nil {
+				t.Errorf("RegisteredAmount = %v, want nil", *result.RegisteredAmount)
+			}
+			if got := result.AmountDiffers(118); got != tt.wantDiffers {
+				t.Errorf("AmountDiffers(118) = %v, want %v", got, tt.wantDiffers)
+			}
+			if result.AmountDiffers(150) {
+				t.Errorf("AmountDiffers(150) = true, want false")
+			}
+		})
+	}
+}
@@ -4,24 +4,36 @@ package sunatlib
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/henrybravos/sunatlib/signer"
 	"github.com/henrybravos/sunatlib/utils"
 )
 
 // VoidedDocument represents a document to be voided
 type VoidedDocument struct {
 	DocumentTypeCode string // Document type code (01=Invoice, 03=Receipt, etc.)
-	DocumentSeries   string // Document series (e.g., "F001", "B001")
+	DocumentSeries   string // Document series (e.g., "F001", "B001"), or SERIE-NUMERO when DocumentNumber is empty
 	DocumentNumber   string // Document correlative number
 	VoidedReason     string // Reason for voiding the document
 }
 
+// Length limits of the voided reason (sac:VoidReasonDescription) accepted by SUNAT
+const (
+	MinVoidedReasonLength = 3
+	MaxVoidedReasonLength = 100
+)
+
 // VoidedDocumentsRequest represents a voided documents communication request
 type VoidedDocumentsRequest struct {
 	RUC             string           // Company RUC
@@ -31,24 +43,89 @@ type VoidedDocumentsRequest struct {
 	ReferenceDate   time.Time        // Reference date (date of voided documents)
 	Documents       []VoidedDocument // List of documents to void
 	Description     string           // Description of the voiding communication
+	SignatureID     string           // Signature Id referenced by the document (defaults to signer.DefaultSignatureID)
+	UBLVersionID    string           // cbc:UBLVersionID (defaults to the RA version of utils.GetDocumentVersion)
+	CustomizationID string           // cbc:CustomizationID (defaults to the RA version of utils.GetDocumentVersion)
+}
+
+// AcceptedVoidedDocumentsVersions lists the UBLVersionID/CustomizationID pairs accepted for voided
+// documents. Append to it if SUNAT publishes a new schema version before the library is updated
+var AcceptedVoidedDocumentsVersions = []utils.DocumentVersion{
+	{UBLVersionID: "2.0", CustomizationID: "1.0"},
+}
+
+// version returns the UBL versions of the communication, applying the defaults for empty fields
+func (req *VoidedDocumentsRequest) version() utils.DocumentVersion {
+	version, _ := utils.GetDocumentVersion("RA")
+	if req.UBLVersionID != "" {
+		version.UBLVersionID = req.UBLVersionID
+	}
+	if req.CustomizationID != "" {
+		version.CustomizationID = req.CustomizationID
+	}
+	return version
+}
+
+// validateVersion checks the UBL versions of the communication against AcceptedVoidedDocumentsVersions
+func (req *VoidedDocumentsRequest) validateVersion() error {
+	version := req.version()
+	for _, accepted := range AcceptedVoidedDocumentsVersions {
+		if version == accepted {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported UBLVersionID %s / CustomizationID %s for voided documents (see AcceptedVoidedDocumentsVersions)",
+		version.UBLVersionID, version.CustomizationID)
 }
 
+// ErrVoidedDocumentsNotAccepted is returned by VoidAndWait when SUNAT does not accept
+// the voided documents communication and therefore no ticket is issued
+var ErrVoidedDocumentsNotAccepted = errors.New("voided documents communication not accepted by SUNAT")
+
 // VoidedDocumentsResponse represents the response from SUNAT
 type VoidedDocumentsResponse struct {
 	Success         bool
 	Message         string
 	Ticket          string // Ticket number for async status checking
 	ResponseXML     []byte
-	Error           error
+	Unrecognized    bool // True when the response could not be interpreted; see ResponseXML
+	FaultCode       string // SUNAT error code of a SOAP fault (e.g., "1032"), empty otherwise
+	FaultDetail     string // Text of the fault's <detail> element, often holding the actual error, if any
+	AlreadyVoided   bool   // True when the documents were already voided (see SUNATClient.TreatAlreadyVoidedAsSuccess)
+	Error           error  // Typed error of the fault, if any (e.g., ErrCredentialLocked or ErrCertificateRejectedBySUNAT)
+}
+
+// AlreadyVoidedCode is the fault SUNAT returns when a document was already informed in a
+// comunicación de baja
+const AlreadyVoidedCode = "1032"
+
+// DebugString returns a truncated, credential-free dump of the response for support reports
+func (r *VoidedDocumentsResponse) DebugString() string {
+	return debugDump("VoidedDocumentsResponse", r.Success, r.Unrecognized, r.Message, r.ResponseXML)
 }
 
 
-// GenerateVoidedDocumentsXML generates the XML for voided documents communication
+// GenerateVoidedDocumentsXML generates the XML for voided documents communication. It is
+// UTF-8 encoded as declared in its XML prolog, so accented names are preserved
 func (c *SUNATClient) GenerateVoidedDocumentsXML(request *VoidedDocumentsRequest) ([]byte, error) {
+	if docType := seriesDocumentType(request.SeriesNumber); docType != "" && docType != "RA" {
+		return nil, utils.NewUnsupportedDocumentTypeError(docType, "GenerateVoidedDocumentsXML", "RA")
+	}
+
 	if len(request.Documents) == 0 {
 		return nil, fmt.Errorf("no documents to void")
 	}
 
+	if err := request.validateVersion(); err != nil {
+		return nil, err
+	}
+	version := request.version()
+
+	signatureID := request.SignatureID
+	if signatureID == "" {
+		signatureID = signer.DefaultSignatureID
+	}
+
 	// Generate XML content based on SUNAT VoidedDocuments schema (following PHP example format)
 	xmlContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
 <VoidedDocuments xmlns="urn:sunat:names:specification:ubl:peru:schema:xsd:VoidedDocuments-1"
@@ -62,13 +139,13 @@ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
 <ext:ExtensionContent>
     </ext:ExtensionContent>
 </ext:UBLExtension></ext:UBLExtensions>
-<cbc:UBLVersionID>2.0</cbc:UBLVersionID>
-<cbc:CustomizationID>1.0</cbc:CustomizationID>
+<cbc:UBLVersionID>%s</cbc:UBLVersionID>
+<cbc:CustomizationID>%s</cbc:CustomizationID>
 <cbc:ID>%s</cbc:ID>
 <cbc:ReferenceDate>%s</cbc:ReferenceDate>
 <cbc:IssueDate>%s</cbc:IssueDate>
 <cac:Signature>
-<cbc:ID>IDSignKG</cbc:ID>
+<cbc:ID>%s</cbc:ID>
 <cac:SignatoryParty>
 <cac:PartyIdentification>
 <cbc:ID>%s</cbc:ID>
@@ -79,7 +156,7 @@ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
 </cac:SignatoryParty>
 <cac:DigitalSignatureAttachment>
 <cac:ExternalReference>
-<cbc:URI>#signatureKG</cbc:URI>
+<cbc:URI>#%s</cbc:URI>
 </cac:ExternalReference>
 </cac:DigitalSignatureAttachment>
 </cac:Signature>
@@ -92,16 +169,21 @@ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
 </cac:PartyLegalEntity>
 </cac:Party>
 </cac:AccountingSupplierParty>`,
+		version.UBLVersionID,
+		version.CustomizationID,
 		request.SeriesNumber,
 		request.ReferenceDate.Format("2006-01-02"),
 		request.IssueDate.Format("2006-01-02"),
+		signatureID,
 		request.RUC,
 		utils.ValidateSpecialCharacters(request.CompanyName),
+		signatureID,
 		request.RUC,
 		utils.ValidateSpecialCharacters(request.CompanyName))
 
 	// Add voided document lines
 	for i, doc := range request.Documents {
+		series, number := doc.seriesAndNumber()
 		line := fmt.Sprintf(`
 <sac:VoidedDocumentsLine>
 <cbc:LineID>%d</cbc:LineID>
@@ -112,9 +194,9 @@ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
 </sac:VoidedDocumentsLine>`,
 			i+1,
 			doc.DocumentTypeCode,
-			doc.DocumentSeries,
-			doc.DocumentNumber,
-			utils.ValidateSpecialCharacters(doc.VoidedReason))
+			series,
+			number,
+			utils.ValidateSpecialCharacters(doc.cleanVoidedReason()))
 		xmlContent += line
 	}
 
@@ -126,6 +208,18 @@ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
 
 // SendVoidedDocuments sends voided documents communication to SUNAT
 func (c *SUNATClient) SendVoidedDocuments(request *VoidedDocumentsRequest) (*VoidedDocumentsResponse, error) {
+	return c.SendVoidedDocumentsContext(context.Background(), request)
+}
+
+// SendVoidedDocumentsContext is like SendVoidedDocuments, cancelling the request when ctx is done
+func (c *SUNATClient) SendVoidedDocumentsContext(ctx context.Context, request *VoidedDocumentsRequest) (response *VoidedDocumentsResponse, err error) {
+	attempted := false
+	defer func() {
+		if attempted {
+			c.metrics.recordSend(err == nil && response != nil && response.Success)
+		}
+	}()
+
 	// Validate request first
 	if err := request.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
@@ -154,6 +248,17 @@ func (c *SUNATClient) SendVoidedDocuments(request *VoidedDocumentsRequest) (*Voi
 		return nil, fmt.Errorf("failed to create ZIP: %w", err)
 	}
 
+	attempted = true
+	responseData, err := c.postSendSummary(ctx, zipName, zipData)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.parseVoidedDocumentsResponse(responseData)
+}
+
+// postSendSummary sends a ZIP package with the sendSummary operation and returns the raw response
+func (c *SUNATClient) postSendSummary(ctx context.Context, zipName string, zipData []byte) ([]byte, error) {
 	// Encode to base64
 	zipB64 := base64.StdEncoding.EncodeToString(zipData)
 
@@ -174,33 +279,38 @@ func (c *SUNATClient) SendVoidedDocuments(request *VoidedDocumentsRequest) (*Voi
       <contentFile>%s</contentFile>
     </ser:sendSummary>
   </soapenv:Body>
-</soapenv:Envelope>`, c.RUC, c.Username, c.Password, zipName, zipB64)
+</soapenv:Envelope>`, c.senderRUC(), c.Username, c.Password, zipName, zipB64)
 
 	// Send HTTP request
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpointFor(ServiceSummary), bytes.NewBuffer([]byte(soapBody)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", "urn:sendSummary")
+	utils.ApplyExtraHeaders(req, c.extraHeaders)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, c.redactError(fmt.Errorf("failed to send HTTP request: %w", err))
 	}
 	defer resp.Body.Close()
 
-	responseData, err := io.ReadAll(resp.Body)
+	responseData, err := readResponseBody(resp, c.MaxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return c.parseVoidedDocumentsResponse(responseData)
+	return responseData, nil
 }
 
 // createVoidedDocumentsZIP creates a ZIP file for voided documents
 func (c *SUNATClient) createVoidedDocumentsZIP(signedXML []byte, seriesNumber string) ([]byte, string, error) {
+	if err := ValidateDocumentSize(signedXML); err != nil {
+		return nil, "", err
+	}
+
 	xmlName := fmt.Sprintf("%s-%s.xml", c.RUC, seriesNumber)
 	zipName := fmt.Sprintf("%s-%s.zip", c.RUC, seriesNumber)
 
@@ -218,11 +328,32 @@ func (c *SUNATClient) createVoidedDocumentsZIP(signedXML []byte, seriesNumber st
 
 	zipWriter.Close()
 
+	if err := validateZIPSize(zipName, buf.Bytes()); err != nil {
+		return nil, "", err
+	}
 	return buf.Bytes(), zipName, nil
 }
 
-// parseVoidedDocumentsResponse parses SUNAT's response for voided documents
+// parseVoidedDocumentsResponse parses SUNAT's response for voided documents. With
+// TreatAlreadyVoidedAsSuccess, the AlreadyVoidedCode fault is a success without ticket
 func (c *SUNATClient) parseVoidedDocumentsResponse(responseData []byte) (*VoidedDocumentsResponse, error) {
+	response, err := c.parseTicketResponse(responseData, "sendSummaryResponse", msgVoidedDocumentsSent)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TreatAlreadyVoidedAsSuccess && response.FaultCode == AlreadyVoidedCode {
+		response.Success = true
+		response.AlreadyVoided = true
+		response.Error = nil
+	}
+
+	return response, nil
+}
+
+// parseTicketResponse parses the response of an asynchronous operation (sendSummary, sendPack)
+// whose responseElement carries the ticket
+func (c *SUNATClient) parseTicketResponse(responseData []byte, responseElement string, successMessage messageKey) (*VoidedDocumentsResponse, error) {
 	responseStr := string(responseData)
 	response := &VoidedDocumentsResponse{
 		ResponseXML: responseData,
@@ -231,24 +362,27 @@ func (c *SUNATClient) parseVoidedDocumentsResponse(responseData []byte) (*Voided
 	// Check for SOAP fault
 	if strings.Contains(responseStr, "<soap-env:Fault") {
 		response.Success = false
+		response.FaultCode = soapFaultCode(responseStr)
 
 		// Extract fault string
 		if start := strings.Index(responseStr, "<faultstring>"); start != -1 {
 			start += 13
 			if end := strings.Index(responseStr[start:], "</faultstring>"); end != -1 {
-				response.Message = responseStr[start : start+end]
+				response.Message = c.redact(responseStr[start : start+end])
 				// Decode HTML entities
 				response.Message = strings.ReplaceAll(response.Message, "&#243;", "ó")
 			}
 		}
+		response.FaultDetail = soapFaultDetail(responseStr)
+		response.Error = faultError(response.FaultCode, response.Message)
 
 		return response, nil
 	}
 
-	// Check for successful response - sendSummary returns a ticket
-	if strings.Contains(responseStr, "sendSummaryResponse") {
+	// Check for successful response - asynchronous operations return a ticket
+	if strings.Contains(responseStr, responseElement) {
 		response.Success = true
-		response.Message = "Comunicación de baja enviada exitosamente"
+		response.Message = c.Language.message(successMessage)
 
 		// Extract ticket
 		if start := strings.Index(responseStr, "<ticket>"); start != -1 {
@@ -262,12 +396,20 @@ func (c *SUNATClient) parseVoidedDocumentsResponse(responseData []byte) (*Voided
 	}
 
 	response.Success = false
-	response.Message = "Respuesta no reconocida de SUNAT"
+	response.Unrecognized = true
+	response.Message = c.Language.message(msgUnrecognizedResponse)
 	return response, nil
 }
 
-// GetVoidedDocumentsStatus checks the status of a voided documents communication using the ticket
+// GetVoidedDocumentsStatus checks the status of a voided documents communication using the ticket.
+// Network errors are retried according to SUNATClient.StatusRetry
 func (c *SUNATClient) GetVoidedDocumentsStatus(ticket string) (*SUNATResponse, error) {
+	return c.GetVoidedDocumentsStatusContext(context.Background(), ticket)
+}
+
+// GetVoidedDocumentsStatusContext is like GetVoidedDocumentsStatus, cancelling the query and its
+// retries when ctx is done
+func (c *SUNATClient) GetVoidedDocumentsStatusContext(ctx context.Context, ticket string) (*SUNATResponse, error) {
 	// Build SOAP envelope for getStatus
 	soapBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
 <soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.sunat.gob.pe" xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
@@ -284,29 +426,19 @@ func (c *SUNATClient) GetVoidedDocumentsStatus(ticket string) (*SUNATResponse, e
       <ticket>%s</ticket>
     </ser:getStatus>
   </soapenv:Body>
-</soapenv:Envelope>`, c.RUC, c.Username, c.Password, ticket)
+</soapenv:Envelope>`, c.senderRUC(), c.Username, c.Password, ticket)
 
-	// Send HTTP request
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	req.Header.Set("SOAPAction", "urn:getStatus")
-
-	resp, err := http.DefaultClient.Do(req)
+	// getStatus is idempotent, so network errors are retried
+	responseData, attempts, err := c.postStatusQuery(ctx, soapBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	response, err := c.parseResponse("getStatus", responseData)
+	if response != nil {
+		response.Attempts = attempts
 	}
-
-	return c.parseResponse(responseData)
+	return response, err
 }
 
 // Validate validates the voided documents request
@@ -327,15 +459,34 @@ func (req *VoidedDocumentsRequest) Validate() error {
 		return fmt.Errorf("series number is required")
 	}
 
+	if err := req.validateVersion(); err != nil {
+		return err
+	}
+
+	// The communication cannot be issued before the date of the documents it voids;
+	// compare the calendar dates sent in the XML
+	if req.IssueDate.Format("2006-01-02") < req.ReferenceDate.Format("2006-01-02") {
+		return fmt.Errorf("issue date %s is before reference date %s",
+			req.IssueDate.Format("2006-01-02"), req.ReferenceDate.Format("2006-01-02"))
+	}
+
 	if len(req.Documents) == 0 {
 		return fmt.Errorf("at least one document is required")
 	}
 
-	// Validate each document
+	// Validate each document and reject duplicates, SUNAT rejects the whole communication
+	seen := make(map[string]int, len(req.Documents))
 	for i, doc := range req.Documents {
 		if err := doc.Validate(); err != nil {
 			return fmt.Errorf("document %d: %w", i+1, err)
 		}
+
+		// F001-123 and F001-00000123 are the same document
+		key := doc.DocumentTypeCode + "-" + utils.NormalizeSerieNumero(doc.seriesAndNumber())
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("document %d: duplicate of document %d (%s)", i+1, first, key)
+		}
+		seen[key] = i + 1
 	}
 
 	return nil
@@ -355,25 +506,54 @@ func (doc *VoidedDocument) Validate() error {
 		return fmt.Errorf("document series is required")
 	}
 
-	if !utils.ValidateDocumentSeries(doc.DocumentSeries) {
-		return fmt.Errorf("invalid document series format: %s", doc.DocumentSeries)
+	series, number := doc.DocumentSeries, doc.DocumentNumber
+	if number == "" && strings.Contains(series, "-") {
+		var err error
+		if series, number, err = utils.SplitSerieNumero(series); err != nil {
+			return err
+		}
 	}
 
-	if doc.DocumentNumber == "" {
+	if !utils.ValidateDocumentSeries(series) {
+		return fmt.Errorf("invalid document series format: %s", series)
+	}
+
+	if number == "" {
 		return fmt.Errorf("document number is required")
 	}
 
-	if !utils.ValidateDocumentNumber(doc.DocumentNumber) {
-		return fmt.Errorf("invalid document number format: %s", doc.DocumentNumber)
+	if !utils.ValidateDocumentNumber(number) {
+		return fmt.Errorf("invalid document number format: %s", number)
 	}
 
-	if doc.VoidedReason == "" {
+	reason := doc.cleanVoidedReason()
+	if reason == "" {
 		return fmt.Errorf("voided reason is required")
 	}
 
+	if length := utf8.RuneCountInString(reason); length < MinVoidedReasonLength || length > MaxVoidedReasonLength {
+		return fmt.Errorf("voided reason must be between %d and %d characters, got %d", MinVoidedReasonLength, MaxVoidedReasonLength, length)
+	}
+
 	return nil
 }
 
+// seriesAndNumber returns the document series and number, splitting DocumentSeries when
+// it holds the combined SERIE-NUMERO identifier
+func (doc *VoidedDocument) seriesAndNumber() (string, string) {
+	if doc.DocumentNumber == "" {
+		if series, number, err := utils.SplitSerieNumero(doc.DocumentSeries); err == nil {
+			return series, number
+		}
+	}
+	return doc.DocumentSeries, doc.DocumentNumber
+}
+
+// cleanVoidedReason returns the voided reason without control characters or surrounding spaces
+func (doc *VoidedDocument) cleanVoidedReason() string {
+	return strings.TrimSpace(utils.StripControlCharacters(doc.VoidedReason))
+}
+
 // TicketStatusResponse represents the response from ticket status query
 type TicketStatusResponse struct {
 	Success           bool
@@ -384,18 +564,46 @@ type TicketStatusResponse struct {
 	ProcessDate       time.Time   // Date when the document was processed
 	ResponseXML       []byte      // Full SOAP response
 	ApplicationResponse []byte    // CDR ZIP content if available
+	CDRPath           string      // Path where the CDR was saved, if any
+	Unrecognized      bool        // True when the response could not be interpreted; see ResponseXML
+	Attempts          int         // Number of getStatus requests made (network errors are retried)
+	FaultCode         string      // SUNAT error code of a SOAP fault (e.g., 0127), if any
+	FaultDetail       string      // Text of the fault's <detail> element, if any
 	Error             error
+	ReceivedAt        time.Time   // When the response was received, on the client clock
+	AlreadyVoided     bool        // True when VoidAndWait found the documents already voided; no ticket was issued
+
+	language               Language // Language of GetTicketStatusDescription, from the client that made the query
+	applicationResponseB64 string   // Base64 CDR as received, streamed to disk by QueryVoidedDocumentsTicketAndSave
+}
+
+// TicketNotFoundCode is the SUNAT fault code returned when a ticket does not exist
+const TicketNotFoundCode = "0127"
+
+// TicketNotFoundGracePeriod is how long after polling starts WaitForTicketProcessing treats
+// TicketNotFoundCode as "not registered yet": SUNAT may not know a ticket it has just issued
+var TicketNotFoundGracePeriod = 15 * time.Second
+
+// IsTicketNotFound returns true if SUNAT reported that the ticket does not exist
+func (r *TicketStatusResponse) IsTicketNotFound() bool {
+	return r.FaultCode == TicketNotFoundCode
 }
 
-// GetTicketStatusDescription returns a human-readable description of the ticket status
+// DebugString returns a truncated, credential-free dump of the response for support reports
+func (r *TicketStatusResponse) DebugString() string {
+	return debugDump("TicketStatusResponse", r.Success, r.Unrecognized, r.Message, r.ResponseXML)
+}
+
+// GetTicketStatusDescription returns a human-readable description of the ticket status in the
+// Language of the client that made the query
 func (r *TicketStatusResponse) GetTicketStatusDescription() string {
 	switch r.StatusCode {
 	case "0":
-		return "Procesado correctamente"
+		return r.language.message(msgTicketProcessed)
 	case "98":
-		return "En proceso"
+		return r.language.message(msgTicketInProgress)
 	case "99":
-		return "Procesado con errores"
+		return r.language.message(msgTicketProcessedWithErrors)
 	default:
 		return r.StatusDescription
 	}
@@ -432,8 +640,15 @@ func (r *TicketStatusResponse) HasApplicationResponse() bool {
 }
 
 // QueryVoidedDocumentsTicket queries the status of a voided documents communication ticket
-// This is a more specific and enhanced version of GetVoidedDocumentsStatus
+// This is a more specific and enhanced version of GetVoidedDocumentsStatus. Network errors are
+// retried according to SUNATClient.StatusRetry
 func (c *SUNATClient) QueryVoidedDocumentsTicket(ticket string) (*TicketStatusResponse, error) {
+	return c.QueryVoidedDocumentsTicketContext(context.Background(), ticket)
+}
+
+// QueryVoidedDocumentsTicketContext is like QueryVoidedDocumentsTicket, cancelling the query and
+// its retries when ctx is done
+func (c *SUNATClient) QueryVoidedDocumentsTicketContext(ctx context.Context, ticket string) (*TicketStatusResponse, error) {
 	if ticket == "" {
 		return nil, fmt.Errorf("ticket number is required")
 	}
@@ -454,29 +669,44 @@ func (c *SUNATClient) QueryVoidedDocumentsTicket(ticket string) (*TicketStatusRe
       <ticket>%s</ticket>
     </ser:getStatus>
   </soapenv:Body>
-</soapenv:Envelope>`, c.RUC, c.Username, c.Password, ticket)
+</soapenv:Envelope>`, c.senderRUC(), c.Username, c.Password, ticket)
 
-	// Send HTTP request
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer([]byte(soapBody)))
+	// getStatus is idempotent, so network errors are retried
+	responseData, attempts, err := c.postStatusQuery(ctx, soapBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	req.Header.Set("SOAPAction", "urn:getStatus")
+	response, err := c.parseTicketStatusResponse(responseData, ticket)
+	if response != nil {
+		response.Attempts = attempts
+	}
+	return response, err
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// QueryVoidedDocumentsTicketAndSave queries a ticket and, once processed, saves the CDR
+// (or the error CDR) into cdrDir. Nothing is written while the ticket is still in progress
+func (c *SUNATClient) QueryVoidedDocumentsTicketAndSave(ticket, cdrDir string) (*TicketStatusResponse, error) {
+	response, err := c.QueryVoidedDocumentsTicket(ticket)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	if !response.IsProcessed() || !response.HasApplicationResponse() {
+		return response, nil
+	}
+
+	if err := os.MkdirAll(cdrDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create CDR directory: %w", err)
 	}
 
-	return c.parseTicketStatusResponse(responseData, ticket)
+	cdrPath := filepath.Join(cdrDir, fmt.Sprintf("R-%s-%s.zip", c.RUC, ticket))
+	if err := SaveCDRBase64(cdrPath, response.applicationResponseB64); err != nil {
+		return nil, err
+	}
+	response.CDRPath = cdrPath
+
+	return response, nil
 }
 
 // parseTicketStatusResponse parses SUNAT's response for ticket status queries
@@ -485,6 +715,8 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 	response := &TicketStatusResponse{
 		ResponseXML: responseData,
 		Ticket:      ticket,
+		ReceivedAt:  c.clock().Now(),
+		language:    c.Language,
 	}
 
 	// Check for SOAP fault
@@ -495,7 +727,7 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 		if start := strings.Index(responseStr, "<faultstring>"); start != -1 {
 			start += 13
 			if end := strings.Index(responseStr[start:], "</faultstring>"); end != -1 {
-				response.Message = responseStr[start : start+end]
+				response.Message = c.redact(responseStr[start : start+end])
 				// Decode HTML entities
 				response.Message = strings.ReplaceAll(response.Message, "&#243;", "ó")
 				response.Message = strings.ReplaceAll(response.Message, "&lt;", "<")
@@ -503,6 +735,9 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 				response.Message = strings.ReplaceAll(response.Message, "&amp;", "&")
 			}
 		}
+		response.FaultCode = soapFaultCode(responseStr)
+		response.FaultDetail = soapFaultDetail(responseStr)
+		response.Error = faultError(response.FaultCode, response.Message)
 
 		return response, nil
 	}
@@ -530,14 +765,15 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 					contentB64 := responseStr[start : start+end]
 					if decodedContent, err := base64.StdEncoding.DecodeString(contentB64); err == nil {
 						response.ApplicationResponse = decodedContent
+						response.applicationResponseB64 = contentB64
 					}
 				}
 			}
-			response.Message = "Comunicación de baja procesada exitosamente"
+			response.Message = c.Language.message(msgVoidedDocumentsProcessed)
 		} else if response.StatusCode == "98" {
-			response.Message = "Comunicación de baja en proceso de validación"
+			response.Message = c.Language.message(msgVoidedDocumentsInProgress)
 		} else if response.StatusCode == "99" {
-			response.Message = "Comunicación de baja procesada con errores"
+			response.Message = c.Language.message(msgVoidedDocumentsProcessedWithErrors)
 			// Try to extract error content for more details
 			if start := strings.Index(responseStr, "<content>"); start != -1 {
 				start += 9
@@ -545,6 +781,7 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 					contentB64 := responseStr[start : start+end]
 					if decodedContent, err := base64.StdEncoding.DecodeString(contentB64); err == nil {
 						response.ApplicationResponse = decodedContent
+						response.applicationResponseB64 = contentB64
 					}
 				}
 			}
@@ -554,28 +791,142 @@ func (c *SUNATClient) parseTicketStatusResponse(responseData []byte, ticket stri
 	}
 
 	response.Success = false
-	response.Message = "Respuesta no reconocida de SUNAT para consulta de ticket"
+	response.Unrecognized = true
+	response.Message = c.Language.message(msgUnrecognizedTicketResponse)
 	return response, nil
 }
 
+// soapFaultDetail returns the text of the <detail> element of a SOAP fault, where some SUNAT
+// services put the actual error code and message. Nested elements are joined with " | " and
+// whitespace is collapsed; an empty string is returned when the fault has no detail
+func soapFaultDetail(responseStr string) string {
+	decoder := xml.NewDecoder(strings.NewReader(responseStr))
+
+	var (
+		depth int
+		parts []string
+	)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth > 0 || t.Name.Local == "detail" {
+				depth++
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					return strings.Join(parts, " | ")
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				if text := strings.Join(strings.Fields(string(t)), " "); text != "" {
+					parts = append(parts, text)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// soapFaultCode returns the numeric SUNAT code of a SOAP fault, taken from faultcode
+// (e.g., "soap-env:Client.0127") or from faultstring when it only holds the code
+func soapFaultCode(responseStr string) string {
+	for _, element := range []string{"faultcode", "faultstring"} {
+		start := strings.Index(responseStr, "<"+element+">")
+		if start == -1 {
+			continue
+		}
+		start += len(element) + 2
+		end := strings.Index(responseStr[start:], "</"+element+">")
+		if end == -1 {
+			continue
+		}
+
+		value := strings.TrimSpace(responseStr[start : start+end])
+		if i := strings.LastIndex(value, "."); i != -1 {
+			value = value[i+1:]
+		}
+		if value != "" && strings.Trim(value, "0123456789") == "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ErrTicketWaitCancelled is returned by WaitForTicketProcessingWithStop when the stop channel
+// is closed before the ticket is processed
+var ErrTicketWaitCancelled = errors.New("ticket wait cancelled")
+
 // WaitForTicketProcessing waits for a ticket to be processed, polling every interval
 // Returns the final status response when processing is complete or timeout is reached
 func (c *SUNATClient) WaitForTicketProcessing(ticket string, maxWaitTime time.Duration, pollInterval time.Duration) (*TicketStatusResponse, error) {
+	return c.WaitForTicketProcessingWithStop(ticket, maxWaitTime, pollInterval, nil)
+}
+
+// WaitForTicketProcessingWithStop is like WaitForTicketProcessing but returns as soon as stop
+// is closed, with the last status received (nil if none) and ErrTicketWaitCancelled.
+// A nil stop channel never cancels
+func (c *SUNATClient) WaitForTicketProcessingWithStop(ticket string, maxWaitTime time.Duration, pollInterval time.Duration, stop <-chan struct{}) (*TicketStatusResponse, error) {
+	return c.waitForTicket(context.Background(), ticket, maxWaitTime, pollInterval, stop)
+}
+
+// WaitForTicketProcessingContext is like WaitForTicketProcessing but stops polling, cancelling
+// the query in flight, when ctx is done. It then returns the last status received (nil if none)
+// and ctx.Err()
+func (c *SUNATClient) WaitForTicketProcessingContext(ctx context.Context, ticket string, maxWaitTime time.Duration, pollInterval time.Duration) (*TicketStatusResponse, error) {
+	response, err := c.waitForTicket(ctx, ticket, maxWaitTime, pollInterval, ctx.Done())
+	if errors.Is(err, ErrTicketWaitCancelled) {
+		return response, ctx.Err()
+	}
+	return response, err
+}
+
+// waitForTicket polls a ticket with ctx until it is processed, maxWaitTime elapses or stop is closed
+func (c *SUNATClient) waitForTicket(ctx context.Context, ticket string, maxWaitTime time.Duration, pollInterval time.Duration, stop <-chan struct{}) (*TicketStatusResponse, error) {
 	if pollInterval <= 0 {
 		pollInterval = 30 * time.Second // Default to 30 seconds
 	}
 
-	startTime := time.Now()
+	clock := c.clock()
+	startTime := clock.Now()
 
+	var response *TicketStatusResponse
 	for {
-		response, err := c.QueryVoidedDocumentsTicket(ticket)
+		select {
+		case <-stop:
+			return response, ErrTicketWaitCancelled
+		default:
+		}
+
+		status, err := c.QueryVoidedDocumentsTicketContext(ctx, ticket)
 		if err != nil {
+			select {
+			case <-stop:
+				return response, ErrTicketWaitCancelled
+			default:
+			}
 			return nil, fmt.Errorf("error querying ticket: %w", err)
 		}
+		response = status
 
-		// Return immediately if there's an error in the response
+		// Return immediately if there's an error in the response, except for a ticket that
+		// SUNAT has not registered yet right after it was issued
 		if !response.Success {
-			return response, nil
+			notReady := response.IsTicketNotFound() && clock.Since(startTime) < TicketNotFoundGracePeriod
+			if !notReady || clock.Since(startTime) >= maxWaitTime {
+				return response, nil
+			}
+			if !c.sleepOrStop(pollInterval, stop) {
+				return response, ErrTicketWaitCancelled
+			}
+			continue
 		}
 
 		// Return if processing is complete (success or error)
@@ -584,14 +935,60 @@ func (c *SUNATClient) WaitForTicketProcessing(ticket string, maxWaitTime time.Du
 		}
 
 		// Check timeout
-		if time.Since(startTime) >= maxWaitTime {
-			response.Message = "Timeout esperando procesamiento del ticket"
+		if clock.Since(startTime) >= maxWaitTime {
+			response.Message = c.Language.message(msgTicketWaitTimeout)
 			return response, nil
 		}
 
 		// Wait before next poll
-		time.Sleep(pollInterval)
+		if !c.sleepOrStop(pollInterval, stop) {
+			return response, ErrTicketWaitCancelled
+		}
+	}
+}
+
+// VoidAndWait validates and sends a voided documents communication, then waits for
+// its ticket to be processed and returns the final status (with CDR when available).
+// Errors while sending are wrapped with ErrVoidedDocumentsNotAccepted or the send error,
+// while processing errors are reported through the returned TicketStatusResponse. With
+// TreatAlreadyVoidedAsSuccess, documents already voided return a successful status with
+// AlreadyVoided set, without polling
+func (c *SUNATClient) VoidAndWait(request *VoidedDocumentsRequest, maxWaitTime time.Duration, pollInterval time.Duration) (*TicketStatusResponse, error) {
+	return c.VoidAndWaitContext(context.Background(), request, maxWaitTime, pollInterval)
+}
+
+// VoidAndWaitContext is like VoidAndWait, cancelling the send and the polling when ctx is done
+func (c *SUNATClient) VoidAndWaitContext(ctx context.Context, request *VoidedDocumentsRequest, maxWaitTime time.Duration, pollInterval time.Duration) (*TicketStatusResponse, error) {
+	sendResponse, err := c.SendVoidedDocumentsContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send voided documents: %w", err)
+	}
+
+	if sendResponse.AlreadyVoided {
+		status := &TicketStatusResponse{
+			Success:       true,
+			Message:       sendResponse.Message,
+			StatusCode:    "0",
+			FaultCode:     sendResponse.FaultCode,
+			FaultDetail:   sendResponse.FaultDetail,
+			ReceivedAt:    c.clock().Now(),
+			AlreadyVoided: true,
+			language:      c.Language,
+		}
+		status.StatusDescription = status.GetTicketStatusDescription()
+		return status, nil
 	}
+
+	if !sendResponse.Success || sendResponse.Ticket == "" {
+		return nil, fmt.Errorf("%w: %s", ErrVoidedDocumentsNotAccepted, sendResponse.Message)
+	}
+
+	status, err := c.WaitForTicketProcessingContext(ctx, sendResponse.Ticket, maxWaitTime, pollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for ticket %s: %w", sendResponse.Ticket, err)
+	}
+
+	return status, nil
 }
 
 // BatchQueryTickets queries multiple tickets and returns their status
@@ -618,14 +1015,26 @@ func (c *SUNATClient) BatchQueryTickets(tickets []string) ([]*TicketStatusRespon
 		}
 
 		// Small delay to avoid overwhelming SUNAT servers
-		time.Sleep(100 * time.Millisecond)
+		c.clock().Sleep(100 * time.Millisecond)
 	}
 
 	return responses, nil
 }
 
+// GenerateSeries generates the series number of a daily communication
+// Format: PREFIX-YYYYMMDD-### where ### is a sequential number
+func GenerateSeries(prefix string, date time.Time, sequential int) string {
+	return fmt.Sprintf("%s-%s-%03d", prefix, date.Format("20060102"), sequential)
+}
+
 // GenerateVoidedDocumentsSeries generates a series number for voided documents
 // Format: RA-YYYYMMDD-### where ### is a sequential number
 func GenerateVoidedDocumentsSeries(referenceDate time.Time, sequential int) string {
-	return fmt.Sprintf("RA-%s-%03d", referenceDate.Format("20060102"), sequential)
+	return GenerateSeries("RA", referenceDate, sequential)
+}
+
+// GenerateSummaryDocumentsSeries generates a series number for summary documents (resumen diario)
+// Format: RC-YYYYMMDD-### where ### is a sequential number
+func GenerateSummaryDocumentsSeries(date time.Time, sequential int) string {
+	return GenerateSeries("RC", date, sequential)
 }
\ No newline at end of file
@@ -0,0 +1,640 @@
+package sunatlib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/henrybravos/sunatlib/signer"
+	"github.com/henrybravos/sunatlib/utils"
+)
+
+const (
+	testRUC         = "20100070970"
+	testTicket      = "1700000000001"
+	testCDRContents = "fake-cdr-zip"
+)
+
+// newTestVoidedDocumentsRequest returns a valid voided documents request for tests
+func newTestVoidedDocumentsRequest() *VoidedDocumentsRequest {
+	return &VoidedDocumentsRequest{
+		RUC:           testRUC,
+		CompanyName:   "EMPRESA DE PRUEBA S.A.C.",
+		SeriesNumber:  "RA-20260427-001",
+		IssueDate:     time.Date(2026, 4, 28, 0, 0, 0, 0, time.UTC),
+		ReferenceDate: time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC),
+		Documents: []VoidedDocument{
+			{
+				DocumentTypeCode: "01",
+				DocumentSeries:   "F001",
+				DocumentNumber:   "123",
+				VoidedReason:     "ERROR EN DATOS DEL CLIENTE",
+			},
+		},
+	}
+}
+
+func sendSummaryResponse(ticket string) string {
+	return fmt.Sprintf(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:sendSummaryResponse xmlns:br="http://service.sunat.gob.pe"><ticket>%s</ticket></br:sendSummaryResponse></soap-env:Body></soap-env:Envelope>`, ticket)
+}
+
+func getStatusResponse(statusCode, content string) string {
+	return fmt.Sprintf(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><br:getStatusResponse xmlns:br="http://service.sunat.gob.pe"><status><statusCode>%s</statusCode><content>%s</content></status></br:getStatusResponse></soap-env:Body></soap-env:Envelope>`, statusCode, content)
+}
+
+func soapFaultResponse(code, message string) string {
+	return fmt.Sprintf(`<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><soap-env:Fault><faultcode>soap-env:Client.%s</faultcode><faultstring>%s</faultstring></soap-env:Fault></soap-env:Body></soap-env:Envelope>`, code, message)
+}
+
+// newSOAPTestServer routes SOAP requests by operation name to the given handlers
+func newSOAPTestServer(t *testing.T, handlers map[string]func() string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for operation, handler := range handlers {
+			if strings.Contains(string(body), "<ser:"+operation+">") {
+				w.Header().Set("Content-Type", "text/xml")
+				io.WriteString(w, handler())
+				return
+			}
+		}
+		t.Errorf("unexpected SOAP request: %s", body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+}
+
+func TestVoidAndWait(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString([]byte(testCDRContents))
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendSummary": func() string { return sendSummaryResponse(testTicket) },
+		"getStatus":   func() string { return getStatusResponse("0", cdr) },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	status, err := client.VoidAndWait(newTestVoidedDocumentsRequest(), time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("VoidAndWait() error = %v", err)
+	}
+
+	if status.Ticket != testTicket {
+		t.Errorf("Expected ticket %s, got %s", testTicket, status.Ticket)
+	}
+	if !status.IsSuccessful() {
+		t.Errorf("Expected successful status, got %s", status.StatusCode)
+	}
+	if string(status.ApplicationResponse) != testCDRContents {
+		t.Errorf("Expected CDR %q, got %q", testCDRContents, status.ApplicationResponse)
+	}
+}
+
+func TestVoidAndWait_SendRejected(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendSummary": func() string {
+			return soapFaultResponse("0111", "No tiene el perfil para enviar comprobantes electronicos")
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	_, err := client.VoidAndWait(newTestVoidedDocumentsRequest(), time.Second, time.Millisecond)
+	if !errors.Is(err, ErrVoidedDocumentsNotAccepted) {
+		t.Fatalf("Expected ErrVoidedDocumentsNotAccepted, got %v", err)
+	}
+}
+
+func TestVoidAndWait_AlreadyVoided(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendSummary": func() string {
+			return soapFaultResponse(AlreadyVoidedCode, "El comprobante fue informado previamente en una comunicación de baja")
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	if _, err := client.VoidAndWait(newTestVoidedDocumentsRequest(), time.Second, time.Millisecond); !errors.Is(err, ErrVoidedDocumentsNotAccepted) {
+		t.Fatalf("Expected ErrVoidedDocumentsNotAccepted without TreatAlreadyVoidedAsSuccess, got %v", err)
+	}
+
+	client.TreatAlreadyVoidedAsSuccess = true
+	status, err := client.VoidAndWait(newTestVoidedDocumentsRequest(), time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("VoidAndWait() error = %v", err)
+	}
+	if !status.Success || !status.AlreadyVoided || !status.IsSuccessful() {
+		t.Errorf("Expected a successful already voided status, got %+v", status)
+	}
+	if status.FaultCode != AlreadyVoidedCode || status.Error != nil {
+		t.Errorf("FaultCode = %q, Error = %v, want %s without error", status.FaultCode, status.Error, AlreadyVoidedCode)
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_Versions(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+
+	xmlContent, err := client.GenerateVoidedDocumentsXML(newTestVoidedDocumentsRequest())
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"<cbc:UBLVersionID>2.0</cbc:UBLVersionID>",
+		"<cbc:CustomizationID>1.0</cbc:CustomizationID>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateVoidedDocumentsXML() missing expected string: %s", expected)
+		}
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_ConfiguredVersions(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+
+	accepted := AcceptedVoidedDocumentsVersions
+	t.Cleanup(func() { AcceptedVoidedDocumentsVersions = accepted })
+	AcceptedVoidedDocumentsVersions = append(AcceptedVoidedDocumentsVersions, utils.DocumentVersion{UBLVersionID: "2.1", CustomizationID: "1.1"})
+
+	request := newTestVoidedDocumentsRequest()
+	request.UBLVersionID = "2.1"
+	request.CustomizationID = "1.1"
+
+	xmlContent, err := client.GenerateVoidedDocumentsXML(request)
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+	for _, expected := range []string{
+		"<cbc:UBLVersionID>2.1</cbc:UBLVersionID>",
+		"<cbc:CustomizationID>1.1</cbc:CustomizationID>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateVoidedDocumentsXML() missing expected string: %s", expected)
+		}
+	}
+
+	// Only the pairs in AcceptedVoidedDocumentsVersions are accepted
+	request.CustomizationID = ""
+	if _, err := client.GenerateVoidedDocumentsXML(request); err == nil || !strings.Contains(err.Error(), "unsupported UBLVersionID 2.1 / CustomizationID 1.0") {
+		t.Errorf("GenerateVoidedDocumentsXML() error = %v, want unsupported version", err)
+	}
+	if err := request.Validate(); err == nil {
+		t.Error("Validate() should reject an unsupported version")
+	}
+}
+
+func TestQueryVoidedDocumentsTicketAndSave(t *testing.T) {
+	cdr := base64.StdEncoding.EncodeToString([]byte(testCDRContents))
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("0", cdr) },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	cdrDir := t.TempDir()
+
+	response, err := client.QueryVoidedDocumentsTicketAndSave(testTicket, cdrDir)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicketAndSave() error = %v", err)
+	}
+
+	expectedPath := filepath.Join(cdrDir, "R-"+testRUC+"-"+testTicket+".zip")
+	if response.CDRPath != expectedPath {
+		t.Errorf("Expected CDR path %s, got %s", expectedPath, response.CDRPath)
+	}
+
+	content, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("failed to read saved CDR: %v", err)
+	}
+	if string(content) != testCDRContents {
+		t.Errorf("Expected CDR %q, got %q", testCDRContents, content)
+	}
+}
+
+func TestQueryVoidedDocumentsTicketAndSave_InProgress(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("98", "") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	cdrDir := filepath.Join(t.TempDir(), "cdr")
+
+	response, err := client.QueryVoidedDocumentsTicketAndSave(testTicket, cdrDir)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicketAndSave() error = %v", err)
+	}
+
+	if response.CDRPath != "" {
+		t.Errorf("Expected no CDR path while in progress, got %s", response.CDRPath)
+	}
+	if _, err := os.Stat(cdrDir); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written while in progress")
+	}
+}
+
+func TestVoidedDocumentsRequest_Validate_Duplicates(t *testing.T) {
+	request := newTestVoidedDocumentsRequest()
+	request.Documents = append(request.Documents,
+		VoidedDocument{DocumentTypeCode: "01", DocumentSeries: "F001", DocumentNumber: "124", VoidedReason: "ERROR EN MONTO"},
+		request.Documents[0],
+	)
+
+	err := request.Validate()
+	if err == nil {
+		t.Fatal("Expected error for duplicated document")
+	}
+	if !contains(err.Error(), "document 3: duplicate of document 1 (01-F001-123)") {
+		t.Errorf("Validate() error = %v, want duplicate identification", err)
+	}
+}
+
+func TestVoidedDocumentsRequest_Validate_DuplicatesZeroPadded(t *testing.T) {
+	request := newTestVoidedDocumentsRequest()
+	duplicate := request.Documents[0]
+	duplicate.DocumentNumber = "00000" + duplicate.DocumentNumber
+	request.Documents = append(request.Documents, duplicate)
+
+	err := request.Validate()
+	if err == nil {
+		t.Fatal("Expected error for a zero-padded duplicate")
+	}
+	if !contains(err.Error(), "duplicate of document 1") {
+		t.Errorf("Validate() error = %v, want duplicate identification", err)
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_SignatureID(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+
+	tests := []struct {
+		name        string
+		signatureID string
+		expected    string
+	}{
+		{"Default", "", signer.DefaultSignatureID},
+		{"Custom", "SignatureSP-RA-001", "SignatureSP-RA-001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newTestVoidedDocumentsRequest()
+			request.SignatureID = tt.signatureID
+
+			xmlContent, err := client.GenerateVoidedDocumentsXML(request)
+			if err != nil {
+				t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+			}
+
+			for _, expected := range []string{
+				"<cac:Signature>\n<cbc:ID>" + tt.expected + "</cbc:ID>",
+				"<cbc:URI>#" + tt.expected + "</cbc:URI>",
+			} {
+				if !strings.Contains(string(xmlContent), expected) {
+					t.Errorf("GenerateVoidedDocumentsXML() missing expected string: %s", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestVoidedDocument_Validate_VoidedReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		wantErr bool
+		msg     string
+	}{
+		{"Acceptable", "ERROR EN DATOS DEL CLIENTE", false, ""},
+		{"Minimum Length", "ERR", false, ""},
+		{"Maximum Length", strings.Repeat("A", MaxVoidedReasonLength), false, ""},
+		{"Control Characters Stripped", "ERROR\x00\x07 EN MONTO\n", false, ""},
+		{"Empty", "", true, "voided reason is required"},
+		{"Only Control Characters", "\t\n\x01", true, "voided reason is required"},
+		{"Too Short", "ER", true, "voided reason must be between 3 and 100 characters, got 2"},
+		{"Too Short After Cleaning", " E\x00R ", true, "got 2"},
+		{"Too Long", strings.Repeat("A", MaxVoidedReasonLength+1), true, "got 101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := VoidedDocument{DocumentTypeCode: "01", DocumentSeries: "F001", DocumentNumber: "123", VoidedReason: tt.reason}
+
+			err := doc.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !contains(err.Error(), tt.msg) {
+				t.Errorf("Validate() error = %v, want msg containing %v", err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_StripsControlCharacters(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+	request := newTestVoidedDocumentsRequest()
+	request.Documents[0].VoidedReason = "ERROR\x07 EN\nMONTO "
+
+	xmlContent, err := client.GenerateVoidedDocumentsXML(request)
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+
+	expected := "<sac:VoidReasonDescription>ERROR EN MONTO</sac:VoidReasonDescription>"
+	if !strings.Contains(string(xmlContent), expected) {
+		t.Errorf("GenerateVoidedDocumentsXML() missing expected string: %s", expected)
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_EncodingMatchesDeclaration(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+	request := newTestVoidedDocumentsRequest()
+	request.CompanyName = "CONSTRUCCIÓN Y DISEÑO S.A.C."
+	request.Documents[0].VoidedReason = "ANULACIÓN POR ERROR EN AÑO"
+
+	xmlContent, err := client.GenerateVoidedDocumentsXML(request)
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+
+	if !bytes.HasPrefix(xmlContent, []byte(`<?xml version="1.0" encoding="UTF-8"`)) {
+		t.Fatalf("Expected UTF-8 declaration, got %q", xmlContent[:bytes.IndexByte(xmlContent, '\n')])
+	}
+	if !utf8.Valid(xmlContent) {
+		t.Fatal("Expected UTF-8 bytes matching the declared encoding")
+	}
+
+	// encoding/xml only decodes UTF-8 without a CharsetReader, so a mismatched
+	// declaration or byte encoding fails here
+	var parsed struct {
+		Supplier struct {
+			Name string `xml:"Party>PartyLegalEntity>RegistrationName"`
+		} `xml:"AccountingSupplierParty"`
+		Line struct {
+			Reason string `xml:"VoidReasonDescription"`
+		} `xml:"VoidedDocumentsLine"`
+	}
+	if err := xml.Unmarshal(xmlContent, &parsed); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if parsed.Supplier.Name != request.CompanyName {
+		t.Errorf("RegistrationName = %q, want %q", parsed.Supplier.Name, request.CompanyName)
+	}
+	if parsed.Line.Reason != request.Documents[0].VoidedReason {
+		t.Errorf("VoidReasonDescription = %q, want %q", parsed.Line.Reason, request.Documents[0].VoidedReason)
+	}
+}
+
+func TestGenerateVoidedDocumentsXML_CombinedSerieNumero(t *testing.T) {
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", "")
+	request := newTestVoidedDocumentsRequest()
+	request.Documents[0].DocumentSeries = "F001-123"
+	request.Documents[0].DocumentNumber = ""
+
+	if err := request.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	xmlContent, err := client.GenerateVoidedDocumentsXML(request)
+	if err != nil {
+		t.Fatalf("GenerateVoidedDocumentsXML() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"<sac:DocumentSerialID>F001</sac:DocumentSerialID>",
+		"<sac:DocumentNumberID>123</sac:DocumentNumberID>",
+	} {
+		if !strings.Contains(string(xmlContent), expected) {
+			t.Errorf("GenerateVoidedDocumentsXML() missing expected string: %s", expected)
+		}
+	}
+
+	request.Documents[0].DocumentSeries = "F001-ABC"
+	if err := request.Validate(); err == nil {
+		t.Error("Expected error for malformed SERIE-NUMERO")
+	}
+}
+
+func TestGenerateSeries(t *testing.T) {
+	date := time.Date(2026, 4, 27, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "Voided Documents", got: GenerateVoidedDocumentsSeries(date, 1), want: "RA-20260427-001"},
+		{name: "Summary Documents", got: GenerateSummaryDocumentsSeries(date, 12), want: "RC-20260427-012"},
+		{name: "Generic Prefix", got: GenerateSeries("RR", date, 123), want: "RR-20260427-123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoidedDocumentsRequest_Validate_IssueDateBeforeReferenceDate(t *testing.T) {
+	request := newTestVoidedDocumentsRequest()
+	request.IssueDate, request.ReferenceDate = request.ReferenceDate, request.IssueDate
+
+	if err := request.Validate(); err == nil || !strings.Contains(err.Error(), "before reference date") {
+		t.Errorf("Validate() error = %v, want issue date before reference date error", err)
+	}
+
+	// Same day communications are accepted regardless of the time of day
+	request.ReferenceDate = time.Date(2026, 4, 27, 18, 0, 0, 0, time.UTC)
+	request.IssueDate = time.Date(2026, 4, 27, 9, 0, 0, 0, time.UTC)
+	if err := request.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil for same day dates", err)
+	}
+}
+
+func TestWaitForTicketProcessing_TicketNotReady(t *testing.T) {
+	var polls int
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string {
+			polls++
+			if polls == 1 {
+				return soapFaultResponse(TicketNotFoundCode, "El ticket no existe")
+			}
+			return getStatusResponse("0", base64.StdEncoding.EncodeToString([]byte(testCDRContents)))
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	status, err := client.WaitForTicketProcessing(testTicket, time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForTicketProcessing() error = %v", err)
+	}
+	if !status.IsSuccessful() || polls != 2 {
+		t.Errorf("Expected success after a not-found poll, got %+v after %d polls", status, polls)
+	}
+}
+
+func TestWaitForTicketProcessing_PermanentFault(t *testing.T) {
+	var polls int
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string {
+			polls++
+			return soapFaultResponse("0111", "No tiene el perfil para enviar comprobantes electronicos")
+		},
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+	status, err := client.WaitForTicketProcessing(testTicket, time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForTicketProcessing() error = %v", err)
+	}
+	if status.Success || status.FaultCode != "0111" || polls != 1 {
+		t.Errorf("Expected the permanent fault to be returned on the first poll, got %+v after %d polls", status, polls)
+	}
+}
+
+func TestSendVoidedDocuments_AlreadyVoided(t *testing.T) {
+	tests := []struct {
+		name              string
+		treatAsSuccess    bool
+		faultCode         string
+		wantSuccess       bool
+		wantAlreadyVoided bool
+	}{
+		{"already voided as success", true, AlreadyVoidedCode, true, true},
+		{"already voided as fault", false, AlreadyVoidedCode, false, false},
+		{"other fault", true, "2105", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSOAPTestServer(t, map[string]func() string{
+				"sendSummary": func() string {
+					return soapFaultResponse(tt.faultCode, "El comprobante fue informado previamente en una comunicación de baja")
+				},
+			})
+			defer server.Close()
+
+			client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+			client.TreatAlreadyVoidedAsSuccess = tt.treatAsSuccess
+
+			response, err := client.SendVoidedDocuments(newTestVoidedDocumentsRequest())
+			if err != nil {
+				t.Fatalf("SendVoidedDocuments() error = %v", err)
+			}
+			if response.Success != tt.wantSuccess || response.AlreadyVoided != tt.wantAlreadyVoided {
+				t.Errorf("Success = %v, AlreadyVoided = %v, want %v, %v",
+					response.Success, response.AlreadyVoided, tt.wantSuccess, tt.wantAlreadyVoided)
+			}
+			if response.FaultCode != tt.faultCode {
+				t.Errorf("FaultCode = %q, want %q", response.FaultCode, tt.faultCode)
+			}
+			if tt.wantAlreadyVoided && response.Error != nil {
+				t.Errorf("Error = %v, want nil for an already voided success", response.Error)
+			}
+		})
+	}
+}
+
+func TestWaitForTicketProcessingWithStop(t *testing.T) {
+	server := newSOAPTestServer(t, map[string]func() string{
+		"getStatus": func() string { return getStatusResponse("98", "") },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	stop := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+
+	start := time.Now()
+	status, err := client.WaitForTicketProcessingWithStop(testTicket, time.Minute, 10*time.Second, stop)
+	if !errors.Is(err, ErrTicketWaitCancelled) {
+		t.Fatalf("WaitForTicketProcessingWithStop() error = %v, want ErrTicketWaitCancelled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("WaitForTicketProcessingWithStop() returned after %s, want a prompt return", elapsed)
+	}
+	if status == nil || status.StatusCode != "98" {
+		t.Errorf("status = %+v, want the last in-progress status", status)
+	}
+
+	// A closed stop channel cancels before querying
+	status, err = client.WaitForTicketProcessingWithStop(testTicket, time.Minute, time.Millisecond, stop)
+	if !errors.Is(err, ErrTicketWaitCancelled) || status != nil {
+		t.Errorf("got %+v, %v, want nil status and ErrTicketWaitCancelled", status, err)
+	}
+}
+
+func TestSOAPFaultDetail(t *testing.T) {
+	fault := `<soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><soap-env:Fault>
+<faultcode>soap-env:Server</faultcode>
+<faultstring>El sistema no puede
+responder su solicitud</faultstring>
+<detail>
+  <ns0:error xmlns:ns0="http://service.sunat.gob.pe">
+    <ns0:code>2335</ns0:code>
+    <ns0:message>El documento
+      ya fue informado</ns0:message>
+  </ns0:error>
+</detail>
+</soap-env:Fault></soap-env:Body></soap-env:Envelope>`
+	wantDetail := "2335 | El documento ya fue informado"
+
+	server := newSOAPTestServer(t, map[string]func() string{
+		"sendBill":    func() string { return fault },
+		"sendSummary": func() string { return fault },
+		"getStatus":   func() string { return fault },
+	})
+	defer server.Close()
+
+	client := NewSUNATClient(testRUC, "MODDATOS", "MODDATOS", server.URL)
+
+	billResponse, err := client.SendToSUNAT([]byte("<Invoice/>"), "01", "F001-1")
+	if err != nil {
+		t.Fatalf("SendToSUNAT() error = %v", err)
+	}
+	if billResponse.Success || billResponse.FaultDetail != wantDetail {
+		t.Errorf("sendBill FaultDetail = %q, want %q", billResponse.FaultDetail, wantDetail)
+	}
+	if !strings.Contains(billResponse.Message, "responder su solicitud") {
+		t.Errorf("sendBill Message = %q, want the whole faultstring", billResponse.Message)
+	}
+
+	voidResponse, err := client.SendVoidedDocuments(newTestVoidedDocumentsRequest())
+	if err != nil {
+		t.Fatalf("SendVoidedDocuments() error = %v", err)
+	}
+	if voidResponse.FaultDetail != wantDetail {
+		t.Errorf("sendSummary FaultDetail = %q, want %q", voidResponse.FaultDetail, wantDetail)
+	}
+
+	status, err := client.QueryVoidedDocumentsTicket(testTicket)
+	if err != nil {
+		t.Fatalf("QueryVoidedDocumentsTicket() error = %v", err)
+	}
+	if status.FaultDetail != wantDetail {
+		t.Errorf("getStatus FaultDetail = %q, want %q", status.FaultDetail, wantDetail)
+	}
+
+	if detail := soapFaultDetail(soapFaultResponse("0127", "El ticket no existe")); detail != "" {
+		t.Errorf("soapFaultDetail() without detail = %q, want empty", detail)
+	}
+}